@@ -6,12 +6,11 @@ import { provideRouter } from '@angular/router';
 import { routes } from './app.routes';
 import { provideClientHydration, withEventReplay } from '@angular/platform-browser';
 import {
-  HTTP_INTERCEPTORS,
   provideHttpClient,
   withInterceptors,
   withInterceptorsFromDi,
 } from '@angular/common/http';
-import { BaseUrlInterceptor } from './shared/interseptors/base-url.interseptor';
+import { httpInterceptorProviders } from './shared/interseptors';
 
 export const appConfig: ApplicationConfig = {
   providers: [
@@ -22,6 +21,6 @@ export const appConfig: ApplicationConfig = {
     provideClientHydration(withEventReplay()),
     provideEventPlugins(),
     provideHttpClient(withInterceptorsFromDi()),
-    { provide: HTTP_INTERCEPTORS, useClass: BaseUrlInterceptor, multi: true },
+    httpInterceptorProviders,
   ],
 };
@@ -0,0 +1,9 @@
+import { HTTP_INTERCEPTORS } from '@angular/common/http';
+import { BaseUrlInterceptor } from './base-url.interseptor';
+import { XsrfInterceptor } from './xsrf.interseptor';
+
+// Порядок важен: XsrfInterceptor смотрит на уже абсолютный URL.
+export const httpInterceptorProviders = [
+  { provide: HTTP_INTERCEPTORS, useClass: BaseUrlInterceptor, multi: true },
+  { provide: HTTP_INTERCEPTORS, useClass: XsrfInterceptor, multi: true },
+];
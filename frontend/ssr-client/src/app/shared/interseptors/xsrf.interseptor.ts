@@ -0,0 +1,33 @@
+import { inject, Injectable } from '@angular/core';
+import {
+  HttpRequest,
+  HttpHandler,
+  HttpEvent,
+  HttpInterceptor,
+  HttpXsrfTokenExtractor
+} from '@angular/common/http';
+import { Observable } from 'rxjs';
+import { environment } from '../../../environments/environment.development';
+
+export const XSRF_HEADER = 'X-XSRF-TOKEN';
+
+// Angular сам кладёт XSRF-TOKEN в заголовок только для относительных URL,
+// а BaseUrlInterceptor делает все запросы к API абсолютными. Поэтому токен
+// добавляется здесь, и только для изменяющих запросов к нашему API.
+@Injectable()
+export class XsrfInterceptor implements HttpInterceptor {
+  private baseUrl = environment.baseUrl;
+  private tokenExtractor = inject(HttpXsrfTokenExtractor);
+
+  intercept(request: HttpRequest<unknown>, next: HttpHandler): Observable<HttpEvent<unknown>> {
+    const safe = request.method === 'GET' || request.method === 'HEAD' || request.method === 'OPTIONS';
+    if (safe || !request.url.startsWith(`${this.baseUrl}/`) || request.headers.has(XSRF_HEADER)) {
+      return next.handle(request);
+    }
+    const token = this.tokenExtractor.getToken();
+    if (token === null) {
+      return next.handle(request);
+    }
+    return next.handle(request.clone({ setHeaders: { [XSRF_HEADER]: token } }));
+  }
+}
@@ -0,0 +1,24 @@
+export type Vec2 = {
+  x: number;
+  y: number;
+};
+
+export type Player = {
+  id: string;
+  position: Vec2;
+  velocity: Vec2;
+  // seq последнего применённого сервером ввода
+  lastInput: number;
+};
+
+export type GameObject = {
+  id: string;
+  kind: string;
+  position: Vec2;
+};
+
+export type GameState = {
+  tick: number;
+  players: Player[];
+  objects: GameObject[];
+};
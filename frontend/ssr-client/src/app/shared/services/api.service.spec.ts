@@ -7,7 +7,13 @@ import {
 } from '@angular/common/http';
 import { of, switchMap, throwError } from 'rxjs';
 import { provideZonelessChangeDetection } from '@angular/core';
-import { provideHttpClientTesting } from '@angular/common/http/testing';
+import {
+  HttpTestingController,
+  provideHttpClientTesting,
+} from '@angular/common/http/testing';
+import { httpInterceptorProviders } from '../interseptors';
+import { XSRF_HEADER } from '../interseptors/xsrf.interseptor';
+import { environment } from '../../../environments/environment.development';
 
 describe('ApiService', () => {
   let service: ApiService;
@@ -137,14 +143,14 @@ describe('ApiService', () => {
   // Logout tests
   it('should call logout endpoint', (done) => {
     service.logout().subscribe(() => {
-      expect(httpClient.get).toHaveBeenCalledWith('logout');
+      expect(httpClient.post).toHaveBeenCalledWith('logout', null);
       done();
     });
   });
 
   it('should return expected logout response', (done) => {
     const testResponse = {};
-    (httpClient.get as jasmine.Spy).and.returnValue(of(testResponse));
+    (httpClient.post as jasmine.Spy).and.returnValue(of(testResponse));
 
     service.logout().subscribe((response) => {
       expect(response).toEqual(testResponse);
@@ -195,3 +201,54 @@ describe('ApiService', () => {
     });
   });
 });
+
+describe('ApiService through the interceptors', () => {
+  let service: ApiService;
+  let httpTesting: HttpTestingController;
+
+  beforeEach(() => {
+    TestBed.configureTestingModule({
+      providers: [
+        ApiService,
+        provideZonelessChangeDetection(),
+        provideHttpClient(withInterceptorsFromDi()),
+        provideHttpClientTesting(),
+        httpInterceptorProviders,
+      ],
+    });
+    service = TestBed.inject(ApiService);
+    httpTesting = TestBed.inject(HttpTestingController);
+    // go-auth выдаёт эту cookie при входе.
+    document.cookie = 'XSRF-TOKEN=csrf-token; path=/';
+  });
+
+  afterEach(() => {
+    httpTesting.verify();
+    document.cookie = 'XSRF-TOKEN=; path=/; expires=Thu, 01 Jan 1970 00:00:00 GMT';
+    TestBed.resetTestingModule();
+  });
+
+  it('should send the XSRF token with logout to the absolute API url', (done) => {
+    service.logout().subscribe({
+      next: (response) => {
+        expect(response).toBeNull();
+        done();
+      },
+      error: done.fail,
+    });
+
+    const req = httpTesting.expectOne(`${environment.baseUrl}/logout`);
+    expect(req.request.method).toBe('POST');
+    expect(req.request.withCredentials).toBeTrue();
+    expect(req.request.headers.get(XSRF_HEADER)).toBe('csrf-token');
+    req.flush(null, { status: 204, statusText: 'No Content' });
+  });
+
+  it('should not send the XSRF token to other hosts', () => {
+    TestBed.inject(HttpClient).post('https://example.com/collect', null).subscribe();
+
+    const req = httpTesting.expectOne('https://example.com/collect');
+    expect(req.request.headers.has(XSRF_HEADER)).toBeFalse();
+    req.flush(null);
+  });
+});
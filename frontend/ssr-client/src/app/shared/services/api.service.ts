@@ -32,6 +32,6 @@ export class ApiService {
     );
   }
   logout() {
-    return this.http.get('logout');
+    return this.http.post<null>('logout', null);
   }
 }
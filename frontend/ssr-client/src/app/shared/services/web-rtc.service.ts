@@ -1,12 +1,18 @@
 import { Injectable } from '@angular/core';
 import { BehaviorSubject } from 'rxjs';
+import { GameState } from '../models/game-state';
+import { SnapshotReceiver } from '../utils/snapshot';
 
 @Injectable({ providedIn: 'root' })
 export class WebRTCService {
   peerConnection!: RTCPeerConnection;
   dataChannel!: RTCDataChannel;
+  unreliableChannel!: RTCDataChannel;
   connectionState$ = new BehaviorSubject<string>('disconnected');
   iceGatheringState$ = new BehaviorSubject<string>('new');
+  // Последнее состояние игры от сервера
+  gameState$ = new BehaviorSubject<GameState | null>(null);
+  private snapshots = new SnapshotReceiver();
 
   async initPeer(): Promise<RTCSessionDescriptionInit> {
     const peerConnection = new RTCPeerConnection({
@@ -52,12 +58,19 @@ export class WebRTCService {
       }
     };
 
-    // Создаем канал для игровых данных
-    this.dataChannel = peerConnection.createDataChannel('gameData', {
+    // Надёжный канал: чат, инвентарь, игровые события
+    this.dataChannel = peerConnection.createDataChannel('reliable', {
       ordered: true, // Гарантирует порядок доставки сообщений
-      // maxPacketLifeTime: 1000, // 1 секунда
-      // maxRetransmits: 3
     });
+    // Ненадёжный канал: снапшоты состояния и ввод, без повторной отправки
+    this.unreliableChannel = peerConnection.createDataChannel('unreliable', {
+      ordered: false,
+      maxRetransmits: 0,
+    });
+    this.unreliableChannel.binaryType = 'arraybuffer';
+    this.snapshots.reset();
+    this.gameState$.next(null);
+    this.unreliableChannel.onmessage = (e) => this.onSnapshot(e.data);
 
     this.dataChannel.onopen = () => {
       console.log('Data channel opened!');
@@ -104,6 +117,39 @@ export class WebRTCService {
     );
   }
 
+  // Декодирует снапшот и подтверждает его, чтобы сервер слал дельты
+  // относительно него. Канал не гарантирует порядок, поэтому опоздавшие
+  // снапшоты подтверждаются, но не заменяют более новое состояние.
+  private onSnapshot(data: ArrayBuffer) {
+    let state: GameState | undefined;
+    try {
+      state = this.snapshots.receive(data);
+    } catch (error) {
+      console.error('Bad snapshot:', error);
+      return;
+    }
+    if (!state) {
+      // Базы уже нет; без подтверждений сервер перейдёт на полные снапшоты
+      return;
+    }
+    this.sendAck(state.tick);
+    const current = this.gameState$.value;
+    if (!current || state.tick > current.tick) {
+      this.gameState$.next(state);
+    }
+  }
+
+  private sendAck(tick: number) {
+    if (this.unreliableChannel?.readyState !== 'open') {
+      return;
+    }
+    try {
+      this.unreliableChannel.send(JSON.stringify({ type: 'ack', tick }));
+    } catch (error) {
+      console.error('Error sending ack:', error);
+    }
+  }
+
   sendCommand(command: any): boolean {
     console.log(this.dataChannel);
 
@@ -0,0 +1,100 @@
+import { GameState } from '../models/game-state';
+import {
+  decodeSnapshot,
+  SnapshotBaseError,
+  SnapshotError,
+  SnapshotReceiver,
+} from './snapshot';
+
+// Снапшоты закодированы pkg/snapshot в go-game
+const FULL =
+  '01010a0205616c696365900c8070001953ee0703626f6220004000000000000001056368657374803e401f046c6f6f74';
+const DELTA =
+  '0102ac020a0103626f62020505616c696365100f8070ac0207037a6f6560008000e0ff000000000108056368657374046f70656e';
+
+const BASE_STATE: GameState = {
+  tick: 10,
+  players: [
+    {
+      id: 'alice',
+      position: { x: 100.5, y: 900 },
+      velocity: { x: 200, y: -141.40625 },
+      lastInput: 7,
+    },
+    {
+      id: 'bob',
+      position: { x: 1, y: 2 },
+      velocity: { x: 0, y: 0 },
+      lastInput: 0,
+    },
+  ],
+  objects: [{ id: 'chest', kind: 'loot', position: { x: 500, y: 250 } }],
+};
+
+const NEXT_STATE: GameState = {
+  tick: 300,
+  players: [
+    {
+      id: 'alice',
+      position: { x: 120.5, y: 900 },
+      velocity: { x: 200, y: -141.40625 },
+      lastInput: 300,
+    },
+    {
+      id: 'zoe',
+      position: { x: 3, y: 4 },
+      velocity: { x: -1, y: 0 },
+      lastInput: 0,
+    },
+  ],
+  objects: [{ id: 'chest', kind: 'open', position: { x: 500, y: 250 } }],
+};
+
+function bytes(hex: string): ArrayBuffer {
+  const b = new Uint8Array(hex.length / 2);
+  for (let i = 0; i < b.length; i++) {
+    b[i] = parseInt(hex.slice(i * 2, i * 2 + 2), 16);
+  }
+  return b.buffer;
+}
+
+describe('snapshot', () => {
+  it('decodes a full snapshot', () => {
+    expect(decodeSnapshot(bytes(FULL))).toEqual(BASE_STATE);
+  });
+
+  it('decodes a delta against its base', () => {
+    expect(decodeSnapshot(bytes(DELTA), BASE_STATE)).toEqual(NEXT_STATE);
+    expect(() => decodeSnapshot(bytes(DELTA))).toThrowError(SnapshotBaseError);
+    expect(() => decodeSnapshot(bytes(DELTA), NEXT_STATE)).toThrowError(
+      SnapshotBaseError
+    );
+  });
+
+  it('rejects truncated and unknown snapshots', () => {
+    const full = bytes(FULL);
+    for (let i = 0; i < full.byteLength; i++) {
+      expect(() => decodeSnapshot(full.slice(0, i))).toThrowError(
+        SnapshotError
+      );
+    }
+    expect(() => decodeSnapshot(bytes('02' + FULL.slice(2)))).toThrowError(
+      SnapshotError,
+      'snapshot: unsupported version'
+    );
+    expect(() => decodeSnapshot(bytes(FULL + '00'))).toThrowError(
+      SnapshotError,
+      'snapshot: malformed'
+    );
+  });
+
+  it('keeps the states deltas are based on', () => {
+    const receiver = new SnapshotReceiver();
+    expect(receiver.receive(bytes(DELTA))).toBeUndefined();
+    expect(receiver.receive(bytes(FULL))).toEqual(BASE_STATE);
+    expect(receiver.receive(bytes(DELTA))).toEqual(NEXT_STATE);
+
+    receiver.reset();
+    expect(receiver.receive(bytes(DELTA))).toBeUndefined();
+  });
+});
@@ -0,0 +1,316 @@
+import { GameObject, GameState, Player } from '../models/game-state';
+
+// Двоичный формат снапшотов go-game (pkg/snapshot). Снапшот начинается с
+// версии схемы, вида и тика. Полный снапшот перечисляет всех игроков и
+// объекты, дельта — тик базы (снапшота, который клиент подтвердил) и только
+// удалённое и изменённое с тех пор. Целые числа — varint, координаты и
+// скорости — числа с фиксированной точкой в 1/SCALE, little endian uint16
+// и int16.
+export const SNAPSHOT_VERSION = 1;
+export const KIND_FULL = 1;
+export const KIND_DELTA = 2;
+export const SCALE = 32;
+// Насколько старой может быть база дельты, в тиках
+export const MAX_DELTA_AGE = 32;
+
+const MAX_ID_LEN = 255;
+
+const FIELD_POSITION = 1 << 0;
+const FIELD_VELOCITY = 1 << 1;
+const FIELD_LAST_INPUT = 1 << 2;
+const FIELD_KIND = 1 << 3;
+
+const PLAYER_FIELDS = FIELD_POSITION | FIELD_VELOCITY | FIELD_LAST_INPUT;
+const OBJECT_FIELDS = FIELD_POSITION | FIELD_KIND;
+
+export class SnapshotError extends Error {}
+
+// Дельта пришла относительно снапшота, которого у клиента нет
+export class SnapshotBaseError extends SnapshotError {}
+
+// Тик базы снапшота или undefined для полного
+export function snapshotBaseTick(data: ArrayBuffer): number | undefined {
+  const r = new Reader(data);
+  r.version();
+  const kind = r.byte();
+  r.uvarint();
+  const base = kind === KIND_DELTA ? r.uvarint() : undefined;
+  r.check();
+  return base;
+}
+
+// Декодирует снапшот. Для дельты нужна база, относительно которой она
+// закодирована. Игроки и объекты отсортированы по id.
+export function decodeSnapshot(data: ArrayBuffer, base?: GameState): GameState {
+  const r = new Reader(data);
+  r.version();
+  const kind = r.byte();
+  const tick = r.uvarint();
+  r.check();
+
+  let players: Player[];
+  let objects: GameObject[];
+  switch (kind) {
+    case KIND_FULL: {
+      players = [];
+      for (let n = r.count(); n > 0 && !r.failed; n--) {
+        players.push(r.player(emptyPlayer(r.id()), PLAYER_FIELDS));
+      }
+      objects = [];
+      for (let n = r.count(); n > 0 && !r.failed; n--) {
+        objects.push(r.object(emptyObject(r.id()), OBJECT_FIELDS));
+      }
+      break;
+    }
+    case KIND_DELTA: {
+      const baseTick = r.uvarint();
+      r.check();
+      if (!base || base.tick !== baseTick) {
+        throw new SnapshotBaseError('snapshot: delta base not available');
+      }
+      const byId = new Map(base.players.map((p) => [p.id, p]));
+      for (let n = r.count(); n > 0 && !r.failed; n--) {
+        byId.delete(r.id());
+      }
+      for (let n = r.count(); n > 0 && !r.failed; n--) {
+        const mask = r.byte();
+        const id = r.id();
+        const old = byId.get(id);
+        if (!old && mask !== PLAYER_FIELDS) {
+          r.fail();
+        }
+        byId.set(id, r.player(copyPlayer(old ?? emptyPlayer(id)), mask));
+      }
+      players = [...byId.values()];
+
+      const objectsById = new Map(base.objects.map((o) => [o.id, o]));
+      for (let n = r.count(); n > 0 && !r.failed; n--) {
+        objectsById.delete(r.id());
+      }
+      for (let n = r.count(); n > 0 && !r.failed; n--) {
+        const mask = r.byte();
+        const id = r.id();
+        const old = objectsById.get(id);
+        if (!old && mask !== OBJECT_FIELDS) {
+          r.fail();
+        }
+        objectsById.set(id, r.object(copyObject(old ?? emptyObject(id)), mask));
+      }
+      objects = [...objectsById.values()];
+      break;
+    }
+    default:
+      throw new SnapshotError('snapshot: malformed');
+  }
+  r.check();
+  if (!r.done) {
+    throw new SnapshotError('snapshot: malformed');
+  }
+  players.sort((a, b) => compareIds(a.id, b.id));
+  objects.sort((a, b) => compareIds(a.id, b.id));
+  return { tick, players, objects };
+}
+
+// Хранит последние снапшоты, чтобы декодировать дельты относительно них.
+// Каждый декодированный снапшот клиент подтверждает через {"type":"ack"};
+// сервер берёт за базу последний подтверждённый, а если подтверждений нет
+// дольше MAX_DELTA_AGE тиков, снова шлёт полные снапшоты.
+export class SnapshotReceiver {
+  private states = new Map<number, GameState>();
+
+  // Возвращает состояние или undefined, если базы дельты уже нет
+  receive(data: ArrayBuffer): GameState | undefined {
+    const baseTick = snapshotBaseTick(data);
+    const base =
+      baseTick === undefined ? undefined : this.states.get(baseTick);
+    let state: GameState;
+    try {
+      state = decodeSnapshot(data, base);
+    } catch (error) {
+      if (error instanceof SnapshotBaseError) {
+        return undefined;
+      }
+      throw error;
+    }
+    this.states.set(state.tick, state);
+    // Подтверждения опаздывают, поэтому храним вдвое больше, чем может
+    // понадобиться серверу
+    for (const tick of this.states.keys()) {
+      if (tick + 2 * MAX_DELTA_AGE < state.tick) {
+        this.states.delete(tick);
+      }
+    }
+    return state;
+  }
+
+  reset() {
+    this.states.clear();
+  }
+}
+
+function emptyPlayer(id: string): Player {
+  return {
+    id,
+    position: { x: 0, y: 0 },
+    velocity: { x: 0, y: 0 },
+    lastInput: 0,
+  };
+}
+
+function copyPlayer(p: Player): Player {
+  return { ...p, position: { ...p.position }, velocity: { ...p.velocity } };
+}
+
+function emptyObject(id: string): GameObject {
+  return { id, kind: '', position: { x: 0, y: 0 } };
+}
+
+function copyObject(o: GameObject): GameObject {
+  return { ...o, position: { ...o.position } };
+}
+
+// Порядок байтов, как sort.Strings в Go, для ASCII id
+function compareIds(a: string, b: string): number {
+  return a < b ? -1 : a > b ? 1 : 0;
+}
+
+// Запоминает первую ошибку, чтобы проверять её один раз после серии чтений
+class Reader {
+  private view: DataView;
+  private offset = 0;
+  failed = false;
+
+  constructor(data: ArrayBuffer) {
+    this.view = new DataView(data);
+  }
+
+  get done(): boolean {
+    return this.offset === this.view.byteLength;
+  }
+
+  private get left(): number {
+    return this.view.byteLength - this.offset;
+  }
+
+  fail() {
+    this.failed = true;
+    this.offset = this.view.byteLength;
+  }
+
+  check() {
+    if (this.failed) {
+      throw new SnapshotError('snapshot: malformed');
+    }
+  }
+
+  version() {
+    const v = this.byte();
+    this.check();
+    if (v !== SNAPSHOT_VERSION) {
+      throw new SnapshotError('snapshot: unsupported version');
+    }
+  }
+
+  byte(): number {
+    if (this.left < 1) {
+      this.fail();
+      return 0;
+    }
+    return this.view.getUint8(this.offset++);
+  }
+
+  uint16(): number {
+    if (this.left < 2) {
+      this.fail();
+      return 0;
+    }
+    const v = this.view.getUint16(this.offset, true);
+    this.offset += 2;
+    return v;
+  }
+
+  int16(): number {
+    if (this.left < 2) {
+      this.fail();
+      return 0;
+    }
+    const v = this.view.getInt16(this.offset, true);
+    this.offset += 2;
+    return v;
+  }
+
+  // Умножение вместо сдвигов: побитовые операции в JS 32-битные
+  uvarint(): number {
+    let value = 0;
+    let mul = 1;
+    for (let i = 0; i < 10 && this.left > 0; i++) {
+      const b = this.view.getUint8(this.offset++);
+      value += (b & 0x7f) * mul;
+      if (b < 0x80) {
+        return value;
+      }
+      mul *= 128;
+    }
+    this.fail();
+    return 0;
+  }
+
+  // Каждая запись занимает хотя бы байт, так что испорченное количество не
+  // заставит выделить больше, чем есть в данных
+  count(): number {
+    const n = this.uvarint();
+    if (n > this.left) {
+      this.fail();
+      return 0;
+    }
+    return n;
+  }
+
+  id(): string {
+    const n = this.uvarint();
+    if (n > MAX_ID_LEN || n > this.left) {
+      this.fail();
+      return '';
+    }
+    const bytes = new Uint8Array(
+      this.view.buffer,
+      this.view.byteOffset + this.offset,
+      n
+    );
+    this.offset += n;
+    return new TextDecoder().decode(bytes);
+  }
+
+  player(p: Player, mask: number): Player {
+    if (mask & ~PLAYER_FIELDS) {
+      this.fail();
+    }
+    if (mask & FIELD_POSITION) {
+      p.position = { x: this.uint16() / SCALE, y: this.uint16() / SCALE };
+    }
+    if (mask & FIELD_VELOCITY) {
+      p.velocity = { x: this.int16() / SCALE, y: this.int16() / SCALE };
+    }
+    if (mask & FIELD_LAST_INPUT) {
+      const v = this.uvarint();
+      if (v > 0xffffffff) {
+        this.fail();
+      }
+      p.lastInput = v;
+    }
+    return p;
+  }
+
+  object(o: GameObject, mask: number): GameObject {
+    if (mask & ~OBJECT_FIELDS) {
+      this.fail();
+    }
+    if (mask & FIELD_POSITION) {
+      o.position = { x: this.uint16() / SCALE, y: this.uint16() / SCALE };
+    }
+    if (mask & FIELD_KIND) {
+      o.kind = this.id();
+    }
+    return o;
+  }
+}
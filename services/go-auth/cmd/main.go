@@ -1,22 +1,36 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"go-auth/internal/app"
 	"go-auth/internal/config"
+	"go-auth/internal/grpcserver"
 	"go-auth/internal/router"
+	"go-auth/internal/router/cookies"
 	"go-auth/internal/services"
 	"go-auth/internal/storage"
+	"go-auth/pkg/audit"
 	"go-auth/pkg/db"
+	"go-auth/pkg/hasher"
+	"go-auth/pkg/kafka"
+	"go-auth/pkg/keyring"
+	"go-auth/pkg/mailer"
 	"go-auth/pkg/metrics"
+	"go-auth/pkg/oidc"
+	"go-auth/pkg/outbox"
+	"go-auth/pkg/password"
+	"go-auth/pkg/ratelimit"
 	"go-auth/pkg/redis"
 	"go-auth/pkg/tracer"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"go.uber.org/dig"
 )
@@ -38,9 +52,17 @@ func main() {
 		Level:     slog.LevelDebug,
 	})))
 
-	metrics.Start(":8081")
+	cfg := appConfig()
+	slog.LogAttrs(context.Background(), slog.LevelInfo, "security policy", cfg.PolicyAttrs()...)
+
+	go runAccountPurge()
+	ctx, cancel := context.WithCancel(context.Background())
+	go runAuditConsumer(ctx)
+	go runOutboxRelay(ctx)
+
+	metrics.Start(cfg.MetricsAddr)
 	httpServer := &http.Server{
-		Addr:    ":8080",
+		Addr:    cfg.ServerAddr,
 		Handler: router.New(),
 	}
 
@@ -55,11 +77,30 @@ func main() {
 		}
 	}()
 
+	grpcServer := grpcserver.New()
+	go func() {
+		lis, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			panic(err)
+		}
+		if err := grpcServer.Serve(lis); err != nil {
+			slog.Error(err.Error())
+		}
+	}()
+
 	<-exit
 
+	grpcServer.GracefulStop()
 	if err := httpServer.Close(); err != nil {
 		slog.Error(err.Error())
 	}
+	cancel()
+	if err := app.AppContainer.Invoke(func(log app.AppAuditLog, producer app.AppProducer) {
+		log.Close()
+		producer.Close()
+	}); err != nil {
+		slog.Error(err.Error())
+	}
 
 	// if err := app.AppContainer.Invoke(func(rds *redis.Redis) {
 	// 	rds.Close()
@@ -69,15 +110,101 @@ func main() {
 
 }
 
+// runAccountPurge hard-deletes accounts whose deletion grace period is
+// over. Running it on every instance is harmless.
+func runAccountPurge() {
+	var profileService app.AppProfileService
+	if err := app.AppContainer.Invoke(func(ps app.AppProfileService) {
+		profileService = ps
+	}); err != nil {
+		panic(fmt.Sprintf("profile service can not be resolved: %s", err.Error()))
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		n, err := profileService.PurgeDeletedAccounts(context.Background())
+		if err != nil {
+			slog.Error(err.Error())
+		} else if n > 0 {
+			slog.Info("purged deleted accounts", "count", n)
+		}
+		<-ticker.C
+	}
+}
+
+// runAuditConsumer stores the events of the audit topic in auth_events.
+// Instances share a consumer group, so each event is stored once.
+func runAuditConsumer(ctx context.Context) {
+	var consumer *kafka.AuditConsumer
+	if err := app.AppContainer.Invoke(func(c *kafka.AuditConsumer) {
+		consumer = c
+	}); err != nil {
+		panic(fmt.Sprintf("audit consumer can not be resolved: %s", err.Error()))
+	}
+	defer consumer.Close()
+	consumer.Run(ctx)
+}
+
+// runOutboxRelay publishes the account lifecycle events queued by the
+// user storage.
+func runOutboxRelay(ctx context.Context) {
+	var relay *outbox.Relay
+	if err := app.AppContainer.Invoke(func(r *outbox.Relay) {
+		relay = r
+	}); err != nil {
+		panic(fmt.Sprintf("outbox relay can not be resolved: %s", err.Error()))
+	}
+	relay.Run(ctx)
+}
+
+// appConfig resolves the config; an invalid one panics here, before any
+// server is started.
+func appConfig() *config.Config {
+	var cfg *config.Config
+	if err := app.AppContainer.Invoke(func(c app.AppConfig) {
+		cfg = c.GetConfig()
+	}); err != nil {
+		panic(fmt.Sprintf("config can not be resolved: %s", err.Error()))
+	}
+	return cfg
+}
+
 func initAppContainer() {
 	app.AppContainer = dig.New()
 
+	if err := app.AppContainer.Provide(services.NewSessions); err != nil {
+		panic(fmt.Sprintf("sessions can not be provided: %s", err.Error()))
+	}
 	if err := app.AppContainer.Provide(services.AuthNew, dig.As(new(app.AppAuthService))); err != nil {
 		panic(fmt.Sprintf("auth service can not be provided: %s", err.Error()))
 	}
+	if err := app.AppContainer.Provide(services.NewMFAService, dig.As(new(app.AppMFAService))); err != nil {
+		panic(fmt.Sprintf("mfa service can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(services.NewOAuthService, dig.As(new(app.AppOAuthService))); err != nil {
+		panic(fmt.Sprintf("oauth service can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(services.NewProfileService, dig.As(new(app.AppProfileService))); err != nil {
+		panic(fmt.Sprintf("profile service can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(services.NewClientService, dig.As(new(app.AppClientService))); err != nil {
+		panic(fmt.Sprintf("client service can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(services.NewRoleService, dig.As(new(app.AppRoleService))); err != nil {
+		panic(fmt.Sprintf("role service can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(services.NewAuditService, dig.As(new(app.AppAuditService))); err != nil {
+		panic(fmt.Sprintf("audit service can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(services.NewIntrospectionService, dig.As(new(app.AppIntrospectionService))); err != nil {
+		panic(fmt.Sprintf("introspection service can not be provided: %s", err.Error()))
+	}
 	if err := app.AppContainer.Provide(config.New, dig.As(new(app.AppConfig))); err != nil {
 		panic(fmt.Sprintf("config can not be provided:%s", err.Error()))
 	}
+	if err := app.AppContainer.Provide(cookies.New, dig.As(new(app.AppCookies))); err != nil {
+		panic(fmt.Sprintf("cookie policy can not be provided: %s", err.Error()))
+	}
 	if err := app.AppContainer.Provide(db.New, dig.As(new(app.DB))); err != nil {
 		panic(fmt.Sprintf("database not be provided: %s", err.Error()))
 	}
@@ -87,6 +214,69 @@ func initAppContainer() {
 	if err := app.AppContainer.Provide(storage.NewTokenStorage, dig.As(new(app.AppTokenStorage))); err != nil {
 		panic(fmt.Sprintf("token storage can not be provided: %s", err.Error()))
 	}
+	if err := app.AppContainer.Provide(hasher.New, dig.As(new(app.AppPasswordHasher))); err != nil {
+		panic(fmt.Sprintf("password hasher can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(password.New, dig.As(new(app.AppPasswordPolicy))); err != nil {
+		panic(fmt.Sprintf("password policy can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(keyring.New, dig.As(new(app.AppKeyRing))); err != nil {
+		panic(fmt.Sprintf("key ring can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(storage.NewSessionStorage, dig.As(new(app.AppSessionStorage))); err != nil {
+		panic(fmt.Sprintf("session storage can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(ratelimit.New, dig.As(new(app.AppRateLimiter))); err != nil {
+		panic(fmt.Sprintf("rate limiter can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(ratelimit.NewLoginLockout, dig.As(new(app.AppLoginGuard))); err != nil {
+		panic(fmt.Sprintf("login lockout can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(ratelimit.NewAccountLockout, dig.As(new(app.AppAccountGuard))); err != nil {
+		panic(fmt.Sprintf("account lockout can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(storage.NewActionTokenStorage, dig.As(new(app.AppActionTokenStorage))); err != nil {
+		panic(fmt.Sprintf("action token storage can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(mailer.New, dig.As(new(app.AppMailer))); err != nil {
+		panic(fmt.Sprintf("mailer can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(storage.NewMFAStorage, dig.As(new(app.AppMFAStorage))); err != nil {
+		panic(fmt.Sprintf("mfa storage can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(storage.NewOAuthStateStorage, dig.As(new(app.AppOAuthStateStorage))); err != nil {
+		panic(fmt.Sprintf("oauth state storage can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(storage.NewIdentityStorage, dig.As(new(app.AppIdentityStorage))); err != nil {
+		panic(fmt.Sprintf("identity storage can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(oidc.New, dig.As(new(app.AppOAuthProviders))); err != nil {
+		panic(fmt.Sprintf("oauth providers can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(storage.NewRoleStorage, dig.As(new(app.AppRoleStorage))); err != nil {
+		panic(fmt.Sprintf("role storage can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(storage.NewClientStorage, dig.As(new(app.AppClientStorage))); err != nil {
+		panic(fmt.Sprintf("client storage can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(kafka.NewProducer, dig.As(new(app.AppProducer))); err != nil {
+		panic(fmt.Sprintf("kafka producer can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(audit.New, dig.As(new(app.AppAuditLog))); err != nil {
+		panic(fmt.Sprintf("audit log can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(storage.NewAuditStorage, dig.As(new(app.AppAuditStorage))); err != nil {
+		panic(fmt.Sprintf("audit storage can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(kafka.NewAuditConsumer); err != nil {
+		panic(fmt.Sprintf("audit consumer can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(storage.NewOutboxStorage, dig.As(new(app.AppOutboxStorage))); err != nil {
+		panic(fmt.Sprintf("outbox storage can not be provided: %s", err.Error()))
+	}
+	if err := app.AppContainer.Provide(outbox.New); err != nil {
+		panic(fmt.Sprintf("outbox relay can not be provided: %s", err.Error()))
+	}
 	if err := app.AppContainer.Provide(storage.NewUserStorage, dig.As(new(app.AppUserStorage))); err != nil {
 		panic(fmt.Sprintf("user storage not be provided: %s", err.Error()))
 	}
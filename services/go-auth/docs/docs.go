@@ -15,9 +15,180 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/login": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Возвращает JWKS с активным и выводимыми из оборота ключами для проверки JWT другими сервисами",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Публичные ключи подписи токенов",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.JWKSet"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/audit/events": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает события (регистрация, входы, обновление токенов, выход, смена и сброс пароля) от новых к старым. События попадают в журнал через Kafka, последние несколько секунд могут отсутствовать. Требует разрешение audit:read",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аудит"
+                ],
+                "summary": "Журнал событий безопасности",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "userId",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "registered",
+                            "login_succeeded",
+                            "login_failed",
+                            "token_refreshed",
+                            "refresh_token_reused",
+                            "logged_out",
+                            "password_changed",
+                            "password_reset"
+                        ],
+                        "type": "string",
+                        "description": "Тип события",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-05-01T00:00:00Z",
+                        "description": "Начало периода включительно, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-05-02T00:00:00Z",
+                        "description": "Конец периода не включительно, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько событий вернуть, от 1 до 1000, по умолчанию 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AuthEvent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный фильтр",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/clients": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает всех клиентов, включая отозванных. Требует разрешение clients:manage",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "API-клиенты"
+                ],
+                "summary": "Список API-клиентов",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.APIClient"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Проверяет учетные данные и возвращает JWT токены в cookies",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Создаёт клиента для client credentials grant. Scope — имена разрешений. Секрет возвращается только в этом ответе. Требует разрешение clients:manage",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,76 +196,280 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Аутентификация"
+                    "API-клиенты"
                 ],
-                "summary": "Аутентификация пользователя",
+                "summary": "Регистрация API-клиента",
                 "parameters": [
                     {
-                        "description": "Учетные данные",
+                        "description": "Имя и разрешённые scope",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UserCreateReq"
+                            "$ref": "#/definitions/models.APIClientCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIClientCreateRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Не указано имя или неизвестный scope",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/clients/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Клиент больше не получает токены, выданные ему токены перестают приниматься. Требует разрешение clients:manage",
+                "tags": [
+                    "API-клиенты"
+                ],
+                "summary": "Отзыв API-клиента",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID клиента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Клиент отозван"
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "404": {
+                        "description": "Клиент не найден",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/email/verify": {
+            "post": {
+                "description": "Подтверждает email по одноразовому токену из письма",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аккаунт"
+                ],
+                "summary": "Подтверждение email",
+                "parameters": [
+                    {
+                        "description": "Токен из письма",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.EmailVerifyReq"
                         }
                     }
                 ],
                 "responses": {
                     "204": {
-                        "description": "Успешная аутентификация, токены установлены в cookies"
+                        "description": "Email подтвержден"
                     },
                     "400": {
-                        "description": "Неверный запрос или неверные логин/пароль",
+                        "description": "Неверный или просроченный токен",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorRes"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorRes"
                         }
                     }
                 }
             }
         },
-        "/logout": {
-            "get": {
+        "/introspect": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Удаляет JWT токены из cookies и хранилища",
+                "description": "Для других сервисов: проверяет access token (подпись, срок действия, отзыв сессии в Redis) и возвращает субъект, роли и время истечения. Для неактивного токена возвращается только active=false. Вызывающий сервис передаёт общий секрет в заголовке Authorization: Bearer",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Интроспекция"
+                ],
+                "summary": "Интроспекция токена (RFC 7662)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Проверяемый токен",
+                        "name": "token",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подсказка о типе токена, игнорируется",
+                        "name": "token_type_hint",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Introspection"
+                        }
+                    },
+                    "400": {
+                        "description": "Не передан токен",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "401": {
+                        "description": "Неизвестный вызывающий сервис",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Проверяет учетные данные и возвращает JWT токены в cookies или, с заголовком X-Token-Mode: json, в теле ответа (models.TokenRes). Если включена 2FA, вместо токенов возвращается MFA challenge для /login/mfa",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Аутентификация"
                 ],
-                "summary": "Выход из системы",
+                "summary": "Аутентификация пользователя",
+                "parameters": [
+                    {
+                        "enum": [
+                            "json"
+                        ],
+                        "type": "string",
+                        "description": "json — вернуть токены в теле ответа",
+                        "name": "X-Token-Mode",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Учетные данные",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserCreateReq"
+                        }
+                    }
+                ],
                 "responses": {
+                    "200": {
+                        "description": "Требуется второй фактор",
+                        "schema": {
+                            "$ref": "#/definitions/models.MFAChallengeRes"
+                        }
+                    },
                     "204": {
-                        "description": "Успешный выход, токены удалены"
+                        "description": "Успешная аутентификация, токены установлены в cookies"
                     },
                     "400": {
-                        "description": "Неверный запрос",
+                        "description": "Неверный запрос или не указаны логин/пароль",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверные логин или пароль",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много попыток, см. заголовок Retry-After",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorRes"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorRes"
                         }
                     }
                 }
             }
         },
-        "/register": {
+        "/login/mfa": {
             "post": {
-                "description": "Создает нового пользователя и возвращает JWT токены в cookies",
+                "description": "Обменивает MFA challenge из /login и код второго фактора (TOTP или код восстановления) на JWT токены в cookies или, с заголовком X-Token-Mode: json, в теле ответа",
                 "consumes": [
                     "application/json"
                 ],
@@ -104,43 +479,1835 @@ const docTemplate = `{
                 "tags": [
                     "Аутентификация"
                 ],
-                "summary": "Регистрация нового пользователя",
+                "summary": "Второй шаг входа",
                 "parameters": [
                     {
-                        "description": "Данные для регистрации",
+                        "enum": [
+                            "json"
+                        ],
+                        "type": "string",
+                        "description": "json — вернуть токены в теле ответа",
+                        "name": "X-Token-Mode",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Challenge и код",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UserCreateReq"
+                            "$ref": "#/definitions/models.MFALoginReq"
                         }
                     }
                 ],
                 "responses": {
+                    "200": {
+                        "description": "Успешная аутентификация (режим json)",
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenRes"
+                        }
+                    },
                     "204": {
-                        "description": "Успешная регистрация, токены установлены в cookies"
+                        "description": "Успешная аутентификация, токены установлены в cookies"
                     },
                     "400": {
-                        "description": "Неверный запрос или неверные логин/пароль",
+                        "description": "Неверный код или просроченный challenge",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много попыток, см. заголовок Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorRes"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.UserCreateReq": {
-            "description": "Данные для регистрации или входа пользователя",
+        },
+        "/logout": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Отзывает семейство refresh токенов и удаляет JWT токены из cookies и хранилища. Клиент с заголовком Authorization: Bearer завершает сессию своего access token",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Выход из системы",
+                "responses": {
+                    "204": {
+                        "description": "Успешный выход, токены удалены"
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/me": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Профиль текущего пользователя",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Profile"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Завершает все сессии и удаляет аккаунт по истечении ACCOUNT_DELETION_GRACE. Вход в течение этого срока отменяет удаление",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Удаление аккаунта",
+                "parameters": [
+                    {
+                        "description": "Пароль, если он задан",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AccountDeleteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Аккаунт будет удален"
+                    },
+                    "400": {
+                        "description": "Неверный пароль",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много попыток, см. заголовок Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Меняет имя, email или аватар. Новый email нужно подтвердить заново, письмо отправляется на него",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Изменение профиля",
+                "parameters": [
+                    {
+                        "description": "Изменения профиля",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ProfileUpdateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Profile"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные данные",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "409": {
+                        "description": "Email уже используется",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/password": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Меняет пароль и завершает все остальные сессии. Текущий пароль не нужен, если пароль еще не задан (вход через внешнего провайдера)",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Смена пароля",
+                "parameters": [
+                    {
+                        "description": "Текущий и новый пароль",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PasswordChangeReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Пароль изменен"
+                    },
+                    "400": {
+                        "description": "Неверный текущий пароль, пустой новый или новый пароль не соответствует политике",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много попыток, см. заголовок Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/mfa/recovery-codes": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Заменяет все коды восстановления новыми. Требует действующий код",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "2FA"
+                ],
+                "summary": "Новые коды восстановления",
+                "parameters": [
+                    {
+                        "description": "Код из приложения или код восстановления",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MFACodeReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RecoveryCodesRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный код",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "409": {
+                        "description": "2FA не включена",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много попыток, см. заголовок Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/mfa/totp/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Включает 2FA, если код из приложения верный, и возвращает коды восстановления",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "2FA"
+                ],
+                "summary": "Подтверждение TOTP",
+                "parameters": [
+                    {
+                        "description": "Код из приложения",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MFACodeReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RecoveryCodesRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный код",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "409": {
+                        "description": "2FA уже включена или не начата настройка",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/mfa/totp/disable": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Отключает 2FA и удаляет коды восстановления. Требует действующий код",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "2FA"
+                ],
+                "summary": "Отключение TOTP",
+                "parameters": [
+                    {
+                        "description": "Код из приложения или код восстановления",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MFACodeReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "2FA отключена"
+                    },
+                    "400": {
+                        "description": "Неверный код",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "409": {
+                        "description": "2FA не включена",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много попыток, см. заголовок Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/mfa/totp/setup": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Генерирует секрет и otpauth URI для приложения-аутентификатора. 2FA включается после подтверждения кодом",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "2FA"
+                ],
+                "summary": "Начало подключения TOTP",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TOTPSetupRes"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "409": {
+                        "description": "2FA уже включена",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth/{provider}/callback": {
+            "get": {
+                "description": "Принимается только вместе с cookie oauth_state из /oauth/{provider}/login или /oauth/{provider}/link. Завершает вход или привязку и перенаправляет во фронтенд: на \"/\" с токенами в cookies, на \"/login/mfa?challenge=\" если включена 2FA, на \"/settings?linked=\" после привязки или на \"/login?error=\" при ошибке",
+                "tags": [
+                    "OAuth"
+                ],
+                "summary": "Возврат от внешнего провайдера",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя провайдера",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Код авторизации",
+                        "name": "code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "State из /oauth/{provider}/login",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Перенаправление во фронтенд"
+                    }
+                }
+            }
+        },
+        "/oauth/{provider}/link": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Перенаправляет к OIDC провайдеру, чтобы привязать его аккаунт к текущему пользователю",
+                "tags": [
+                    "OAuth"
+                ],
+                "summary": "Привязка внешнего аккаунта",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя провайдера из OAUTH_PROVIDERS",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Перенаправление к провайдеру"
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "404": {
+                        "description": "Неизвестный провайдер",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth/{provider}/login": {
+            "get": {
+                "description": "Перенаправляет на страницу входа OIDC провайдера (authorization code + PKCE) и выставляет cookie oauth_state, которая привязывает вход к браузеру",
+                "tags": [
+                    "OAuth"
+                ],
+                "summary": "Вход через внешнего провайдера",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя провайдера из OAUTH_PROVIDERS",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Перенаправление к провайдеру"
+                    },
+                    "404": {
+                        "description": "Неизвестный провайдер",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. заголовок Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/forgot": {
+            "post": {
+                "description": "Отправляет ссылку для сброса пароля на email. Ответ не зависит от того, зарегистрирован ли email",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аккаунт"
+                ],
+                "summary": "Запрос сброса пароля",
+                "parameters": [
+                    {
+                        "description": "Email пользователя",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PasswordForgotReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Если email зарегистрирован, письмо отправлено"
+                    },
+                    "400": {
+                        "description": "Неверный email",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. заголовок Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/reset": {
+            "post": {
+                "description": "Устанавливает новый пароль по одноразовому токену из письма и завершает все сессии пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аккаунт"
+                ],
+                "summary": "Сброс пароля",
+                "parameters": [
+                    {
+                        "description": "Токен из письма и новый пароль",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PasswordResetReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Пароль изменен"
+                    },
+                    "400": {
+                        "description": "Неверный или просроченный токен, пустой пароль или пароль не соответствует политике",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. заголовок Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/refresh": {
+            "post": {
+                "description": "Обменивает refresh token на новую пару токенов, старый refresh token становится недействительным. В режиме cookies refresh token берётся из cookie, в режиме X-Token-Mode: json — из тела запроса",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Обновление токенов",
+                "parameters": [
+                    {
+                        "enum": [
+                            "json"
+                        ],
+                        "type": "string",
+                        "description": "json — принять и вернуть токены в теле запроса и ответа",
+                        "name": "X-Token-Mode",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Refresh token (режим json)",
+                        "name": "input",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Новые токены (режим json)",
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenRes"
+                        }
+                    },
+                    "204": {
+                        "description": "Новые токены установлены в cookies"
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "401": {
+                        "description": "Refresh token недействителен, отозван или уже использован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. заголовок Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/register": {
+            "post": {
+                "description": "Создает нового пользователя и возвращает JWT токены в cookies или, с заголовком X-Token-Mode: json, в теле ответа. Если указан email, на него отправляется письмо для подтверждения",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Регистрация нового пользователя",
+                "parameters": [
+                    {
+                        "enum": [
+                            "json"
+                        ],
+                        "type": "string",
+                        "description": "json — вернуть токены в теле ответа",
+                        "name": "X-Token-Mode",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Данные для регистрации",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешная регистрация (режим json)",
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenRes"
+                        }
+                    },
+                    "204": {
+                        "description": "Успешная регистрация, токены установлены в cookies"
+                    },
+                    "400": {
+                        "description": "Неверный запрос, неверный email или логин/пароль не соответствуют политике (code validation_failed, поля в fields)",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "409": {
+                        "description": "Логин или email уже заняты",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. заголовок Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/roles": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает все роли и их разрешения. Требует разрешение users:read",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Роли"
+                ],
+                "summary": "Список ролей",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Role"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает все активные сессии текущего пользователя, текущая помечена флагом current",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Сессии"
+                ],
+                "summary": "Список активных сессий",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Session"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Отзывает все сессии текущего пользователя, включая текущую, и очищает cookies",
+                "tags": [
+                    "Сессии"
+                ],
+                "summary": "Выход на всех устройствах",
+                "responses": {
+                    "204": {
+                        "description": "Все сессии завершены"
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Отзывает одну сессию текущего пользователя. При отзыве текущей сессии cookies очищаются",
+                "tags": [
+                    "Сессии"
+                ],
+                "summary": "Завершение сессии",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Сессия завершена"
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/token": {
+            "post": {
+                "description": "Client credentials grant (RFC 6749, 4.4). Клиент передаёт client_id и client_secret в заголовке Authorization: Basic или в теле формы. Полученный токен передаётся в заголовке Authorization: Bearer",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "API-клиенты"
+                ],
+                "summary": "Выдача токена API-клиенту",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "client_credentials",
+                        "name": "grant_type",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Запрашиваемые scope через пробел, по умолчанию все разрешённые клиенту",
+                        "name": "scope",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID клиента, если не передан в Authorization",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Секрет клиента, если не передан в Authorization",
+                        "name": "client_secret",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ClientTokenRes"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_request, unsupported_grant_type или invalid_scope",
+                        "schema": {
+                            "$ref": "#/definitions/models.OAuthErrorRes"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid_client",
+                        "schema": {
+                            "$ref": "#/definitions/models.OAuthErrorRes"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов, см. заголовок Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/roles": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает роли пользователя и итоговые разрешения. Требует разрешение users:read",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Роли"
+                ],
+                "summary": "Роли пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Grants"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/roles/{role}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Назначает роль пользователю. Изменение попадает в токены пользователя при следующем обновлении. Требует разрешение roles:manage",
+                "tags": [
+                    "Роли"
+                ],
+                "summary": "Назначение роли",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Роль",
+                        "name": "role",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Роль назначена"
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь или роль не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Отзывает роль у пользователя. Требует разрешение roles:manage",
+                "tags": [
+                    "Роли"
+                ],
+                "summary": "Отзыв роли",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Роль",
+                        "name": "role",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Роль отозвана"
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorRes"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.APIClient": {
+            "type": "object",
+            "properties": {
+                "clientId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "revokedAt": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.APIClientCreateReq": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.APIClientCreateRes": {
+            "type": "object",
+            "properties": {
+                "clientId": {
+                    "type": "string"
+                },
+                "clientSecret": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "revokedAt": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.AccountDeleteReq": {
+            "description": "Пароль для подтверждения удаления, если он задан",
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AuthEvent": {
+            "description": "Событие безопасности",
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string",
+                    "example": "0f5c2d1e-7a4b-4c39-9b1d-3e6f2a8c9d10"
+                },
+                "ip": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "login": {
+                    "type": "string",
+                    "example": "player1"
+                },
+                "sessionId": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "login_failed"
+                },
+                "userAgent": {
+                    "type": "string",
+                    "example": "Mozilla/5.0"
+                },
+                "userId": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "models.ClientTokenRes": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EmailVerifyReq": {
+            "description": "Токен подтверждения из письма",
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ErrorRes": {
+            "description": "Ошибка. Поле code не меняется между версиями, message — для человека, fields перечисляет отклонённые поля запроса",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "validation_failed"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Validation failed"
+                },
+                "traceId": {
+                    "type": "string",
+                    "example": "4bf92f3577b34da6a3ce929d0e0e4736"
+                }
+            }
+        },
+        "models.FieldError": {
+            "description": "Ошибка валидации поля запроса",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "too_short"
+                },
+                "field": {
+                    "type": "string",
+                    "example": "password"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "must be at least 8 characters"
+                }
+            }
+        },
+        "models.Grants": {
+            "type": "object",
+            "properties": {
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.Introspection": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "exp": {
+                    "type": "integer"
+                },
+                "iat": {
+                    "type": "integer"
+                },
+                "jti": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "sid": {
+                    "type": "string"
+                },
+                "sub": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.JWK": {
+            "description": "Публичный ключ для проверки подписи JWT",
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string",
+                    "example": "EdDSA"
+                },
+                "crv": {
+                    "type": "string",
+                    "example": "Ed25519"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string",
+                    "example": "0f5c2d1e-7a4b-4c39-9b1d-3e6f2a8c9d10"
+                },
+                "kty": {
+                    "type": "string",
+                    "example": "OKP"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string",
+                    "example": "sig"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.JWKSet": {
+            "description": "Набор публичных ключей (JWKS)",
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.JWK"
+                    }
+                }
+            }
+        },
+        "models.MFAChallengeRes": {
+            "description": "Требуется второй фактор: передайте challenge и код в /login/mfa",
+            "type": "object",
+            "properties": {
+                "challenge": {
+                    "type": "string"
+                },
+                "mfaRequired": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "models.MFACodeReq": {
+            "description": "Код из приложения-аутентификатора или код восстановления",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "models.MFALoginReq": {
+            "description": "Токен MFA challenge из ответа /login и код второго фактора",
+            "type": "object",
+            "properties": {
+                "challenge": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "models.OAuthErrorRes": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PasswordChangeReq": {
+            "description": "Текущий и новый пароль. Текущий не нужен, если пароль еще не задан",
+            "type": "object",
+            "properties": {
+                "currentPassword": {
+                    "type": "string"
+                },
+                "newPassword": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "minLength": 6,
+                    "example": "strongPassword123"
+                }
+            }
+        },
+        "models.PasswordForgotReq": {
+            "description": "Email, на который будет отправлена ссылка для сброса пароля",
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "user@example.com"
+                }
+            }
+        },
+        "models.PasswordResetReq": {
+            "description": "Токен сброса из письма и новый пароль",
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "minLength": 6,
+                    "example": "strongPassword123"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Profile": {
+            "description": "Профиль текущего пользователя",
+            "type": "object",
+            "properties": {
+                "avatarUrl": {
+                    "type": "string",
+                    "example": "https://example.com/avatar.png"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string",
+                    "example": "User"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "user@example.com"
+                },
+                "emailVerified": {
+                    "type": "boolean"
+                },
+                "hasPassword": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "login": {
+                    "type": "string",
+                    "example": "user123"
+                },
+                "mfaEnabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ProfileUpdateReq": {
+            "description": "Изменения профиля. Отсутствующие поля не меняются, пустая строка очищает поле",
+            "type": "object",
+            "properties": {
+                "avatarUrl": {
+                    "type": "string",
+                    "example": "https://example.com/avatar.png"
+                },
+                "displayName": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "User"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "user@example.com"
+                }
+            }
+        },
+        "models.RecoveryCodesRes": {
+            "description": "Коды восстановления. Показываются один раз",
+            "type": "object",
+            "properties": {
+                "codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.RefreshReq": {
+            "description": "Refresh token для обновления пары токенов",
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Role": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.Session": {
+            "description": "Активная сессия пользователя",
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "current": {
+                    "type": "boolean"
+                },
+                "device": {
+                    "type": "string",
+                    "example": "Pixel 8"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "0f5c2d1e-7a4b-4c39-9b1d-3e6f2a8c9d10"
+                },
+                "ip": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "lastSeen": {
+                    "type": "string"
+                },
+                "userAgent": {
+                    "type": "string",
+                    "example": "Mozilla/5.0"
+                },
+                "userId": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "models.TOTPSetupRes": {
+            "description": "Секрет и otpauth URI для приложения-аутентификатора",
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+                },
+                "uri": {
+                    "type": "string",
+                    "example": "otpauth://totp/go-auth:user123?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP\u0026issuer=go-auth"
+                }
+            }
+        },
+        "models.TokenRes": {
+            "description": "Токены в теле ответа (режим X-Token-Mode: json)",
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 900
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string",
+                    "example": "Bearer"
+                }
+            }
+        },
+        "models.UserCreateReq": {
+            "description": "Данные для регистрации или входа пользователя",
             "type": "object",
             "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "user@example.com"
+                },
                 "login": {
                     "type": "string",
                     "maxLength": 20,
@@ -149,8 +2316,8 @@ const docTemplate = `{
                 },
                 "password": {
                     "type": "string",
-                    "maxLength": 32,
-                    "minLength": 6,
+                    "maxLength": 64,
+                    "minLength": 8,
                     "example": "strongPassword123"
                 }
             }
@@ -5,17 +5,70 @@ import (
 	"database/sql"
 	"go-auth/internal/config"
 	"go-auth/internal/models"
+	"net/http"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
 )
 
 type AppAuthService interface {
-	Create(user models.UserCreateReq) (*models.TokenDto, error)
-	Login(user models.UserCreateReq) (*models.TokenDto, error)
+	Create(user models.UserCreateReq, client models.ClientInfo) (*models.TokenDto, error)
+	Login(user models.UserCreateReq, client models.ClientInfo) (*models.TokenDto, error)
 	RefreshToken(refreshToken string) (*models.TokenDto, error)
+	Logout(ctx context.Context, refresh, access string) error
+	Authenticate(ctx context.Context, accessToken string) (*models.TokenClaims, error)
+	ListSessions(ctx context.Context, userId string) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userId, sessionId string) error
+	RevokeAllSessions(ctx context.Context, userId string) error
+	VerifyEmail(ctx context.Context, token string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
 }
 
+type AppMFAService interface {
+	LoginMFA(ctx context.Context, challenge, code string, client models.ClientInfo) (*models.TokenDto, error)
+	SetupTOTP(ctx context.Context, userId string) (*models.TOTPSetupRes, error)
+	ConfirmTOTP(ctx context.Context, userId, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userId, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userId, code string) ([]string, error)
+}
+
+type AppOAuthService interface {
+	OAuthStart(ctx context.Context, provider, linkUserId string) (string, string, error)
+	OAuthCallback(ctx context.Context, provider, state, code string, client models.ClientInfo) (*models.TokenDto, error)
+}
+
+type AppProfileService interface {
+	GetProfile(ctx context.Context, userId string) (*models.Profile, error)
+	UpdateProfile(ctx context.Context, userId string, req models.ProfileUpdateReq) (*models.Profile, error)
+	ChangePassword(ctx context.Context, userId, sessionId, currentPassword, newPassword string) error
+	DeleteAccount(ctx context.Context, userId, password string) error
+	PurgeDeletedAccounts(ctx context.Context) (int64, error)
+}
+
+type AppClientService interface {
+	RegisterClient(ctx context.Context, req models.APIClientCreateReq) (*models.APIClientCreateRes, error)
+	ListClients(ctx context.Context) ([]models.APIClient, error)
+	RevokeClient(ctx context.Context, clientId string) error
+	ClientToken(ctx context.Context, clientId, clientSecret, scope string) (*models.ClientTokenRes, error)
+	AuthenticateClient(ctx context.Context, accessToken string) (*models.TokenClaims, error)
+}
+
+type AppRoleService interface {
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	UserGrants(ctx context.Context, userId string) (*models.Grants, error)
+	AssignRole(ctx context.Context, userId, role string) error
+	RevokeRole(ctx context.Context, userId, role string) error
+}
+
+type AppAuditService interface {
+	ListAuthEvents(ctx context.Context, filter models.AuthEventFilter) ([]models.AuthEvent, error)
+}
+
+type AppIntrospectionService interface {
+	Introspect(ctx context.Context, accessToken string) (*models.Introspection, error)
+}
 
 type AppConfig interface {
 	GetConfig() *config.Config
@@ -24,6 +77,50 @@ type AppConfig interface {
 type AppTokenStorage interface {
 	SetTokens(ctx context.Context, jwt *models.TokenDto) error
 	RemoveToken(ctx context.Context, refresh, access string) error
+	SaveRefreshFamily(ctx context.Context, family, jti string, ttl time.Duration) error
+	RotateRefreshFamily(ctx context.Context, family, oldJti, newJti string) error
+	RevokeRefreshFamily(ctx context.Context, family string) error
+}
+
+type AppSessionStorage interface {
+	Save(ctx context.Context, session models.Session, ttl time.Duration) error
+	Get(ctx context.Context, id string) (*models.Session, error)
+	Touch(ctx context.Context, id string, lastSeen time.Time) error
+	ListByUser(ctx context.Context, userId string) ([]models.Session, error)
+	Delete(ctx context.Context, userId, id string) error
+}
+
+type AppActionTokenStorage interface {
+	Save(ctx context.Context, purpose string, token models.ActionToken, ttl time.Duration) error
+	Consume(ctx context.Context, purpose, userId, jti string) (*models.ActionToken, error)
+}
+
+type AppMailer interface {
+	Send(ctx context.Context, mail models.Mail) error
+}
+
+type AppProducer interface {
+	Produce(topic, key, value string) error
+	Close()
+}
+
+// AppAuditLog records security events. Record never blocks on or fails
+// because of the audit pipeline; Close flushes what is queued.
+type AppAuditLog interface {
+	Record(ctx context.Context, event models.AuthEvent)
+	Close()
+}
+
+// AppOutboxStorage hands queued user events to the relay; see
+// storage.OutboxStorage.Relay.
+type AppOutboxStorage interface {
+	Relay(limit int, send func(models.OutboxMessage) error) (int, error)
+	PurgeSent(before time.Time) (int64, error)
+}
+
+type AppAuditStorage interface {
+	Save(event models.AuthEvent) error
+	List(filter models.AuthEventFilter) ([]models.AuthEvent, error)
 }
 
 type AppRedis interface {
@@ -32,16 +129,117 @@ type AppRedis interface {
 	Get(ctx context.Context, key string) *redis.StringCmd
 	Del(ctx context.Context, keys ...string) *redis.IntCmd
 	Pipeline() redis.Pipeliner
+	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
+	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	PTTL(ctx context.Context, key string) *redis.DurationCmd
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+	GetDel(ctx context.Context, key string) *redis.StringCmd
 }
 
 type AppUserStorage interface {
 	Save(user models.UserCreateDto) (models.UserCreateRes, error)
-	Get(user models.UserCreateDto) (*models.UserCreateRes, error)
+	GetByLogin(login string) (*models.User, error)
+	GetByEmail(email string) (*models.User, error)
+	VerifyEmail(userId, email string) error
 	Update(user models.UserCreateDto) error
-	GetById(userId string) (*models.UserCreateRes, error)
+	GetById(userId string) (*models.User, error)
+	UpdateProfile(userId string, p models.ProfileUpdateDto) error
+	SoftDelete(userId string) error
+	Restore(userId string) error
+	PurgeDeleted(before time.Time) (int64, error)
+}
+
+type AppMFAStorage interface {
+	GetTOTP(userId string) (*models.TOTPState, error)
+	SetPendingTOTP(userId, secret string) error
+	EnableTOTP(userId string, step int64, codeHashes []string) error
+	DisableTOTP(userId string) error
+	ReplaceRecoveryCodes(userId string, codeHashes []string) error
+	UseTOTPStep(userId string, step int64) (bool, error)
+	UseRecoveryCode(userId, codeHash string) (bool, error)
+}
+
+type AppRoleStorage interface {
+	GetGrants(userId string) (*models.Grants, error)
+	ListRoles() ([]models.Role, error)
+	AssignRole(userId, role string) error
+	RevokeRole(userId, role string) error
+}
+
+type AppClientStorage interface {
+	Save(client models.APIClient) error
+	Get(id string) (*models.APIClient, error)
+	List() ([]models.APIClient, error)
+	Revoke(id string) error
+}
+
+type AppIdentityStorage interface {
+	GetUser(provider, subject string) (*models.User, error)
+	Link(userId string, identity models.Identity) error
+	CreateUser(user models.UserCreateDto, identity models.Identity) (*models.User, error)
+}
+
+type AppOAuthStateStorage interface {
+	Save(ctx context.Context, state string, data models.OAuthState, ttl time.Duration) error
+	Consume(ctx context.Context, state string) (*models.OAuthState, error)
+}
+
+// AppOAuthProvider runs the authorization code flow with PKCE against one
+// external identity provider.
+type AppOAuthProvider interface {
+	Name() string
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*models.Identity, error)
+}
+
+type AppOAuthProviders interface {
+	Provider(name string) (AppOAuthProvider, bool)
+}
+
+type AppPasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+	NeedsRehash(encoded string) bool
+}
+
+// AppPasswordPolicy returns every rule a new password of login breaks.
+type AppPasswordPolicy interface {
+	Check(login, password string) []models.FieldError
+}
+
+type AppKeyRing interface {
+	Sign(claims jwt.Claims) (string, error)
+	Keyfunc(token *jwt.Token) (interface{}, error)
+	JWKS() models.JWKSet
+}
+
+type AppRateLimiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error)
+}
+
+type AppLoginGuard interface {
+	Check(ctx context.Context, key string) (time.Duration, error)
+	Fail(ctx context.Context, key string) (time.Duration, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// AppAccountGuard locks a login out whatever address the attempts come
+// from; AppLoginGuard is scoped to one address by its callers.
+type AppAccountGuard interface {
+	AppLoginGuard
 }
 
 type DB interface {
 	Close()
 	GetConnection() *sql.DB
 }
+
+// AppCookies writes the cookies go-auth hands to browsers.
+type AppCookies interface {
+	SetTokens(w http.ResponseWriter, jwt *models.TokenDto)
+	SetRefreshed(w http.ResponseWriter, jwt *models.TokenDto)
+	SetCSRF(w http.ResponseWriter) error
+	Clear(w http.ResponseWriter)
+	SetOAuthState(w http.ResponseWriter, state string, maxAge time.Duration)
+	ClearOAuthState(w http.ResponseWriter)
+}
@@ -1,17 +1,56 @@
 package config
 
+import "time"
+
 type Config struct {
-	Secret           string
-	RedisAddr        string
-	MetricsAddr      string
-	ServerAddr       string
-	JaegerAddr       string
-	PostgresHost     string
-	PostgresPort     string
-	PostgresUser     string
-	PostgresPassword string
-	PostgresDB       string
-	MigrationPath    string
+	RedisAddr            string
+	MetricsAddr          string
+	ServerAddr           string
+	JaegerAddr           string
+	PostgresHost         string
+	PostgresPort         string
+	PostgresUser         string
+	PostgresPassword     string
+	PostgresDB           string
+	MigrationPath        string
+	PasswordHasher       string
+	JWTSigningAlg        string
+	JWTKeyRotation       time.Duration
+	JWTKeyRetention      time.Duration
+	JWTKeyEncryptionKey  string
+	PublicURL            string
+	Mailer               string
+	MailerDir            string
+	MailFrom             string
+	SMTPHost             string
+	SMTPPort             string
+	SMTPUser             string
+	SMTPPassword         string
+	MFAIssuer            string
+	AuthPublicURL        string
+	OAuthProviders       OAuthProviders
+	AccountDeletionGrace time.Duration
+	IntrospectionTokens  []string
+	GRPCAddr             string
+	KafkaBrokers         []string
+	KafkaGroupId         string
+	AuditTopic           string
+	UserEventsTopic      string
+	TrustedOrigins       []string
+	TrustedProxies       []string
+	RateLimits           RateLimits
+	LoginLockout         Lockout
+	AccountLockout       Lockout
+	AccessTokenTTL       time.Duration
+	RefreshTokenTTL      time.Duration
+	AccessCookieMaxAge   time.Duration
+	CookieSecure         bool
+	CookieSameSite       string
+	CookieDomain         string
+	PasswordMinLength    int
+	PasswordMaxLength    int
+	PasswordMinClasses   int
+	BreachedHashesFile   string
 }
 
 func New() *Config {
@@ -19,19 +58,60 @@ func New() *Config {
 	if err != nil {
 		panic(err.Error())
 	}
-	return &Config{
-		Secret:           cfg.Secret,
-		RedisAddr:        cfg.RedisAddr,
-		MetricsAddr:      cfg.MetricsAddr,
-		ServerAddr:       cfg.ServerAddr,
-		JaegerAddr:       cfg.JaegerAddr,
-		PostgresHost:     cfg.PostgresHost,
-		PostgresPort:     cfg.PostgresPort,
-		PostgresUser:     cfg.PostgresUser,
-		PostgresPassword: cfg.PostgresPassword,
-		PostgresDB:       cfg.PostgresDB,
-		MigrationPath:    cfg.MigrationPath,
+	c := &Config{
+		RedisAddr:            cfg.RedisAddr,
+		MetricsAddr:          cfg.MetricsAddr,
+		ServerAddr:           cfg.ServerAddr,
+		JaegerAddr:           cfg.JaegerAddr,
+		PostgresHost:         cfg.PostgresHost,
+		PostgresPort:         cfg.PostgresPort,
+		PostgresUser:         cfg.PostgresUser,
+		PostgresPassword:     cfg.PostgresPassword,
+		PostgresDB:           cfg.PostgresDB,
+		MigrationPath:        cfg.MigrationPath,
+		PasswordHasher:       cfg.PasswordHasher,
+		JWTSigningAlg:        cfg.JWTSigningAlg,
+		JWTKeyRotation:       cfg.JWTKeyRotation,
+		JWTKeyRetention:      cfg.JWTKeyRetention,
+		JWTKeyEncryptionKey:  cfg.JWTKeyEncryptionKey,
+		PublicURL:            cfg.PublicURL,
+		Mailer:               cfg.Mailer,
+		MailerDir:            cfg.MailerDir,
+		MailFrom:             cfg.MailFrom,
+		SMTPHost:             cfg.SMTPHost,
+		SMTPPort:             cfg.SMTPPort,
+		SMTPUser:             cfg.SMTPUser,
+		SMTPPassword:         cfg.SMTPPassword,
+		MFAIssuer:            cfg.MFAIssuer,
+		AuthPublicURL:        cfg.AuthPublicURL,
+		OAuthProviders:       cfg.OAuthProviders,
+		AccountDeletionGrace: cfg.AccountDeletionGrace,
+		IntrospectionTokens:  cfg.IntrospectionTokens,
+		GRPCAddr:             cfg.GRPCAddr,
+		KafkaBrokers:         cfg.KafkaBrokers,
+		KafkaGroupId:         cfg.KafkaGroupId,
+		AuditTopic:           cfg.AuditTopic,
+		UserEventsTopic:      cfg.UserEventsTopic,
+		TrustedOrigins:       cfg.TrustedOrigins,
+		TrustedProxies:       cfg.TrustedProxies,
+		RateLimits:           cfg.RateLimits,
+		LoginLockout:         cfg.LoginLockout,
+		AccountLockout:       cfg.AccountLockout,
+		AccessTokenTTL:       cfg.AccessTokenTTL,
+		RefreshTokenTTL:      cfg.RefreshTokenTTL,
+		AccessCookieMaxAge:   cfg.AccessCookieMaxAge,
+		CookieSecure:         cfg.CookieSecure,
+		CookieSameSite:       cfg.CookieSameSite,
+		CookieDomain:         cfg.CookieDomain,
+		PasswordMinLength:    cfg.PasswordMinLength,
+		PasswordMaxLength:    cfg.PasswordMaxLength,
+		PasswordMinClasses:   cfg.PasswordMinClasses,
+		BreachedHashesFile:   cfg.BreachedHashesFile,
+	}
+	if err := c.Validate(); err != nil {
+		panic(err.Error())
 	}
+	return c
 }
 func (cfg *Config) GetConfig() *Config {
 	return cfg
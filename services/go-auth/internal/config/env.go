@@ -1,25 +1,95 @@
 package config
 
 import (
+	"time"
+
 	"github.com/caarlos0/env/v11"
 )
 
 type Envs struct {
-	Secret           string `env:"JWT_SECRET"`
-	RedisAddr        string `env:"REDIS_ADDRESS"`
-	MetricsAddr      string `env:"METRICS_ADDRESS"`
-	ServerAddr       string `env:"SERVER_ADDRESS"`
-	JaegerAddr       string `env:"JEAGER_ADDRESS"`
-	PostgresHost     string `env:"POSTGRES_HOST"`
-	PostgresPort     string `env:"POSTGRES_PORT"`
-	PostgresUser     string `env:"POSTGRES_USER"`
-	PostgresPassword string `env:"POSTGRES_PASSWORD"`
-	PostgresDB       string `env:"POSTGRES_DB"`
-	MigrationPath    string `env:"MIGRATIONS_PATH"`
+	RedisAddr        string        `env:"REDIS_ADDRESS"`
+	MetricsAddr      string        `env:"METRICS_ADDRESS" envDefault:":8081"`
+	ServerAddr       string        `env:"SERVER_ADDRESS" envDefault:":8080"`
+	JaegerAddr       string        `env:"JEAGER_ADDRESS"`
+	PostgresHost     string        `env:"POSTGRES_HOST"`
+	PostgresPort     string        `env:"POSTGRES_PORT"`
+	PostgresUser     string        `env:"POSTGRES_USER"`
+	PostgresPassword string        `env:"POSTGRES_PASSWORD"`
+	PostgresDB       string        `env:"POSTGRES_DB"`
+	MigrationPath    string        `env:"MIGRATIONS_PATH"`
+	PasswordHasher   string        `env:"PASSWORD_HASHER" envDefault:"argon2id"`
+	JWTSigningAlg    string        `env:"JWT_SIGNING_ALG" envDefault:"EdDSA"`
+	JWTKeyRotation   time.Duration `env:"JWT_KEY_ROTATION" envDefault:"168h"`
+	JWTKeyRetention  time.Duration `env:"JWT_KEY_RETENTION" envDefault:"720h"`
+	AccessTokenTTL   time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL  time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+	// JWTKeyEncryptionKey is a base64 AES-256 key that seals the signing
+	// keys stored in Redis. Without it they are stored in the clear.
+	JWTKeyEncryptionKey string `env:"JWT_KEY_ENCRYPTION_KEY"`
+	// PublicURL is the frontend base used in links sent by email.
+	PublicURL    string `env:"PUBLIC_URL" envDefault:"http://localhost:4200"`
+	Mailer       string `env:"MAILER" envDefault:"log"`
+	MailerDir    string `env:"MAILER_DIR" envDefault:"./tmp/mail"`
+	MailFrom     string `env:"MAIL_FROM" envDefault:"no-reply@localhost"`
+	SMTPHost     string `env:"SMTP_HOST"`
+	SMTPPort     string `env:"SMTP_PORT" envDefault:"587"`
+	SMTPUser     string `env:"SMTP_USER"`
+	SMTPPassword string `env:"SMTP_PASSWORD"`
+	// MFAIssuer is the account name prefix shown in authenticator apps.
+	MFAIssuer string `env:"MFA_ISSUER" envDefault:"go-auth"`
+	// AuthPublicURL is where this service is reachable from browsers; OAuth
+	// callbacks are registered with providers under it.
+	AuthPublicURL  string         `env:"AUTH_PUBLIC_URL" envDefault:"http://localhost:8080"`
+	OAuthProviders OAuthProviders `env:"OAUTH_PROVIDERS"`
+	// AccountDeletionGrace is how long a deleted account can be restored by
+	// logging in before it is purged.
+	AccountDeletionGrace time.Duration `env:"ACCOUNT_DELETION_GRACE" envDefault:"720h"`
+	// IntrospectionTokens are the shared secrets other services present as
+	// a bearer token to /introspect and the gRPC IntrospectionService. With
+	// none set introspection answers 401 to everyone.
+	IntrospectionTokens []string `env:"INTROSPECTION_TOKENS" envSeparator:","`
+	GRPCAddr            string   `env:"GRPC_ADDRESS" envDefault:":9090"`
+	KafkaBrokers        []string `env:"KAFKA_BROKERS" envSeparator:"," envDefault:"kafka:9092"`
+	KafkaGroupId        string   `env:"KAFKA_GROUP_ID" envDefault:"go-auth"`
+	// AuditTopic carries security events on their way to auth_events.
+	AuditTopic string `env:"AUDIT_TOPIC" envDefault:"auth_events"`
+	// TrustedOrigins may call the API from a browser with credentials;
+	// state-changing cookie requests from other origins are rejected.
+	TrustedOrigins []string `env:"TRUSTED_ORIGINS" envSeparator:"," envDefault:"http://localhost:4200"`
+	// TrustedProxies are the CIDRs of the reverse proxies in front of the
+	// service. Only they may set the client IP with X-Forwarded-For or
+	// X-Real-IP; with none set the peer address is the client IP.
+	TrustedProxies []string   `env:"TRUSTED_PROXIES" envSeparator:","`
+	RateLimits     RateLimits `env:"RATE_LIMITS"`
+	// LoginLockout is read from LOGIN_LOCKOUT_MAX_FAILURES and so on,
+	// AccountLockout from ACCOUNT_LOCKOUT_*. Variables left out keep the
+	// default.
+	LoginLockout   Lockout `envPrefix:"LOGIN_LOCKOUT_"`
+	AccountLockout Lockout `envPrefix:"ACCOUNT_LOCKOUT_"`
+	// UserEventsTopic carries user.created, user.updated and user.deleted
+	// for services that keep their own copy of accounts.
+	UserEventsTopic string `env:"USER_EVENTS_TOPIC" envDefault:"user_events"`
+	// AccessCookieMaxAge outlives the access token so that an expired one
+	// still reaches WithAuth and is refreshed there.
+	AccessCookieMaxAge time.Duration `env:"ACCESS_COOKIE_MAX_AGE" envDefault:"1h"`
+	CookieSecure       bool          `env:"COOKIE_SECURE" envDefault:"false"`
+	// CookieSameSite is strict, lax or none; none requires CookieSecure.
+	CookieSameSite string `env:"COOKIE_SAME_SITE" envDefault:"strict"`
+	CookieDomain   string `env:"COOKIE_DOMAIN"`
+	// New passwords have PasswordMinLength to PasswordMaxLength characters
+	// and mix PasswordMinClasses of lower case, upper case, digits and
+	// symbols.
+	PasswordMinLength  int `env:"PASSWORD_MIN_LENGTH" envDefault:"8"`
+	PasswordMaxLength  int `env:"PASSWORD_MAX_LENGTH" envDefault:"64"`
+	PasswordMinClasses int `env:"PASSWORD_MIN_CLASSES" envDefault:"2"`
+	// BreachedHashesFile lists SHA-1 hashes of breached passwords, one
+	// per line as in the Pwned Passwords downloads. New passwords found in
+	// it are rejected; without it the check is skipped.
+	BreachedHashesFile string `env:"BREACHED_PASSWORDS_FILE"`
 }
 
 func ParseEnv() (*Envs, error) {
-	e := Envs{}
+	e := Envs{LoginLockout: DefaultLoginLockout, AccountLockout: DefaultAccountLockout}
 	if err := env.Parse(&e); err != nil {
 		return nil, err
 	}
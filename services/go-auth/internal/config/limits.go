@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit allows Limit requests per client IP within Window.
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// DefaultRateLimits are the limits of the rate-limited endpoints by scope.
+var DefaultRateLimits = RateLimits{
+	"register":        {Limit: 5, Window: time.Hour},
+	"login":           {Limit: 20, Window: time.Minute},
+	"login_mfa":       {Limit: 20, Window: time.Minute},
+	"oauth_login":     {Limit: 20, Window: time.Minute},
+	"password_forgot": {Limit: 5, Window: time.Hour},
+	"password_reset":  {Limit: 10, Window: time.Hour},
+	"token":           {Limit: 60, Window: time.Minute},
+	"refresh":         {Limit: 60, Window: time.Minute},
+}
+
+// RateLimits is read from RATE_LIMITS as comma separated scope=limit/window
+// pairs, e.g. login=10/1m,register=3/1h. Scopes left out keep their
+// default.
+type RateLimits map[string]RateLimit
+
+func (l *RateLimits) UnmarshalText(text []byte) error {
+	limits := RateLimits{}
+	for _, pair := range strings.Split(string(text), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		scope, value, ok := strings.Cut(pair, "=")
+		limit, window, ok2 := strings.Cut(value, "/")
+		if !ok || !ok2 {
+			return fmt.Errorf("RATE_LIMITS: %q is not scope=limit/window", pair)
+		}
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return fmt.Errorf("RATE_LIMITS: %q: %w", pair, err)
+		}
+		d, err := time.ParseDuration(window)
+		if err != nil {
+			return fmt.Errorf("RATE_LIMITS: %q: %w", pair, err)
+		}
+		limits[strings.TrimSpace(scope)] = RateLimit{Limit: n, Window: d}
+	}
+	*l = limits
+	return nil
+}
+
+// Get returns the limit of scope, the default one unless configured.
+func (l RateLimits) Get(scope string) RateLimit {
+	if limit, ok := l[scope]; ok {
+		return limit
+	}
+	return DefaultRateLimits[scope]
+}
+
+func (l RateLimits) validate() []error {
+	var errs []error
+	scopes := make([]string, 0, len(l))
+	for scope := range l {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	for _, scope := range scopes {
+		limit := l[scope]
+		if _, ok := DefaultRateLimits[scope]; !ok {
+			errs = append(errs, fmt.Errorf("RATE_LIMITS: unknown scope %q", scope))
+		} else if limit.Limit < 1 || limit.Window <= 0 {
+			errs = append(errs, fmt.Errorf("RATE_LIMITS: %s must allow at least one request in a positive window", scope))
+		}
+	}
+	return errs
+}
+
+// Lockout locks a key out for BaseLock after MaxFailures failed logins
+// within Window. Every further lock within Memory doubles it up to MaxLock.
+type Lockout struct {
+	MaxFailures int           `env:"MAX_FAILURES"`
+	Window      time.Duration `env:"WINDOW"`
+	BaseLock    time.Duration `env:"BASE_LOCK"`
+	MaxLock     time.Duration `env:"MAX_LOCK"`
+	Memory      time.Duration `env:"MEMORY"`
+}
+
+// DefaultLoginLockout applies to a login from one client IP.
+var DefaultLoginLockout = Lockout{
+	MaxFailures: 5,
+	Window:      15 * time.Minute,
+	BaseLock:    time.Minute,
+	MaxLock:     time.Hour,
+	Memory:      24 * time.Hour,
+}
+
+// DefaultAccountLockout applies to a login from all addresses together,
+// so rotating addresses doesn't buy more guesses. It allows more failures
+// than the per-IP lockout, so locking the owner out everywhere takes many
+// addresses.
+var DefaultAccountLockout = Lockout{
+	MaxFailures: 50,
+	Window:      time.Hour,
+	BaseLock:    5 * time.Minute,
+	MaxLock:     time.Hour,
+	Memory:      24 * time.Hour,
+}
+
+func (l Lockout) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("max_failures", l.MaxFailures),
+		slog.Duration("window", l.Window),
+		slog.Duration("base_lock", l.BaseLock),
+		slog.Duration("max_lock", l.MaxLock),
+		slog.Duration("memory", l.Memory),
+	)
+}
+
+// validate reports problems under the env prefix of the lockout.
+func (l Lockout) validate(prefix string) []error {
+	var errs []error
+	if l.MaxFailures < 1 || l.Window <= 0 {
+		errs = append(errs, fmt.Errorf("%sMAX_FAILURES and %sWINDOW must be positive", prefix, prefix))
+	}
+	if l.BaseLock <= 0 || l.MaxLock < l.BaseLock {
+		errs = append(errs, fmt.Errorf("%sBASE_LOCK (%s) and %sMAX_LOCK (%s) must satisfy 0 < base <= max", prefix, l.BaseLock, prefix, l.MaxLock))
+	}
+	if l.Memory <= 0 {
+		errs = append(errs, fmt.Errorf("%sMEMORY must be positive, got %s", prefix, l.Memory))
+	}
+	return errs
+}
+
+// TrustedProxyPrefixes parses TRUSTED_PROXIES. A bare address trusts just
+// that address.
+func (c *Config) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, proxy := range c.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: invalid CIDR or address %q", proxy)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
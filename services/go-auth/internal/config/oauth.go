@@ -0,0 +1,21 @@
+package config
+
+import "encoding/json"
+
+// OAuthProvider configures one OpenID Connect provider. Endpoints are
+// discovered from Issuer.
+type OAuthProvider struct {
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret"`
+	Scopes       []string `json:"scopes"`
+}
+
+// OAuthProviders is read from OAUTH_PROVIDERS as a JSON array, e.g.
+// [{"name":"google","issuer":"https://accounts.google.com","clientId":"...","clientSecret":"..."}]
+type OAuthProviders []OAuthProvider
+
+func (p *OAuthProviders) UnmarshalText(text []byte) error {
+	return json.Unmarshal(text, (*[]OAuthProvider)(p))
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Validate checks the security policy, so a misconfigured instance fails
+// at startup instead of handing out unusable or unsafe cookies.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.ServerAddr == "" {
+		errs = append(errs, errors.New("SERVER_ADDRESS is empty"))
+	}
+	if c.MetricsAddr == "" {
+		errs = append(errs, errors.New("METRICS_ADDRESS is empty"))
+	}
+	if c.AccessTokenTTL <= 0 {
+		errs = append(errs, fmt.Errorf("ACCESS_TOKEN_TTL must be positive, got %s", c.AccessTokenTTL))
+	}
+	if c.RefreshTokenTTL <= c.AccessTokenTTL {
+		errs = append(errs, fmt.Errorf("REFRESH_TOKEN_TTL (%s) must be longer than ACCESS_TOKEN_TTL (%s)", c.RefreshTokenTTL, c.AccessTokenTTL))
+	}
+	// A retired signing key has to verify the refresh tokens it signed
+	// until they expire.
+	if c.JWTKeyRetention < c.RefreshTokenTTL {
+		errs = append(errs, fmt.Errorf("JWT_KEY_RETENTION (%s) must not be shorter than REFRESH_TOKEN_TTL (%s)", c.JWTKeyRetention, c.RefreshTokenTTL))
+	}
+	// The names are those of pkg/keyring, which imports this package.
+	if c.JWTSigningAlg != "RS256" && c.JWTSigningAlg != "EdDSA" {
+		errs = append(errs, fmt.Errorf("JWT_SIGNING_ALG must be RS256 or EdDSA, got %q", c.JWTSigningAlg))
+	}
+	if _, err := c.KeyEncryptionKey(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.AccessCookieMaxAge < c.AccessTokenTTL {
+		errs = append(errs, fmt.Errorf("ACCESS_COOKIE_MAX_AGE (%s) must not be shorter than ACCESS_TOKEN_TTL (%s)", c.AccessCookieMaxAge, c.AccessTokenTTL))
+	}
+	sameSite, err := parseSameSite(c.CookieSameSite)
+	if err != nil {
+		errs = append(errs, err)
+	} else if sameSite == http.SameSiteNoneMode && !c.CookieSecure {
+		errs = append(errs, errors.New("COOKIE_SAME_SITE=none requires COOKIE_SECURE=true"))
+	}
+	if c.PasswordMinLength < 1 || c.PasswordMaxLength < c.PasswordMinLength {
+		errs = append(errs, fmt.Errorf("PASSWORD_MIN_LENGTH (%d) and PASSWORD_MAX_LENGTH (%d) must satisfy 1 <= min <= max", c.PasswordMinLength, c.PasswordMaxLength))
+	}
+	if c.PasswordMinClasses < 0 || c.PasswordMinClasses > 4 {
+		errs = append(errs, fmt.Errorf("PASSWORD_MIN_CLASSES must be between 0 and 4, got %d", c.PasswordMinClasses))
+	}
+	if len(c.TrustedOrigins) == 0 {
+		errs = append(errs, errors.New("TRUSTED_ORIGINS is empty"))
+	}
+	for _, origin := range c.TrustedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if _, err := c.TrustedProxyPrefixes(); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, c.RateLimits.validate()...)
+	errs = append(errs, c.LoginLockout.validate("LOGIN_LOCKOUT_")...)
+	errs = append(errs, c.AccountLockout.validate("ACCOUNT_LOCKOUT_")...)
+	if c.AccountLockout.MaxFailures <= c.LoginLockout.MaxFailures {
+		errs = append(errs, fmt.Errorf("ACCOUNT_LOCKOUT_MAX_FAILURES (%d) must be higher than LOGIN_LOCKOUT_MAX_FAILURES (%d)", c.AccountLockout.MaxFailures, c.LoginLockout.MaxFailures))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return nil
+}
+
+// KeyEncryptionKey decodes JWT_KEY_ENCRYPTION_KEY. It is nil when the
+// signing keys are not to be encrypted.
+func (c *Config) KeyEncryptionKey() ([]byte, error) {
+	if c.JWTKeyEncryptionKey == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(c.JWTKeyEncryptionKey)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("JWT_KEY_ENCRYPTION_KEY must be 32 bytes in base64")
+	}
+	return key, nil
+}
+
+// SameSite is the SameSite mode of the cookies set by the service.
+func (c *Config) SameSite() http.SameSite {
+	mode, err := parseSameSite(c.CookieSameSite)
+	if err != nil {
+		return http.SameSiteStrictMode
+	}
+	return mode
+}
+
+// PolicyAttrs describes the effective security policy for the startup log.
+func (c *Config) PolicyAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("server_addr", c.ServerAddr),
+		slog.String("metrics_addr", c.MetricsAddr),
+		slog.String("grpc_addr", c.GRPCAddr),
+		slog.Duration("access_token_ttl", c.AccessTokenTTL),
+		slog.Duration("refresh_token_ttl", c.RefreshTokenTTL),
+		slog.Duration("access_cookie_max_age", c.AccessCookieMaxAge),
+		slog.Bool("cookie_secure", c.CookieSecure),
+		slog.String("cookie_same_site", strings.ToLower(c.CookieSameSite)),
+		slog.String("cookie_domain", c.CookieDomain),
+		slog.Any("trusted_origins", c.TrustedOrigins),
+		slog.Any("trusted_proxies", c.TrustedProxies),
+		slog.Any("login_lockout", c.LoginLockout),
+		slog.Any("account_lockout", c.AccountLockout),
+		slog.String("jwt_signing_alg", c.JWTSigningAlg),
+		slog.Bool("jwt_key_encryption", c.JWTKeyEncryptionKey != ""),
+		slog.String("password_hasher", c.PasswordHasher),
+		slog.Int("password_min_length", c.PasswordMinLength),
+		slog.Int("password_max_length", c.PasswordMaxLength),
+		slog.Int("password_min_classes", c.PasswordMinClasses),
+		slog.Bool("breached_password_check", c.BreachedHashesFile != ""),
+	}
+}
+
+func parseSameSite(mode string) (http.SameSite, error) {
+	switch strings.ToLower(mode) {
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("COOKIE_SAME_SITE must be strict, lax or none, got %q", mode)
+}
+
+// validateOrigin accepts a bare scheme://host[:port]. Wildcards are not
+// allowed because the API is called with credentials.
+func validateOrigin(origin string) error {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		strings.Contains(u.Host, "*") || u.Path != "" || u.RawQuery != "" {
+		return fmt.Errorf("TRUSTED_ORIGINS: invalid origin %q", origin)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validConfig() *Config {
+	return &Config{
+		ServerAddr:         ":8080",
+		MetricsAddr:        ":8081",
+		AccessTokenTTL:     15 * time.Minute,
+		RefreshTokenTTL:    30 * 24 * time.Hour,
+		JWTSigningAlg:      "EdDSA",
+		JWTKeyRetention:    30 * 24 * time.Hour,
+		AccessCookieMaxAge: time.Hour,
+		CookieSameSite:     "strict",
+		TrustedOrigins:     []string{"http://localhost:4200"},
+		PasswordMinLength:  8,
+		PasswordMaxLength:  64,
+		PasswordMinClasses: 2,
+		LoginLockout:       DefaultLoginLockout,
+		AccountLockout:     DefaultAccountLockout,
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name          string
+		modify        func(*Config)
+		expectedError string
+	}{
+		{name: "defaults", modify: func(c *Config) {}},
+		{name: "lax", modify: func(c *Config) { c.CookieSameSite = "Lax" }},
+		{name: "none with secure", modify: func(c *Config) { c.CookieSameSite = "none"; c.CookieSecure = true }},
+		{
+			name:          "none without secure",
+			modify:        func(c *Config) { c.CookieSameSite = "none" },
+			expectedError: "COOKIE_SAME_SITE=none requires COOKIE_SECURE=true",
+		},
+		{
+			name:          "unknown same site",
+			modify:        func(c *Config) { c.CookieSameSite = "sometimes" },
+			expectedError: `COOKIE_SAME_SITE must be strict, lax or none, got "sometimes"`,
+		},
+		{
+			name:          "zero access ttl",
+			modify:        func(c *Config) { c.AccessTokenTTL = 0 },
+			expectedError: "ACCESS_TOKEN_TTL must be positive",
+		},
+		{
+			name:          "refresh shorter than access",
+			modify:        func(c *Config) { c.RefreshTokenTTL = time.Minute },
+			expectedError: "REFRESH_TOKEN_TTL (1m0s) must be longer than ACCESS_TOKEN_TTL (15m0s)",
+		},
+		{
+			name:          "keys retired before the refresh tokens expire",
+			modify:        func(c *Config) { c.JWTKeyRetention = 24 * time.Hour },
+			expectedError: "JWT_KEY_RETENTION (24h0m0s) must not be shorter than REFRESH_TOKEN_TTL (720h0m0s)",
+		},
+		{name: "rs256", modify: func(c *Config) { c.JWTSigningAlg = "RS256" }},
+		{
+			name:          "unknown signing alg",
+			modify:        func(c *Config) { c.JWTSigningAlg = "HS256" },
+			expectedError: `JWT_SIGNING_ALG must be RS256 or EdDSA, got "HS256"`,
+		},
+		{name: "key encryption key", modify: func(c *Config) { c.JWTKeyEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" }},
+		{
+			name:          "short key encryption key",
+			modify:        func(c *Config) { c.JWTKeyEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZg==" },
+			expectedError: "JWT_KEY_ENCRYPTION_KEY must be 32 bytes in base64",
+		},
+		{
+			name:          "access cookie expires before the token",
+			modify:        func(c *Config) { c.AccessCookieMaxAge = time.Minute },
+			expectedError: "ACCESS_COOKIE_MAX_AGE (1m0s) must not be shorter than ACCESS_TOKEN_TTL (15m0s)",
+		},
+		{
+			name:          "no server address",
+			modify:        func(c *Config) { c.ServerAddr = "" },
+			expectedError: "SERVER_ADDRESS is empty",
+		},
+		{
+			name:          "password max below min",
+			modify:        func(c *Config) { c.PasswordMaxLength = 4 },
+			expectedError: "PASSWORD_MIN_LENGTH (8) and PASSWORD_MAX_LENGTH (4) must satisfy 1 <= min <= max",
+		},
+		{
+			name:          "too many password classes",
+			modify:        func(c *Config) { c.PasswordMinClasses = 5 },
+			expectedError: "PASSWORD_MIN_CLASSES must be between 0 and 4, got 5",
+		},
+		{
+			name:          "no trusted origins",
+			modify:        func(c *Config) { c.TrustedOrigins = nil },
+			expectedError: "TRUSTED_ORIGINS is empty",
+		},
+		{
+			name:          "wildcard origin",
+			modify:        func(c *Config) { c.TrustedOrigins = []string{"*"} },
+			expectedError: `invalid origin "*"`,
+		},
+		{
+			name:          "origin with path",
+			modify:        func(c *Config) { c.TrustedOrigins = []string{"https://example.com/app"} },
+			expectedError: `invalid origin "https://example.com/app"`,
+		},
+		{name: "trusted proxies", modify: func(c *Config) { c.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.10", "fd00::/8"} }},
+		{
+			name:          "invalid trusted proxy",
+			modify:        func(c *Config) { c.TrustedProxies = []string{"10.0.0.0/33"} },
+			expectedError: `TRUSTED_PROXIES: invalid CIDR or address "10.0.0.0/33"`,
+		},
+		{name: "rate limits", modify: func(c *Config) { c.RateLimits = RateLimits{"login": {Limit: 5, Window: time.Minute}} }},
+		{
+			name:          "unknown rate limit scope",
+			modify:        func(c *Config) { c.RateLimits = RateLimits{"signup": {Limit: 5, Window: time.Minute}} },
+			expectedError: `RATE_LIMITS: unknown scope "signup"`,
+		},
+		{
+			name:          "zero rate limit",
+			modify:        func(c *Config) { c.RateLimits = RateLimits{"login": {Window: time.Minute}} },
+			expectedError: "RATE_LIMITS: login must allow at least one request in a positive window",
+		},
+		{
+			name:          "zero lockout failures",
+			modify:        func(c *Config) { c.LoginLockout.MaxFailures = 0 },
+			expectedError: "LOGIN_LOCKOUT_MAX_FAILURES and LOGIN_LOCKOUT_WINDOW must be positive",
+		},
+		{
+			name:          "lock longer than the cap",
+			modify:        func(c *Config) { c.AccountLockout.BaseLock = 2 * time.Hour },
+			expectedError: "ACCOUNT_LOCKOUT_BASE_LOCK (2h0m0s) and ACCOUNT_LOCKOUT_MAX_LOCK (1h0m0s) must satisfy 0 < base <= max",
+		},
+		{
+			name:          "no lockout memory",
+			modify:        func(c *Config) { c.LoginLockout.Memory = 0 },
+			expectedError: "LOGIN_LOCKOUT_MEMORY must be positive",
+		},
+		{
+			name:          "account lockout not above the per-IP one",
+			modify:        func(c *Config) { c.AccountLockout.MaxFailures = 5 },
+			expectedError: "ACCOUNT_LOCKOUT_MAX_FAILURES (5) must be higher than LOGIN_LOCKOUT_MAX_FAILURES (5)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestConfig_ValidateReportsEveryProblem(t *testing.T) {
+	cfg := validConfig()
+	cfg.ServerAddr = ""
+	cfg.CookieSameSite = "none"
+
+	err := cfg.Validate()
+
+	assert.ErrorContains(t, err, "SERVER_ADDRESS is empty")
+	assert.ErrorContains(t, err, "COOKIE_SAME_SITE=none requires COOKIE_SECURE=true")
+}
+
+func TestConfig_SameSite(t *testing.T) {
+	cfg := validConfig()
+	assert.Equal(t, http.SameSiteStrictMode, cfg.SameSite())
+	cfg.CookieSameSite = "LAX"
+	assert.Equal(t, http.SameSiteLaxMode, cfg.SameSite())
+}
+
+func TestRateLimits_UnmarshalText(t *testing.T) {
+	var limits RateLimits
+	assert.NoError(t, limits.UnmarshalText([]byte("login=10/1m, register=3/1h")))
+	assert.Equal(t, RateLimit{Limit: 10, Window: time.Minute}, limits.Get("login"))
+	assert.Equal(t, RateLimit{Limit: 3, Window: time.Hour}, limits.Get("register"))
+	assert.Equal(t, DefaultRateLimits["token"], limits.Get("token"), "scopes left out keep the default")
+
+	for _, text := range []string{"login", "login=10", "login=ten/1m", "login=10/minute"} {
+		assert.Error(t, limits.UnmarshalText([]byte(text)), text)
+	}
+}
@@ -3,4 +3,16 @@ package constants
 const (
 	AccessTokenCookie  = "access_token"
 	RefreshTokenCookie = "refresh_token"
+	DeviceNameHeader   = "X-Device-Name"
+	// TokenModeHeader set to TokenModeJSON makes /register, /login,
+	// /login/mfa and /refresh return tokens in the body instead of cookies.
+	TokenModeHeader = "X-Token-Mode"
+	TokenModeJSON   = "json"
+	// CSRFTokenCookie and CSRFTokenHeader carry the double-submit CSRF
+	// token. The names are the ones Angular's HttpClient uses by default.
+	CSRFTokenCookie = "XSRF-TOKEN"
+	CSRFTokenHeader = "X-XSRF-TOKEN"
+	// OAuthStateCookie holds a hash of the state of the OAuth flow the
+	// browser started; the callback is only accepted along with it.
+	OAuthStateCookie = "oauth_state"
 )
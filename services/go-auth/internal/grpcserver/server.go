@@ -0,0 +1,79 @@
+// Package grpcserver serves the service's gRPC API to other services.
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+
+	"go-auth/internal/app"
+	"go-auth/internal/router/middlewares"
+	"go-auth/pkg/introspect/introspectpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func New() *grpc.Server {
+	var introspection app.AppIntrospectionService
+	var cfg app.AppConfig
+	if err := app.AppContainer.Invoke(func(is app.AppIntrospectionService, c app.AppConfig) {
+		introspection = is
+		cfg = c
+	}); err != nil {
+		panic("grpcserver New, get introspection service: " + err.Error())
+	}
+	return NewServer(introspection, cfg.GetConfig().IntrospectionTokens)
+}
+
+// NewServer registers the services on a new grpc.Server that admits only
+// callers presenting one of secrets, like POST /introspect does.
+func NewServer(introspection app.AppIntrospectionService, secrets []string) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(requireServiceToken(secrets)))
+	introspectpb.RegisterIntrospectionServiceServer(server, &introspectionServer{introspection: introspection})
+	return server
+}
+
+func requireServiceToken(secrets []string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var authorization string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("authorization"); len(values) > 0 {
+				authorization = values[0]
+			}
+		}
+		if !middlewares.ValidServiceToken(authorization, secrets) {
+			slog.Warn("grpc requireServiceToken rejected caller", "method", info.FullMethod)
+			return nil, status.Error(codes.Unauthenticated, "unknown caller")
+		}
+		return handler(ctx, req)
+	}
+}
+
+type introspectionServer struct {
+	introspectpb.UnimplementedIntrospectionServiceServer
+	introspection app.AppIntrospectionService
+}
+
+func (s *introspectionServer) Introspect(ctx context.Context, req *introspectpb.IntrospectRequest) (*introspectpb.IntrospectResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	res, err := s.introspection.Introspect(ctx, req.GetToken())
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, status.Error(codes.Internal, "failed to introspect token")
+	}
+	return &introspectpb.IntrospectResponse{
+		Active:      res.Active,
+		Sub:         res.Subject,
+		Sid:         res.SessionId,
+		Roles:       res.Roles,
+		Permissions: res.Permissions,
+		Exp:         res.ExpiresAt,
+		Iat:         res.IssuedAt,
+		Jti:         res.Id,
+		TokenType:   res.TokenType,
+	}, nil
+}
@@ -0,0 +1,91 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/pkg/introspect"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type MockIntrospectionService struct {
+	mock.Mock
+}
+
+func (m *MockIntrospectionService) Introspect(ctx context.Context, accessToken string) (*models.Introspection, error) {
+	args := m.Called(ctx, accessToken)
+	res, _ := args.Get(0).(*models.Introspection)
+	return res, args.Error(1)
+}
+
+func dial(t *testing.T, introspection app.AppIntrospectionService) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := NewServer(introspection, []string{"service-secret"})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestIntrospectionServer(t *testing.T) {
+	ctx := context.Background()
+	mockIntrospection := &MockIntrospectionService{}
+	mockIntrospection.On("Introspect", mock.Anything, "good").Return(&models.Introspection{
+		Active:      true,
+		Subject:     "123",
+		SessionId:   "session-1",
+		Roles:       []string{"player"},
+		Permissions: []string{"game:play"},
+		ExpiresAt:   1700000000,
+		TokenType:   models.TokenTypeAccess,
+	}, nil)
+	mockIntrospection.On("Introspect", mock.Anything, "revoked").Return(&models.Introspection{}, nil)
+	mockIntrospection.On("Introspect", mock.Anything, "boom").Return(nil, errors.New("redis down"))
+	conn := dial(t, mockIntrospection)
+
+	transport := introspect.NewGRPCTransport(conn, "service-secret")
+	res, err := transport.Introspect(ctx, "good")
+	assert.NoError(t, err)
+	assert.Equal(t, &introspect.Result{
+		Active:      true,
+		Subject:     "123",
+		SessionId:   "session-1",
+		Roles:       []string{"player"},
+		Permissions: []string{"game:play"},
+		ExpiresAt:   1700000000,
+		TokenType:   models.TokenTypeAccess,
+	}, res)
+
+	res, err = transport.Introspect(ctx, "revoked")
+	assert.NoError(t, err)
+	assert.False(t, res.Active)
+
+	_, err = transport.Introspect(ctx, "boom")
+	assert.Error(t, err)
+
+	_, err = transport.Introspect(ctx, "")
+	assert.Error(t, err)
+
+	_, err = introspect.NewGRPCTransport(conn, "wrong").Introspect(ctx, "good")
+	assert.ErrorIs(t, err, introspect.ErrUnauthorized)
+
+	mockIntrospection.AssertNumberOfCalls(t, "Introspect", 3)
+}
@@ -0,0 +1,34 @@
+package models
+
+// EmailVerifyReq represents email confirmation request
+// @Description Токен подтверждения из письма
+type EmailVerifyReq struct {
+	Token string `json:"token"`
+}
+
+// PasswordForgotReq represents password reset request
+// @Description Email, на который будет отправлена ссылка для сброса пароля
+type PasswordForgotReq struct {
+	Email string `json:"email" example:"user@example.com"`
+}
+
+// PasswordResetReq represents new password request
+// @Description Токен сброса из письма и новый пароль
+type PasswordResetReq struct {
+	Token    string `json:"token"`
+	Password string `json:"password" example:"strongPassword123" minLength:"6" maxLength:"32"`
+}
+
+// ActionToken is what a one-time email token is bound to.
+type ActionToken struct {
+	Jti    string `json:"jti"`
+	UserId string `json:"userId"`
+	Email  string `json:"email"`
+}
+
+// Mail is a plain text email message.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
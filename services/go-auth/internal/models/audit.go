@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Types of AuthEvent.
+const (
+	AuthEventRegistered      = "registered"
+	AuthEventLoginSucceeded  = "login_succeeded"
+	AuthEventLoginFailed     = "login_failed"
+	AuthEventTokenRefreshed  = "token_refreshed"
+	AuthEventTokenReused     = "refresh_token_reused"
+	AuthEventLoggedOut       = "logged_out"
+	AuthEventPasswordChanged = "password_changed"
+	AuthEventPasswordReset   = "password_reset"
+)
+
+// AuthEvent is one security-relevant thing that happened to an account.
+// Login is set when there is no user to attribute a failed login to.
+// @Description Событие безопасности
+type AuthEvent struct {
+	Id        string            `json:"id" example:"0f5c2d1e-7a4b-4c39-9b1d-3e6f2a8c9d10"`
+	Type      string            `json:"type" example:"login_failed"`
+	UserId    string            `json:"userId,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Login     string            `json:"login,omitempty" example:"player1"`
+	SessionId string            `json:"sessionId,omitempty"`
+	IP        string            `json:"ip,omitempty" example:"203.0.113.7"`
+	UserAgent string            `json:"userAgent,omitempty" example:"Mozilla/5.0"`
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// AuthEventFilter selects events for GET /audit/events. Zero fields don't
+// filter.
+type AuthEventFilter struct {
+	UserId string
+	Type   string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// APIClient is a machine caller that gets tokens by the client-credentials
+// grant. Its secret is stored hashed and shown only on registration.
+type APIClient struct {
+	Id         string     `json:"clientId"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	SecretHash string     `json:"-"`
+}
+
+type APIClientCreateReq struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type APIClientCreateRes struct {
+	APIClient
+	ClientSecret string `json:"clientSecret"`
+}
+
+// ClientTokenRes is the RFC 6749 token response.
+type ClientTokenRes struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// OAuthErrorRes is the RFC 6749 error response of /token.
+type OAuthErrorRes struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
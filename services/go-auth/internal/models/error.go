@@ -0,0 +1,20 @@
+package models
+
+// FieldError describes why a request field was rejected
+// @Description Ошибка валидации поля запроса
+type FieldError struct {
+	Field   string `json:"field" example:"password"`
+	Code    string `json:"code" example:"too_short"`
+	Message string `json:"message" example:"must be at least 8 characters"`
+}
+
+// ErrorRes is the body of every error response. Code is stable for clients
+// to branch on, Message is for people. TraceId ties the response to the
+// request trace and logs.
+// @Description Ошибка. Поле code не меняется между версиями, message — для человека, fields перечисляет отклонённые поля запроса
+type ErrorRes struct {
+	Code    string       `json:"code" example:"validation_failed"`
+	Message string       `json:"message" example:"Validation failed"`
+	Fields  []FieldError `json:"fields,omitempty"`
+	TraceId string       `json:"traceId,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+}
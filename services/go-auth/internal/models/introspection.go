@@ -0,0 +1,15 @@
+package models
+
+// Introspection is the RFC 7662 answer about a token. For a token that is
+// not active only Active is set, so nothing leaks about why it was rejected.
+type Introspection struct {
+	Active      bool     `json:"active"`
+	Subject     string   `json:"sub,omitempty"`
+	SessionId   string   `json:"sid,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	ExpiresAt   int64    `json:"exp,omitempty"`
+	IssuedAt    int64    `json:"iat,omitempty"`
+	Id          string   `json:"jti,omitempty"`
+	TokenType   string   `json:"token_type,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// JWK is a public token signing key as published on /.well-known/jwks.json
+// @Description Публичный ключ для проверки подписи JWT
+type JWK struct {
+	Kty string `json:"kty" example:"OKP"`
+	Kid string `json:"kid" example:"0f5c2d1e-7a4b-4c39-9b1d-3e6f2a8c9d10"`
+	Use string `json:"use" example:"sig"`
+	Alg string `json:"alg" example:"EdDSA"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty" example:"Ed25519"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet is the JSON Web Key Set document
+// @Description Набор публичных ключей (JWKS)
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
@@ -0,0 +1,43 @@
+package models
+
+// TOTPSetupRes represents TOTP enrollment data
+// @Description Секрет и otpauth URI для приложения-аутентификатора
+type TOTPSetupRes struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	Uri    string `json:"uri" example:"otpauth://totp/go-auth:user123?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP&issuer=go-auth"`
+}
+
+// MFACodeReq represents second factor code
+// @Description Код из приложения-аутентификатора или код восстановления
+type MFACodeReq struct {
+	Code string `json:"code" example:"123456"`
+}
+
+// RecoveryCodesRes represents freshly generated recovery codes
+// @Description Коды восстановления. Показываются один раз
+type RecoveryCodesRes struct {
+	Codes []string `json:"codes"`
+}
+
+// MFALoginReq represents second step of login
+// @Description Токен MFA challenge из ответа /login и код второго фактора
+type MFALoginReq struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code" example:"123456"`
+}
+
+// MFAChallengeRes is returned by login when a second factor is required
+// @Description Требуется второй фактор: передайте challenge и код в /login/mfa
+type MFAChallengeRes struct {
+	MfaRequired bool   `json:"mfaRequired" example:"true"`
+	Challenge   string `json:"challenge"`
+}
+
+// TOTPState is the stored TOTP enrollment of a user. Secret is set during
+// setup and kept once Enabled.
+type TOTPState struct {
+	Login    string
+	Secret   string
+	Enabled  bool
+	LastStep int64
+}
@@ -0,0 +1,19 @@
+package models
+
+// Identity is a user as asserted by an external identity provider.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OAuthState is what we remember between redirecting to a provider and its
+// callback. UserId is set when an already signed in user links an account.
+type OAuthState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	UserId   string `json:"userId,omitempty"`
+}
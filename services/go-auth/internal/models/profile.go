@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Profile represents the current user
+// @Description Профиль текущего пользователя
+type Profile struct {
+	Id            string    `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Login         string    `json:"login" example:"user123"`
+	Email         string    `json:"email,omitempty" example:"user@example.com"`
+	EmailVerified bool      `json:"emailVerified"`
+	DisplayName   string    `json:"displayName,omitempty" example:"User"`
+	AvatarUrl     string    `json:"avatarUrl,omitempty" example:"https://example.com/avatar.png"`
+	MfaEnabled    bool      `json:"mfaEnabled"`
+	HasPassword   bool      `json:"hasPassword"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// ProfileUpdateReq represents profile changes
+// @Description Изменения профиля. Отсутствующие поля не меняются, пустая строка очищает поле
+type ProfileUpdateReq struct {
+	DisplayName *string `json:"displayName,omitempty" example:"User" maxLength:"64"`
+	Email       *string `json:"email,omitempty" example:"user@example.com"`
+	AvatarUrl   *string `json:"avatarUrl,omitempty" example:"https://example.com/avatar.png"`
+}
+
+// PasswordChangeReq represents password change request
+// @Description Текущий и новый пароль. Текущий не нужен, если пароль еще не задан
+type PasswordChangeReq struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword" example:"strongPassword123" minLength:"6" maxLength:"32"`
+}
+
+// AccountDeleteReq represents account deletion request
+// @Description Пароль для подтверждения удаления, если он задан
+type AccountDeleteReq struct {
+	Password string `json:"password"`
+}
+
+// ProfileUpdateDto holds validated changes; nil fields are left as is.
+type ProfileUpdateDto struct {
+	DisplayName *string
+	Email       *string
+	AvatarUrl   *string
+}
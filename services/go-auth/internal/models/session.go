@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Session is a single login of a user. Its id is the refresh token family.
+// @Description Активная сессия пользователя
+type Session struct {
+	Id        string    `json:"id" example:"0f5c2d1e-7a4b-4c39-9b1d-3e6f2a8c9d10"`
+	UserId    string    `json:"userId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Device    string    `json:"device" example:"Pixel 8"`
+	UserAgent string    `json:"userAgent" example:"Mozilla/5.0"`
+	IP        string    `json:"ip" example:"203.0.113.7"`
+	CreatedAt time.Time `json:"createdAt"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Current   bool      `json:"current"`
+}
+
+// ClientInfo describes the client a session is created for.
+type ClientInfo struct {
+	Device    string
+	UserAgent string
+	IP        string
+}
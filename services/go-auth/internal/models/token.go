@@ -1,6 +1,65 @@
 package models
 
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	// One-time tokens sent by email.
+	TokenTypeEmailVerify   = "email_verify"
+	TokenTypePasswordReset = "password_reset"
+	// Proves the password step of a login that still needs a second factor.
+	TokenTypeMFAChallenge = "mfa_challenge"
+	// Issued to an API client by the client-credentials grant.
+	TokenTypeClient = "client"
+)
+
 type TokenDto struct {
 	Refresh string
 	Access  string
+	// ExpiresIn is the lifetime of Access.
+	ExpiresIn time.Duration
+}
+
+// TokenRes carries the tokens in the body for clients that can't use
+// cookies. It has the shape of an RFC 6749 token response.
+// @Description Токены в теле ответа (режим X-Token-Mode: json)
+type TokenRes struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int    `json:"expires_in" example:"900"`
+}
+
+// RefreshReq is the body of /refresh in JSON token mode.
+// @Description Refresh token для обновления пары токенов
+type RefreshReq struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenClaims are carried by every token we sign. Family groups
+// every refresh token produced by rotation from a single login. Roles and
+// Permissions are only set in access tokens and are as of when the token
+// was issued. Client tokens carry the granted Scope instead, space
+// separated as in RFC 6749, and their subject is the client id.
+type TokenClaims struct {
+	Type        string   `json:"typ"`
+	Family      string   `json:"fam,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"perms,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Principal is the authenticated caller of a request: a user, or an API
+// client when ClientId is set.
+type Principal struct {
+	UserId    string
+	SessionId string
+	ClientId  string
+	Claims    TokenClaims
 }
@@ -1,10 +1,13 @@
 package models
 
+import "time"
+
 // UserCreateReq represents user registration/login request
 // @Description Данные для регистрации или входа пользователя
 type UserCreateReq struct {
 	Login    string `json:"login" example:"user123" minLength:"3" maxLength:"20"`
-	Password string `json:"password" example:"strongPassword123" minLength:"6" maxLength:"32"`
+	Password string `json:"password" example:"strongPassword123" minLength:"8" maxLength:"64"`
+	Email    string `json:"email,omitempty" example:"user@example.com"`
 }
 
 // UserCreateRes represents successful registration response
@@ -17,10 +20,19 @@ type UserCreateRes struct {
 type UserCreateDto struct {
 	Login        string
 	PasswordHash string
+	Email        string
 }
 
 type User struct {
-	Id           string `db:"id" json:"id"`
-	Login        string `db:"login" json:"login"`
-	PasswordHash string `db:"password_hash" json:"-"`
+	Id            string    `db:"id" json:"id"`
+	Login         string    `db:"login" json:"login"`
+	PasswordHash  string    `db:"password_hash" json:"-"`
+	Email         string    `db:"email" json:"email,omitempty"`
+	EmailVerified bool      `db:"email_verified_at" json:"emailVerified"`
+	MFAEnabled    bool      `db:"totp_enabled_at" json:"mfaEnabled"`
+	DisplayName   string    `db:"display_name" json:"displayName,omitempty"`
+	AvatarUrl     string    `db:"avatar_url" json:"avatarUrl,omitempty"`
+	CreatedAt     time.Time `db:"created_at" json:"createdAt"`
+	// DeletedAt is set while the account waits out the deletion grace period.
+	DeletedAt *time.Time `db:"deleted_at" json:"-"`
 }
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Types of UserEvent.
+const (
+	UserEventCreated = "user.created"
+	UserEventUpdated = "user.updated"
+	UserEventDeleted = "user.deleted"
+)
+
+// UserEvent tells other services about a change to an account. Created and
+// updated events carry the whole public profile, so consumers can upsert
+// without calling back; deleted events only carry the ids.
+type UserEvent struct {
+	Id            string     `json:"id"`
+	Type          string     `json:"type"`
+	UserId        string     `json:"userId"`
+	Login         string     `json:"login,omitempty"`
+	Email         string     `json:"email,omitempty"`
+	EmailVerified bool       `json:"emailVerified,omitempty"`
+	DisplayName   string     `json:"displayName,omitempty"`
+	AvatarUrl     string     `json:"avatarUrl,omitempty"`
+	CreatedAt     *time.Time `json:"createdAt,omitempty"`
+	// DeletedAt is set while the account waits out the deletion grace
+	// period. user.deleted follows once it is over.
+	DeletedAt  *time.Time `json:"deletedAt,omitempty"`
+	OccurredAt time.Time  `json:"occurredAt"`
+}
+
+// OutboxMessage is a UserEvent waiting to be published.
+type OutboxMessage struct {
+	Id      int64
+	Type    string
+	UserId  string
+	Payload string
+}
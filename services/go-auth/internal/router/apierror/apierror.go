@@ -0,0 +1,59 @@
+// Package apierror writes error responses in the one JSON shape every
+// endpoint uses, models.ErrorRes.
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"go-auth/internal/models"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Codes shared by several endpoints. Errors of the service layer have
+// their own, more specific codes.
+const (
+	CodeInvalidRequest      = "invalid_request"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeNotFound            = "not_found"
+	CodeValidation          = "validation_failed"
+	CodeCSRF                = "csrf_failed"
+	CodeTooManyRequests     = "too_many_requests"
+	CodeInternal            = "internal_error"
+	CodeInvalidRefreshToken = "invalid_refresh_token"
+)
+
+// Write answers status with an error body.
+func Write(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	WriteFields(w, r, status, code, message, nil)
+}
+
+// WriteFields answers status with an error body listing the rejected
+// request fields.
+func WriteFields(w http.ResponseWriter, r *http.Request, status int, code, message string, fields []models.FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(models.ErrorRes{
+		Code:    code,
+		Message: message,
+		Fields:  fields,
+		TraceId: TraceId(r.Context()),
+	})
+	if err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// TraceId identifies the request for support: the OpenTelemetry trace id
+// when the request is traced, the request id otherwise.
+func TraceId(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return middleware.GetReqID(ctx)
+}
@@ -0,0 +1,52 @@
+package apierror
+
+import (
+	"context"
+	"go-auth/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWriteFields(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/register", nil)
+	w := httptest.NewRecorder()
+
+	WriteFields(w, r, http.StatusBadRequest, CodeValidation, "Validation failed", []models.FieldError{
+		{Field: "password", Code: "too_short", Message: "must be at least 8 characters"},
+	})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"code":"validation_failed","message":"Validation failed","fields":[`+
+		`{"field":"password","code":"too_short","message":"must be at least 8 characters"}]}`, w.Body.String())
+}
+
+func TestTraceId(t *testing.T) {
+	traceId, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanId, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	traced := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceId,
+		SpanID:  spanId,
+	}))
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+	}{
+		{name: "traced", ctx: context.WithValue(traced, middleware.RequestIDKey, "host/1"), expected: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "request id", ctx: context.WithValue(context.Background(), middleware.RequestIDKey, "host/1"), expected: "host/1"},
+		{name: "neither", ctx: context.Background(), expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, TraceId(tt.ctx))
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Package cookies writes every cookie go-auth hands to browsers, so the
+// attributes follow one policy taken from the config. The policy is
+// provided by the container as app.AppCookies.
+package cookies
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/config"
+	"go-auth/internal/constants"
+	"go-auth/internal/models"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Policy holds the cookie attributes.
+type Policy struct {
+	Secure   bool
+	SameSite http.SameSite
+	Domain   string
+	// AccessMaxAge and RefreshMaxAge are the lifetimes of the access and
+	// refresh token cookies; the CSRF token lives as long as the refresh one.
+	AccessMaxAge  time.Duration
+	RefreshMaxAge time.Duration
+}
+
+// DefaultPolicy matches the default config.
+var DefaultPolicy = Policy{
+	SameSite:      http.SameSiteStrictMode,
+	AccessMaxAge:  time.Hour,
+	RefreshMaxAge: 30 * 24 * time.Hour,
+}
+
+func New(cfg app.AppConfig) Policy {
+	return PolicyFrom(cfg.GetConfig())
+}
+
+// PolicyFrom takes the cookie policy from cfg.
+func PolicyFrom(cfg *config.Config) Policy {
+	return Policy{
+		Secure:        cfg.CookieSecure,
+		SameSite:      cfg.SameSite(),
+		Domain:        cfg.CookieDomain,
+		AccessMaxAge:  cfg.AccessCookieMaxAge,
+		RefreshMaxAge: cfg.RefreshTokenTTL,
+	}
+}
+
+// SetTokens sets the access and refresh token cookies. A new session also
+// gets a new CSRF token; should that fail, middlewares.CSRF hands one out
+// on the next GET.
+func (p Policy) SetTokens(w http.ResponseWriter, jwt *models.TokenDto) {
+	p.SetRefreshed(w, jwt)
+	if err := p.SetCSRF(w); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// SetRefreshed replaces the token cookies after WithAuth refreshed them on
+// the fly; the CSRF token of the session stays.
+func (p Policy) SetRefreshed(w http.ResponseWriter, jwt *models.TokenDto) {
+	http.SetCookie(w, p.cookie(constants.AccessTokenCookie, jwt.Access, p.AccessMaxAge, true))
+	http.SetCookie(w, p.cookie(constants.RefreshTokenCookie, jwt.Refresh, p.RefreshMaxAge, true))
+}
+
+// SetCSRF issues a new CSRF token. Unlike the token cookies it is readable
+// by scripts, which is what lets the frontend echo it in a header.
+func (p Policy) SetCSRF(w http.ResponseWriter) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("cookies SetCSRF: %w", err)
+	}
+	http.SetCookie(w, p.cookie(constants.CSRFTokenCookie, base64.RawURLEncoding.EncodeToString(b), p.RefreshMaxAge, false))
+	return nil
+}
+
+// Clear expires the token and CSRF cookies.
+func (p Policy) Clear(w http.ResponseWriter) {
+	http.SetCookie(w, p.cookie(constants.AccessTokenCookie, "", -1, true))
+	http.SetCookie(w, p.cookie(constants.RefreshTokenCookie, "", -1, true))
+	http.SetCookie(w, p.cookie(constants.CSRFTokenCookie, "", -1, false))
+}
+
+// SetOAuthState binds the OAuth flow with state to the browser starting it,
+// so an attacker can't complete their own flow in the victim's browser. The
+// cookie is sent to the OAuth routes only and is Lax at most, since the
+// provider redirects back with a cross-site navigation.
+func (p Policy) SetOAuthState(w http.ResponseWriter, state string, maxAge time.Duration) {
+	http.SetCookie(w, p.oauthStateCookie(hashOAuthState(state), maxAge))
+}
+
+// ClearOAuthState expires the OAuth state cookie.
+func (p Policy) ClearOAuthState(w http.ResponseWriter) {
+	http.SetCookie(w, p.oauthStateCookie("", -1))
+}
+
+// OAuthStateMatches reports whether r carries the cookie SetOAuthState set
+// for state.
+func OAuthStateMatches(r *http.Request, state string) bool {
+	c, err := r.Cookie(constants.OAuthStateCookie)
+	if err != nil || state == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.Value), []byte(hashOAuthState(state))) == 1
+}
+
+func hashOAuthState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (p Policy) oauthStateCookie(value string, maxAge time.Duration) *http.Cookie {
+	c := p.cookie(constants.OAuthStateCookie, value, maxAge, true)
+	c.Path = "/oauth"
+	if c.SameSite != http.SameSiteNoneMode {
+		c.SameSite = http.SameSiteLaxMode
+	}
+	return c
+}
+
+func (p Policy) cookie(name, value string, maxAge time.Duration, httpOnly bool) *http.Cookie {
+	c := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   p.Domain,
+		MaxAge:   int(maxAge / time.Second),
+		HttpOnly: httpOnly,
+		Secure:   p.Secure,
+		SameSite: p.SameSite,
+	}
+	if maxAge < 0 {
+		c.MaxAge = -1
+	}
+	return c
+}
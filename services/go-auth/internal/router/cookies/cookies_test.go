@@ -0,0 +1,104 @@
+package cookies
+
+import (
+	"go-auth/internal/config"
+	"go-auth/internal/constants"
+	"go-auth/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func findCookie(cookies []*http.Cookie, name string) *http.Cookie {
+	for _, c := range cookies {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestSetTokens(t *testing.T) {
+	p := PolicyFrom(&config.Config{
+		AccessCookieMaxAge: 2 * time.Hour,
+		RefreshTokenTTL:    7 * 24 * time.Hour,
+		CookieSecure:       true,
+		CookieSameSite:     "lax",
+		CookieDomain:       "example.com",
+	})
+
+	w := httptest.NewRecorder()
+	p.SetTokens(w, &models.TokenDto{Access: "access", Refresh: "refresh"})
+
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 3)
+	tests := []struct {
+		name     string
+		value    string
+		maxAge   int
+		httpOnly bool
+	}{
+		{name: constants.AccessTokenCookie, value: "access", maxAge: 7200, httpOnly: true},
+		{name: constants.RefreshTokenCookie, value: "refresh", maxAge: 7 * 24 * 3600, httpOnly: true},
+		{name: constants.CSRFTokenCookie, maxAge: 7 * 24 * 3600},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := findCookie(cookies, tt.name)
+			if !assert.NotNil(t, c) {
+				return
+			}
+			if tt.value != "" {
+				assert.Equal(t, tt.value, c.Value)
+			} else {
+				assert.NotEmpty(t, c.Value)
+			}
+			assert.Equal(t, tt.maxAge, c.MaxAge)
+			assert.Equal(t, tt.httpOnly, c.HttpOnly)
+			assert.True(t, c.Secure)
+			assert.Equal(t, http.SameSiteLaxMode, c.SameSite)
+			assert.Equal(t, "example.com", c.Domain)
+			assert.Equal(t, "/", c.Path)
+		})
+	}
+}
+
+func TestClear(t *testing.T) {
+	w := httptest.NewRecorder()
+	DefaultPolicy.Clear(w)
+
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 3)
+	for _, c := range cookies {
+		assert.Empty(t, c.Value, c.Name)
+		assert.Equal(t, -1, c.MaxAge, c.Name)
+		assert.Equal(t, http.SameSiteStrictMode, c.SameSite, c.Name)
+	}
+}
+
+func TestOAuthState(t *testing.T) {
+	p := PolicyFrom(&config.Config{CookieSecure: true, CookieSameSite: "strict", CookieDomain: "example.com"})
+
+	w := httptest.NewRecorder()
+	p.SetOAuthState(w, "state-1", 10*time.Minute)
+	c := findCookie(w.Result().Cookies(), constants.OAuthStateCookie)
+	if !assert.NotNil(t, c) {
+		return
+	}
+	assert.NotEqual(t, "state-1", c.Value)
+	assert.Equal(t, "/oauth", c.Path)
+	assert.Equal(t, 600, c.MaxAge)
+	assert.True(t, c.HttpOnly)
+	assert.True(t, c.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, c.SameSite, "the provider redirects back cross-site")
+
+	r := httptest.NewRequest(http.MethodGet, "/oauth/fake/callback", nil)
+	assert.False(t, OAuthStateMatches(r, "state-1"), "no cookie")
+	r.AddCookie(c)
+	assert.True(t, OAuthStateMatches(r, "state-1"))
+	assert.False(t, OAuthStateMatches(r, "state-2"))
+	assert.False(t, OAuthStateMatches(r, ""))
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/router/apierror"
+	"go-auth/internal/services"
+	"net/http"
+)
+
+// VerifyEmail godoc
+// @Summary Подтверждение email
+// @Description Подтверждает email по одноразовому токену из письма
+// @Tags Аккаунт
+// @Accept json
+// @Param input body models.EmailVerifyReq true "Токен из письма"
+// @Success 204 "Email подтвержден"
+// @Failure 400 {object} models.ErrorRes "Неверный или просроченный токен"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Router /email/verify [post]
+func VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.EmailVerifyReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	authService, ok := resolveAuthService(w, r)
+	if !ok {
+		return
+	}
+	if err := authService.VerifyEmail(r.Context(), req.Token); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForgotPassword godoc
+// @Summary Запрос сброса пароля
+// @Description Отправляет ссылку для сброса пароля на email. Ответ не зависит от того, зарегистрирован ли email
+// @Tags Аккаунт
+// @Accept json
+// @Param input body models.PasswordForgotReq true "Email пользователя"
+// @Success 204 "Если email зарегистрирован, письмо отправлено"
+// @Failure 400 {object} models.ErrorRes "Неверный email"
+// @Failure 429 {object} models.ErrorRes "Слишком много запросов, см. заголовок Retry-After"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Router /password/forgot [post]
+func ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordForgotReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	authService, ok := resolveAuthService(w, r)
+	if !ok {
+		return
+	}
+	if err := authService.ForgotPassword(r.Context(), req.Email); err != nil {
+		if errors.Is(err, services.ErrInvalidEmail) {
+			writeServiceError(w, r, err)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to send reset link")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetPassword godoc
+// @Summary Сброс пароля
+// @Description Устанавливает новый пароль по одноразовому токену из письма и завершает все сессии пользователя
+// @Tags Аккаунт
+// @Accept json
+// @Param input body models.PasswordResetReq true "Токен из письма и новый пароль"
+// @Success 204 "Пароль изменен"
+// @Failure 400 {object} models.ErrorRes "Неверный или просроченный токен, пустой пароль или пароль не соответствует политике"
+// @Failure 429 {object} models.ErrorRes "Слишком много запросов, см. заголовок Retry-After"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Router /password/reset [post]
+func ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordResetReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	authService, ok := resolveAuthService(w, r)
+	if !ok {
+		return
+	}
+	if err := authService.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	c, ok := resolveCookies(w, r)
+	if !ok {
+		return
+	}
+	c.Clear(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func resolveAuthService(w http.ResponseWriter, r *http.Request) (app.AppAuthService, bool) {
+	var authService app.AppAuthService
+	if err := app.AppContainer.Invoke(func(as app.AppAuthService) {
+		authService = as
+	}); err != nil {
+		writeInternalError(w, r, err, "Failed to resolve AuthService")
+		return nil, false
+	}
+	return authService, true
+}
+
+func resolveCookies(w http.ResponseWriter, r *http.Request) (app.AppCookies, bool) {
+	var c app.AppCookies
+	if err := app.AppContainer.Invoke(func(cookies app.AppCookies) {
+		c = cookies
+	}); err != nil {
+		writeInternalError(w, r, err, "Failed to resolve cookies")
+		return nil, false
+	}
+	return c, true
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/router/cookies"
+	"go-auth/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/dig"
+)
+
+type AccountTestCase struct {
+	name           string
+	handler        http.HandlerFunc
+	body           string
+	setupMocks     func(*MockAuthService)
+	expectedStatus int
+	expectedBody   string
+}
+
+func TestAccountHandlers(t *testing.T) {
+	invalidToken := fmt.Errorf("%w: token is expired", services.ErrInvalidActionToken)
+
+	tests := []AccountTestCase{
+		{
+			name:    "VerifyEmail success",
+			handler: VerifyEmail,
+			body:    `{"token":"t"}`,
+			setupMocks: func(a *MockAuthService) {
+				a.On("VerifyEmail", mock.Anything, "t").Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:    "VerifyEmail expired token",
+			handler: VerifyEmail,
+			body:    `{"token":"t"}`,
+			setupMocks: func(a *MockAuthService) {
+				a.On("VerifyEmail", mock.Anything, "t").Return(invalidToken)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid or expired token",
+		},
+		{
+			name:           "VerifyEmail invalid body",
+			handler:        VerifyEmail,
+			body:           `nope`,
+			setupMocks:     func(a *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body",
+		},
+		{
+			name:    "ForgotPassword success",
+			handler: ForgotPassword,
+			body:    `{"email":"user@example.com"}`,
+			setupMocks: func(a *MockAuthService) {
+				a.On("ForgotPassword", mock.Anything, "user@example.com").Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:    "ForgotPassword invalid email",
+			handler: ForgotPassword,
+			body:    `{"email":"nope"}`,
+			setupMocks: func(a *MockAuthService) {
+				a.On("ForgotPassword", mock.Anything, "nope").Return(services.ErrInvalidEmail)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid email",
+		},
+		{
+			name:    "ForgotPassword mailer error",
+			handler: ForgotPassword,
+			body:    `{"email":"user@example.com"}`,
+			setupMocks: func(a *MockAuthService) {
+				a.On("ForgotPassword", mock.Anything, "user@example.com").Return(errors.New("smtp down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Internal server error",
+		},
+		{
+			name:    "ResetPassword success",
+			handler: ResetPassword,
+			body:    `{"token":"t","password":"newPassword"}`,
+			setupMocks: func(a *MockAuthService) {
+				a.On("ResetPassword", mock.Anything, "t", "newPassword").Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:    "ResetPassword empty password",
+			handler: ResetPassword,
+			body:    `{"token":"t"}`,
+			setupMocks: func(a *MockAuthService) {
+				a.On("ResetPassword", mock.Anything, "t", "").Return(services.ErrPasswordRequired)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Password is required",
+		},
+		{
+			name:    "ResetPassword wrong token type",
+			handler: ResetPassword,
+			body:    `{"token":"t","password":"newPassword"}`,
+			setupMocks: func(a *MockAuthService) {
+				a.On("ResetPassword", mock.Anything, "t", "newPassword").Return(services.ErrInvalidTokenType)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid or expired token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuth := new(MockAuthService)
+			tt.setupMocks(mockAuth)
+
+			AppContainer := dig.New()
+			AppContainer.Provide(func() app.AppAuthService { return mockAuth })
+			AppContainer.Provide(func() app.AppCookies { return cookies.DefaultPolicy })
+			app.AppContainer = AppContainer
+
+			req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			tt.handler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			} else {
+				assert.Empty(t, w.Body.String())
+			}
+			mockAuth.AssertExpectations(t)
+		})
+	}
+}
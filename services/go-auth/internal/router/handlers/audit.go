@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/router/apierror"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ListAuthEvents godoc
+// @Summary Журнал событий безопасности
+// @Description Возвращает события (регистрация, входы, обновление токенов, выход, смена и сброс пароля) от новых к старым. События попадают в журнал через Kafka, последние несколько секунд могут отсутствовать. Требует разрешение audit:read
+// @Tags Аудит
+// @Produce json
+// @Param userId query string false "ID пользователя"
+// @Param type query string false "Тип события" Enums(registered, login_succeeded, login_failed, token_refreshed, refresh_token_reused, logged_out, password_changed, password_reset)
+// @Param from query string false "Начало периода включительно, RFC 3339" example(2024-05-01T00:00:00Z)
+// @Param to query string false "Конец периода не включительно, RFC 3339" example(2024-05-02T00:00:00Z)
+// @Param limit query int false "Сколько событий вернуть, от 1 до 1000, по умолчанию 100"
+// @Success 200 {array} models.AuthEvent
+// @Failure 400 {object} models.ErrorRes "Неверный фильтр"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 403 {object} models.ErrorRes "Недостаточно прав"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /audit/events [get]
+func ListAuthEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.AuthEventFilter{
+		UserId: query.Get("userId"),
+		Type:   query.Get("type"),
+	}
+	var err error
+	if v := query.Get("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, "invalid_filter", "Invalid from, expected RFC 3339 time")
+			return
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, "invalid_filter", "Invalid to, expected RFC 3339 time")
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 1 {
+			apierror.Write(w, r, http.StatusBadRequest, "invalid_filter", "Invalid limit")
+			return
+		}
+	}
+
+	auditService, ok := resolveAuditService(w, r)
+	if !ok {
+		return
+	}
+	events, err := auditService.ListAuthEvents(r.Context(), filter)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, events)
+}
+
+func resolveAuditService(w http.ResponseWriter, r *http.Request) (app.AppAuditService, bool) {
+	var auditService app.AppAuditService
+	if err := app.AppContainer.Invoke(func(s app.AppAuditService) {
+		auditService = s
+	}); err != nil {
+		writeInternalError(w, r, err, "Failed to resolve AuditService")
+		return nil, false
+	}
+	return auditService, true
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/router/cookies"
+	"go-auth/internal/router/middlewares"
+	"go-auth/internal/services"
+	"go-auth/pkg/authz"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/dig"
+)
+
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) ListAuthEvents(ctx context.Context, filter models.AuthEventFilter) ([]models.AuthEvent, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AuthEvent), args.Error(1)
+}
+
+func TestListAuthEvents(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	event := models.AuthEvent{Id: "e1", Type: models.AuthEventLoginFailed, Login: "player1", CreatedAt: from}
+
+	tests := []struct {
+		name           string
+		query          string
+		permissions    []string
+		setupMocks     func(*MockAuditService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:        "filtered by user and time range",
+			query:       "?userId=123&from=2024-05-01T00:00:00Z&to=2024-05-02T00:00:00Z&limit=10",
+			permissions: []string{authz.PermAuditRead},
+			setupMocks: func(a *MockAuditService) {
+				a.On("ListAuthEvents", mock.Anything, models.AuthEventFilter{UserId: "123", From: from, To: from.Add(24 * time.Hour), Limit: 10}).
+					Return([]models.AuthEvent{event}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"id":"e1","type":"login_failed","login":"player1","createdAt":"2024-05-01T00:00:00Z"}]`,
+		},
+		{
+			name:        "no filter",
+			permissions: []string{authz.PermAuditRead},
+			setupMocks: func(a *MockAuditService) {
+				a.On("ListAuthEvents", mock.Anything, models.AuthEventFilter{}).Return([]models.AuthEvent{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[]`,
+		},
+		{
+			name:           "malformed from",
+			query:          "?from=yesterday",
+			permissions:    []string{authz.PermAuditRead},
+			setupMocks:     func(a *MockAuditService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid from",
+		},
+		{
+			name:           "malformed limit",
+			query:          "?limit=-1",
+			permissions:    []string{authz.PermAuditRead},
+			setupMocks:     func(a *MockAuditService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid limit",
+		},
+		{
+			name:        "rejected filter",
+			query:       "?limit=5000",
+			permissions: []string{authz.PermAuditRead},
+			setupMocks: func(a *MockAuditService) {
+				a.On("ListAuthEvents", mock.Anything, models.AuthEventFilter{Limit: 5000}).
+					Return(nil, fmt.Errorf("%w: limit must be between 1 and 1000", services.ErrInvalidEventFilter))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "limit must be between 1 and 1000",
+		},
+		{
+			name:           "without permission",
+			permissions:    []string{authz.PermGamePlay},
+			setupMocks:     func(a *MockAuditService) {},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAudit := new(MockAuditService)
+			tt.setupMocks(mockAudit)
+
+			AppContainer := dig.New()
+			AppContainer.Provide(func() app.AppAuditService { return mockAudit })
+			AppContainer.Provide(func() app.AppCookies { return cookies.DefaultPolicy })
+			app.AppContainer = AppContainer
+
+			router := chi.NewRouter()
+			router.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					next.ServeHTTP(w, r.WithContext(middlewares.WithPrincipal(r.Context(), &models.TokenClaims{
+						Type:             models.TokenTypeAccess,
+						Permissions:      tt.permissions,
+						RegisteredClaims: jwt.RegisteredClaims{Subject: "support"},
+					})))
+				})
+			})
+			router.With(authz.RequirePermission(authz.PermAuditRead)).Get("/audit/events", ListAuthEvents)
+
+			req := httptest.NewRequest(http.MethodGet, "/audit/events"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+			mockAudit.AssertExpectations(t)
+		})
+	}
+}
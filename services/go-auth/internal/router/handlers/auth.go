@@ -6,26 +6,35 @@ import (
 	"go-auth/internal/app"
 	"go-auth/internal/constants"
 	"go-auth/internal/models"
+	"go-auth/internal/router/apierror"
+	"go-auth/internal/router/middlewares"
 	"go-auth/internal/services"
+	"go-auth/pkg/metrics"
 	"log/slog"
+	"net"
 	"net/http"
+	"strings"
 )
 
 // Register godoc
 // @Summary Регистрация нового пользователя
-// @Description Создает нового пользователя и возвращает JWT токены в cookies
+// @Description Создает нового пользователя и возвращает JWT токены в cookies или, с заголовком X-Token-Mode: json, в теле ответа. Если указан email, на него отправляется письмо для подтверждения
 // @Tags Аутентификация
 // @Accept json
 // @Produce json
+// @Param X-Token-Mode header string false "json — вернуть токены в теле ответа" Enums(json)
 // @Param input body models.UserCreateReq true "Данные для регистрации"
+// @Success 200 {object} models.TokenRes "Успешная регистрация (режим json)"
 // @Success 204 "Успешная регистрация, токены установлены в cookies"
-// @Failure 400 {string} string "Неверный запрос или неверные логин/пароль"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
+// @Failure 400 {object} models.ErrorRes "Неверный запрос, неверный email или логин/пароль не соответствуют политике (code validation_failed, поля в fields)"
+// @Failure 409 {object} models.ErrorRes "Логин или email уже заняты"
+// @Failure 429 {object} models.ErrorRes "Слишком много запросов, см. заголовок Retry-After"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
 // @Router /register [post]
 func Register(w http.ResponseWriter, r *http.Request) {
 	var u models.UserCreateReq
 	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
 		return
 	}
 
@@ -36,127 +45,100 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		tokenStore = s
 		authService = as
 	}); err != nil {
-		http.Error(w, "Failed to resolve AuthService", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Failed to resolve AuthService")
 		return
 	}
-	jwt, err := authService.Create(u)
+	jwt, err := authService.Create(u, clientInfo(r))
 	if err != nil {
-		slog.Error(err.Error())
-		if errors.Is(err, services.ErrLoginAndPasswordAreRequired){
-			http.Error(w, "Wrong login or password", http.StatusBadRequest)
-			return 
-		}
-		http.Error(w, "Bad request", http.StatusBadRequest)
+		writeServiceError(w, r, err)
 		return
 	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     constants.AccessTokenCookie,
-		Value:    jwt.Access,
-		Path:     "/",
-		MaxAge:   3600,
-		HttpOnly: true,
-		// Secure:   true, // Только для HTTPS
-		SameSite: http.SameSiteStrictMode,
-	})
-	http.SetCookie(w, &http.Cookie{
-		Name:     constants.RefreshTokenCookie,
-		Value:    jwt.Refresh,
-		Path:     "/",
-		MaxAge:   30 * 24 * 3600, // 30 дней
-		HttpOnly: true,
-		// Secure:   true,
-		SameSite: http.SameSiteStrictMode,
-	})
-
 	if err := tokenStore.SetTokens(r.Context(), jwt); err != nil {
-		slog.Error(err.Error())
-		http.Error(w, "token store error", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "token store error")
+		return
 	}
-
-	w.WriteHeader(http.StatusNoContent)
-	w.Write([]byte(""))
-
+	writeTokens(w, r, jwt)
 }
 
 // Logout godoc
 // @Summary Выход из системы
-// @Description Удаляет JWT токены из cookies и хранилища
+// @Description Отзывает семейство refresh токенов и удаляет JWT токены из cookies и хранилища. Клиент с заголовком Authorization: Bearer завершает сессию своего access token
 // @Tags Аутентификация
 // @Produce json
 // @Success 204 "Успешный выход, токены удалены"
-// @Failure 400 {string} string "Неверный запрос"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
+// @Failure 400 {object} models.ErrorRes "Неверный запрос"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
 // @Security ApiKeyAuth
-// @Router /logout [get]
+// @Router /logout [post]
 func Logout(w http.ResponseWriter, r *http.Request) {
+	if access, ok := middlewares.BearerToken(r); ok {
+		// Bearer clients keep no cookies; the access token names the session.
+		authService, ok := resolveAuthService(w, r)
+		if !ok {
+			return
+		}
+		if err := authService.Logout(r.Context(), "", access); err != nil {
+			writeInternalError(w, r, err, "Failed to remove token")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
 	access, err := r.Cookie("access_token")
 	if err != nil {
-
-		slog.Error(err.Error())
-		http.Error(w, "Failed to read cookie access", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Failed to read cookie access")
 		return
 	}
 	refresh, err := r.Cookie("refresh_token")
 	if err != nil {
-		slog.Error(err.Error())
-		http.Error(w, "Failed to read cookie refresh", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Failed to read cookie refresh")
 		return
 	}
 
-	var tokenStore app.AppTokenStorage
-	err = app.AppContainer.Invoke(func(s app.AppTokenStorage) {
-		tokenStore = s
+	var authService app.AppAuthService
+	err = app.AppContainer.Invoke(func(as app.AppAuthService) {
+		authService = as
 	})
 	if err != nil {
-		slog.Error(err.Error())
-		http.Error(w, "Failed to resolve AppTokenStorage", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Failed to resolve AuthService")
 		return
 	}
 
-	err = tokenStore.RemoveToken(r.Context(), refresh.Value, access.Value)
+	err = authService.Logout(r.Context(), refresh.Value, access.Value)
 	if err != nil {
-		slog.Error(err.Error())
-		http.Error(w, "Failed to remove token", http.StatusInternalServerError)
-		return
-	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     "access_token",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-		// Secure:   true, // Только для HTTPS
-		SameSite: http.SameSiteStrictMode,
-	})
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-		// Secure:   true,
-		SameSite: http.SameSiteStrictMode,
-	})
+		writeInternalError(w, r, err, "Failed to remove token")
+		return
+	}
+	c, ok := resolveCookies(w, r)
+	if !ok {
+		return
+	}
+	c.Clear(w)
 	w.WriteHeader(http.StatusNoContent)
 	w.Write([]byte(""))
 }
 
 // Login godoc
 // @Summary Аутентификация пользователя
-// @Description Проверяет учетные данные и возвращает JWT токены в cookies
+// @Description Проверяет учетные данные и возвращает JWT токены в cookies или, с заголовком X-Token-Mode: json, в теле ответа (models.TokenRes). Если включена 2FA, вместо токенов возвращается MFA challenge для /login/mfa
 // @Tags Аутентификация
 // @Accept json
 // @Produce json
+// @Param X-Token-Mode header string false "json — вернуть токены в теле ответа" Enums(json)
 // @Param input body models.UserCreateReq true "Учетные данные"
+// @Success 200 {object} models.MFAChallengeRes "Требуется второй фактор"
 // @Success 204 "Успешная аутентификация, токены установлены в cookies"
-// @Failure 400 {string} string "Неверный запрос или неверные логин/пароль"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
+// @Failure 400 {object} models.ErrorRes "Неверный запрос или не указаны логин/пароль"
+// @Failure 401 {object} models.ErrorRes "Неверные логин или пароль"
+// @Failure 429 {object} models.ErrorRes "Слишком много попыток, см. заголовок Retry-After"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
 // @Router /login [post]
 func Login(w http.ResponseWriter, r *http.Request) {
 
 	var u models.UserCreateReq
 	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
 		return
 	}
 
@@ -166,42 +148,140 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		tokenStore = s
 		authService = as
 	}); err != nil {
-		http.Error(w, "Failed to resolve AuthService & TokenStorage", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Failed to resolve AuthService & TokenStorage")
 		return
 	}
-	jwt, err := authService.Login(u)
+	jwt, err := authService.Login(u, clientInfo(r))
 	if err != nil {
-		if errors.Is(err, services.ErrWrongLoginOrPassword) || errors.Is(err, services.ErrLoginAndPasswordAreRequired){
-			http.Error(w, "Wrong login or password", http.StatusBadRequest)
-			return 
+		var mfa *services.MFARequiredError
+		if errors.As(err, &mfa) {
+			writeJSON(w, models.MFAChallengeRes{MfaRequired: true, Challenge: mfa.Challenge})
+			return
 		}
-		slog.Error(err.Error())
-		http.Error(w, "Bad request", http.StatusBadRequest)
-		return
-	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     "access_token",
-		Value:    jwt.Access,
-		Path:     "/",
-		MaxAge:   3600,
-		HttpOnly: true,
-		// Secure:   true, // Только для HTTPS
-		SameSite: http.SameSiteStrictMode,
-	})
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    jwt.Refresh,
-		Path:     "/",
-		MaxAge:   30 * 24 * 3600, // 30 дней
-		HttpOnly: true,
-		// Secure:   true,
-		SameSite: http.SameSiteStrictMode,
-	})
-
+		if errors.Is(err, services.ErrTooManyAttempts) {
+			metrics.RecordBlocked("login", "lockout")
+		}
+		writeServiceError(w, r, err)
+		return
+	}
 	if err := tokenStore.SetTokens(r.Context(), jwt); err != nil {
-		slog.Error(err.Error())
-		http.Error(w, "token store error", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "token store error")
+		return
 	}
+	writeTokens(w, r, jwt)
+}
+
+// isRefreshRejected reports whether err means the refresh token itself is
+// no good, as opposed to the refresh failing.
+func isRefreshRejected(err error) bool {
+	for _, target := range []error{
+		services.ErrInvalidToken,
+		services.ErrTokenExpired,
+		services.ErrInvalidTokenType,
+		services.ErrRefreshTokenReused,
+		services.ErrRefreshTokenRevoked,
+		services.ErrSessionRevoked,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Refresh godoc
+// @Summary Обновление токенов
+// @Description Обменивает refresh token на новую пару токенов, старый refresh token становится недействительным. В режиме cookies refresh token берётся из cookie, в режиме X-Token-Mode: json — из тела запроса
+// @Tags Аутентификация
+// @Accept json
+// @Produce json
+// @Param X-Token-Mode header string false "json — принять и вернуть токены в теле запроса и ответа" Enums(json)
+// @Param input body models.RefreshReq false "Refresh token (режим json)"
+// @Success 200 {object} models.TokenRes "Новые токены (режим json)"
+// @Success 204 "Новые токены установлены в cookies"
+// @Failure 400 {object} models.ErrorRes "Неверный запрос"
+// @Failure 401 {object} models.ErrorRes "Refresh token недействителен, отозван или уже использован"
+// @Failure 429 {object} models.ErrorRes "Слишком много запросов, см. заголовок Retry-After"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Router /refresh [post]
+func Refresh(w http.ResponseWriter, r *http.Request) {
+	var refresh string
+	if jsonTokenMode(r) {
+		var req models.RefreshReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
+			return
+		}
+		refresh = req.RefreshToken
+	} else if cookie, err := r.Cookie(constants.RefreshTokenCookie); err == nil {
+		refresh = cookie.Value
+	}
+	if refresh == "" {
+		apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Refresh token is required")
+		return
+	}
+
+	authService, ok := resolveAuthService(w, r)
+	if !ok {
+		return
+	}
+	jwt, err := authService.RefreshToken(refresh)
+	if isRefreshRejected(err) {
+		// The client has to log in again; the cookies are no use to it.
+		slog.Info("Refresh rejected: " + err.Error())
+		if !jsonTokenMode(r) {
+			c, ok := resolveCookies(w, r)
+			if !ok {
+				return
+			}
+			c.Clear(w)
+		}
+		apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeInvalidRefreshToken, "Invalid refresh token")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeTokens(w, r, jwt)
+}
+
+// jsonTokenMode reports whether the client asked for tokens in the body.
+func jsonTokenMode(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get(constants.TokenModeHeader), constants.TokenModeJSON)
+}
+
+// writeTokens hands out a new token pair: in the body in JSON token mode,
+// as cookies otherwise.
+func writeTokens(w http.ResponseWriter, r *http.Request, jwt *models.TokenDto) {
+	if jsonTokenMode(r) {
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, models.TokenRes{
+			AccessToken:  jwt.Access,
+			RefreshToken: jwt.Refresh,
+			TokenType:    "Bearer",
+			ExpiresIn:    int(jwt.ExpiresIn.Seconds()),
+		})
+		return
+	}
+	c, ok := resolveCookies(w, r)
+	if !ok {
+		return
+	}
+	c.SetTokens(w, jwt)
 	w.WriteHeader(http.StatusNoContent)
-	w.Write([]byte(""))
+}
+
+// clientInfo describes the caller for the session being created. The device
+// name is optional and supplied by the client.
+func clientInfo(r *http.Request) models.ClientInfo {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+	return models.ClientInfo{
+		Device:    r.Header.Get(constants.DeviceNameHeader),
+		UserAgent: r.UserAgent(),
+		IP:        ip,
+	}
 }
@@ -5,12 +5,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"go-auth/internal/app"
 	"go-auth/internal/models"
+	"go-auth/internal/router/cookies"
 	"go-auth/internal/services"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -26,15 +31,55 @@ func (m *MockAuthService) RefreshToken(refreshToken string) (*models.TokenDto, e
 	return args.Get(0).(*models.TokenDto), args.Error(1)
 }
 
-func (m *MockAuthService) Login(user models.UserCreateReq) (*models.TokenDto, error) {
-	args := m.Called(user)
+func (m *MockAuthService) Login(user models.UserCreateReq, client models.ClientInfo) (*models.TokenDto, error) {
+	args := m.Called(user, client)
 	return args.Get(0).(*models.TokenDto), args.Error(1)
 }
 
-func (m *MockAuthService) Create(user models.UserCreateReq) (*models.TokenDto, error) {
-	args := m.Called(user)
+func (m *MockAuthService) Create(user models.UserCreateReq, client models.ClientInfo) (*models.TokenDto, error) {
+	args := m.Called(user, client)
 	return args.Get(0).(*models.TokenDto), args.Error(1)
 }
+func (m *MockAuthService) Logout(ctx context.Context, refresh, access string) error {
+	args := m.Called(ctx, refresh, access)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) Authenticate(ctx context.Context, accessToken string) (*models.TokenClaims, error) {
+	args := m.Called(ctx, accessToken)
+	return args.Get(0).(*models.TokenClaims), args.Error(1)
+}
+
+func (m *MockAuthService) ListSessions(ctx context.Context, userId string) ([]models.Session, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *MockAuthService) RevokeSession(ctx context.Context, userId, sessionId string) error {
+	args := m.Called(ctx, userId, sessionId)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) RevokeAllSessions(ctx context.Context, userId string) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) VerifyEmail(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ForgotPassword(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ResetPassword(ctx context.Context, token, password string) error {
+	args := m.Called(ctx, token, password)
+	return args.Error(0)
+}
+
 func (m *MockAuthService) generateJWT(user models.UserCreateRes) (string, error) {
 	args := m.Called(user)
 	return "", args.Error(1)
@@ -55,6 +100,21 @@ func (m *MockTokenStorage) SetTokens(ctx context.Context, tokens *models.TokenDt
 	return args.Error(0)
 }
 
+func (m *MockTokenStorage) SaveRefreshFamily(ctx context.Context, family, jti string, ttl time.Duration) error {
+	args := m.Called(ctx, family, jti, ttl)
+	return args.Error(0)
+}
+
+func (m *MockTokenStorage) RotateRefreshFamily(ctx context.Context, family, oldJti, newJti string) error {
+	args := m.Called(ctx, family, oldJti, newJti)
+	return args.Error(0)
+}
+
+func (m *MockTokenStorage) RevokeRefreshFamily(ctx context.Context, family string) error {
+	args := m.Called(ctx, family)
+	return args.Error(0)
+}
+
 // Helper function to find cookie by name
 func findCookie(cookies []*http.Cookie, name string) *http.Cookie {
 	for _, c := range cookies {
@@ -88,7 +148,7 @@ func TestRegister(t *testing.T) {
 					Access:  "access_token",
 					Refresh: "refresh_token",
 				}
-				a.On("Create", mock.AnythingOfType("models.UserCreateReq")).Return(tokens, nil)
+				a.On("Create", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).Return(tokens, nil)
 				ts.On("SetTokens", mock.Anything, tokens).Return(nil)
 			},
 
@@ -115,12 +175,63 @@ func TestRegister(t *testing.T) {
 				Password: "testpass",
 			},
 			setupMocks: func(a *MockAuthService, ts *MockTokenStorage) {
-				a.On("Create", mock.AnythingOfType("models.UserCreateReq")).
+				a.On("Create", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).
 					Return(&models.TokenDto{}, services.ErrLoginAndPasswordAreRequired)
 			},
 
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Wrong login or password",
+			expectedBody:   `{"code":"credentials_required","message":"Login and password are required"}`,
+			checkCookies:   false,
+		},
+		{
+			name: "WeakPassword",
+			requestBody: models.UserCreateReq{
+				Login:    "testuser",
+				Password: "qwerty",
+			},
+			setupMocks: func(a *MockAuthService, ts *MockTokenStorage) {
+				a.On("Create", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).
+					Return((*models.TokenDto)(nil), &services.ValidationError{Fields: []models.FieldError{
+						{Field: "password", Code: "too_short", Message: "must be at least 8 characters"},
+						{Field: "password", Code: "common", Message: "is too common"},
+					}})
+			},
+
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Validation failed","fields":[` +
+				`{"field":"password","code":"too_short","message":"must be at least 8 characters"},` +
+				`{"field":"password","code":"common","message":"is too common"}]}`,
+			checkCookies: false,
+		},
+		{
+			name: "UserExists",
+			requestBody: models.UserCreateReq{
+				Login:    "testuser",
+				Password: "testpass",
+			},
+			setupMocks: func(a *MockAuthService, ts *MockTokenStorage) {
+				a.On("Create", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).
+					Return((*models.TokenDto)(nil), fmt.Errorf("AuthService Create: %w", services.ErrUserExists))
+			},
+
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"code":"user_exists","message":"User already exists"}`,
+			checkCookies:   false,
+		},
+		{
+			name: "InvalidEmail",
+			requestBody: models.UserCreateReq{
+				Login:    "testuser",
+				Password: "testpass",
+				Email:    "nope",
+			},
+			setupMocks: func(a *MockAuthService, ts *MockTokenStorage) {
+				a.On("Create", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).
+					Return(&models.TokenDto{}, services.ErrInvalidEmail)
+			},
+
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid email",
 			checkCookies:   false,
 		},
 		{
@@ -134,12 +245,12 @@ func TestRegister(t *testing.T) {
 					Access:  "access_token",
 					Refresh: "refresh_token",
 				}
-				a.On("Create", mock.AnythingOfType("models.UserCreateReq")).Return(tokens, nil)
+				a.On("Create", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).Return(tokens, nil)
 				ts.On("SetTokens", mock.Anything, tokens).Return(errors.New("storage error"))
 			},
 
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   "token store error",
+			expectedBody:   "Internal server error",
 			checkCookies:   false,
 		},
 	}
@@ -154,6 +265,7 @@ func TestRegister(t *testing.T) {
 			// Prepare container with our mocks
 			AppContainer := dig.New()
 			AppContainer.Provide(func() app.AppAuthService { return mockAuth })
+			AppContainer.Provide(func() app.AppCookies { return cookies.DefaultPolicy })
 			AppContainer.Provide(func() app.AppTokenStorage { return mockTokenStorage })
 			app.AppContainer = AppContainer
 
@@ -203,7 +315,7 @@ func TestRegister(t *testing.T) {
 type LogoutTestCase struct {
 	name                string
 	setupRequest        func(*http.Request)
-	setupMocks          func(*MockTokenStorage)
+	setupMocks          func(*MockAuthService)
 	expectedStatus      int
 	expectedBody        string
 	checkCookiesCleared bool
@@ -217,20 +329,32 @@ func TestLogout(t *testing.T) {
 				r.AddCookie(&http.Cookie{Name: "access_token", Value: "valid_access"})
 				r.AddCookie(&http.Cookie{Name: "refresh_token", Value: "valid_refresh"})
 			},
-			setupMocks: func(ts *MockTokenStorage) {
-				ts.On("RemoveToken", mock.Anything, "valid_refresh", "valid_access").Return(nil)
+			setupMocks: func(a *MockAuthService) {
+				a.On("Logout", mock.Anything, "valid_refresh", "valid_access").Return(nil)
 			},
 			expectedStatus:      http.StatusNoContent,
 			expectedBody:        "",
 			checkCookiesCleared: true,
 		},
+		{
+			name: "BearerToken",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer valid_access")
+			},
+			setupMocks: func(a *MockAuthService) {
+				a.On("Logout", mock.Anything, "", "valid_access").Return(nil)
+			},
+			expectedStatus:      http.StatusNoContent,
+			expectedBody:        "",
+			checkCookiesCleared: false,
+		},
 		{
 			name: "MissingAccessToken",
 			setupRequest: func(r *http.Request) {
 				r.AddCookie(&http.Cookie{Name: "refresh_token", Value: "valid_refresh"})
 			},
-			setupMocks:          func(*MockTokenStorage) {},
-			expectedStatus:      http.StatusInternalServerError,
+			setupMocks:          func(*MockAuthService) {},
+			expectedStatus:      http.StatusBadRequest,
 			expectedBody:        "Failed to read cookie access",
 			checkCookiesCleared: false,
 		},
@@ -239,8 +363,8 @@ func TestLogout(t *testing.T) {
 			setupRequest: func(r *http.Request) {
 				r.AddCookie(&http.Cookie{Name: "access_token", Value: "valid_access"})
 			},
-			setupMocks:          func(*MockTokenStorage) {},
-			expectedStatus:      http.StatusInternalServerError,
+			setupMocks:          func(*MockAuthService) {},
+			expectedStatus:      http.StatusBadRequest,
 			expectedBody:        "Failed to read cookie refresh",
 			checkCookiesCleared: false,
 		},
@@ -250,12 +374,12 @@ func TestLogout(t *testing.T) {
 				r.AddCookie(&http.Cookie{Name: "access_token", Value: "valid_access"})
 				r.AddCookie(&http.Cookie{Name: "refresh_token", Value: "valid_refresh"})
 			},
-			setupMocks: func(ts *MockTokenStorage) {
-				ts.On("RemoveToken", mock.Anything, "valid_refresh", "valid_access").
+			setupMocks: func(a *MockAuthService) {
+				a.On("Logout", mock.Anything, "valid_refresh", "valid_access").
 					Return(errors.New("storage error"))
 			},
 			expectedStatus:      http.StatusInternalServerError,
-			expectedBody:        "Failed to remove token",
+			expectedBody:        "Internal server error",
 			checkCookiesCleared: false,
 		},
 	}
@@ -263,12 +387,13 @@ func TestLogout(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Prepare mocks
-			mockTokenStorage := new(MockTokenStorage)
-			tt.setupMocks(mockTokenStorage)
+			mockAuth := new(MockAuthService)
+			tt.setupMocks(mockAuth)
 
 			// Prepare container with our mock
 			AppContainer := dig.New()
-			AppContainer.Provide(func() app.AppTokenStorage { return mockTokenStorage })
+			AppContainer.Provide(func() app.AppAuthService { return mockAuth })
+			AppContainer.Provide(func() app.AppCookies { return cookies.DefaultPolicy })
 			app.AppContainer = AppContainer
 
 			// Create request
@@ -304,7 +429,7 @@ func TestLogout(t *testing.T) {
 			}
 
 			// Verify all expectations were met
-			mockTokenStorage.AssertExpectations(t)
+			mockAuth.AssertExpectations(t)
 		})
 	}
 }
@@ -312,11 +437,13 @@ func TestLogout(t *testing.T) {
 type LoginTestCase struct {
 	name            string
 	requestBody     interface{}
+	requestHeaders  map[string]string
 	setupMocks      func(*MockAuthService, *MockTokenStorage)
 	expectedStatus  int
 	expectedBody    string
 	checkCookies    bool
 	expectedCookies map[string]string
+	expectedHeaders map[string]string
 }
 
 func TestLogin(t *testing.T) {
@@ -332,7 +459,7 @@ func TestLogin(t *testing.T) {
 					Access:  "access_token",
 					Refresh: "refresh_token",
 				}
-				a.On("Login", mock.AnythingOfType("models.UserCreateReq")).Return(tokens, nil)
+				a.On("Login", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).Return(tokens, nil)
 				ts.On("SetTokens", mock.Anything, tokens).Return(nil)
 			},
 			expectedStatus: http.StatusNoContent,
@@ -343,6 +470,29 @@ func TestLogin(t *testing.T) {
 				"refresh_token": "refresh_token",
 			},
 		},
+		{
+			name: "JSONTokenMode",
+			requestBody: models.UserCreateReq{
+				Login:    "testuser",
+				Password: "testpass",
+			},
+			requestHeaders: map[string]string{"X-Token-Mode": "json"},
+			setupMocks: func(a *MockAuthService, ts *MockTokenStorage) {
+				tokens := &models.TokenDto{
+					Access:    "access_token",
+					Refresh:   "refresh_token",
+					ExpiresIn: 15 * time.Minute,
+				}
+				a.On("Login", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).Return(tokens, nil)
+				ts.On("SetTokens", mock.Anything, tokens).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"access_token":"access_token","refresh_token":"refresh_token","token_type":"Bearer","expires_in":900}`,
+			checkCookies:   false,
+			expectedHeaders: map[string]string{
+				"Cache-Control": "no-store",
+			},
+		},
 		{
 			name:           "InvalidRequestBody",
 			requestBody:    "invalid json",
@@ -357,11 +507,11 @@ func TestLogin(t *testing.T) {
 				Password: "testpass",
 			},
 			setupMocks: func(a *MockAuthService, ts *MockTokenStorage) {
-				a.On("Login", mock.AnythingOfType("models.UserCreateReq")).
+				a.On("Login", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).
 					Return(&models.TokenDto{}, services.ErrLoginAndPasswordAreRequired)
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Wrong login or password",
+			expectedBody:   `{"code":"credentials_required","message":"Login and password are required"}`,
 			checkCookies:   false,
 		},
 		{
@@ -371,11 +521,11 @@ func TestLogin(t *testing.T) {
 				Password: "wrongpass",
 			},
 			setupMocks: func(a *MockAuthService, ts *MockTokenStorage) {
-				a.On("Login", mock.AnythingOfType("models.UserCreateReq")).
+				a.On("Login", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).
 					Return(&models.TokenDto{}, services.ErrWrongLoginOrPassword)
 			},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Wrong login or password",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"code":"wrong_credentials","message":"Wrong login or password"}`,
 			checkCookies:   false,
 		},
 		{
@@ -389,12 +539,43 @@ func TestLogin(t *testing.T) {
 					Access:  "access_token",
 					Refresh: "refresh_token",
 				}
-				a.On("Login", mock.AnythingOfType("models.UserCreateReq")).Return(tokens, nil)
+				a.On("Login", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).Return(tokens, nil)
 				ts.On("SetTokens", mock.Anything, tokens).Return(errors.New("storage error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   "token store error",
+			expectedBody:   "Internal server error",
+			checkCookies:   false,
+		},
+		{
+			name: "MFARequired",
+			requestBody: models.UserCreateReq{
+				Login:    "testuser",
+				Password: "testpass",
+			},
+			setupMocks: func(a *MockAuthService, ts *MockTokenStorage) {
+				a.On("Login", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).
+					Return((*models.TokenDto)(nil), &services.MFARequiredError{Challenge: "challenge_token"})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"mfaRequired":true,"challenge":"challenge_token"}`,
+			checkCookies:   false,
+		},
+		{
+			name: "TooManyAttempts",
+			requestBody: models.UserCreateReq{
+				Login:    "testuser",
+				Password: "testpass",
+			},
+			setupMocks: func(a *MockAuthService, ts *MockTokenStorage) {
+				a.On("Login", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).
+					Return(&models.TokenDto{}, &services.RetryAfterError{RetryAfter: 90 * time.Second})
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   `{"code":"too_many_attempts","message":"Too many attempts"}`,
 			checkCookies:   false,
+			expectedHeaders: map[string]string{
+				"Retry-After": "90",
+			},
 		},
 		{
 			name: "AuthServiceError",
@@ -403,11 +584,11 @@ func TestLogin(t *testing.T) {
 				Password: "testpass",
 			},
 			setupMocks: func(a *MockAuthService, ts *MockTokenStorage) {
-				a.On("Login", mock.AnythingOfType("models.UserCreateReq")).
+				a.On("Login", mock.AnythingOfType("models.UserCreateReq"), mock.AnythingOfType("models.ClientInfo")).
 					Return(&models.TokenDto{}, errors.New("some auth error"))
 			},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Bad request",
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"code":"internal_error","message":"Internal server error"}`,
 			checkCookies:   false,
 		},
 	}
@@ -422,6 +603,7 @@ func TestLogin(t *testing.T) {
 			// Prepare container with our mocks
 			AppContainer := dig.New()
 			AppContainer.Provide(func() app.AppAuthService { return mockAuth })
+			AppContainer.Provide(func() app.AppCookies { return cookies.DefaultPolicy })
 			AppContainer.Provide(func() app.AppTokenStorage { return mockTokenStorage })
 			app.AppContainer = AppContainer
 
@@ -436,6 +618,9 @@ func TestLogin(t *testing.T) {
 
 			req := httptest.NewRequest("POST", "/login", bytes.NewReader(body))
 			req.Header.Set("Content-Type", "application/json")
+			for name, value := range tt.requestHeaders {
+				req.Header.Set(name, value)
+			}
 			w := httptest.NewRecorder()
 
 			// Call the handler
@@ -451,6 +636,10 @@ func TestLogin(t *testing.T) {
 				assert.Empty(t, w.Body.String())
 			}
 
+			for name, value := range tt.expectedHeaders {
+				assert.Equal(t, value, w.Header().Get(name), "header %s mismatch", name)
+			}
+
 			// Check cookies if needed
 			if tt.checkCookies {
 				cookies := w.Result().Cookies()
@@ -467,3 +656,144 @@ func TestLogin(t *testing.T) {
 		})
 	}
 }
+
+// anyCSRFToken stands for the random CSRF token in expected cookies.
+const anyCSRFToken = "<random>"
+
+func TestRefresh(t *testing.T) {
+	tokens := &models.TokenDto{Access: "new_access", Refresh: "new_refresh", ExpiresIn: 15 * time.Minute}
+
+	tests := []struct {
+		name            string
+		setupRequest    func(*http.Request)
+		setupMocks      func(*MockAuthService)
+		expectedStatus  int
+		expectedBody    string
+		expectedCookies map[string]string
+	}{
+		{
+			name: "cookie mode",
+			setupRequest: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "refresh_token", Value: "old_refresh"})
+			},
+			setupMocks: func(a *MockAuthService) {
+				a.On("RefreshToken", "old_refresh").Return(tokens, nil)
+			},
+			expectedStatus:  http.StatusNoContent,
+			expectedCookies: map[string]string{"access_token": "new_access", "refresh_token": "new_refresh", "XSRF-TOKEN": anyCSRFToken},
+		},
+		{
+			name: "json mode",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Token-Mode", "json")
+				r.Body = io.NopCloser(strings.NewReader(`{"refresh_token":"old_refresh"}`))
+			},
+			setupMocks: func(a *MockAuthService) {
+				a.On("RefreshToken", "old_refresh").Return(tokens, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"access_token":"new_access","refresh_token":"new_refresh","token_type":"Bearer","expires_in":900}`,
+		},
+		{
+			name: "json mode ignores cookies",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Token-Mode", "json")
+				r.AddCookie(&http.Cookie{Name: "refresh_token", Value: "old_refresh"})
+				r.Body = io.NopCloser(strings.NewReader(`{}`))
+			},
+			setupMocks:     func(a *MockAuthService) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Refresh token is required",
+		},
+		{
+			name: "json mode malformed body",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Token-Mode", "json")
+				r.Body = io.NopCloser(strings.NewReader(`nope`))
+			},
+			setupMocks:     func(a *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body",
+		},
+		{
+			name:           "no refresh token",
+			setupRequest:   func(r *http.Request) {},
+			setupMocks:     func(a *MockAuthService) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Refresh token is required",
+		},
+		{
+			name: "reused refresh token clears cookies",
+			setupRequest: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "refresh_token", Value: "reused"})
+			},
+			setupMocks: func(a *MockAuthService) {
+				a.On("RefreshToken", "reused").Return((*models.TokenDto)(nil), services.ErrRefreshTokenReused)
+			},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedBody:    "Invalid refresh token",
+			expectedCookies: map[string]string{"access_token": "", "refresh_token": "", "XSRF-TOKEN": ""},
+		},
+		{
+			name: "expired refresh token clears cookies",
+			setupRequest: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "refresh_token", Value: "expired"})
+			},
+			setupMocks: func(a *MockAuthService) {
+				a.On("RefreshToken", "expired").Return((*models.TokenDto)(nil), fmt.Errorf("%w: token is expired", services.ErrTokenExpired))
+			},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedBody:    `"code":"invalid_refresh_token"`,
+			expectedCookies: map[string]string{"access_token": "", "refresh_token": "", "XSRF-TOKEN": ""},
+		},
+		{
+			name: "storage failure keeps cookies",
+			setupRequest: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "refresh_token", Value: "old_refresh"})
+			},
+			setupMocks: func(a *MockAuthService) {
+				a.On("RefreshToken", "old_refresh").Return((*models.TokenDto)(nil), errors.New("failed to store tokens: redis down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `"code":"internal_error"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuth := new(MockAuthService)
+			tt.setupMocks(mockAuth)
+
+			AppContainer := dig.New()
+			AppContainer.Provide(func() app.AppAuthService { return mockAuth })
+			AppContainer.Provide(func() app.AppCookies { return cookies.DefaultPolicy })
+			app.AppContainer = AppContainer
+
+			req := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+			tt.setupRequest(req)
+			w := httptest.NewRecorder()
+
+			Refresh(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			} else {
+				assert.Empty(t, w.Body.String())
+			}
+			cookies := w.Result().Cookies()
+			assert.Len(t, cookies, len(tt.expectedCookies))
+			for name, value := range tt.expectedCookies {
+				cookie := findCookie(cookies, name)
+				if assert.NotNil(t, cookie, "cookie %s not found", name) {
+					if value == anyCSRFToken {
+						assert.NotEmpty(t, cookie.Value, "cookie %s is empty", name)
+						continue
+					}
+					assert.Equal(t, value, cookie.Value, "cookie %s value mismatch", name)
+				}
+			}
+			mockAuth.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/router/apierror"
+	"go-auth/internal/services"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Token godoc
+// @Summary Выдача токена API-клиенту
+// @Description Client credentials grant (RFC 6749, 4.4). Клиент передаёт client_id и client_secret в заголовке Authorization: Basic или в теле формы. Полученный токен передаётся в заголовке Authorization: Bearer
+// @Tags API-клиенты
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "client_credentials"
+// @Param scope formData string false "Запрашиваемые scope через пробел, по умолчанию все разрешённые клиенту"
+// @Param client_id formData string false "ID клиента, если не передан в Authorization"
+// @Param client_secret formData string false "Секрет клиента, если не передан в Authorization"
+// @Success 200 {object} models.ClientTokenRes
+// @Failure 400 {object} models.OAuthErrorRes "invalid_request, unsupported_grant_type или invalid_scope"
+// @Failure 401 {object} models.OAuthErrorRes "invalid_client"
+// @Failure 429 {object} models.ErrorRes "Слишком много запросов, см. заголовок Retry-After"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Router /token [post]
+func Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "malformed form")
+		return
+	}
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+	case "":
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "grant_type is required")
+		return
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+		return
+	}
+
+	clientId, clientSecret, basic := r.BasicAuth()
+	if basic {
+		// RFC 6749 form-encodes both before Basic encoding.
+		var err1, err2 error
+		clientId, err1 = url.QueryUnescape(clientId)
+		clientSecret, err2 = url.QueryUnescape(clientSecret)
+		if err1 != nil || err2 != nil {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request", "malformed client credentials")
+			return
+		}
+	} else {
+		clientId, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	clientService, ok := resolveClientService(w, r)
+	if !ok {
+		return
+	}
+	res, err := clientService.ClientToken(r.Context(), clientId, clientSecret, r.PostForm.Get("scope"))
+	switch {
+	case errors.Is(err, services.ErrInvalidClient):
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+		}
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "")
+		return
+	case errors.Is(err, services.ErrInvalidScope):
+		writeOAuthError(w, http.StatusBadRequest, "invalid_scope", "")
+		return
+	case err != nil:
+		writeInternalError(w, r, err, "Failed to issue token")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	writeJSON(w, res)
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(models.OAuthErrorRes{Error: code, ErrorDescription: description}); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// RegisterClient godoc
+// @Summary Регистрация API-клиента
+// @Description Создаёт клиента для client credentials grant. Scope — имена разрешений. Секрет возвращается только в этом ответе. Требует разрешение clients:manage
+// @Tags API-клиенты
+// @Accept json
+// @Produce json
+// @Param input body models.APIClientCreateReq true "Имя и разрешённые scope"
+// @Success 201 {object} models.APIClientCreateRes
+// @Failure 400 {object} models.ErrorRes "Не указано имя или неизвестный scope"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 403 {object} models.ErrorRes "Недостаточно прав"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /clients [post]
+func RegisterClient(w http.ResponseWriter, r *http.Request) {
+	var req models.APIClientCreateReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	clientService, ok := resolveClientService(w, r)
+	if !ok {
+		return
+	}
+	res, err := clientService.RegisterClient(r.Context(), req)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// ListClients godoc
+// @Summary Список API-клиентов
+// @Description Возвращает всех клиентов, включая отозванных. Требует разрешение clients:manage
+// @Tags API-клиенты
+// @Produce json
+// @Success 200 {array} models.APIClient
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 403 {object} models.ErrorRes "Недостаточно прав"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /clients [get]
+func ListClients(w http.ResponseWriter, r *http.Request) {
+	clientService, ok := resolveClientService(w, r)
+	if !ok {
+		return
+	}
+	clients, err := clientService.ListClients(r.Context())
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list clients")
+		return
+	}
+	writeJSON(w, clients)
+}
+
+// RevokeClient godoc
+// @Summary Отзыв API-клиента
+// @Description Клиент больше не получает токены, выданные ему токены перестают приниматься. Требует разрешение clients:manage
+// @Tags API-клиенты
+// @Param id path string true "ID клиента"
+// @Success 204 "Клиент отозван"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 403 {object} models.ErrorRes "Недостаточно прав"
+// @Failure 404 {object} models.ErrorRes "Клиент не найден"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /clients/{id} [delete]
+func RevokeClient(w http.ResponseWriter, r *http.Request) {
+	clientService, ok := resolveClientService(w, r)
+	if !ok {
+		return
+	}
+	if err := clientService.RevokeClient(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func resolveClientService(w http.ResponseWriter, r *http.Request) (app.AppClientService, bool) {
+	var clientService app.AppClientService
+	if err := app.AppContainer.Invoke(func(s app.AppClientService) {
+		clientService = s
+	}); err != nil {
+		writeInternalError(w, r, err, "Failed to resolve ClientService")
+		return nil, false
+	}
+	return clientService, true
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"context"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/router/cookies"
+	"go-auth/internal/router/middlewares"
+	"go-auth/internal/services"
+	"go-auth/pkg/authz"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/dig"
+)
+
+type MockClientService struct {
+	mock.Mock
+}
+
+func (m *MockClientService) RegisterClient(ctx context.Context, req models.APIClientCreateReq) (*models.APIClientCreateRes, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIClientCreateRes), args.Error(1)
+}
+
+func (m *MockClientService) ListClients(ctx context.Context) ([]models.APIClient, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.APIClient), args.Error(1)
+}
+
+func (m *MockClientService) RevokeClient(ctx context.Context, clientId string) error {
+	args := m.Called(ctx, clientId)
+	return args.Error(0)
+}
+
+func (m *MockClientService) ClientToken(ctx context.Context, clientId, clientSecret, scope string) (*models.ClientTokenRes, error) {
+	args := m.Called(ctx, clientId, clientSecret, scope)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ClientTokenRes), args.Error(1)
+}
+
+func (m *MockClientService) AuthenticateClient(ctx context.Context, accessToken string) (*models.TokenClaims, error) {
+	args := m.Called(ctx, accessToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TokenClaims), args.Error(1)
+}
+
+func TestToken(t *testing.T) {
+	issued := &models.ClientTokenRes{AccessToken: "token", TokenType: "Bearer", ExpiresIn: 900, Scope: "users:read"}
+
+	tests := []struct {
+		name           string
+		form           url.Values
+		basic          []string
+		setupMocks     func(*MockClientService)
+		expectedStatus int
+		expectedBody   string
+		expectedHeader string
+	}{
+		{
+			name:  "basic auth",
+			form:  url.Values{"grant_type": {"client_credentials"}, "scope": {"users:read"}},
+			basic: []string{"cl_nest", "s%3Acret"},
+			setupMocks: func(a *MockClientService) {
+				a.On("ClientToken", mock.Anything, "cl_nest", "s:cret", "users:read").Return(issued, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"access_token":"token","token_type":"Bearer","expires_in":900,"scope":"users:read"}`,
+		},
+		{
+			name: "credentials in form",
+			form: url.Values{"grant_type": {"client_credentials"}, "client_id": {"cl_nest"}, "client_secret": {"secret"}},
+			setupMocks: func(a *MockClientService) {
+				a.On("ClientToken", mock.Anything, "cl_nest", "secret", "").Return(issued, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "wrong secret",
+			form:  url.Values{"grant_type": {"client_credentials"}},
+			basic: []string{"cl_nest", "wrong"},
+			setupMocks: func(a *MockClientService) {
+				a.On("ClientToken", mock.Anything, "cl_nest", "wrong", "").Return(nil, services.ErrInvalidClient)
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"invalid_client"}`,
+			expectedHeader: `Basic realm="token"`,
+		},
+		{
+			name: "scope not allowed",
+			form: url.Values{"grant_type": {"client_credentials"}, "client_id": {"cl_nest"}, "client_secret": {"secret"}, "scope": {"roles:manage"}},
+			setupMocks: func(a *MockClientService) {
+				a.On("ClientToken", mock.Anything, "cl_nest", "secret", "roles:manage").Return(nil, services.ErrInvalidScope)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid_scope"}`,
+		},
+		{
+			name:           "password grant",
+			form:           url.Values{"grant_type": {"password"}},
+			setupMocks:     func(a *MockClientService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"unsupported_grant_type"}`,
+		},
+		{
+			name:           "no grant type",
+			form:           url.Values{},
+			setupMocks:     func(a *MockClientService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid_request","error_description":"grant_type is required"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClients := new(MockClientService)
+			tt.setupMocks(mockClients)
+
+			AppContainer := dig.New()
+			AppContainer.Provide(func() app.AppClientService { return mockClients })
+			AppContainer.Provide(func() app.AppCookies { return cookies.DefaultPolicy })
+			app.AppContainer = AppContainer
+
+			req := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.basic != nil {
+				req.SetBasicAuth(tt.basic[0], tt.basic[1])
+			}
+			w := httptest.NewRecorder()
+			Token(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			assert.Equal(t, tt.expectedHeader, w.Header().Get("WWW-Authenticate"))
+			mockClients.AssertExpectations(t)
+		})
+	}
+}
+
+func TestClientsHandlers(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		permissions    []string
+		setupMocks     func(*MockClientService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:        "RegisterClient",
+			method:      http.MethodPost,
+			path:        "/clients",
+			body:        `{"name":"nest","scopes":["users:read"]}`,
+			permissions: []string{authz.PermClientsManage},
+			setupMocks: func(a *MockClientService) {
+				a.On("RegisterClient", mock.Anything, models.APIClientCreateReq{Name: "nest", Scopes: []string{"users:read"}}).Return(&models.APIClientCreateRes{
+					APIClient:    models.APIClient{Id: "cl_1", Name: "nest", Scopes: []string{"users:read"}, SecretHash: "hash"},
+					ClientSecret: "secret",
+				}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"clientId":"cl_1","name":"nest","scopes":["users:read"],"createdAt":"0001-01-01T00:00:00Z","clientSecret":"secret"}`,
+		},
+		{
+			name:        "RegisterClient unknown scope",
+			method:      http.MethodPost,
+			path:        "/clients",
+			body:        `{"name":"nest","scopes":["everything"]}`,
+			permissions: []string{authz.PermClientsManage},
+			setupMocks: func(a *MockClientService) {
+				a.On("RegisterClient", mock.Anything, mock.Anything).Return(nil, services.ErrInvalidScope)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "RegisterClient without permission",
+			method:         http.MethodPost,
+			path:           "/clients",
+			body:           `{"name":"nest","scopes":["users:read"]}`,
+			permissions:    []string{authz.PermRolesManage},
+			setupMocks:     func(a *MockClientService) {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:        "ListClients",
+			method:      http.MethodGet,
+			path:        "/clients",
+			permissions: []string{authz.PermClientsManage},
+			setupMocks: func(a *MockClientService) {
+				a.On("ListClients", mock.Anything).Return([]models.APIClient{{Id: "cl_1", Name: "nest", Scopes: []string{}, SecretHash: "hash"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"clientId":"cl_1","name":"nest","scopes":[],"createdAt":"0001-01-01T00:00:00Z"}]`,
+		},
+		{
+			name:        "RevokeClient",
+			method:      http.MethodDelete,
+			path:        "/clients/cl_1",
+			permissions: []string{authz.PermClientsManage},
+			setupMocks: func(a *MockClientService) {
+				a.On("RevokeClient", mock.Anything, "cl_1").Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:        "RevokeClient unknown",
+			method:      http.MethodDelete,
+			path:        "/clients/cl_2",
+			permissions: []string{authz.PermClientsManage},
+			setupMocks: func(a *MockClientService) {
+				a.On("RevokeClient", mock.Anything, "cl_2").Return(services.ErrClientNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClients := new(MockClientService)
+			tt.setupMocks(mockClients)
+
+			AppContainer := dig.New()
+			AppContainer.Provide(func() app.AppClientService { return mockClients })
+			AppContainer.Provide(func() app.AppCookies { return cookies.DefaultPolicy })
+			app.AppContainer = AppContainer
+
+			router := chi.NewRouter()
+			router.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					next.ServeHTTP(w, r.WithContext(middlewares.WithPrincipal(r.Context(), &models.TokenClaims{
+						Type:             models.TokenTypeAccess,
+						Permissions:      tt.permissions,
+						RegisteredClaims: jwt.RegisteredClaims{Subject: "123"},
+					})))
+				})
+			})
+			router.Route("/clients", func(r chi.Router) {
+				r.Use(authz.RequirePermission(authz.PermClientsManage))
+				r.Post("/", RegisterClient)
+				r.Get("/", ListClients)
+				r.Delete("/{id}", RevokeClient)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockClients.AssertExpectations(t)
+		})
+	}
+}
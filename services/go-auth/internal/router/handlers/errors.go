@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"go-auth/internal/router/apierror"
+	"go-auth/internal/router/middlewares"
+	"go-auth/internal/services"
+	"go-auth/internal/storage"
+	"log/slog"
+	"net/http"
+)
+
+// serviceError is how a sentinel error of the service or storage layer is
+// answered. With detailed set the error text is the message: it was
+// written for the client.
+type serviceError struct {
+	err      error
+	status   int
+	code     string
+	message  string
+	detailed bool
+}
+
+// serviceErrors is searched in order with errors.Is, so an error wrapping
+// two sentinels is answered as the first one listed.
+var serviceErrors = []serviceError{
+	{err: services.ErrLoginAndPasswordAreRequired, status: http.StatusBadRequest, code: "credentials_required", message: "Login and password are required"},
+	{err: services.ErrWrongLoginOrPassword, status: http.StatusUnauthorized, code: "wrong_credentials", message: "Wrong login or password"},
+	{err: services.ErrUserExists, status: http.StatusConflict, code: "user_exists", message: "User already exists"},
+	{err: services.ErrEmailExists, status: http.StatusConflict, code: "email_exists", message: "Email already in use"},
+	{err: services.ErrInvalidEmail, status: http.StatusBadRequest, code: "invalid_email", message: "Invalid email"},
+	{err: services.ErrPasswordRequired, status: http.StatusBadRequest, code: "password_required", message: "Password is required"},
+	{err: services.ErrWrongPassword, status: http.StatusBadRequest, code: "wrong_password", message: "Wrong password"},
+	{err: services.ErrInvalidDisplayName, status: http.StatusBadRequest, code: "invalid_display_name", message: "Display name is too long"},
+	{err: services.ErrInvalidAvatarURL, status: http.StatusBadRequest, code: "invalid_avatar_url", message: "Invalid avatar url"},
+	{err: services.ErrTooManyAttempts, status: http.StatusTooManyRequests, code: "too_many_attempts", message: "Too many attempts"},
+	{err: services.ErrInvalidActionToken, status: http.StatusBadRequest, code: "invalid_action_token", message: "Invalid or expired token"},
+	{err: services.ErrInvalidTokenType, status: http.StatusBadRequest, code: "invalid_action_token", message: "Invalid or expired token"},
+	{err: services.ErrTokenExpired, status: http.StatusUnauthorized, code: "token_expired", message: "Token expired"},
+	{err: services.ErrInvalidToken, status: http.StatusUnauthorized, code: "invalid_token", message: "Invalid token"},
+	{err: services.ErrRefreshTokenReused, status: http.StatusUnauthorized, code: "refresh_token_reused", message: "Refresh token reuse detected"},
+	{err: services.ErrRefreshTokenRevoked, status: http.StatusUnauthorized, code: "refresh_token_revoked", message: "Refresh token revoked"},
+	{err: services.ErrSessionRevoked, status: http.StatusUnauthorized, code: "session_revoked", message: "Session revoked"},
+	{err: services.ErrSessionNotFound, status: http.StatusNotFound, code: "session_not_found", message: "Session not found"},
+	{err: services.ErrMFARequired, status: http.StatusUnauthorized, code: "mfa_required", message: "Second factor required"},
+	{err: services.ErrInvalidMFACode, status: http.StatusBadRequest, code: "invalid_mfa_code", message: "Invalid code"},
+	{err: services.ErrMFAAlreadyEnabled, status: http.StatusConflict, code: "mfa_enabled", message: "Two-factor authentication is already enabled"},
+	{err: services.ErrMFANotSetUp, status: http.StatusConflict, code: "mfa_not_set_up", message: "Two-factor authentication setup has not been started"},
+	{err: services.ErrMFANotEnabled, status: http.StatusConflict, code: "mfa_not_enabled", message: "Two-factor authentication is not enabled"},
+	{err: services.ErrUnknownOAuthProvider, status: http.StatusNotFound, code: "unknown_provider", message: "Unknown provider"},
+	{err: services.ErrInvalidOAuthState, status: http.StatusBadRequest, code: "invalid_oauth_state", message: "Invalid or expired oauth state"},
+	{err: services.ErrOAuthEmailConflict, status: http.StatusConflict, code: "email_conflict", message: "Email belongs to an account with unverified email"},
+	{err: services.ErrIdentityLinked, status: http.StatusConflict, code: "identity_linked", message: "Identity is linked to another user"},
+	{err: services.ErrRoleNotFound, status: http.StatusNotFound, code: "role_not_found", message: "Role not found"},
+	{err: services.ErrUserNotFound, status: http.StatusNotFound, code: "user_not_found", message: "User not found"},
+	{err: services.ErrInvalidClient, status: http.StatusUnauthorized, code: "invalid_client", message: "Invalid client credentials"},
+	{err: services.ErrInvalidScope, status: http.StatusBadRequest, code: "invalid_scope", message: "Unknown scope"},
+	{err: services.ErrInvalidClientName, status: http.StatusBadRequest, code: "invalid_client_name", message: "Client name is required"},
+	{err: services.ErrClientNotFound, status: http.StatusNotFound, code: "client_not_found", message: "Client not found"},
+	{err: services.ErrInvalidEventFilter, status: http.StatusBadRequest, code: "invalid_filter", detailed: true},
+
+	{err: storage.ErrUserNotFound, status: http.StatusNotFound, code: "user_not_found", message: "User not found"},
+	{err: storage.ErrRefreshFamilyNotFound, status: http.StatusUnauthorized, code: "refresh_token_revoked", message: "Refresh token revoked"},
+	{err: storage.ErrRefreshTokenReused, status: http.StatusUnauthorized, code: "refresh_token_reused", message: "Refresh token reuse detected"},
+	{err: storage.ErrSessionNotFound, status: http.StatusNotFound, code: "session_not_found", message: "Session not found"},
+	{err: storage.ErrActionTokenNotFound, status: http.StatusBadRequest, code: "invalid_action_token", message: "Invalid or expired token"},
+	{err: storage.ErrOAuthStateNotFound, status: http.StatusBadRequest, code: "invalid_oauth_state", message: "Invalid or expired oauth state"},
+	{err: storage.ErrIdentityExists, status: http.StatusConflict, code: "identity_linked", message: "Identity is linked to another user"},
+	{err: storage.ErrRoleNotFound, status: http.StatusNotFound, code: "role_not_found", message: "Role not found"},
+	{err: storage.ErrClientNotFound, status: http.StatusNotFound, code: "client_not_found", message: "Client not found"},
+	{err: storage.ErrScopeNotFound, status: http.StatusBadRequest, code: "invalid_scope", message: "Unknown scope"},
+}
+
+// writeServiceError answers with the status and code err is mapped to in
+// serviceErrors. Rejected fields of a *services.ValidationError are listed
+// and the lockout of a *services.RetryAfterError goes into Retry-After.
+// Anything else is logged and answered 500 without details.
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	var invalid *services.ValidationError
+	if errors.As(err, &invalid) {
+		apierror.WriteFields(w, r, http.StatusBadRequest, apierror.CodeValidation, "Validation failed", invalid.Fields)
+		return
+	}
+	var tooMany *services.RetryAfterError
+	if errors.As(err, &tooMany) {
+		middlewares.SetRetryAfter(w, tooMany.RetryAfter)
+	}
+	for _, e := range serviceErrors {
+		if !errors.Is(err, e.err) {
+			continue
+		}
+		message := e.message
+		if e.detailed {
+			message = err.Error()
+		}
+		apierror.Write(w, r, e.status, e.code, message)
+		return
+	}
+	slog.Error(err.Error(), "trace_id", apierror.TraceId(r.Context()))
+	apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Internal server error")
+}
+
+// writeInternalError logs err with message, which says what failed, and
+// answers 500 without details.
+func writeInternalError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	slog.Error(message, "error", err, "trace_id", apierror.TraceId(r.Context()))
+	apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Internal server error")
+}
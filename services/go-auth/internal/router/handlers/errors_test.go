@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-auth/internal/models"
+	"go-auth/internal/services"
+	"go-auth/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteServiceError(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             error
+		expectedStatus  int
+		expectedBody    string
+		expectedHeaders map[string]string
+	}{
+		{
+			name:           "conflict",
+			err:            fmt.Errorf("AuthService Create: %w", services.ErrUserExists),
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"code":"user_exists","message":"User already exists"}`,
+		},
+		{
+			name:           "storage sentinel",
+			err:            fmt.Errorf("AuthService AssignRole: %w", storage.ErrRoleNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"role_not_found","message":"Role not found"}`,
+		},
+		{
+			name:           "mfa not set up",
+			err:            fmt.Errorf("AuthService ConfirmTOTP: %w", services.ErrMFANotSetUp),
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"code":"mfa_not_set_up","message":"Two-factor authentication setup has not been started"}`,
+		},
+		{
+			name:           "detailed",
+			err:            fmt.Errorf("%w: limit must be between 1 and 1000", services.ErrInvalidEventFilter),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"invalid_filter","message":"invalid event filter: limit must be between 1 and 1000"}`,
+		},
+		{
+			name:           "validation",
+			err:            &services.ValidationError{Fields: []models.FieldError{{Field: "login", Code: "too_short", Message: "must be at least 3 characters"}}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Validation failed","fields":[` +
+				`{"field":"login","code":"too_short","message":"must be at least 3 characters"}]}`,
+		},
+		{
+			name:            "retry after",
+			err:             &services.RetryAfterError{RetryAfter: 90 * time.Second},
+			expectedStatus:  http.StatusTooManyRequests,
+			expectedBody:    `{"code":"too_many_attempts","message":"Too many attempts"}`,
+			expectedHeaders: map[string]string{"Retry-After": "90"},
+		},
+		{
+			name:           "unknown",
+			err:            errors.New("connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"code":"internal_error","message":"Internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			w := httptest.NewRecorder()
+
+			writeServiceError(w, r, tt.err)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			for name, value := range tt.expectedHeaders {
+				assert.Equal(t, value, w.Header().Get(name))
+			}
+		})
+	}
+}
+
+func TestWriteInternalError(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	w := httptest.NewRecorder()
+
+	writeInternalError(w, r, errors.New("dial tcp 10.0.0.5:6379: connection refused"), "token store error")
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"code":"internal_error","message":"Internal server error"}`, w.Body.String())
+}
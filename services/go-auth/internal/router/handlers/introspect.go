@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"go-auth/internal/app"
+	"go-auth/internal/router/apierror"
+	"net/http"
+)
+
+// Introspect godoc
+// @Summary Интроспекция токена (RFC 7662)
+// @Description Для других сервисов: проверяет access token (подпись, срок действия, отзыв сессии в Redis) и возвращает субъект, роли и время истечения. Для неактивного токена возвращается только active=false. Вызывающий сервис передаёт общий секрет в заголовке Authorization: Bearer
+// @Tags Интроспекция
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Проверяемый токен"
+// @Param token_type_hint formData string false "Подсказка о типе токена, игнорируется"
+// @Success 200 {object} models.Introspection
+// @Failure 400 {object} models.ErrorRes "Не передан токен"
+// @Failure 401 {object} models.ErrorRes "Неизвестный вызывающий сервис"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /introspect [post]
+func Introspect(w http.ResponseWriter, r *http.Request) {
+	introspectionService, ok := resolveIntrospectionService(w, r)
+	if !ok {
+		return
+	}
+	token := r.PostFormValue("token")
+	if token == "" {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Token is required")
+		return
+	}
+	res, err := introspectionService.Introspect(r.Context(), token)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to introspect token")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, res)
+}
+
+func resolveIntrospectionService(w http.ResponseWriter, r *http.Request) (app.AppIntrospectionService, bool) {
+	var introspectionService app.AppIntrospectionService
+	if err := app.AppContainer.Invoke(func(s app.AppIntrospectionService) {
+		introspectionService = s
+	}); err != nil {
+		writeInternalError(w, r, err, "Failed to resolve IntrospectionService")
+		return nil, false
+	}
+	return introspectionService, true
+}
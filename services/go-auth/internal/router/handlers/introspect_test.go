@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/router/cookies"
+	"go-auth/internal/router/middlewares"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/dig"
+)
+
+type MockIntrospectionService struct {
+	mock.Mock
+}
+
+func (m *MockIntrospectionService) Introspect(ctx context.Context, accessToken string) (*models.Introspection, error) {
+	args := m.Called(ctx, accessToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Introspection), args.Error(1)
+}
+
+func TestIntrospect(t *testing.T) {
+	tests := []struct {
+		name           string
+		authorization  string
+		form           url.Values
+		setupMocks     func(*MockIntrospectionService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:          "active token",
+			authorization: "Bearer service-secret",
+			form:          url.Values{"token": {"access-token"}, "token_type_hint": {"access_token"}},
+			setupMocks: func(a *MockIntrospectionService) {
+				a.On("Introspect", mock.Anything, "access-token").Return(&models.Introspection{
+					Active:    true,
+					Subject:   "123",
+					SessionId: "session-1",
+					Roles:     []string{"player"},
+					ExpiresAt: 1700000000,
+					TokenType: models.TokenTypeAccess,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"active":true,"sub":"123","sid":"session-1","roles":["player"],"exp":1700000000,"token_type":"access"}`,
+		},
+		{
+			name:          "inactive token",
+			authorization: "Bearer service-secret",
+			form:          url.Values{"token": {"revoked"}},
+			setupMocks: func(a *MockIntrospectionService) {
+				a.On("Introspect", mock.Anything, "revoked").Return(&models.Introspection{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"active":false}`,
+		},
+		{
+			name:           "missing token",
+			authorization:  "Bearer service-secret",
+			form:           url.Values{},
+			setupMocks:     func(a *MockIntrospectionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:          "service error",
+			authorization: "Bearer service-secret",
+			form:          url.Values{"token": {"access-token"}},
+			setupMocks: func(a *MockIntrospectionService) {
+				a.On("Introspect", mock.Anything, "access-token").Return(nil, errors.New("redis down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "unknown caller",
+			authorization:  "Bearer wrong",
+			form:           url.Values{"token": {"access-token"}},
+			setupMocks:     func(a *MockIntrospectionService) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockIntrospection := new(MockIntrospectionService)
+			tt.setupMocks(mockIntrospection)
+
+			AppContainer := dig.New()
+			AppContainer.Provide(func() app.AppIntrospectionService { return mockIntrospection })
+			AppContainer.Provide(func() app.AppCookies { return cookies.DefaultPolicy })
+			app.AppContainer = AppContainer
+
+			router := chi.NewRouter()
+			router.With(middlewares.RequireServiceToken([]string{"service-secret"})).Post("/introspect", Introspect)
+
+			req := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Authorization", tt.authorization)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+				assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+			}
+			mockIntrospection.AssertExpectations(t)
+		})
+	}
+}
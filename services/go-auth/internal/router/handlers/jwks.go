@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-auth/internal/app"
+	"log/slog"
+	"net/http"
+)
+
+// JWKS godoc
+// @Summary Публичные ключи подписи токенов
+// @Description Возвращает JWKS с активным и выводимыми из оборота ключами для проверки JWT другими сервисами
+// @Tags Аутентификация
+// @Produce json
+// @Success 200 {object} models.JWKSet
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Router /.well-known/jwks.json [get]
+func JWKS(w http.ResponseWriter, r *http.Request) {
+	var keys app.AppKeyRing
+	if err := app.AppContainer.Invoke(func(kr app.AppKeyRing) {
+		keys = kr
+	}); err != nil {
+		writeInternalError(w, r, err, "Failed to resolve KeyRing")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(keys.JWKS()); err != nil {
+		slog.Error(err.Error())
+	}
+}
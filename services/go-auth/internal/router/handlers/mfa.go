@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/router/apierror"
+	"go-auth/internal/services"
+	"go-auth/pkg/metrics"
+	"log/slog"
+	"net/http"
+)
+
+// LoginMFA godoc
+// @Summary Второй шаг входа
+// @Description Обменивает MFA challenge из /login и код второго фактора (TOTP или код восстановления) на JWT токены в cookies или, с заголовком X-Token-Mode: json, в теле ответа
+// @Tags Аутентификация
+// @Accept json
+// @Produce json
+// @Param X-Token-Mode header string false "json — вернуть токены в теле ответа" Enums(json)
+// @Param input body models.MFALoginReq true "Challenge и код"
+// @Success 200 {object} models.TokenRes "Успешная аутентификация (режим json)"
+// @Success 204 "Успешная аутентификация, токены установлены в cookies"
+// @Failure 400 {object} models.ErrorRes "Неверный код или просроченный challenge"
+// @Failure 429 {object} models.ErrorRes "Слишком много попыток, см. заголовок Retry-After"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Router /login/mfa [post]
+func LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req models.MFALoginReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	var mfaService app.AppMFAService
+	var tokenStore app.AppTokenStorage
+	if err := app.AppContainer.Invoke(func(ms app.AppMFAService, s app.AppTokenStorage) {
+		tokenStore = s
+		mfaService = ms
+	}); err != nil {
+		writeInternalError(w, r, err, "Failed to resolve MFAService & TokenStorage")
+		return
+	}
+	jwt, err := mfaService.LoginMFA(r.Context(), req.Challenge, req.Code, clientInfo(r))
+	if err != nil {
+		writeMFAError(w, r, err)
+		return
+	}
+	if err := tokenStore.SetTokens(r.Context(), jwt); err != nil {
+		writeInternalError(w, r, err, "token store error")
+		return
+	}
+	writeTokens(w, r, jwt)
+}
+
+// SetupTOTP godoc
+// @Summary Начало подключения TOTP
+// @Description Генерирует секрет и otpauth URI для приложения-аутентификатора. 2FA включается после подтверждения кодом
+// @Tags 2FA
+// @Produce json
+// @Success 200 {object} models.TOTPSetupRes
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 409 {object} models.ErrorRes "2FA уже включена"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /mfa/totp/setup [post]
+func SetupTOTP(w http.ResponseWriter, r *http.Request) {
+	claims, ok := currentSession(w, r)
+	if !ok {
+		return
+	}
+	mfaService, ok := resolveMFAService(w, r)
+	if !ok {
+		return
+	}
+	setup, err := mfaService.SetupTOTP(r.Context(), claims.Subject)
+	if err != nil {
+		writeMFAError(w, r, err)
+		return
+	}
+	writeJSON(w, setup)
+}
+
+// ConfirmTOTP godoc
+// @Summary Подтверждение TOTP
+// @Description Включает 2FA, если код из приложения верный, и возвращает коды восстановления
+// @Tags 2FA
+// @Accept json
+// @Produce json
+// @Param input body models.MFACodeReq true "Код из приложения"
+// @Success 200 {object} models.RecoveryCodesRes
+// @Failure 400 {object} models.ErrorRes "Неверный код"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 409 {object} models.ErrorRes "2FA уже включена или не начата настройка"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /mfa/totp/confirm [post]
+func ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	claims, ok := currentSession(w, r)
+	if !ok {
+		return
+	}
+	mfaService, ok := resolveMFAService(w, r)
+	if !ok {
+		return
+	}
+	var req models.MFACodeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	codes, err := mfaService.ConfirmTOTP(r.Context(), claims.Subject, req.Code)
+	if err != nil {
+		writeMFAError(w, r, err)
+		return
+	}
+	writeJSON(w, models.RecoveryCodesRes{Codes: codes})
+}
+
+// DisableTOTP godoc
+// @Summary Отключение TOTP
+// @Description Отключает 2FA и удаляет коды восстановления. Требует действующий код
+// @Tags 2FA
+// @Accept json
+// @Param input body models.MFACodeReq true "Код из приложения или код восстановления"
+// @Success 204 "2FA отключена"
+// @Failure 400 {object} models.ErrorRes "Неверный код"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 409 {object} models.ErrorRes "2FA не включена"
+// @Failure 429 {object} models.ErrorRes "Слишком много попыток, см. заголовок Retry-After"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /mfa/totp/disable [post]
+func DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	claims, ok := currentSession(w, r)
+	if !ok {
+		return
+	}
+	mfaService, ok := resolveMFAService(w, r)
+	if !ok {
+		return
+	}
+	var req models.MFACodeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if err := mfaService.DisableTOTP(r.Context(), claims.Subject, req.Code); err != nil {
+		writeMFAError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Новые коды восстановления
+// @Description Заменяет все коды восстановления новыми. Требует действующий код
+// @Tags 2FA
+// @Accept json
+// @Produce json
+// @Param input body models.MFACodeReq true "Код из приложения или код восстановления"
+// @Success 200 {object} models.RecoveryCodesRes
+// @Failure 400 {object} models.ErrorRes "Неверный код"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 409 {object} models.ErrorRes "2FA не включена"
+// @Failure 429 {object} models.ErrorRes "Слишком много попыток, см. заголовок Retry-After"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /mfa/recovery-codes [post]
+func RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	claims, ok := currentSession(w, r)
+	if !ok {
+		return
+	}
+	mfaService, ok := resolveMFAService(w, r)
+	if !ok {
+		return
+	}
+	var req models.MFACodeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	codes, err := mfaService.RegenerateRecoveryCodes(r.Context(), claims.Subject, req.Code)
+	if err != nil {
+		writeMFAError(w, r, err)
+		return
+	}
+	writeJSON(w, models.RecoveryCodesRes{Codes: codes})
+}
+
+func writeMFAError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, services.ErrTooManyAttempts) {
+		metrics.RecordBlocked("mfa", "lockout")
+	}
+	writeServiceError(w, r, err)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+func resolveMFAService(w http.ResponseWriter, r *http.Request) (app.AppMFAService, bool) {
+	var mfaService app.AppMFAService
+	if err := app.AppContainer.Invoke(func(s app.AppMFAService) {
+		mfaService = s
+	}); err != nil {
+		writeInternalError(w, r, err, "Failed to resolve MFAService")
+		return nil, false
+	}
+	return mfaService, true
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/router/cookies"
+	"go-auth/internal/router/middlewares"
+	"go-auth/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/dig"
+)
+
+type MockMFAService struct {
+	mock.Mock
+}
+
+func (m *MockMFAService) LoginMFA(ctx context.Context, challenge, code string, client models.ClientInfo) (*models.TokenDto, error) {
+	args := m.Called(ctx, challenge, code, client)
+	return args.Get(0).(*models.TokenDto), args.Error(1)
+}
+
+func (m *MockMFAService) SetupTOTP(ctx context.Context, userId string) (*models.TOTPSetupRes, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).(*models.TOTPSetupRes), args.Error(1)
+}
+
+func (m *MockMFAService) ConfirmTOTP(ctx context.Context, userId, code string) ([]string, error) {
+	args := m.Called(ctx, userId, code)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockMFAService) DisableTOTP(ctx context.Context, userId, code string) error {
+	args := m.Called(ctx, userId, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) RegenerateRecoveryCodes(ctx context.Context, userId, code string) ([]string, error) {
+	args := m.Called(ctx, userId, code)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+type MFATestCase struct {
+	name            string
+	handler         http.HandlerFunc
+	body            string
+	authenticated   bool
+	setupMocks      func(*MockMFAService, *MockTokenStorage)
+	expectedStatus  int
+	expectedBody    string
+	expectedHeaders map[string]string
+	expectCookies   bool
+}
+
+func TestMFAHandlers(t *testing.T) {
+	tokens := &models.TokenDto{Access: "access_token", Refresh: "refresh_token"}
+
+	tests := []MFATestCase{
+		{
+			name:    "LoginMFA success",
+			handler: LoginMFA,
+			body:    `{"challenge":"c","code":"123456"}`,
+			setupMocks: func(a *MockMFAService, ts *MockTokenStorage) {
+				a.On("LoginMFA", mock.Anything, "c", "123456", mock.AnythingOfType("models.ClientInfo")).Return(tokens, nil)
+				ts.On("SetTokens", mock.Anything, tokens).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+			expectCookies:  true,
+		},
+		{
+			name:    "LoginMFA wrong code",
+			handler: LoginMFA,
+			body:    `{"challenge":"c","code":"000000"}`,
+			setupMocks: func(a *MockMFAService, ts *MockTokenStorage) {
+				a.On("LoginMFA", mock.Anything, "c", "000000", mock.AnythingOfType("models.ClientInfo")).
+					Return((*models.TokenDto)(nil), services.ErrInvalidMFACode)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid code",
+		},
+		{
+			name:    "LoginMFA expired challenge",
+			handler: LoginMFA,
+			body:    `{"challenge":"c","code":"123456"}`,
+			setupMocks: func(a *MockMFAService, ts *MockTokenStorage) {
+				a.On("LoginMFA", mock.Anything, "c", "123456", mock.AnythingOfType("models.ClientInfo")).
+					Return((*models.TokenDto)(nil), services.ErrInvalidActionToken)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid or expired token",
+		},
+		{
+			name:    "LoginMFA locked",
+			handler: LoginMFA,
+			body:    `{"challenge":"c","code":"123456"}`,
+			setupMocks: func(a *MockMFAService, ts *MockTokenStorage) {
+				a.On("LoginMFA", mock.Anything, "c", "123456", mock.AnythingOfType("models.ClientInfo")).
+					Return((*models.TokenDto)(nil), &services.RetryAfterError{RetryAfter: time.Minute})
+			},
+			expectedStatus:  http.StatusTooManyRequests,
+			expectedBody:    "Too many attempts",
+			expectedHeaders: map[string]string{"Retry-After": "60"},
+		},
+		{
+			name:          "SetupTOTP",
+			handler:       SetupTOTP,
+			authenticated: true,
+			setupMocks: func(a *MockMFAService, ts *MockTokenStorage) {
+				a.On("SetupTOTP", mock.Anything, "123").Return(&models.TOTPSetupRes{Secret: "S", Uri: "otpauth://totp/x"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"secret":"S","uri":"otpauth://totp/x"}`,
+		},
+		{
+			name:           "SetupTOTP unauthenticated",
+			handler:        SetupTOTP,
+			setupMocks:     func(a *MockMFAService, ts *MockTokenStorage) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "SetupTOTP already enabled",
+			handler:       SetupTOTP,
+			authenticated: true,
+			setupMocks: func(a *MockMFAService, ts *MockTokenStorage) {
+				a.On("SetupTOTP", mock.Anything, "123").Return((*models.TOTPSetupRes)(nil), services.ErrMFAAlreadyEnabled)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:          "ConfirmTOTP",
+			handler:       ConfirmTOTP,
+			body:          `{"code":"123456"}`,
+			authenticated: true,
+			setupMocks: func(a *MockMFAService, ts *MockTokenStorage) {
+				a.On("ConfirmTOTP", mock.Anything, "123", "123456").Return([]string{"aaaaa-bbbbb"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"codes":["aaaaa-bbbbb"]}`,
+		},
+		{
+			name:          "DisableTOTP not enabled",
+			handler:       DisableTOTP,
+			body:          `{"code":"123456"}`,
+			authenticated: true,
+			setupMocks: func(a *MockMFAService, ts *MockTokenStorage) {
+				a.On("DisableTOTP", mock.Anything, "123", "123456").Return(services.ErrMFANotEnabled)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:          "RegenerateRecoveryCodes wrong code",
+			handler:       RegenerateRecoveryCodes,
+			body:          `{"code":"123456"}`,
+			authenticated: true,
+			setupMocks: func(a *MockMFAService, ts *MockTokenStorage) {
+				a.On("RegenerateRecoveryCodes", mock.Anything, "123", "123456").Return([]string(nil), services.ErrInvalidMFACode)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid code",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMFA := new(MockMFAService)
+			mockTokenStorage := new(MockTokenStorage)
+			tt.setupMocks(mockMFA, mockTokenStorage)
+
+			AppContainer := dig.New()
+			AppContainer.Provide(func() app.AppMFAService { return mockMFA })
+			AppContainer.Provide(func() app.AppCookies { return cookies.DefaultPolicy })
+			AppContainer.Provide(func() app.AppTokenStorage { return mockTokenStorage })
+			app.AppContainer = AppContainer
+
+			req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.authenticated {
+				req = req.WithContext(middlewares.WithPrincipal(req.Context(), &models.TokenClaims{
+					Type:             models.TokenTypeAccess,
+					Family:           "session-1",
+					RegisteredClaims: jwt.RegisteredClaims{Subject: "123"},
+				}))
+			}
+			w := httptest.NewRecorder()
+
+			tt.handler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+			for name, value := range tt.expectedHeaders {
+				assert.Equal(t, value, w.Header().Get(name), "header %s mismatch", name)
+			}
+			cookies := w.Result().Cookies()
+			if tt.expectCookies {
+				assert.Equal(t, "access_token", findCookie(cookies, "access_token").Value)
+				assert.Equal(t, "refresh_token", findCookie(cookies, "refresh_token").Value)
+			} else {
+				assert.Empty(t, cookies)
+			}
+			mockMFA.AssertExpectations(t)
+			mockTokenStorage.AssertExpectations(t)
+		})
+	}
+}
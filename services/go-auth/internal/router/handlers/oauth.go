@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"go-auth/internal/app"
+	"go-auth/internal/router/apierror"
+	"go-auth/internal/router/cookies"
+	"go-auth/internal/router/middlewares"
+	"go-auth/internal/services"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// OAuthLogin godoc
+// @Summary Вход через внешнего провайдера
+// @Description Перенаправляет на страницу входа OIDC провайдера (authorization code + PKCE) и выставляет cookie oauth_state, которая привязывает вход к браузеру
+// @Tags OAuth
+// @Param provider path string true "Имя провайдера из OAUTH_PROVIDERS"
+// @Success 302 "Перенаправление к провайдеру"
+// @Failure 404 {object} models.ErrorRes "Неизвестный провайдер"
+// @Failure 429 {object} models.ErrorRes "Слишком много запросов, см. заголовок Retry-After"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Router /oauth/{provider}/login [get]
+func OAuthLogin(w http.ResponseWriter, r *http.Request) {
+	oauthStart(w, r, "")
+}
+
+// OAuthLink godoc
+// @Summary Привязка внешнего аккаунта
+// @Description Перенаправляет к OIDC провайдеру, чтобы привязать его аккаунт к текущему пользователю
+// @Tags OAuth
+// @Param provider path string true "Имя провайдера из OAUTH_PROVIDERS"
+// @Success 302 "Перенаправление к провайдеру"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 404 {object} models.ErrorRes "Неизвестный провайдер"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /oauth/{provider}/link [get]
+func OAuthLink(w http.ResponseWriter, r *http.Request) {
+	principal, ok := middlewares.PrincipalFromContext(r.Context())
+	if !ok {
+		apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	oauthStart(w, r, principal.UserId)
+}
+
+func oauthStart(w http.ResponseWriter, r *http.Request, linkUserId string) {
+	oauthService, ok := resolveOAuthService(w, r)
+	if !ok {
+		return
+	}
+	c, ok := resolveCookies(w, r)
+	if !ok {
+		return
+	}
+	authURL, state, err := oauthService.OAuthStart(r.Context(), chi.URLParam(r, "provider"), linkUserId)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	c.SetOAuthState(w, state, services.OAuthStateTTL)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OAuthCallback godoc
+// @Summary Возврат от внешнего провайдера
+// @Description Принимается только вместе с cookie oauth_state из /oauth/{provider}/login или /oauth/{provider}/link. Завершает вход или привязку и перенаправляет во фронтенд: на "/" с токенами в cookies, на "/login/mfa?challenge=" если включена 2FA, на "/settings?linked=" после привязки или на "/login?error=" при ошибке
+// @Tags OAuth
+// @Param provider path string true "Имя провайдера"
+// @Param code query string false "Код авторизации"
+// @Param state query string true "State из /oauth/{provider}/login"
+// @Success 302 "Перенаправление во фронтенд"
+// @Router /oauth/{provider}/callback [get]
+func OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	var oauthService app.AppOAuthService
+	var tokenStore app.AppTokenStorage
+	var cfg app.AppConfig
+	if err := app.AppContainer.Invoke(func(oas app.AppOAuthService, s app.AppTokenStorage, c app.AppConfig) {
+		oauthService = oas
+		tokenStore = s
+		cfg = c
+	}); err != nil {
+		writeInternalError(w, r, err, "Failed to resolve OAuthService & TokenStorage")
+		return
+	}
+	c, ok := resolveCookies(w, r)
+	if !ok {
+		return
+	}
+	frontend := strings.TrimRight(cfg.GetConfig().PublicURL, "/")
+	provider := chi.URLParam(r, "provider")
+	q := r.URL.Query()
+	// The state is one-time, whatever the outcome.
+	c.ClearOAuthState(w)
+
+	if providerErr := q.Get("error"); providerErr != "" {
+		slog.Info("OAuth provider returned error", "provider", provider, "error", providerErr)
+		redirectWithError(w, r, frontend, "access_denied")
+		return
+	}
+	if !cookies.OAuthStateMatches(r, q.Get("state")) {
+		slog.Info("OAuth state not started in this browser", "provider", provider)
+		redirectWithError(w, r, frontend, "invalid_state")
+		return
+	}
+
+	jwt, err := oauthService.OAuthCallback(r.Context(), provider, q.Get("state"), q.Get("code"), clientInfo(r))
+	if err != nil {
+		var mfa *services.MFARequiredError
+		switch {
+		case errors.As(err, &mfa):
+			http.Redirect(w, r, frontend+"/login/mfa?challenge="+url.QueryEscape(mfa.Challenge), http.StatusFound)
+		case errors.Is(err, services.ErrOAuthEmailConflict):
+			redirectWithError(w, r, frontend, "email_conflict")
+		case errors.Is(err, services.ErrIdentityLinked):
+			redirectWithError(w, r, frontend, "identity_linked")
+		case errors.Is(err, services.ErrInvalidOAuthState), errors.Is(err, services.ErrUnknownOAuthProvider):
+			slog.Info(err.Error())
+			redirectWithError(w, r, frontend, "invalid_state")
+		default:
+			slog.Error(err.Error())
+			redirectWithError(w, r, frontend, "oauth_failed")
+		}
+		return
+	}
+	if jwt == nil {
+		http.Redirect(w, r, frontend+"/settings?linked="+url.QueryEscape(provider), http.StatusFound)
+		return
+	}
+
+	c.SetTokens(w, jwt)
+	if err := tokenStore.SetTokens(r.Context(), jwt); err != nil {
+		slog.Error(err.Error())
+		redirectWithError(w, r, frontend, "oauth_failed")
+		return
+	}
+	http.Redirect(w, r, frontend+"/", http.StatusFound)
+}
+
+func redirectWithError(w http.ResponseWriter, r *http.Request, frontend, code string) {
+	http.Redirect(w, r, frontend+"/login?error="+code, http.StatusFound)
+}
+
+func resolveOAuthService(w http.ResponseWriter, r *http.Request) (app.AppOAuthService, bool) {
+	var oauthService app.AppOAuthService
+	if err := app.AppContainer.Invoke(func(s app.AppOAuthService) {
+		oauthService = s
+	}); err != nil {
+		writeInternalError(w, r, err, "Failed to resolve OAuthService")
+		return nil, false
+	}
+	return oauthService, true
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"go-auth/internal/app"
+	"go-auth/internal/config"
+	"go-auth/internal/models"
+	"go-auth/internal/router/cookies"
+	"go-auth/internal/router/middlewares"
+	"go-auth/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/dig"
+)
+
+type MockOAuthService struct {
+	mock.Mock
+}
+
+func (m *MockOAuthService) OAuthStart(ctx context.Context, provider, linkUserId string) (string, string, error) {
+	args := m.Called(ctx, provider, linkUserId)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MockOAuthService) OAuthCallback(ctx context.Context, provider, state, code string, client models.ClientInfo) (*models.TokenDto, error) {
+	args := m.Called(ctx, provider, state, code, client)
+	return args.Get(0).(*models.TokenDto), args.Error(1)
+}
+
+type OAuthTestCase struct {
+	name          string
+	handler       http.HandlerFunc
+	query         string
+	authenticated bool
+	setupMocks    func(*MockOAuthService, *MockTokenStorage)
+	// state is the flow the browser's oauth_state cookie is for.
+	state            string
+	expectedStatus   int
+	expectedLocation string
+	expectCookies    bool
+	// expectedStateMaxAge is the Max-Age of the oauth_state cookie in the
+	// response; zero means it isn't set.
+	expectedStateMaxAge int
+}
+
+func TestOAuthHandlers(t *testing.T) {
+	tokens := &models.TokenDto{Access: "access_token", Refresh: "refresh_token"}
+	client := mock.AnythingOfType("models.ClientInfo")
+
+	tests := []OAuthTestCase{
+		{
+			name:    "OAuthLogin redirects to provider",
+			handler: OAuthLogin,
+			setupMocks: func(a *MockOAuthService, ts *MockTokenStorage) {
+				a.On("OAuthStart", mock.Anything, "fake", "").Return("https://idp.example.com/authorize?state=s", "s", nil)
+			},
+			expectedStatus:      http.StatusFound,
+			expectedLocation:    "https://idp.example.com/authorize?state=s",
+			expectedStateMaxAge: 600,
+		},
+		{
+			name:    "OAuthLogin unknown provider",
+			handler: OAuthLogin,
+			setupMocks: func(a *MockOAuthService, ts *MockTokenStorage) {
+				a.On("OAuthStart", mock.Anything, "fake", "").Return("", "", services.ErrUnknownOAuthProvider)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:          "OAuthLink passes the current user",
+			handler:       OAuthLink,
+			authenticated: true,
+			setupMocks: func(a *MockOAuthService, ts *MockTokenStorage) {
+				a.On("OAuthStart", mock.Anything, "fake", "123").Return("https://idp.example.com/authorize?state=s", "s", nil)
+			},
+			expectedStatus:      http.StatusFound,
+			expectedLocation:    "https://idp.example.com/authorize?state=s",
+			expectedStateMaxAge: 600,
+		},
+		{
+			name:           "OAuthLink without session",
+			handler:        OAuthLink,
+			setupMocks:     func(a *MockOAuthService, ts *MockTokenStorage) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:    "OAuthCallback signs in",
+			handler: OAuthCallback,
+			query:   "?code=c&state=s",
+			setupMocks: func(a *MockOAuthService, ts *MockTokenStorage) {
+				a.On("OAuthCallback", mock.Anything, "fake", "s", "c", client).Return(tokens, nil)
+				ts.On("SetTokens", mock.Anything, tokens).Return(nil)
+			},
+			state:               "s",
+			expectedStateMaxAge: -1,
+			expectedStatus:      http.StatusFound,
+			expectedLocation:    "http://localhost:4200/",
+			expectCookies:       true,
+		},
+		{
+			name:    "OAuthCallback requires second factor",
+			handler: OAuthCallback,
+			query:   "?code=c&state=s",
+			setupMocks: func(a *MockOAuthService, ts *MockTokenStorage) {
+				a.On("OAuthCallback", mock.Anything, "fake", "s", "c", client).
+					Return((*models.TokenDto)(nil), &services.MFARequiredError{Challenge: "a.b+c"})
+			},
+			state:               "s",
+			expectedStateMaxAge: -1,
+			expectedStatus:      http.StatusFound,
+			expectedLocation:    "http://localhost:4200/login/mfa?challenge=a.b%2Bc",
+		},
+		{
+			name:    "OAuthCallback linked",
+			handler: OAuthCallback,
+			query:   "?code=c&state=s",
+			setupMocks: func(a *MockOAuthService, ts *MockTokenStorage) {
+				a.On("OAuthCallback", mock.Anything, "fake", "s", "c", client).Return((*models.TokenDto)(nil), nil)
+			},
+			state:               "s",
+			expectedStateMaxAge: -1,
+			expectedStatus:      http.StatusFound,
+			expectedLocation:    "http://localhost:4200/settings?linked=fake",
+		},
+		{
+			name:    "OAuthCallback email conflict",
+			handler: OAuthCallback,
+			query:   "?code=c&state=s",
+			setupMocks: func(a *MockOAuthService, ts *MockTokenStorage) {
+				a.On("OAuthCallback", mock.Anything, "fake", "s", "c", client).
+					Return((*models.TokenDto)(nil), services.ErrOAuthEmailConflict)
+			},
+			state:               "s",
+			expectedStateMaxAge: -1,
+			expectedStatus:      http.StatusFound,
+			expectedLocation:    "http://localhost:4200/login?error=email_conflict",
+		},
+		{
+			name:    "OAuthCallback invalid state",
+			handler: OAuthCallback,
+			query:   "?code=c&state=forged",
+			setupMocks: func(a *MockOAuthService, ts *MockTokenStorage) {
+				a.On("OAuthCallback", mock.Anything, "fake", "forged", "c", client).
+					Return((*models.TokenDto)(nil), services.ErrInvalidOAuthState)
+			},
+			state:               "forged",
+			expectedStateMaxAge: -1,
+			expectedStatus:      http.StatusFound,
+			expectedLocation:    "http://localhost:4200/login?error=invalid_state",
+		},
+		{
+			name:    "OAuthCallback exchange failure",
+			handler: OAuthCallback,
+			query:   "?code=c&state=s",
+			setupMocks: func(a *MockOAuthService, ts *MockTokenStorage) {
+				a.On("OAuthCallback", mock.Anything, "fake", "s", "c", client).
+					Return((*models.TokenDto)(nil), errors.New("invalid id token"))
+			},
+			state:               "s",
+			expectedStateMaxAge: -1,
+			expectedStatus:      http.StatusFound,
+			expectedLocation:    "http://localhost:4200/login?error=oauth_failed",
+		},
+		{
+			name:                "OAuthCallback denied at provider",
+			handler:             OAuthCallback,
+			query:               "?error=access_denied&state=s",
+			setupMocks:          func(a *MockOAuthService, ts *MockTokenStorage) {},
+			state:               "s",
+			expectedStateMaxAge: -1,
+			expectedStatus:      http.StatusFound,
+			expectedLocation:    "http://localhost:4200/login?error=access_denied",
+		},
+		{
+			name:                "OAuthCallback without state cookie",
+			handler:             OAuthCallback,
+			query:               "?code=c&state=s",
+			setupMocks:          func(a *MockOAuthService, ts *MockTokenStorage) {},
+			expectedStateMaxAge: -1,
+			expectedStatus:      http.StatusFound,
+			expectedLocation:    "http://localhost:4200/login?error=invalid_state",
+		},
+		{
+			name:                "OAuthCallback state started in another browser",
+			handler:             OAuthCallback,
+			query:               "?code=c&state=attacker",
+			setupMocks:          func(a *MockOAuthService, ts *MockTokenStorage) {},
+			state:               "victim",
+			expectedStateMaxAge: -1,
+			expectedStatus:      http.StatusFound,
+			expectedLocation:    "http://localhost:4200/login?error=invalid_state",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockOAuth := new(MockOAuthService)
+			mockTokenStorage := new(MockTokenStorage)
+			tt.setupMocks(mockOAuth, mockTokenStorage)
+
+			AppContainer := dig.New()
+			AppContainer.Provide(func() app.AppOAuthService { return mockOAuth })
+			AppContainer.Provide(func() app.AppCookies { return cookies.DefaultPolicy })
+			AppContainer.Provide(func() app.AppTokenStorage { return mockTokenStorage })
+			AppContainer.Provide(func() app.AppConfig { return &config.Config{PublicURL: "http://localhost:4200"} })
+			app.AppContainer = AppContainer
+
+			req := httptest.NewRequest("GET", "/"+tt.query, nil)
+			if tt.state != "" {
+				rec := httptest.NewRecorder()
+				cookies.DefaultPolicy.SetOAuthState(rec, tt.state, time.Minute)
+				req.AddCookie(findCookie(rec.Result().Cookies(), "oauth_state"))
+			}
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("provider", "fake")
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			if tt.authenticated {
+				ctx = middlewares.WithPrincipal(ctx, &models.TokenClaims{
+					Type:             models.TokenTypeAccess,
+					Family:           "session-1",
+					RegisteredClaims: jwt.RegisteredClaims{Subject: "123"},
+				})
+			}
+			w := httptest.NewRecorder()
+
+			tt.handler(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedLocation, w.Header().Get("Location"))
+			cookies := w.Result().Cookies()
+			if tt.expectCookies {
+				assert.Equal(t, "access_token", findCookie(cookies, "access_token").Value)
+				assert.Equal(t, "refresh_token", findCookie(cookies, "refresh_token").Value)
+			} else {
+				assert.Nil(t, findCookie(cookies, "access_token"))
+				assert.Nil(t, findCookie(cookies, "refresh_token"))
+			}
+			state := findCookie(cookies, "oauth_state")
+			if tt.expectedStateMaxAge == 0 {
+				assert.Nil(t, state)
+			} else if assert.NotNil(t, state) {
+				assert.Equal(t, tt.expectedStateMaxAge, state.MaxAge)
+				assert.Equal(t, "/oauth", state.Path)
+				assert.True(t, state.HttpOnly)
+				assert.Equal(t, http.SameSiteLaxMode, state.SameSite)
+				if state.MaxAge > 0 {
+					assert.NotEqual(t, "s", state.Value, "only a hash of the state is stored")
+				}
+			}
+			mockOAuth.AssertExpectations(t)
+			mockTokenStorage.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/router/apierror"
+	"go-auth/internal/services"
+	"go-auth/pkg/metrics"
+	"net/http"
+)
+
+// GetMe godoc
+// @Summary Профиль текущего пользователя
+// @Tags Профиль
+// @Produce json
+// @Success 200 {object} models.Profile
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /me [get]
+func GetMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := currentSession(w, r)
+	if !ok {
+		return
+	}
+	profileService, ok := resolveProfileService(w, r)
+	if !ok {
+		return
+	}
+	profile, err := profileService.GetProfile(r.Context(), claims.Subject)
+	if err != nil {
+		writeProfileError(w, r, err)
+		return
+	}
+	writeJSON(w, profile)
+}
+
+// UpdateMe godoc
+// @Summary Изменение профиля
+// @Description Меняет имя, email или аватар. Новый email нужно подтвердить заново, письмо отправляется на него
+// @Tags Профиль
+// @Accept json
+// @Produce json
+// @Param input body models.ProfileUpdateReq true "Изменения профиля"
+// @Success 200 {object} models.Profile
+// @Failure 400 {object} models.ErrorRes "Неверные данные"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 409 {object} models.ErrorRes "Email уже используется"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /me [patch]
+func UpdateMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := currentSession(w, r)
+	if !ok {
+		return
+	}
+	profileService, ok := resolveProfileService(w, r)
+	if !ok {
+		return
+	}
+	var req models.ProfileUpdateReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	profile, err := profileService.UpdateProfile(r.Context(), claims.Subject, req)
+	if err != nil {
+		writeProfileError(w, r, err)
+		return
+	}
+	writeJSON(w, profile)
+}
+
+// ChangePassword godoc
+// @Summary Смена пароля
+// @Description Меняет пароль и завершает все остальные сессии. Текущий пароль не нужен, если пароль еще не задан (вход через внешнего провайдера)
+// @Tags Профиль
+// @Accept json
+// @Param input body models.PasswordChangeReq true "Текущий и новый пароль"
+// @Success 204 "Пароль изменен"
+// @Failure 400 {object} models.ErrorRes "Неверный текущий пароль, пустой новый или новый пароль не соответствует политике"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 429 {object} models.ErrorRes "Слишком много попыток, см. заголовок Retry-After"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /me/password [post]
+func ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims, ok := currentSession(w, r)
+	if !ok {
+		return
+	}
+	profileService, ok := resolveProfileService(w, r)
+	if !ok {
+		return
+	}
+	var req models.PasswordChangeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	err := profileService.ChangePassword(r.Context(), claims.Subject, claims.Family, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		writeProfileError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteMe godoc
+// @Summary Удаление аккаунта
+// @Description Завершает все сессии и удаляет аккаунт по истечении ACCOUNT_DELETION_GRACE. Вход в течение этого срока отменяет удаление
+// @Tags Профиль
+// @Accept json
+// @Param input body models.AccountDeleteReq true "Пароль, если он задан"
+// @Success 204 "Аккаунт будет удален"
+// @Failure 400 {object} models.ErrorRes "Неверный пароль"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 429 {object} models.ErrorRes "Слишком много попыток, см. заголовок Retry-After"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /me [delete]
+func DeleteMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := currentSession(w, r)
+	if !ok {
+		return
+	}
+	profileService, ok := resolveProfileService(w, r)
+	if !ok {
+		return
+	}
+	var req models.AccountDeleteReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if err := profileService.DeleteAccount(r.Context(), claims.Subject, req.Password); err != nil {
+		writeProfileError(w, r, err)
+		return
+	}
+	c, ok := resolveCookies(w, r)
+	if !ok {
+		return
+	}
+	c.Clear(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeProfileError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, services.ErrTooManyAttempts) {
+		metrics.RecordBlocked("password", "lockout")
+	}
+	if errors.Is(err, services.ErrUserNotFound) {
+		// The account was deleted while the token was still valid.
+		apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	writeServiceError(w, r, err)
+}
+
+func resolveProfileService(w http.ResponseWriter, r *http.Request) (app.AppProfileService, bool) {
+	var profileService app.AppProfileService
+	if err := app.AppContainer.Invoke(func(s app.AppProfileService) {
+		profileService = s
+	}); err != nil {
+		writeInternalError(w, r, err, "Failed to resolve ProfileService")
+		return nil, false
+	}
+	return profileService, true
+}
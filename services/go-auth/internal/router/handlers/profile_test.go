@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/router/cookies"
+	"go-auth/internal/router/middlewares"
+	"go-auth/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/dig"
+)
+
+type MockProfileService struct {
+	mock.Mock
+}
+
+func (m *MockProfileService) GetProfile(ctx context.Context, userId string) (*models.Profile, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).(*models.Profile), args.Error(1)
+}
+
+func (m *MockProfileService) UpdateProfile(ctx context.Context, userId string, req models.ProfileUpdateReq) (*models.Profile, error) {
+	args := m.Called(ctx, userId, req)
+	return args.Get(0).(*models.Profile), args.Error(1)
+}
+
+func (m *MockProfileService) ChangePassword(ctx context.Context, userId, sessionId, currentPassword, newPassword string) error {
+	args := m.Called(ctx, userId, sessionId, currentPassword, newPassword)
+	return args.Error(0)
+}
+
+func (m *MockProfileService) DeleteAccount(ctx context.Context, userId, password string) error {
+	args := m.Called(ctx, userId, password)
+	return args.Error(0)
+}
+
+func (m *MockProfileService) PurgeDeletedAccounts(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type ProfileTestCase struct {
+	name            string
+	handler         http.HandlerFunc
+	body            string
+	setupMocks      func(*MockProfileService)
+	expectedStatus  int
+	expectedBody    string
+	expectedHeaders map[string]string
+	expectCleared   bool
+}
+
+func TestProfileHandlers(t *testing.T) {
+	name := "New Name"
+	profile := &models.Profile{Id: "123", Login: "testuser", DisplayName: "New Name"}
+
+	tests := []ProfileTestCase{
+		{
+			name:    "GetMe",
+			handler: GetMe,
+			setupMocks: func(a *MockProfileService) {
+				a.On("GetProfile", mock.Anything, "123").Return(profile, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"displayName":"New Name"`,
+		},
+		{
+			name:    "UpdateMe",
+			handler: UpdateMe,
+			body:    `{"displayName":"New Name"}`,
+			setupMocks: func(a *MockProfileService) {
+				a.On("UpdateProfile", mock.Anything, "123", models.ProfileUpdateReq{DisplayName: &name}).Return(profile, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"login":"testuser"`,
+		},
+		{
+			name:    "UpdateMe email in use",
+			handler: UpdateMe,
+			body:    `{"email":"taken@example.com"}`,
+			setupMocks: func(a *MockProfileService) {
+				a.On("UpdateProfile", mock.Anything, "123", mock.Anything).Return((*models.Profile)(nil), services.ErrEmailExists)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "Email already in use",
+		},
+		{
+			name:    "UpdateMe invalid avatar",
+			handler: UpdateMe,
+			body:    `{"avatarUrl":"javascript:alert(1)"}`,
+			setupMocks: func(a *MockProfileService) {
+				a.On("UpdateProfile", mock.Anything, "123", mock.Anything).Return((*models.Profile)(nil), services.ErrInvalidAvatarURL)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid avatar url",
+		},
+		{
+			name:    "ChangePassword",
+			handler: ChangePassword,
+			body:    `{"currentPassword":"old","newPassword":"new"}`,
+			setupMocks: func(a *MockProfileService) {
+				a.On("ChangePassword", mock.Anything, "123", "session-1", "old", "new").Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:    "ChangePassword wrong password",
+			handler: ChangePassword,
+			body:    `{"currentPassword":"wrong","newPassword":"new"}`,
+			setupMocks: func(a *MockProfileService) {
+				a.On("ChangePassword", mock.Anything, "123", "session-1", "wrong", "new").Return(services.ErrWrongPassword)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Wrong password",
+		},
+		{
+			name:    "ChangePassword locked",
+			handler: ChangePassword,
+			body:    `{"currentPassword":"wrong","newPassword":"new"}`,
+			setupMocks: func(a *MockProfileService) {
+				a.On("ChangePassword", mock.Anything, "123", "session-1", "wrong", "new").
+					Return(&services.RetryAfterError{RetryAfter: time.Minute})
+			},
+			expectedStatus:  http.StatusTooManyRequests,
+			expectedHeaders: map[string]string{"Retry-After": "60"},
+		},
+		{
+			name:    "DeleteMe",
+			handler: DeleteMe,
+			body:    `{"password":"password123"}`,
+			setupMocks: func(a *MockProfileService) {
+				a.On("DeleteAccount", mock.Anything, "123", "password123").Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+			expectCleared:  true,
+		},
+		{
+			name:    "DeleteMe internal error",
+			handler: DeleteMe,
+			body:    `{"password":"password123"}`,
+			setupMocks: func(a *MockProfileService) {
+				a.On("DeleteAccount", mock.Anything, "123", "password123").Return(errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "DeleteMe invalid body",
+			handler:        DeleteMe,
+			body:           `nope`,
+			setupMocks:     func(a *MockProfileService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProfile := new(MockProfileService)
+			tt.setupMocks(mockProfile)
+
+			AppContainer := dig.New()
+			AppContainer.Provide(func() app.AppProfileService { return mockProfile })
+			AppContainer.Provide(func() app.AppCookies { return cookies.DefaultPolicy })
+			app.AppContainer = AppContainer
+
+			req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(tt.body)))
+			req = req.WithContext(middlewares.WithPrincipal(req.Context(), &models.TokenClaims{
+				Type:             models.TokenTypeAccess,
+				Family:           "session-1",
+				RegisteredClaims: jwt.RegisteredClaims{Subject: "123"},
+			}))
+			w := httptest.NewRecorder()
+
+			tt.handler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+			for name, value := range tt.expectedHeaders {
+				assert.Equal(t, value, w.Header().Get(name), "header %s mismatch", name)
+			}
+			cookies := w.Result().Cookies()
+			if tt.expectCleared {
+				assert.Equal(t, -1, findCookie(cookies, "access_token").MaxAge)
+			} else {
+				assert.Empty(t, cookies)
+			}
+			mockProfile.AssertExpectations(t)
+		})
+	}
+}
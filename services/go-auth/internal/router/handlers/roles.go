@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"go-auth/internal/app"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListRoles godoc
+// @Summary Список ролей
+// @Description Возвращает все роли и их разрешения. Требует разрешение users:read
+// @Tags Роли
+// @Produce json
+// @Success 200 {array} models.Role
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 403 {object} models.ErrorRes "Недостаточно прав"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /roles [get]
+func ListRoles(w http.ResponseWriter, r *http.Request) {
+	roleService, ok := resolveRoleService(w, r)
+	if !ok {
+		return
+	}
+	roles, err := roleService.ListRoles(r.Context())
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list roles")
+		return
+	}
+	writeJSON(w, roles)
+}
+
+// UserRoles godoc
+// @Summary Роли пользователя
+// @Description Возвращает роли пользователя и итоговые разрешения. Требует разрешение users:read
+// @Tags Роли
+// @Produce json
+// @Param id path string true "ID пользователя"
+// @Success 200 {object} models.Grants
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 403 {object} models.ErrorRes "Недостаточно прав"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /users/{id}/roles [get]
+func UserRoles(w http.ResponseWriter, r *http.Request) {
+	roleService, ok := resolveRoleService(w, r)
+	if !ok {
+		return
+	}
+	grants, err := roleService.UserGrants(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to get roles")
+		return
+	}
+	writeJSON(w, grants)
+}
+
+// AssignRole godoc
+// @Summary Назначение роли
+// @Description Назначает роль пользователю. Изменение попадает в токены пользователя при следующем обновлении. Требует разрешение roles:manage
+// @Tags Роли
+// @Param id path string true "ID пользователя"
+// @Param role path string true "Роль"
+// @Success 204 "Роль назначена"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 403 {object} models.ErrorRes "Недостаточно прав"
+// @Failure 404 {object} models.ErrorRes "Пользователь или роль не найдены"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /users/{id}/roles/{role} [put]
+func AssignRole(w http.ResponseWriter, r *http.Request) {
+	roleService, ok := resolveRoleService(w, r)
+	if !ok {
+		return
+	}
+	if err := roleService.AssignRole(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "role")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeRole godoc
+// @Summary Отзыв роли
+// @Description Отзывает роль у пользователя. Требует разрешение roles:manage
+// @Tags Роли
+// @Param id path string true "ID пользователя"
+// @Param role path string true "Роль"
+// @Success 204 "Роль отозвана"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 403 {object} models.ErrorRes "Недостаточно прав"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /users/{id}/roles/{role} [delete]
+func RevokeRole(w http.ResponseWriter, r *http.Request) {
+	roleService, ok := resolveRoleService(w, r)
+	if !ok {
+		return
+	}
+	if err := roleService.RevokeRole(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "role")); err != nil {
+		writeInternalError(w, r, err, "Failed to revoke role")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func resolveRoleService(w http.ResponseWriter, r *http.Request) (app.AppRoleService, bool) {
+	var roleService app.AppRoleService
+	if err := app.AppContainer.Invoke(func(s app.AppRoleService) {
+		roleService = s
+	}); err != nil {
+		writeInternalError(w, r, err, "Failed to resolve RoleService")
+		return nil, false
+	}
+	return roleService, true
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/router/cookies"
+	"go-auth/internal/router/middlewares"
+	"go-auth/internal/services"
+	"go-auth/pkg/authz"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/dig"
+)
+
+type MockRoleService struct {
+	mock.Mock
+}
+
+func (m *MockRoleService) ListRoles(ctx context.Context) ([]models.Role, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.Role), args.Error(1)
+}
+
+func (m *MockRoleService) UserGrants(ctx context.Context, userId string) (*models.Grants, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).(*models.Grants), args.Error(1)
+}
+
+func (m *MockRoleService) AssignRole(ctx context.Context, userId, role string) error {
+	args := m.Called(ctx, userId, role)
+	return args.Error(0)
+}
+
+func (m *MockRoleService) RevokeRole(ctx context.Context, userId, role string) error {
+	args := m.Called(ctx, userId, role)
+	return args.Error(0)
+}
+
+type RolesTestCase struct {
+	name           string
+	method         string
+	path           string
+	permissions    []string
+	setupMocks     func(*MockRoleService)
+	expectedStatus int
+	expectedBody   string
+}
+
+func TestRolesHandlers(t *testing.T) {
+	tests := []RolesTestCase{
+		{
+			name:        "ListRoles",
+			method:      http.MethodGet,
+			path:        "/roles",
+			permissions: []string{authz.PermUsersRead},
+			setupMocks: func(a *MockRoleService) {
+				a.On("ListRoles", mock.Anything).Return([]models.Role{{Name: "player", Permissions: []string{"game:play"}}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"name":"player","description":"","permissions":["game:play"]}]`,
+		},
+		{
+			name:           "ListRoles without permission",
+			method:         http.MethodGet,
+			path:           "/roles",
+			permissions:    []string{authz.PermGamePlay},
+			setupMocks:     func(a *MockRoleService) {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:        "UserRoles",
+			method:      http.MethodGet,
+			path:        "/users/456/roles",
+			permissions: []string{authz.PermUsersRead},
+			setupMocks: func(a *MockRoleService) {
+				a.On("UserGrants", mock.Anything, "456").Return(&models.Grants{Roles: []string{"player"}, Permissions: []string{"game:play"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"roles":["player"],"permissions":["game:play"]}`,
+		},
+		{
+			name:        "AssignRole",
+			method:      http.MethodPut,
+			path:        "/users/456/roles/moderator",
+			permissions: []string{authz.PermRolesManage},
+			setupMocks: func(a *MockRoleService) {
+				a.On("AssignRole", mock.Anything, "456", "moderator").Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:        "AssignRole unknown role",
+			method:      http.MethodPut,
+			path:        "/users/456/roles/superuser",
+			permissions: []string{authz.PermRolesManage},
+			setupMocks: func(a *MockRoleService) {
+				a.On("AssignRole", mock.Anything, "456", "superuser").Return(services.ErrRoleNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Role not found",
+		},
+		{
+			name:           "AssignRole as moderator",
+			method:         http.MethodPut,
+			path:           "/users/456/roles/admin",
+			permissions:    []string{authz.PermUsersRead, authz.PermChatModerate},
+			setupMocks:     func(a *MockRoleService) {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:        "RevokeRole",
+			method:      http.MethodDelete,
+			path:        "/users/456/roles/moderator",
+			permissions: []string{authz.PermRolesManage},
+			setupMocks: func(a *MockRoleService) {
+				a.On("RevokeRole", mock.Anything, "456", "moderator").Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRoles := new(MockRoleService)
+			tt.setupMocks(mockRoles)
+
+			AppContainer := dig.New()
+			AppContainer.Provide(func() app.AppRoleService { return mockRoles })
+			AppContainer.Provide(func() app.AppCookies { return cookies.DefaultPolicy })
+			app.AppContainer = AppContainer
+
+			router := chi.NewRouter()
+			router.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					next.ServeHTTP(w, r.WithContext(middlewares.WithPrincipal(r.Context(), &models.TokenClaims{
+						Type:             models.TokenTypeAccess,
+						Permissions:      tt.permissions,
+						RegisteredClaims: jwt.RegisteredClaims{Subject: "123"},
+					})))
+				})
+			})
+			router.With(authz.RequirePermission(authz.PermUsersRead)).Get("/roles", ListRoles)
+			router.With(authz.RequirePermission(authz.PermUsersRead)).Get("/users/{id}/roles", UserRoles)
+			router.With(authz.RequirePermission(authz.PermRolesManage)).Put("/users/{id}/roles/{role}", AssignRole)
+			router.With(authz.RequirePermission(authz.PermRolesManage)).Delete("/users/{id}/roles/{role}", RevokeRole)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+			mockRoles.AssertExpectations(t)
+		})
+	}
+}
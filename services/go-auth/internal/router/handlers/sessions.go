@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-auth/internal/models"
+	"go-auth/internal/router/apierror"
+	"go-auth/internal/router/middlewares"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListSessions godoc
+// @Summary Список активных сессий
+// @Description Возвращает все активные сессии текущего пользователя, текущая помечена флагом current
+// @Tags Сессии
+// @Produce json
+// @Success 200 {array} models.Session
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /sessions [get]
+func ListSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := currentSession(w, r)
+	if !ok {
+		return
+	}
+	authService, ok := resolveAuthService(w, r)
+	if !ok {
+		return
+	}
+	sessions, err := authService.ListSessions(r.Context(), claims.Subject)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list sessions")
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Id == claims.Family
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sessions); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// RevokeSession godoc
+// @Summary Завершение сессии
+// @Description Отзывает одну сессию текущего пользователя. При отзыве текущей сессии cookies очищаются
+// @Tags Сессии
+// @Param id path string true "ID сессии"
+// @Success 204 "Сессия завершена"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 404 {object} models.ErrorRes "Сессия не найдена"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /sessions/{id} [delete]
+func RevokeSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := currentSession(w, r)
+	if !ok {
+		return
+	}
+	authService, ok := resolveAuthService(w, r)
+	if !ok {
+		return
+	}
+	sessionId := chi.URLParam(r, "id")
+	if err := authService.RevokeSession(r.Context(), claims.Subject, sessionId); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if sessionId == claims.Family {
+		c, ok := resolveCookies(w, r)
+		if !ok {
+			return
+		}
+		c.Clear(w)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeAllSessions godoc
+// @Summary Выход на всех устройствах
+// @Description Отзывает все сессии текущего пользователя, включая текущую, и очищает cookies
+// @Tags Сессии
+// @Success 204 "Все сессии завершены"
+// @Failure 401 {object} models.ErrorRes "Не авторизован"
+// @Failure 500 {object} models.ErrorRes "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /sessions [delete]
+func RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := currentSession(w, r)
+	if !ok {
+		return
+	}
+	authService, ok := resolveAuthService(w, r)
+	if !ok {
+		return
+	}
+	if err := authService.RevokeAllSessions(r.Context(), claims.Subject); err != nil {
+		writeInternalError(w, r, err, "Failed to revoke sessions")
+		return
+	}
+	c, ok := resolveCookies(w, r)
+	if !ok {
+		return
+	}
+	c.Clear(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func currentSession(w http.ResponseWriter, r *http.Request) (*models.TokenClaims, bool) {
+	principal, ok := middlewares.PrincipalFromContext(r.Context())
+	if !ok {
+		apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+		return nil, false
+	}
+	return &principal.Claims, true
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"errors"
+	"go-auth/internal/router/apierror"
 	"log/slog"
 	"net/http"
 
@@ -33,6 +34,6 @@ func EmitError(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slog.Info("emitError end")
-	http.Error(w, "Error handler", http.StatusBadRequest)
+	apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Error handler")
 
 }
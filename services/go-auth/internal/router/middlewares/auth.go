@@ -1,63 +1,72 @@
 package middlewares
 
 import (
+	"errors"
 	"go-auth/internal/app"
 	"go-auth/internal/constants"
+	"go-auth/internal/router/apierror"
+	"go-auth/internal/services"
 	"log/slog"
 	"net/http"
 	"slices"
 	"strconv"
-	"time"
+	"strings"
 )
 
-func WithAuth(next http.Handler, authService app.AppAuthService) http.Handler {
+// WithAuth verifies the access token and puts the principal into the request
+// context. The token is taken from "Authorization: Bearer" or else from the
+// access_token cookie. Only a genuinely expired (but otherwise valid) cookie
+// falls back to the refresh token; anything else is rejected. Bearer
+// clients refresh through /refresh themselves.
+func WithAuth(next http.Handler, authService app.AppAuthService, cookies app.AppCookies) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := BearerToken(r); ok {
+			claims, err := authService.Authenticate(r.Context(), token)
+			if err != nil {
+				slog.Info("WithAuth bearer token rejected: " + err.Error())
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), claims)))
+			return
+		}
+
 		// Проверяем наличие access token
 		accessTokenCookie := getCookie(r, constants.AccessTokenCookie)
 		if accessTokenCookie == nil {
 			slog.Info("WithAuth accessTokenCookie is nil")
-			w.WriteHeader(http.StatusUnauthorized)
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
 			return
 		}
 
-		// Проверяем срок жизни access token
-		if !isTokenValid(accessTokenCookie) {
-			slog.Info("WithAuth accessTokenCookie is not valid")
-			// Если access token истек, проверяем refresh token
+		claims, err := authService.Authenticate(r.Context(), accessTokenCookie.Value)
+		if errors.Is(err, services.ErrTokenExpired) {
+			// Access token истек - обновляем по refresh token
 			refreshTokenCookie := getCookie(r, constants.RefreshTokenCookie)
-			if refreshTokenCookie == nil || !isTokenValid(refreshTokenCookie) {
-				w.WriteHeader(http.StatusUnauthorized)
+			if refreshTokenCookie == nil {
+				apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
 				return
 			}
 
-			jwt, err := authService.RefreshToken(refreshTokenCookie.Value)
-			if err != nil {
-				slog.Info("WithAuth set refressh token")
-				w.WriteHeader(http.StatusUnauthorized)
+			jwt, refreshErr := authService.RefreshToken(refreshTokenCookie.Value)
+			if refreshErr != nil {
+				slog.Info("WithAuth refresh failed: " + refreshErr.Error())
+				apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
 				return
 			}
 
-			http.SetCookie(w, &http.Cookie{
-				Name:     constants.AccessTokenCookie,
-				Value:    jwt.Access,
-				Path:     "/",
-				MaxAge:   3600,
-				HttpOnly: true,
-				// Secure:   true, // Только для HTTPS
-				SameSite: http.SameSiteStrictMode,
-			})
-			http.SetCookie(w, &http.Cookie{
-				Name:     constants.RefreshTokenCookie,
-				Value:    jwt.Refresh,
-				Path:     "/",
-				MaxAge:   30 * 24 * 3600, // 30 дней
-				HttpOnly: true,
-				// Secure:   true,
-				SameSite: http.SameSiteStrictMode,
-			})
+			cookies.SetRefreshed(w, jwt)
+
+			claims, err = authService.Authenticate(r.Context(), jwt.Access)
+		}
+		if err != nil {
+			slog.Info("WithAuth access token rejected: " + err.Error())
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), claims)))
 	})
 }
 func WithNoAuthOnly(next http.Handler) http.Handler {
@@ -80,7 +89,7 @@ func WithNoAuthOnly(next http.Handler) http.Handler {
 			getCookie(r, constants.RefreshTokenCookie) != nil {
 			slog.Info("WithNoAuthOnly must not are tokens")
 
-			w.WriteHeader(http.StatusForbidden)
+			apierror.Write(w, r, http.StatusForbidden, apierror.CodeForbidden, "Already authenticated")
 			return
 		}
 
@@ -88,8 +97,15 @@ func WithNoAuthOnly(next http.Handler) http.Handler {
 	})
 }
 
-// TODO Rate limiting - защита от брутфорса
-// TODO logging not valid tries
+// BearerToken returns the token of an "Authorization: Bearer" header.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
 func getCookie(r *http.Request, name string) *http.Cookie {
 	cookies := r.Cookies()
 	idx := slices.IndexFunc(cookies, func(c *http.Cookie) bool {
@@ -101,11 +117,3 @@ func getCookie(r *http.Request, name string) *http.Cookie {
 	return cookies[idx]
 }
 
-func isTokenValid(cookie *http.Cookie) bool {
-	// Если у куки нет Expires, считаем ее валидной
-	if cookie.Expires.IsZero() {
-		return true
-	}
-	// Проверяем не истек ли срок
-	return cookie.Expires.After(time.Now())
-}
@@ -0,0 +1,205 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/constants"
+	"go-auth/internal/models"
+	"go-auth/internal/router/cookies"
+	"go-auth/internal/services"
+	"go-auth/pkg/authz"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuthService implements only what WithAuth calls.
+type MockAuthService struct {
+	app.AppAuthService
+	mock.Mock
+}
+
+func (m *MockAuthService) Authenticate(ctx context.Context, accessToken string) (*models.TokenClaims, error) {
+	args := m.Called(ctx, accessToken)
+	claims, _ := args.Get(0).(*models.TokenClaims)
+	return claims, args.Error(1)
+}
+
+func (m *MockAuthService) RefreshToken(refreshToken string) (*models.TokenDto, error) {
+	args := m.Called(refreshToken)
+	tokens, _ := args.Get(0).(*models.TokenDto)
+	return tokens, args.Error(1)
+}
+
+func TestWithAuth(t *testing.T) {
+	claims := func(sub string) *models.TokenClaims {
+		return &models.TokenClaims{
+			Type:             models.TokenTypeAccess,
+			Family:           "session-1",
+			RegisteredClaims: jwt.RegisteredClaims{Subject: sub},
+		}
+	}
+	expired := fmt.Errorf("%w: token is expired", services.ErrTokenExpired)
+
+	tests := []struct {
+		name            string
+		authorization   string
+		access          string
+		refresh         string
+		mockSetup       func(*MockAuthService)
+		expectedStatus  int
+		expectedUserId  string
+		expectedCookies []string
+	}{
+		{
+			name:           "no access cookie",
+			mockSetup:      func(m *MockAuthService) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "valid access token",
+			access: "access",
+			mockSetup: func(m *MockAuthService) {
+				m.On("Authenticate", mock.Anything, "access").Return(claims("123"), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedUserId: "123",
+		},
+		{
+			name:    "forged token is not refreshed",
+			access:  "forged",
+			refresh: "refresh",
+			mockSetup: func(m *MockAuthService) {
+				m.On("Authenticate", mock.Anything, "forged").Return(nil, errors.New("invalid access token: token signature is invalid"))
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:    "expired token falls back to refresh",
+			access:  "expired",
+			refresh: "refresh",
+			mockSetup: func(m *MockAuthService) {
+				m.On("Authenticate", mock.Anything, "expired").Return(nil, expired)
+				m.On("RefreshToken", "refresh").Return(&models.TokenDto{Access: "new-access", Refresh: "new-refresh"}, nil)
+				m.On("Authenticate", mock.Anything, "new-access").Return(claims("123"), nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedUserId:  "123",
+			expectedCookies: []string{constants.AccessTokenCookie, constants.RefreshTokenCookie},
+		},
+		{
+			name:   "expired token without refresh cookie",
+			access: "expired",
+			mockSetup: func(m *MockAuthService) {
+				m.On("Authenticate", mock.Anything, "expired").Return(nil, expired)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:    "refresh rejected",
+			access:  "expired",
+			refresh: "reused",
+			mockSetup: func(m *MockAuthService) {
+				m.On("Authenticate", mock.Anything, "expired").Return(nil, expired)
+				m.On("RefreshToken", "reused").Return(nil, services.ErrRefreshTokenReused)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "bearer token",
+			authorization: "Bearer access",
+			mockSetup: func(m *MockAuthService) {
+				m.On("Authenticate", mock.Anything, "access").Return(claims("123"), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedUserId: "123",
+		},
+		{
+			name:          "bearer token wins over cookie",
+			authorization: "Bearer access",
+			access:        "cookie-access",
+			mockSetup: func(m *MockAuthService) {
+				m.On("Authenticate", mock.Anything, "access").Return(claims("123"), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedUserId: "123",
+		},
+		{
+			name:          "expired bearer token is not refreshed",
+			authorization: "Bearer expired",
+			refresh:       "refresh",
+			mockSetup: func(m *MockAuthService) {
+				m.On("Authenticate", mock.Anything, "expired").Return(nil, expired)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "empty bearer token",
+			authorization:  "Bearer ",
+			mockSetup:      func(m *MockAuthService) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := &MockAuthService{}
+			tt.mockSetup(authService)
+
+			var userId string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, ok := PrincipalFromContext(r.Context())
+				assert.True(t, ok)
+				userId = principal.UserId
+				assert.Equal(t, "session-1", principal.SessionId)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/sessions", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.access != "" {
+				req.AddCookie(&http.Cookie{Name: constants.AccessTokenCookie, Value: tt.access})
+			}
+			if tt.refresh != "" {
+				req.AddCookie(&http.Cookie{Name: constants.RefreshTokenCookie, Value: tt.refresh})
+			}
+			rr := httptest.NewRecorder()
+			WithAuth(next, authService, cookies.DefaultPolicy).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedUserId, userId)
+			var names []string
+			for _, c := range rr.Result().Cookies() {
+				names = append(names, c.Name)
+			}
+			assert.ElementsMatch(t, tt.expectedCookies, names)
+			authService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPrincipalFromContext_Empty(t *testing.T) {
+	_, ok := PrincipalFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func TestWithPrincipal_Subject(t *testing.T) {
+	ctx := WithPrincipal(context.Background(), &models.TokenClaims{
+		Type:             models.TokenTypeAccess,
+		Roles:            []string{authz.RoleModerator},
+		Permissions:      []string{authz.PermChatModerate},
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "123"},
+	})
+	subject, ok := authz.SubjectFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "123", subject.Id)
+	assert.True(t, subject.HasRole(authz.RoleModerator))
+	assert.True(t, subject.Can(authz.PermChatModerate))
+}
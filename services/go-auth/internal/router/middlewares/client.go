@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"errors"
+	"go-auth/internal/app"
+	"go-auth/internal/router/apierror"
+	"go-auth/internal/services"
+	"log/slog"
+	"net/http"
+)
+
+// WithClientAuth admits API clients presenting a client-credentials token
+// as "Authorization: Bearer <token>"; their scopes are checked by
+// authz.RequirePermission like a user's permissions. Everything else,
+// including users' bearer tokens, is authenticated by WithAuth.
+func WithClientAuth(authService app.AppAuthService, clientService app.AppClientService, cookies app.AppCookies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		userAuth := WithAuth(next, authService, cookies)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := BearerToken(r)
+			if !ok {
+				userAuth.ServeHTTP(w, r)
+				return
+			}
+			claims, err := clientService.AuthenticateClient(r.Context(), token)
+			if errors.Is(err, services.ErrInvalidTokenType) {
+				userAuth.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				slog.Info("WithClientAuth client token rejected: " + err.Error())
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithClientPrincipal(r.Context(), claims)))
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"context"
+	"go-auth/internal/app"
+	"go-auth/internal/constants"
+	"go-auth/internal/models"
+	"go-auth/internal/router/cookies"
+	"go-auth/internal/services"
+	"go-auth/pkg/authz"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockClientService struct {
+	app.AppClientService
+	mock.Mock
+}
+
+func (m *MockClientService) AuthenticateClient(ctx context.Context, accessToken string) (*models.TokenClaims, error) {
+	args := m.Called(ctx, accessToken)
+	claims, _ := args.Get(0).(*models.TokenClaims)
+	return claims, args.Error(1)
+}
+
+func TestWithClientAuth(t *testing.T) {
+	clientClaims := &models.TokenClaims{
+		Type:             models.TokenTypeClient,
+		Scope:            "game:admin users:read",
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "cl_nest"},
+	}
+	userClaims := &models.TokenClaims{
+		Type:             models.TokenTypeAccess,
+		Family:           "session-1",
+		Permissions:      []string{authz.PermGamePlay},
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "123"},
+	}
+
+	tests := []struct {
+		name              string
+		authorization     string
+		access            string
+		mockSetup         func(*MockAuthService, *MockClientService)
+		expectedStatus    int
+		expectedPrincipal models.Principal
+	}{
+		{
+			name:          "client with scope",
+			authorization: "Bearer client-token",
+			mockSetup: func(a *MockAuthService, c *MockClientService) {
+				c.On("AuthenticateClient", mock.Anything, "client-token").Return(clientClaims, nil)
+			},
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: models.Principal{ClientId: "cl_nest", Claims: *clientClaims},
+		},
+		{
+			name:          "rejected client token",
+			authorization: "Bearer revoked",
+			mockSetup: func(a *MockAuthService, c *MockClientService) {
+				c.On("AuthenticateClient", mock.Anything, "revoked").Return(nil, services.ErrInvalidClient)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "user bearer token",
+			authorization: "Bearer user-token",
+			mockSetup: func(a *MockAuthService, c *MockClientService) {
+				c.On("AuthenticateClient", mock.Anything, "user-token").Return(nil, services.ErrInvalidTokenType)
+				a.On("Authenticate", mock.Anything, "user-token").Return(userClaims, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "user without the permission",
+			access: "access",
+			mockSetup: func(a *MockAuthService, c *MockClientService) {
+				a.On("Authenticate", mock.Anything, "access").Return(userClaims, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "nobody",
+			mockSetup:      func(a *MockAuthService, c *MockClientService) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := &MockAuthService{}
+			clientService := &MockClientService{}
+			tt.mockSetup(authService, clientService)
+
+			var principal models.Principal
+			next := authz.RequirePermission(authz.PermUsersRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, _ = PrincipalFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/users/123/roles", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.access != "" {
+				req.AddCookie(&http.Cookie{Name: constants.AccessTokenCookie, Value: tt.access})
+			}
+			rr := httptest.NewRecorder()
+			WithClientAuth(authService, clientService, cookies.DefaultPolicy)(next).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedPrincipal, principal)
+			authService.AssertExpectations(t)
+			clientService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"go-auth/internal/constants"
+	"go-auth/internal/router/cookies"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSRF(t *testing.T) {
+	trusted := []string{"http://localhost:4200"}
+	tests := []struct {
+		name           string
+		method         string
+		authorization  string
+		access         string
+		csrfCookie     string
+		csrfHeader     string
+		origin         string
+		expectedStatus int
+		expectIssued   bool
+	}{
+		{name: "anonymous post", method: http.MethodPost, expectedStatus: http.StatusOK},
+		{name: "bearer post", method: http.MethodPost, authorization: "Bearer token", access: "access", expectedStatus: http.StatusOK},
+		{name: "cookie get", method: http.MethodGet, access: "access", csrfCookie: "csrf", expectedStatus: http.StatusOK},
+		{name: "cookie get without token issues one", method: http.MethodGet, access: "access", expectedStatus: http.StatusOK, expectIssued: true},
+		{name: "matching token", method: http.MethodPost, access: "access", csrfCookie: "csrf", csrfHeader: "csrf", expectedStatus: http.StatusOK},
+		{name: "matching token from trusted origin", method: http.MethodDelete, access: "access", csrfCookie: "csrf", csrfHeader: "csrf", origin: "http://localhost:4200", expectedStatus: http.StatusOK},
+		{name: "untrusted origin", method: http.MethodPost, access: "access", csrfCookie: "csrf", csrfHeader: "csrf", origin: "https://evil.example", expectedStatus: http.StatusForbidden},
+		{name: "missing header", method: http.MethodPost, access: "access", csrfCookie: "csrf", expectedStatus: http.StatusForbidden},
+		{name: "wrong header", method: http.MethodPost, access: "access", csrfCookie: "csrf", csrfHeader: "other", expectedStatus: http.StatusForbidden},
+		{name: "missing cookie", method: http.MethodPost, access: "access", csrfHeader: "csrf", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CSRF(trusted, cookies.DefaultPolicy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			req := httptest.NewRequest(tt.method, "/logout", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.access != "" {
+				req.AddCookie(&http.Cookie{Name: constants.AccessTokenCookie, Value: tt.access})
+			}
+			if tt.csrfCookie != "" {
+				req.AddCookie(&http.Cookie{Name: constants.CSRFTokenCookie, Value: tt.csrfCookie})
+			}
+			if tt.csrfHeader != "" {
+				req.Header.Set(constants.CSRFTokenHeader, tt.csrfHeader)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			var issued *http.Cookie
+			for _, c := range rr.Result().Cookies() {
+				if c.Name == constants.CSRFTokenCookie {
+					issued = c
+				}
+			}
+			if tt.expectIssued {
+				if assert.NotNil(t, issued) {
+					assert.NotEmpty(t, issued.Value)
+					assert.False(t, issued.HttpOnly, "the frontend has to read the token")
+				}
+			} else {
+				assert.Nil(t, issued)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"context"
+	"go-auth/internal/models"
+	"go-auth/pkg/authz"
+	"strings"
+)
+
+type principalKey struct{}
+
+// WithPrincipal stores the authenticated token claims in ctx, together with
+// the authz.Subject that authz.RequirePermission checks.
+func WithPrincipal(ctx context.Context, claims *models.TokenClaims) context.Context {
+	ctx = authz.WithSubject(ctx, authz.Subject{
+		Id:          claims.Subject,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+	})
+	return context.WithValue(ctx, principalKey{}, models.Principal{
+		UserId:    claims.Subject,
+		SessionId: claims.Family,
+		Claims:    *claims,
+	})
+}
+
+// WithClientPrincipal stores the claims of an API client token in ctx. The
+// client's scopes become the permissions of its authz.Subject.
+func WithClientPrincipal(ctx context.Context, claims *models.TokenClaims) context.Context {
+	ctx = authz.WithSubject(ctx, authz.Subject{
+		Id:          claims.Subject,
+		Permissions: strings.Fields(claims.Scope),
+	})
+	return context.WithValue(ctx, principalKey{}, models.Principal{
+		ClientId: claims.Subject,
+		Claims:   *claims,
+	})
+}
+
+// PrincipalFromContext returns the principal put there by WithAuth.
+func PrincipalFromContext(ctx context.Context) (models.Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(models.Principal)
+	return p, ok
+}
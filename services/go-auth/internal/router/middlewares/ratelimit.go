@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"go-auth/internal/app"
+	"go-auth/internal/router/apierror"
+	"go-auth/pkg/metrics"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit limits requests per client IP within scope. Limiter failures let
+// the request through: an unavailable Redis must not lock everyone out.
+func RateLimit(limiter app.AppRateLimiter, scope string, limit int, window time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := r.RemoteAddr
+			if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+				ip = host
+			}
+			allowed, retryAfter, err := limiter.Allow(r.Context(), scope+":ip:"+ip, limit, window)
+			if err != nil {
+				slog.Error("RateLimit: " + err.Error())
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !allowed {
+				slog.Warn("RateLimit blocked", "scope", scope, "ip", ip)
+				metrics.RecordBlocked(scope, "rate_limit")
+				SetRetryAfter(w, retryAfter)
+				apierror.Write(w, r, http.StatusTooManyRequests, apierror.CodeTooManyRequests, "Too many requests")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SetRetryAfter writes the Retry-After header in whole seconds, rounded up.
+func SetRetryAfter(w http.ResponseWriter, d time.Duration) {
+	secs := int(math.Ceil(d.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+}
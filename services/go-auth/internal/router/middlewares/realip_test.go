@@ -0,0 +1,83 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.1.10/32")}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expectedIP string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "203.0.113.7:51000",
+			expectedIP: "203.0.113.7:51000",
+		},
+		{
+			name:       "spoofed headers from an untrusted peer",
+			remoteAddr: "203.0.113.7:51000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			expectedIP: "203.0.113.7:51000",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.2:443",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.7"},
+			expectedIP: "203.0.113.7",
+		},
+		{
+			name:       "client prepends a forged hop",
+			remoteAddr: "10.0.0.2:443",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7"},
+			expectedIP: "203.0.113.7",
+		},
+		{
+			name:       "chain of trusted proxies",
+			remoteAddr: "10.0.0.2:443",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.7, 192.168.1.10, 10.0.0.3"},
+			expectedIP: "203.0.113.7",
+		},
+		{
+			name:       "real ip from a trusted proxy",
+			remoteAddr: "192.168.1.10:443",
+			headers:    map[string]string{"X-Real-IP": "203.0.113.7"},
+			expectedIP: "203.0.113.7",
+		},
+		{
+			name:       "malformed hop stops the walk",
+			remoteAddr: "10.0.0.2:443",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.7, garbage, 10.0.0.3"},
+			expectedIP: "10.0.0.3",
+		},
+		{
+			name:       "trusted proxy without headers",
+			remoteAddr: "10.0.0.2:443",
+			expectedIP: "10.0.0.2:443",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tt.expectedIP, got)
+		})
+	}
+}
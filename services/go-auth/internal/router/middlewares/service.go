@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"go-auth/internal/router/apierror"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// RequireServiceToken lets through only other services, which present one
+// of secrets as "Authorization: Bearer <secret>". RFC 7662 requires the
+// introspection endpoint to be protected this way.
+func RequireServiceToken(secrets []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !ValidServiceToken(r.Header.Get("Authorization"), secrets) {
+				slog.Warn("RequireServiceToken rejected caller", "ip", r.RemoteAddr)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="introspect"`)
+				apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ValidServiceToken reports whether authorization is "Bearer <secret>" for
+// one of secrets. Every secret is compared, in constant time, so the
+// answer takes as long whichever one matches.
+func ValidServiceToken(authorization string, secrets []string) bool {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return false
+	}
+	presented := sha256.Sum256([]byte(token))
+	match := 0
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		expected := sha256.Sum256([]byte(secret))
+		match |= subtle.ConstantTimeCompare(presented[:], expected[:])
+	}
+	return match == 1
+}
@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireServiceToken(t *testing.T) {
+	secrets := []string{"", "secret-a", "secret-b"}
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "first secret", authorization: "Bearer secret-a", expectedStatus: http.StatusOK},
+		{name: "second secret", authorization: "bearer secret-b", expectedStatus: http.StatusOK},
+		{name: "wrong secret", authorization: "Bearer secret-c", expectedStatus: http.StatusUnauthorized},
+		{name: "empty configured secret does not match", authorization: "Bearer ", expectedStatus: http.StatusUnauthorized},
+		{name: "basic scheme", authorization: "Basic secret-a", expectedStatus: http.StatusUnauthorized},
+		{name: "no header", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireServiceToken(secrets)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			req := httptest.NewRequest(http.MethodPost, "/introspect", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+
+	t.Run("no secrets configured", func(t *testing.T) {
+		assert.False(t, ValidServiceToken("Bearer anything", nil))
+	})
+}
@@ -5,12 +5,14 @@ import (
 	"go-auth/internal/constants"
 	"go-auth/internal/router/handlers"
 	"go-auth/internal/router/middlewares"
+	"go-auth/pkg/authz"
 	"go-auth/pkg/metrics"
 	"net/http"
 
 	_ "go-auth/docs"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -27,40 +29,96 @@ import (
 // @name Authorization
 func New() *chi.Mux {
 	var authService app.AppAuthService
+	var clientService app.AppClientService
+	var limiter app.AppRateLimiter
+	var cfg app.AppConfig
+	var cookies app.AppCookies
 
-	if err := app.AppContainer.Invoke(func(as app.AppAuthService) {
+	if err := app.AppContainer.Invoke(func(as app.AppAuthService, cs app.AppClientService, l app.AppRateLimiter, c app.AppConfig, ck app.AppCookies) {
 		authService = as
+		clientService = cs
+		limiter = l
+		cfg = c
+		cookies = ck
 	}); err != nil {
 		panic("router New, get authService: " + err.Error())
 	}
+	proxies, err := cfg.GetConfig().TrustedProxyPrefixes()
+	if err != nil {
+		panic("router New: " + err.Error())
+	}
+	limits := cfg.GetConfig().RateLimits
+	rateLimit := func(scope string) func(http.Handler) http.Handler {
+		l := limits.Get(scope)
+		return middlewares.RateLimit(limiter, scope, l.Limit, l.Window)
+	}
 	// TODO add handler middleware
 	router := chi.NewRouter()
+	router.Use(middlewares.RealIP(proxies))
+	router.Use(middleware.RequestID)
+	router.Use(middlewares.Trace)
 	router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:4200"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization", constants.AccessTokenCookie, constants.RefreshTokenCookie},
+		AllowedOrigins:   cfg.GetConfig().TrustedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", constants.AccessTokenCookie, constants.RefreshTokenCookie, constants.DeviceNameHeader, constants.TokenModeHeader, constants.CSRFTokenHeader},
 		AllowCredentials: true,
 		// MaxAge:           300,
 	}))
+	router.Use(middlewares.CSRF(cfg.GetConfig().TrustedOrigins, cookies))
 	router.Use(metrics.MetricsMiddleware)
 	router.Get("/handler", handlers.Handler)
 	router.Get("/error", handlers.EmitError)
+	router.Get("/.well-known/jwks.json", handlers.JWKS)
 	
 	router.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL("/swagger/doc.json"), // URL для json документации
 	))
+	router.Post("/email/verify", handlers.VerifyEmail)
+	router.With(rateLimit("password_forgot")).Post("/password/forgot", handlers.ForgotPassword)
+	router.With(rateLimit("password_reset")).Post("/password/reset", handlers.ResetPassword)
+	router.Get("/oauth/{provider}/callback", handlers.OAuthCallback)
+	router.With(middlewares.RequireServiceToken(cfg.GetConfig().IntrospectionTokens)).Post("/introspect", handlers.Introspect)
+	router.With(rateLimit("token")).Post("/token", handlers.Token)
+	router.With(rateLimit("refresh")).Post("/refresh", handlers.Refresh)
+
 	router.Group(func(r chi.Router) {
 		r.Use(middlewares.WithNoAuthOnly)
-		r.Post("/register", handlers.Register)
-		r.Post("/login", handlers.Login)
-		r.Get("/logout", handlers.Logout)
+		r.With(rateLimit("register")).Post("/register", handlers.Register)
+		r.With(rateLimit("login")).Post("/login", handlers.Login)
+		r.With(rateLimit("login_mfa")).Post("/login/mfa", handlers.LoginMFA)
+		r.With(rateLimit("oauth_login")).Get("/oauth/{provider}/login", handlers.OAuthLogin)
 	})
 
 	router.Group(func(r chi.Router) {
 		r.Use(func(h http.Handler) http.Handler {
-			return middlewares.WithAuth(h, authService)
+			return middlewares.WithAuth(h, authService, cookies)
 		})
-		r.Get("/logout", handlers.Logout)
+		r.Post("/logout", handlers.Logout)
+		r.Get("/sessions", handlers.ListSessions)
+		r.Delete("/sessions", handlers.RevokeAllSessions)
+		r.Delete("/sessions/{id}", handlers.RevokeSession)
+		r.Post("/mfa/totp/setup", handlers.SetupTOTP)
+		r.Post("/mfa/totp/confirm", handlers.ConfirmTOTP)
+		r.Post("/mfa/totp/disable", handlers.DisableTOTP)
+		r.Post("/mfa/recovery-codes", handlers.RegenerateRecoveryCodes)
+		r.Get("/oauth/{provider}/link", handlers.OAuthLink)
+		r.Get("/me", handlers.GetMe)
+		r.Patch("/me", handlers.UpdateMe)
+		r.Delete("/me", handlers.DeleteMe)
+		r.Post("/me/password", handlers.ChangePassword)
+		r.With(authz.RequirePermission(authz.PermClientsManage)).Post("/clients", handlers.RegisterClient)
+		r.With(authz.RequirePermission(authz.PermClientsManage)).Get("/clients", handlers.ListClients)
+		r.With(authz.RequirePermission(authz.PermClientsManage)).Delete("/clients/{id}", handlers.RevokeClient)
+	})
+
+	// Routes API clients may call too, within their scopes.
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.WithClientAuth(authService, clientService, cookies))
+		r.With(authz.RequirePermission(authz.PermUsersRead)).Get("/roles", handlers.ListRoles)
+		r.With(authz.RequirePermission(authz.PermUsersRead)).Get("/users/{id}/roles", handlers.UserRoles)
+		r.With(authz.RequirePermission(authz.PermRolesManage)).Put("/users/{id}/roles/{role}", handlers.AssignRole)
+		r.With(authz.RequirePermission(authz.PermRolesManage)).Delete("/users/{id}/roles/{role}", handlers.RevokeRole)
+		r.With(authz.RequirePermission(authz.PermAuditRead)).Get("/audit/events", handlers.ListAuthEvents)
 	})
 
 	return router
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+	"time"
+
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+)
+
+const (
+	emailVerifyTokenTTL   = 24 * time.Hour
+	passwordResetTokenTTL = time.Hour
+)
+
+// normalizeEmail validates an address and returns it in the form it is
+// stored in. An empty address is allowed: email is optional at registration.
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", ErrInvalidEmail
+	}
+	return strings.ToLower(email), nil
+}
+
+// VerifyEmail redeems a token sent by sendVerification.
+func (s AuthService) VerifyEmail(ctx context.Context, token string) error {
+	action, err := s.sessions.consumeActionToken(ctx, models.TokenTypeEmailVerify, token)
+	if err != nil {
+		return err
+	}
+	err = s.userStorage.VerifyEmail(action.UserId, action.Email)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		// The address was changed after the token had been sent.
+		return ErrInvalidActionToken
+	}
+	if err != nil {
+		return fmt.Errorf("AuthService VerifyEmail: %w", err)
+	}
+	return nil
+}
+
+// ForgotPassword mails a reset link. Whether the address is registered is
+// not disclosed: unknown addresses succeed without sending anything.
+func (s AuthService) ForgotPassword(ctx context.Context, email string) error {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return err
+	}
+	if email == "" {
+		return ErrInvalidEmail
+	}
+	user, err := s.userStorage.GetByEmail(email)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		slog.Info("AuthService ForgotPassword unknown email")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("AuthService ForgotPassword: %w", err)
+	}
+	token, err := s.sessions.issueActionToken(ctx, models.TokenTypePasswordReset, user.Id, user.Email, passwordResetTokenTTL)
+	if err != nil {
+		return fmt.Errorf("AuthService ForgotPassword: %w", err)
+	}
+	err = s.mailer.Send(ctx, models.Mail{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: "Someone requested a password reset for " + user.Login + ".\n\n" +
+			"To choose a new password open the link below within an hour:\n" +
+			s.sessions.publicLink("/password/reset", token) + "\n\n" +
+			"If it wasn't you, ignore this message.",
+	})
+	if err != nil {
+		return fmt.Errorf("AuthService ForgotPassword send: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password and signs the user out everywhere.
+func (s AuthService) ResetPassword(ctx context.Context, token, password string) error {
+	if password == "" {
+		return ErrPasswordRequired
+	}
+	// The link works once, so a weak password is rejected before it is
+	// used up. A link that doesn't parse fails right below.
+	var login string
+	if claims, err := s.sessions.parseToken(token); err == nil {
+		if user, err := s.userStorage.GetById(claims.Subject); err == nil {
+			login = user.Login
+		}
+	}
+	if err := checkNewPassword(s.passwords, login, password); err != nil {
+		return err
+	}
+	action, err := s.sessions.consumeActionToken(ctx, models.TokenTypePasswordReset, token)
+	if err != nil {
+		return err
+	}
+	user, err := s.userStorage.GetByEmail(action.Email)
+	if errors.Is(err, storage.ErrUserNotFound) || (err == nil && user.Id != action.UserId) {
+		return ErrInvalidActionToken
+	}
+	if err != nil {
+		return fmt.Errorf("AuthService ResetPassword: %w", err)
+	}
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("AuthService ResetPassword hash password: %w", err)
+	}
+	if err := s.userStorage.Update(models.UserCreateDto{Login: user.Login, PasswordHash: hash}); err != nil {
+		return fmt.Errorf("AuthService ResetPassword: %w", err)
+	}
+	// The link reached the mailbox, which is as good as a confirmation.
+	if !user.EmailVerified {
+		if err := s.userStorage.VerifyEmail(user.Id, user.Email); err != nil {
+			slog.Error("AuthService ResetPassword verify email: " + err.Error())
+		}
+	}
+	// Login locks are per client IP and expire on their own.
+	if err := s.loginGuard.Reset(ctx, passwordGuardKey(user.Id)); err != nil {
+		slog.Error("AuthService ResetPassword lockout reset: " + err.Error())
+	}
+	if err := s.sessions.revokeAllSessions(ctx, user.Id); err != nil {
+		return fmt.Errorf("AuthService ResetPassword: %w", err)
+	}
+	slog.Info("AuthService password reset", "user_id", user.Id)
+	s.audit.Record(ctx, models.AuthEvent{Type: models.AuthEventPasswordReset, UserId: user.Id})
+	return nil
+}
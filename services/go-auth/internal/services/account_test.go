@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var linkTokenRe = regexp.MustCompile(`\?token=(\S+)`)
+
+// mailedToken extracts the token from the link in the last sent mail.
+func mailedToken(t *testing.T, m *testMailer) string {
+	t.Helper()
+	if !assert.NotEmpty(t, m.sent) {
+		return ""
+	}
+	match := linkTokenRe.FindStringSubmatch(m.sent[len(m.sent)-1].Body)
+	if !assert.Len(t, match, 2) {
+		return ""
+	}
+	token, err := url.QueryUnescape(match[1])
+	assert.NoError(t, err)
+	return token
+}
+
+func newAccountTestService(t *testing.T, mus *MockUserStorage, mts *MockTokenStorage, mss *MockSessionStorage, mailer *testMailer) *AuthService {
+	d := newTestDeps(t)
+	d.users, d.tokens, d.sessions, d.mailer = mus, mts, mss, mailer
+	return d.newAuthService()
+}
+
+func TestAuthService_CreateWithEmail(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         string
+		mockSetup     func(*MockUserStorage)
+		mailErr       error
+		expectedError error
+		wantMail      bool
+	}{
+		{
+			name:  "verification mail is sent",
+			email: "User@Example.com",
+			mockSetup: func(mus *MockUserStorage) {
+				mus.On("GetByEmail", "user@example.com").Return((*models.User)(nil), storage.ErrUserNotFound)
+				mus.On("Save", mock.MatchedBy(func(u models.UserCreateDto) bool {
+					return u.Email == "user@example.com"
+				})).Return(models.UserCreateRes{Id: "123", Login: "testuser"}, nil)
+			},
+			wantMail: true,
+		},
+		{
+			name:  "mail failure does not block registration",
+			email: "user@example.com",
+			mockSetup: func(mus *MockUserStorage) {
+				mus.On("GetByEmail", "user@example.com").Return((*models.User)(nil), storage.ErrUserNotFound)
+				mus.On("Save", mock.Anything).Return(models.UserCreateRes{Id: "123", Login: "testuser"}, nil)
+			},
+			mailErr: errors.New("smtp down"),
+		},
+		{
+			name:          "invalid email",
+			email:         "not an email",
+			mockSetup:     func(mus *MockUserStorage) {},
+			expectedError: ErrInvalidEmail,
+		},
+		{
+			name:  "email in use",
+			email: "taken@example.com",
+			mockSetup: func(mus *MockUserStorage) {
+				mus.On("GetByEmail", "taken@example.com").Return(&models.User{Id: "456"}, nil)
+			},
+			expectedError: ErrEmailExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserStorage := &MockUserStorage{}
+			mockUserStorage.On("GetByLogin", "testuser").Return((*models.User)(nil), storage.ErrUserNotFound)
+			tt.mockSetup(mockUserStorage)
+			mockTokenStorage := &MockTokenStorage{}
+			mockTokenStorage.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+			mockSessionStorage := &MockSessionStorage{}
+			mockSessionStorage.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+			mailer := &testMailer{err: tt.mailErr}
+			service := newAccountTestService(t, mockUserStorage, mockTokenStorage, mockSessionStorage, mailer)
+
+			tokens, err := service.Create(models.UserCreateReq{Login: "testuser", Password: "correct horse 42", Email: tt.email}, testClient)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, tokens)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, tokens)
+			if tt.wantMail {
+				assert.Len(t, mailer.sent, 1)
+				assert.Equal(t, "user@example.com", mailer.sent[0].To)
+				assert.Contains(t, mailer.sent[0].Body, "http://localhost:4200/email/verify?token=")
+			} else {
+				assert.Empty(t, mailer.sent)
+			}
+			mockUserStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_VerifyEmail(t *testing.T) {
+	mockUserStorage := &MockUserStorage{}
+	mailer := &testMailer{}
+	service := newAccountTestService(t, mockUserStorage, &MockTokenStorage{}, &MockSessionStorage{}, mailer)
+	ctx := context.Background()
+
+	assert.NoError(t, service.sessions.sendVerification(ctx, models.UserCreateRes{Id: "123", Login: "testuser"}, "user@example.com"))
+	first := mailedToken(t, mailer)
+	assert.NoError(t, service.sessions.sendVerification(ctx, models.UserCreateRes{Id: "123", Login: "testuser"}, "user@example.com"))
+	token := mailedToken(t, mailer)
+
+	// Only the latest link is valid.
+	assert.ErrorIs(t, service.VerifyEmail(ctx, first), ErrInvalidActionToken)
+
+	mockUserStorage.On("VerifyEmail", "123", "user@example.com").Return(nil).Once()
+	assert.NoError(t, service.VerifyEmail(ctx, token))
+	assert.ErrorIs(t, service.VerifyEmail(ctx, token), ErrInvalidActionToken, "token is one-time")
+	mockUserStorage.AssertExpectations(t)
+
+	// The address was changed after the link had been sent.
+	assert.NoError(t, service.sessions.sendVerification(ctx, models.UserCreateRes{Id: "123", Login: "testuser"}, "old@example.com"))
+	mockUserStorage.On("VerifyEmail", "123", "old@example.com").Return(storage.ErrUserNotFound)
+	assert.ErrorIs(t, service.VerifyEmail(ctx, mailedToken(t, mailer)), ErrInvalidActionToken)
+
+	assert.ErrorIs(t, service.VerifyEmail(ctx, "garbage"), ErrInvalidActionToken)
+}
+
+func TestAuthService_ForgotPassword(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         string
+		mockSetup     func(*MockUserStorage)
+		expectedError error
+		wantMail      bool
+	}{
+		{
+			name:  "registered email",
+			email: "user@example.com",
+			mockSetup: func(mus *MockUserStorage) {
+				mus.On("GetByEmail", "user@example.com").Return(&models.User{Id: "123", Login: "testuser", Email: "user@example.com"}, nil)
+			},
+			wantMail: true,
+		},
+		{
+			name:  "unknown email is not disclosed",
+			email: "nobody@example.com",
+			mockSetup: func(mus *MockUserStorage) {
+				mus.On("GetByEmail", "nobody@example.com").Return((*models.User)(nil), storage.ErrUserNotFound)
+			},
+		},
+		{
+			name:          "invalid email",
+			email:         "nope",
+			mockSetup:     func(mus *MockUserStorage) {},
+			expectedError: ErrInvalidEmail,
+		},
+		{
+			name:          "empty email",
+			mockSetup:     func(mus *MockUserStorage) {},
+			expectedError: ErrInvalidEmail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserStorage := &MockUserStorage{}
+			tt.mockSetup(mockUserStorage)
+			mailer := &testMailer{}
+			service := newAccountTestService(t, mockUserStorage, &MockTokenStorage{}, &MockSessionStorage{}, mailer)
+
+			err := service.ForgotPassword(context.Background(), tt.email)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.wantMail {
+				assert.Len(t, mailer.sent, 1)
+				assert.Contains(t, mailer.sent[0].Body, "http://localhost:4200/password/reset?token=")
+			} else {
+				assert.Empty(t, mailer.sent)
+			}
+			mockUserStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_ResetPassword(t *testing.T) {
+	user := &models.User{Id: "123", Login: "testuser", Email: "user@example.com"}
+	mockUserStorage := &MockUserStorage{}
+	mockUserStorage.On("GetByEmail", "user@example.com").Return(user, nil)
+	mockUserStorage.On("GetById", "123").Return(user, nil)
+	mockTokenStorage := &MockTokenStorage{}
+	mockSessionStorage := &MockSessionStorage{}
+	mailer := &testMailer{}
+	service := newAccountTestService(t, mockUserStorage, mockTokenStorage, mockSessionStorage, mailer)
+	ctx := context.Background()
+
+	assert.NoError(t, service.ForgotPassword(ctx, "user@example.com"))
+	token := mailedToken(t, mailer)
+
+	assert.ErrorIs(t, service.ResetPassword(ctx, token, ""), ErrPasswordRequired)
+	assert.ErrorIs(t, service.VerifyEmail(ctx, token), ErrInvalidTokenType, "reset token can't verify email")
+
+	var newHash string
+	mockUserStorage.On("Update", mock.MatchedBy(func(u models.UserCreateDto) bool {
+		newHash = u.PasswordHash
+		return u.Login == "testuser"
+	})).Return(nil).Once()
+	mockUserStorage.On("VerifyEmail", "123", "user@example.com").Return(nil).Once()
+	mockSessionStorage.On("ListByUser", mock.Anything, "123").Return([]models.Session{{Id: "s1", UserId: "123"}, {Id: "s2", UserId: "123"}}, nil)
+	mockTokenStorage.On("RevokeRefreshFamily", mock.Anything, "s1").Return(nil).Once()
+	mockTokenStorage.On("RevokeRefreshFamily", mock.Anything, "s2").Return(nil).Once()
+	mockSessionStorage.On("Delete", mock.Anything, "123", "s1").Return(nil).Once()
+	mockSessionStorage.On("Delete", mock.Anything, "123", "s2").Return(nil).Once()
+
+	assert.NoError(t, service.ResetPassword(ctx, token, "newPassword123"))
+	ok, err := newTestHasher().Verify("newPassword123", newHash)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	mockUserStorage.AssertExpectations(t)
+	mockTokenStorage.AssertExpectations(t)
+	mockSessionStorage.AssertExpectations(t)
+
+	assert.ErrorIs(t, service.ResetPassword(ctx, token, "another Pass 1"), ErrInvalidActionToken, "token is one-time")
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+)
+
+const (
+	defaultAuthEventLimit = 100
+	maxAuthEventLimit     = 1000
+)
+
+// AuditService reads the stored security events.
+type AuditService struct {
+	auditStore app.AppAuditStorage
+}
+
+func NewAuditService(auditStore app.AppAuditStorage) *AuditService {
+	return &AuditService{auditStore: auditStore}
+}
+
+// ListAuthEvents returns stored security events, newest first. Events go
+// through Kafka, so the last few seconds may be missing.
+func (s AuditService) ListAuthEvents(ctx context.Context, filter models.AuthEventFilter) ([]models.AuthEvent, error) {
+	if filter.Limit < 0 || filter.Limit > maxAuthEventLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidEventFilter, maxAuthEventLimit)
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuthEventLimit
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidEventFilter)
+	}
+	events, err := s.auditStore.List(filter)
+	if err != nil {
+		return nil, fmt.Errorf("AuditService ListAuthEvents: %w", err)
+	}
+	return events, nil
+}
+
+// clientEvent is an event of typ caused by a request from client.
+func clientEvent(typ, userId string, client models.ClientInfo) models.AuthEvent {
+	return models.AuthEvent{Type: typ, UserId: userId, IP: client.IP, UserAgent: client.UserAgent}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+	"go-auth/pkg/hasher"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testAudit remembers the recorded events.
+type testAudit struct {
+	mu     sync.Mutex
+	events []models.AuthEvent
+}
+
+func newTestAudit() *testAudit {
+	return &testAudit{}
+}
+
+func (a *testAudit) Record(ctx context.Context, event models.AuthEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, event)
+}
+
+func (a *testAudit) Close() {}
+
+func (a *testAudit) types() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var types []string
+	for _, e := range a.events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func (a *testAudit) last() models.AuthEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.events[len(a.events)-1]
+}
+
+// testAuditStorage returns events and remembers the last filter.
+type testAuditStorage struct {
+	events []models.AuthEvent
+	err    error
+	filter models.AuthEventFilter
+}
+
+func newTestAuditStorage() *testAuditStorage {
+	return &testAuditStorage{}
+}
+
+func (s *testAuditStorage) Save(event models.AuthEvent) error {
+	s.events = append(s.events, event)
+	return s.err
+}
+
+func (s *testAuditStorage) List(filter models.AuthEventFilter) ([]models.AuthEvent, error) {
+	s.filter = filter
+	return s.events, s.err
+}
+
+func (d *testDeps) newAuditService() *AuditService {
+	return NewAuditService(d.auditStore)
+}
+
+// newAuditTestService is an AuthService whose events go to audit.
+func newAuditTestService(t *testing.T, mus *MockUserStorage, audit *testAudit) *AuthService {
+	mts := &MockTokenStorage{}
+	mts.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+	mss := &MockSessionStorage{}
+	mss.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+	d := newTestDeps(t)
+	d.users, d.tokens, d.sessions, d.audit = mus, mts, mss, audit
+	return d.newAuthService()
+}
+
+func TestAuthService_LoginEvents(t *testing.T) {
+	tests := []struct {
+		name           string
+		login          models.UserCreateReq
+		mockSetup      func(*testing.T, *MockUserStorage)
+		expectedType   string
+		expectedUserId string
+		expectedReason string
+	}{
+		{
+			name:  "success",
+			login: models.UserCreateReq{Login: "testuser", Password: "password123"},
+			mockSetup: func(t *testing.T, m *MockUserStorage) {
+				m.On("GetByLogin", "testuser").Return(&models.User{Id: "123", Login: "testuser", PasswordHash: mustHash(t, hasher.NewArgon2id(testArgon2Params), "password123")}, nil)
+			},
+			expectedType:   models.AuthEventLoginSucceeded,
+			expectedUserId: "123",
+		},
+		{
+			name:  "wrong password",
+			login: models.UserCreateReq{Login: "testuser", Password: "wrong"},
+			mockSetup: func(t *testing.T, m *MockUserStorage) {
+				m.On("GetByLogin", "testuser").Return(&models.User{Id: "123", Login: "testuser", PasswordHash: mustHash(t, hasher.NewArgon2id(testArgon2Params), "password123")}, nil)
+			},
+			expectedType:   models.AuthEventLoginFailed,
+			expectedUserId: "123",
+			expectedReason: "wrong_password",
+		},
+		{
+			name:  "unknown login",
+			login: models.UserCreateReq{Login: "nobody", Password: "password123"},
+			mockSetup: func(t *testing.T, m *MockUserStorage) {
+				m.On("GetByLogin", "nobody").Return((*models.User)(nil), storage.ErrUserNotFound)
+			},
+			expectedType:   models.AuthEventLoginFailed,
+			expectedReason: "unknown_login",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mus := &MockUserStorage{}
+			tt.mockSetup(t, mus)
+			audit := newTestAudit()
+			service := newAuditTestService(t, mus, audit)
+
+			_, _ = service.Login(tt.login, testClient)
+
+			assert.Equal(t, []string{tt.expectedType}, audit.types())
+			event := audit.last()
+			assert.Equal(t, tt.expectedUserId, event.UserId)
+			assert.Equal(t, tt.expectedReason, event.Details["reason"])
+			assert.Equal(t, testClient.IP, event.IP)
+			assert.Equal(t, testClient.UserAgent, event.UserAgent)
+			if tt.expectedType == models.AuthEventLoginSucceeded {
+				assert.NotEmpty(t, event.SessionId)
+			} else {
+				assert.Equal(t, tt.login.Login, event.Login)
+			}
+		})
+	}
+}
+
+func TestAuditService_ListAuthEvents(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		filter        models.AuthEventFilter
+		storeErr      error
+		expectedLimit int
+		expectedError error
+	}{
+		{
+			name:          "default limit",
+			filter:        models.AuthEventFilter{UserId: "123"},
+			expectedLimit: defaultAuthEventLimit,
+		},
+		{
+			name:          "time range",
+			filter:        models.AuthEventFilter{From: from, To: from.Add(time.Hour), Limit: 10},
+			expectedLimit: 10,
+		},
+		{
+			name:          "limit too large",
+			filter:        models.AuthEventFilter{Limit: maxAuthEventLimit + 1},
+			expectedError: ErrInvalidEventFilter,
+		},
+		{
+			name:          "from after to",
+			filter:        models.AuthEventFilter{From: from, To: from.Add(-time.Hour)},
+			expectedError: ErrInvalidEventFilter,
+		},
+		{
+			name:          "storage error",
+			filter:        models.AuthEventFilter{},
+			storeErr:      errors.New("db down"),
+			expectedError: errors.New("AuditService ListAuthEvents: db down"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestAuditStorage()
+			store.err = tt.storeErr
+			store.events = []models.AuthEvent{{Id: "1", Type: models.AuthEventLoggedOut}}
+			d := newTestDeps(t)
+			d.auditStore = store
+			service := d.newAuditService()
+
+			events, err := service.ListAuthEvents(context.Background(), tt.filter)
+			if tt.expectedError != nil {
+				if errors.Is(tt.expectedError, ErrInvalidEventFilter) {
+					assert.ErrorIs(t, err, tt.expectedError)
+				} else {
+					assert.EqualError(t, err, tt.expectedError.Error())
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, events, 1)
+			assert.Equal(t, tt.expectedLimit, store.filter.Limit)
+			assert.Equal(t, tt.filter.UserId, store.filter.UserId)
+		})
+	}
+}
@@ -4,34 +4,88 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"go-auth/internal/app"
 	"go-auth/internal/models"
 	"go-auth/internal/storage"
+	"go-auth/pkg/hasher"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+const (
+	// sessionTouchInterval limits how often last-seen is written back.
+	sessionTouchInterval = time.Minute
+)
+
+// AuthService signs users up and in, keeps their sessions and recovers
+// their accounts.
 type AuthService struct {
-	userStorage app.AppUserStorage
-	tokenStore  app.AppTokenStorage
+	sessions     *Sessions
+	userStorage  app.AppUserStorage
+	tokenStore   app.AppTokenStorage
+	sessionStore app.AppSessionStorage
+	hasher       app.AppPasswordHasher
+	keys         app.AppKeyRing
+	loginGuard   app.AppLoginGuard
+	accountGuard app.AppAccountGuard
+	mailer       app.AppMailer
+	audit        app.AppAuditLog
+	passwords    app.AppPasswordPolicy
+	// dummyHash is verified against when a login has no password to check,
+	// so unknown logins take as long as wrong passwords.
+	dummyHash string
 }
 
-func AuthNew(userStorage app.AppUserStorage, tokenStore app.AppTokenStorage) *AuthService {
-	return &AuthService{userStorage: userStorage, tokenStore: tokenStore}
+func AuthNew(
+	sessions *Sessions,
+	userStorage app.AppUserStorage,
+	tokenStore app.AppTokenStorage,
+	sessionStore app.AppSessionStorage,
+	hasher app.AppPasswordHasher,
+	keys app.AppKeyRing,
+	loginGuard app.AppLoginGuard,
+	accountGuard app.AppAccountGuard,
+	mailer app.AppMailer,
+	audit app.AppAuditLog,
+	passwords app.AppPasswordPolicy,
+) *AuthService {
+	return &AuthService{
+		sessions:     sessions,
+		userStorage:  userStorage,
+		tokenStore:   tokenStore,
+		sessionStore: sessionStore,
+		hasher:       hasher,
+		keys:         keys,
+		loginGuard:   loginGuard,
+		accountGuard: accountGuard,
+		mailer:       mailer,
+		audit:        audit,
+		passwords:    passwords,
+		dummyHash:    dummyPasswordHash(hasher),
+	}
+}
+
+func dummyPasswordHash(hasher app.AppPasswordHasher) string {
+	hash, err := hasher.Hash(uuid.NewString())
+	if err != nil {
+		slog.Error("AuthService dummy password hash: " + err.Error())
+	}
+	return hash
 }
 
-func (s AuthService) Create(user models.UserCreateReq) (*models.TokenDto, error) {
-	jwt := models.TokenDto{}
+func (s AuthService) Create(user models.UserCreateReq, client models.ClientInfo) (*models.TokenDto, error) {
 	if user.Login == "" || user.Password == "" {
-		return nil, errors.New("login and password are required")
+		return nil, ErrLoginAndPasswordAreRequired
 	}
-	pswdHash, err := getHash(user.Password)
-	if err != nil {
-		return nil, err
+	fields := append(checkLogin(user.Login), s.passwords.Check(user.Login, user.Password)...)
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
 	}
-	existingUser, err := s.userStorage.Get(models.UserCreateDto{Login: user.Login, PasswordHash: pswdHash})
+	existingUser, err := s.userStorage.GetByLogin(user.Login)
 
 	if err != nil && err != storage.ErrUserNotFound {
 		return nil, fmt.Errorf("AuthService Create check existingUser: %v", err)
@@ -39,165 +93,322 @@ func (s AuthService) Create(user models.UserCreateReq) (*models.TokenDto, error)
 	if existingUser != nil {
 		return nil, ErrUserExists
 	}
-	u, err := s.userStorage.Save(models.UserCreateDto{Login: user.Login, PasswordHash: pswdHash})
+	email, err := normalizeEmail(user.Email)
 	if err != nil {
-		return nil, fmt.Errorf("AuthService Create userStorage Save: %v", err)
+		return nil, err
 	}
-	// TODO refresh and access tokens
-
-	access, err := s.generateJWT(u)
+	if email != "" {
+		_, err := s.userStorage.GetByEmail(email)
+		if err == nil {
+			return nil, ErrEmailExists
+		}
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			return nil, fmt.Errorf("AuthService Create check email: %w", err)
+		}
+	}
+	pswdHash, err := s.hasher.Hash(user.Password)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("AuthService Create hash password: %w", err)
 	}
-	refresh, err := s.generateJWT(u)
+	u, err := s.userStorage.Save(models.UserCreateDto{Login: user.Login, PasswordHash: pswdHash, Email: email})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("AuthService Create userStorage Save: %v", err)
 	}
-	jwt.Access = access
-	jwt.Refresh = refresh
 
-	return &jwt, nil
+	ctx := context.Background()
+	s.audit.Record(ctx, clientEvent(models.AuthEventRegistered, u.Id, client))
+	if email != "" {
+		// Registration doesn't depend on the mail server being up; the
+		// address just stays unverified.
+		if err := s.sessions.sendVerification(ctx, u, email); err != nil {
+			slog.Error("AuthService Create send verification: " + err.Error())
+		}
+	}
+	return s.sessions.startSession(ctx, u, client)
 }
 
-func (s AuthService) Login(user models.UserCreateReq) (*models.TokenDto, error) {
-	jwt := models.TokenDto{}
+func (s AuthService) Login(user models.UserCreateReq, client models.ClientInfo) (*models.TokenDto, error) {
 	if user.Login == "" || user.Password == "" {
-		return nil, errors.New("login and password are required")
+		return nil, ErrLoginAndPasswordAreRequired
 	}
-	pswdHash, err := getHash(user.Password)
-	if err != nil {
-		return nil, err
+	ctx := context.Background()
+	if retryAfter := s.lockedOut(ctx, user.Login, client); retryAfter > 0 {
+		event := clientEvent(models.AuthEventLoginFailed, "", client)
+		event.Login, event.Details = user.Login, map[string]string{"reason": "locked"}
+		s.audit.Record(ctx, event)
+		return nil, &RetryAfterError{RetryAfter: retryAfter}
 	}
-	existingUser, err := s.userStorage.Get(models.UserCreateDto{Login: user.Login, PasswordHash: pswdHash})
-	if err != nil {
+
+	existingUser, err := s.userStorage.GetByLogin(user.Login)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
 		return nil, err
 	}
 	if existingUser == nil {
-		return nil, ErrWrongLoginOrPassword
+		s.hasher.Verify(user.Password, s.dummyHash)
+		return nil, s.loginFailed(ctx, user.Login, "", "unknown_login", client)
 	}
-	// TODO refresh and access tokens
-
-	access, err := s.generateJWT(*existingUser)
-	if err != nil {
-		return nil, err
+	// Users created by social login have no password.
+	if existingUser.PasswordHash == "" {
+		s.hasher.Verify(user.Password, s.dummyHash)
+		return nil, s.loginFailed(ctx, user.Login, existingUser.Id, "no_password", client)
+	}
+	ok, err := s.hasher.Verify(user.Password, existingUser.PasswordHash)
+	if errors.Is(err, hasher.ErrUnknownHashFormat) || errors.Is(err, hasher.ErrInvalidHash) {
+		slog.Error("AuthService Login verify password", "user_id", existingUser.Id, "error", err)
+		return nil, s.loginFailed(ctx, user.Login, existingUser.Id, "invalid_hash", client)
 	}
-	refresh, err := s.generateJWT(*existingUser)
 	if err != nil {
+		return nil, fmt.Errorf("AuthService Login verify password: %w", err)
+	}
+	if !ok {
+		return nil, s.loginFailed(ctx, user.Login, existingUser.Id, "wrong_password", client)
+	}
+	if !s.sessions.restorable(existingUser) {
+		return nil, s.loginFailed(ctx, user.Login, existingUser.Id, "account_deleted", client)
+	}
+	// The failures of the login from all addresses are left to expire, or
+	// the owner logging in would clear the guesses made from elsewhere.
+	if err := s.loginGuard.Reset(ctx, loginGuardKey(user.Login, client)); err != nil {
+		slog.Error("AuthService Login lockout reset: " + err.Error())
+	}
+	s.rehashIfNeeded(existingUser, user.Password)
+
+	if existingUser.MFAEnabled {
+		challenge, err := s.sessions.issueActionToken(ctx, models.TokenTypeMFAChallenge, existingUser.Id, "", mfaChallengeTTL)
+		if err != nil {
+			return nil, fmt.Errorf("AuthService Login mfa challenge: %w", err)
+		}
+		return nil, &MFARequiredError{Challenge: challenge}
+	}
+
+	if _, err := s.sessions.restoreIfDeleted(existingUser); err != nil {
 		return nil, err
 	}
-	jwt.Access = access
-	jwt.Refresh = refresh
+	return s.sessions.startSession(ctx, models.UserCreateRes{Id: existingUser.Id, Login: existingUser.Login}, client)
+}
 
-	return &jwt, nil
+// loginGuardKey scopes the login lockout to the client IP as well, so
+// guessing from one address doesn't lock the owner out everywhere. The
+// per-IP rate limit of /login bounds guesses across logins, and the account
+// lockout, with a higher threshold, guesses at one login across addresses.
+func loginGuardKey(login string, client models.ClientInfo) string {
+	return login + ":ip:" + client.IP
 }
 
-func (s AuthService) RefreshToken(refreshToken string) (*models.TokenDto, error) {
-	// 1. Валидация refresh token
-	var secret string
-	err := app.AppContainer.Invoke(func(cfg app.AppConfig) {
-		secret = cfg.GetConfig().Secret
-	})
+// lockedOut returns how long login stays locked out for the client, zero if
+// it isn't. A lockout that can't be checked doesn't block the login.
+func (s AuthService) lockedOut(ctx context.Context, login string, client models.ClientInfo) time.Duration {
+	retryAfter, err := s.loginGuard.Check(ctx, loginGuardKey(login, client))
 	if err != nil {
-		return nil, fmt.Errorf("error getting config: %w", err)
+		slog.Error("AuthService Login lockout check: " + err.Error())
 	}
-
-	token, err := validateJWT(refreshToken, secret)
+	accountRetryAfter, err := s.accountGuard.Check(ctx, login)
 	if err != nil {
-		return nil, fmt.Errorf("invalid refresh token: %w", err)
+		slog.Error("AuthService Login account lockout check: " + err.Error())
 	}
+	return max(retryAfter, accountRetryAfter)
+}
 
-	// 2. Проверка срока действия
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, errors.New("invalid token claims")
+// loginFailed counts a failed attempt towards the lockouts of login from the
+// client IP and from all addresses, and records it. userId is empty when
+// login belongs to nobody.
+func (s AuthService) loginFailed(ctx context.Context, login, userId, reason string, client models.ClientInfo) error {
+	slog.Info("AuthService failed login", "login", login, "ip", client.IP)
+	event := clientEvent(models.AuthEventLoginFailed, userId, client)
+	event.Login, event.Details = login, map[string]string{"reason": reason}
+	s.audit.Record(ctx, event)
+	locked, err := s.loginGuard.Fail(ctx, loginGuardKey(login, client))
+	if err != nil {
+		slog.Error("AuthService Login lockout fail: " + err.Error())
+	}
+	if locked > 0 {
+		slog.Warn("AuthService login locked", "login", login, "ip", client.IP, "duration", locked.String())
+	}
+	locked, err = s.accountGuard.Fail(ctx, login)
+	if err != nil {
+		slog.Error("AuthService Login account lockout fail: " + err.Error())
+	}
+	if locked > 0 {
+		slog.Warn("AuthService login locked from all addresses", "login", login, "duration", locked.String())
 	}
+	return ErrWrongLoginOrPassword
+}
 
-	exp, ok := claims["exp"].(float64)
-	if !ok {
-		return nil, errors.New("invalid expiration claim")
+func (s AuthService) RefreshToken(refreshToken string) (*models.TokenDto, error) {
+	// 1. Валидация refresh token
+	claims, err := s.sessions.parseToken(refreshToken)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExpired, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid refresh token: %v", ErrInvalidToken, err)
 	}
 
-	if time.Now().Unix() > int64(exp) {
-		return nil, errors.New("refresh token expired")
+	// 2. Проверка типа токена
+	if claims.Type != models.TokenTypeRefresh {
+		return nil, ErrInvalidTokenType
+	}
+	if claims.Family == "" || claims.ID == "" {
+		return nil, fmt.Errorf("%w: invalid refresh token: missing family or jti", ErrInvalidToken)
 	}
 
 	// 3. Получение информации о пользователе
-	sub, ok := claims["sub"].(string)
-	if !ok {
-		return nil, errors.New("invalid subject claim")
+	user, err := s.userStorage.GetById(claims.Subject)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil, ErrSessionRevoked
 	}
-
-	user, err := s.userStorage.GetById(sub)
 	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
+		return nil, fmt.Errorf("AuthService RefreshToken get user: %w", err)
 	}
 
-	// 4. Генерация новых токенов
-	newAccess, err := s.generateJWT(*user)
+	// 4. Генерация новых токенов в том же семействе
+	if user.DeletedAt != nil {
+		return nil, ErrSessionRevoked
+	}
+	newTokens, newJti, err := s.sessions.issueTokens(models.UserCreateRes{Id: user.Id, Login: user.Login}, claims.Family)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
+		return nil, err
 	}
 
-	newRefresh, err := s.generateJWT(*user)
+	// 5. Ротация: старый refresh token становится одноразовым
+	ctx := context.Background()
+	err = s.tokenStore.RotateRefreshFamily(ctx, claims.Family, claims.ID, newJti)
+	if errors.Is(err, storage.ErrRefreshTokenReused) {
+		slog.Warn("AuthService refresh token reuse detected, family revoked",
+			"user_id", claims.Subject,
+			"family", claims.Family,
+		)
+		// The family is already gone; ending the session as well makes the
+		// access tokens issued in it stop authenticating right away.
+		if err := s.sessionStore.Delete(ctx, claims.Subject, claims.Family); err != nil {
+			return nil, fmt.Errorf("AuthService RefreshToken end reused session: %w", err)
+		}
+		s.audit.Record(ctx, models.AuthEvent{Type: models.AuthEventTokenReused, UserId: claims.Subject, SessionId: claims.Family})
+		return nil, ErrRefreshTokenReused
+	}
+	if errors.Is(err, storage.ErrRefreshFamilyNotFound) {
+		return nil, ErrRefreshTokenRevoked
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
 	}
 
-	// 5. Обновление токенов в хранилище
-	newTokens := &models.TokenDto{
-		Access:  newAccess,
-		Refresh: newRefresh,
+	// 6. Обновление токенов в хранилище
+	err = s.tokenStore.SetTokens(ctx, newTokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store tokens: %w", err)
 	}
 
-	// Если есть tokenStore, сохраняем новые токены
-	if s.tokenStore != nil {
-		ctx := context.Background()
-		err = s.tokenStore.SetTokens(ctx, newTokens)
-		if err != nil {
-			return nil, fmt.Errorf("failed to store tokens: %w", err)
-		}
-
-		// Удаляем старый refresh token
-		err = s.tokenStore.RemoveToken(ctx, refreshToken, "")
-		if err != nil {
-			return nil, fmt.Errorf("failed to remove old token: %w", err)
-		}
+	err = s.tokenStore.RemoveToken(ctx, refreshToken, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove old token: %w", err)
+	}
+	if err := s.sessionStore.Touch(ctx, claims.Family, time.Now()); err != nil {
+		slog.Error("AuthService RefreshToken touch session: " + err.Error())
 	}
+	s.audit.Record(ctx, models.AuthEvent{Type: models.AuthEventTokenRefreshed, UserId: claims.Subject, SessionId: claims.Family})
 
 	return newTokens, nil
 }
-func (s AuthService) generateJWT(user models.UserCreateRes) (string, error) {
-	var secret string
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": user.Id,
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
-		"iat": time.Now().Unix(),
-	})
-	err := app.AppContainer.Invoke(func(cfg app.AppConfig) {
-		secret = cfg.GetConfig().Secret
-	})
-	if err != nil {
-		return "", fmt.Errorf("error with invoke config, %w", err)
-	}
-	return token.SignedString([]byte(secret))
+
+// Logout ends the session of the presented refresh token, so neither it nor
+// any earlier rotation of it can be used again, and removes the stored tokens.
+// Without a refresh token the session of the access token is ended.
+func (s AuthService) Logout(ctx context.Context, refresh, access string) error {
+	claims, err := s.sessions.parseToken(refresh)
+	if err != nil || claims.Family == "" {
+		claims, err = s.sessions.parseToken(access)
+	}
+	if err == nil && claims.Family != "" {
+		if err := s.sessions.endSession(ctx, claims.Subject, claims.Family); err != nil {
+			return fmt.Errorf("AuthService Logout revoke family: %w", err)
+		}
+		s.audit.Record(ctx, models.AuthEvent{Type: models.AuthEventLoggedOut, UserId: claims.Subject, SessionId: claims.Family})
+	}
+	if err := s.tokenStore.RemoveToken(ctx, refresh, access); err != nil {
+		return fmt.Errorf("AuthService Logout: %w", err)
+	}
+	return nil
 }
 
-func validateJWT(tokenString string, secret string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+// Authenticate fully validates an access token (signature, kid, exp, typ)
+// and checks that its session has not been revoked. An expired but otherwise
+// genuine token yields ErrTokenExpired so the caller may fall back to refresh.
+func (s AuthService) Authenticate(ctx context.Context, accessToken string) (*models.TokenClaims, error) {
+	claims := &models.TokenClaims{}
+	_, err := jwt.ParseWithClaims(accessToken, claims, s.keys.Keyfunc, jwt.WithExpirationRequired())
+	// jwt verifies the signature before the claims, so an expiry error can
+	// only come from a token we signed.
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExpired, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Type != models.TokenTypeAccess {
+		return nil, ErrInvalidTokenType
+	}
 
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	session, err := s.sessionStore.Get(ctx, claims.Family)
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		return nil, ErrSessionRevoked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("AuthService Authenticate: %w", err)
+	}
+	if session.UserId != claims.Subject {
+		return nil, ErrSessionRevoked
+	}
+	if now := time.Now(); now.Sub(session.LastSeen) > sessionTouchInterval {
+		if err := s.sessionStore.Touch(ctx, session.Id, now); err != nil {
+			slog.Error("AuthService Authenticate touch session: " + err.Error())
 		}
-		return []byte(secret), nil
-	})
+	}
+	return claims, nil
+}
 
+func (s AuthService) ListSessions(ctx context.Context, userId string) ([]models.Session, error) {
+	sessions, err := s.sessionStore.ListByUser(ctx, userId)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("AuthService ListSessions: %w", err)
+	}
+	return sessions, nil
+}
+
+func (s AuthService) RevokeSession(ctx context.Context, userId, sessionId string) error {
+	session, err := s.sessionStore.Get(ctx, sessionId)
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("AuthService RevokeSession: %w", err)
 	}
+	// Someone else's session is reported the same as a missing one.
+	if session.UserId != userId {
+		return ErrSessionNotFound
+	}
+	return s.sessions.endSession(ctx, userId, sessionId)
+}
 
-	return token, nil
+func (s AuthService) RevokeAllSessions(ctx context.Context, userId string) error {
+	return s.sessions.revokeAllSessions(ctx, userId)
 }
 
-func getHash(s string) (string, error) {
-	return "", nil
+// rehashIfNeeded upgrades a stored hash that was produced with weaker
+// parameters than the current policy. The plaintext is only available at
+// login, so this is the one place it can happen; failures don't block login.
+func (s AuthService) rehashIfNeeded(user *models.User, password string) {
+	if !s.hasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+	newHash, err := s.hasher.Hash(password)
+	if err != nil {
+		slog.Error("AuthService rehash password: " + err.Error())
+		return
+	}
+	if err := s.userStorage.Update(models.UserCreateDto{Login: user.Login, PasswordHash: newHash}); err != nil {
+		slog.Error("AuthService update rehashed password: " + err.Error())
+		return
+	}
+	slog.Info("AuthService password hash upgraded", "user_id", user.Id)
 }
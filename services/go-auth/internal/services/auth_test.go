@@ -3,16 +3,24 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"go-auth/internal/app"
 	"go-auth/internal/config"
 	"go-auth/internal/models"
+	"go-auth/internal/storage"
+	"go-auth/pkg/hasher"
+	"go-auth/pkg/keyring"
+	"go-auth/pkg/oidc"
+	"go-auth/pkg/password"
+	"go-auth/pkg/ratelimit"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/dig"
 )
 
@@ -20,14 +28,34 @@ type MockUserStorage struct {
 	mock.Mock
 }
 
-func (m *MockUserStorage) GetById(userId string) (*models.UserCreateRes, error) {
+func (m *MockUserStorage) GetById(userId string) (*models.User, error) {
 	args := m.Called(userId)
-	return args.Get(0).(*models.UserCreateRes), args.Error(1)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserStorage) UpdateProfile(userId string, p models.ProfileUpdateDto) error {
+	args := m.Called(userId, p)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) SoftDelete(userId string) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) Restore(userId string) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) PurgeDeleted(before time.Time) (int64, error) {
+	args := m.Called(before)
+	return args.Get(0).(int64), args.Error(1)
 }
 
 func (m *MockUserStorage) Update(user models.UserCreateDto) error {
 	args := m.Called(user)
-	return args.Error(1)
+	return args.Error(0)
 }
 
 func (m *MockUserStorage) Save(user models.UserCreateDto) (models.UserCreateRes, error) {
@@ -35,9 +63,193 @@ func (m *MockUserStorage) Save(user models.UserCreateDto) (models.UserCreateRes,
 	return args.Get(0).(models.UserCreateRes), args.Error(1)
 }
 
-func (m *MockUserStorage) Get(user models.UserCreateDto) (*models.UserCreateRes, error) {
-	args := m.Called(user)
-	return args.Get(0).(*models.UserCreateRes), args.Error(1)
+func (m *MockUserStorage) GetByLogin(login string) (*models.User, error) {
+	args := m.Called(login)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserStorage) GetByEmail(email string) (*models.User, error) {
+	args := m.Called(email)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserStorage) VerifyEmail(userId, email string) error {
+	args := m.Called(userId, email)
+	return args.Error(0)
+}
+
+type MockSessionStorage struct {
+	mock.Mock
+}
+
+func (m *MockSessionStorage) Save(ctx context.Context, session models.Session, ttl time.Duration) error {
+	args := m.Called(ctx, session, ttl)
+	return args.Error(0)
+}
+
+func (m *MockSessionStorage) Get(ctx context.Context, id string) (*models.Session, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *MockSessionStorage) Touch(ctx context.Context, id string, lastSeen time.Time) error {
+	args := m.Called(ctx, id, lastSeen)
+	return args.Error(0)
+}
+
+func (m *MockSessionStorage) ListByUser(ctx context.Context, userId string) ([]models.Session, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *MockSessionStorage) Delete(ctx context.Context, userId, id string) error {
+	args := m.Called(ctx, userId, id)
+	return args.Error(0)
+}
+
+// testArgon2Params keeps argon2 cheap enough for unit tests.
+var testArgon2Params = hasher.Argon2Params{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var testClient = models.ClientInfo{Device: "test device", UserAgent: "go-test", IP: "127.0.0.1"}
+
+func newTestHasher() *hasher.Policy {
+	return hasher.NewPolicy(hasher.NewArgon2id(testArgon2Params), hasher.NewBcrypt(4))
+}
+
+func newTestKeyRing(t *testing.T) *keyring.KeyRing {
+	t.Helper()
+	kr, err := keyring.NewKeyRing(context.Background(), keyring.NewMemoryStore(), keyring.Options{
+		Algorithm:        keyring.AlgEdDSA,
+		RotationInterval: time.Hour,
+		Retention:        time.Hour,
+	})
+	assert.NoError(t, err)
+	return kr
+}
+
+func newTestLockout() *ratelimit.Lockout {
+	return ratelimit.NewLockout(ratelimit.NewMemoryStore(), config.DefaultLoginLockout)
+}
+
+func newTestAccountLockout() *ratelimit.Lockout {
+	return ratelimit.NewLockout(ratelimit.NewMemoryStore(), config.DefaultAccountLockout)
+}
+
+// testActionTokens is an in-memory AppActionTokenStorage.
+type testActionTokens struct {
+	tokens map[string]models.ActionToken
+}
+
+func newTestActionTokens() *testActionTokens {
+	return &testActionTokens{tokens: map[string]models.ActionToken{}}
+}
+
+func (s *testActionTokens) Save(ctx context.Context, purpose string, token models.ActionToken, ttl time.Duration) error {
+	s.tokens[purpose+":"+token.UserId] = token
+	return nil
+}
+
+func (s *testActionTokens) Consume(ctx context.Context, purpose, userId, jti string) (*models.ActionToken, error) {
+	token, ok := s.tokens[purpose+":"+userId]
+	if !ok || token.Jti != jti {
+		return nil, storage.ErrActionTokenNotFound
+	}
+	delete(s.tokens, purpose+":"+userId)
+	return &token, nil
+}
+
+// testMailer records sent mail.
+type testMailer struct {
+	sent []models.Mail
+	err  error
+}
+
+func (m *testMailer) Send(ctx context.Context, mail models.Mail) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, mail)
+	return nil
+}
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+func testConfig() *config.Config {
+	return &config.Config{PublicURL: "http://localhost:4200", MFAIssuer: "go-auth", AccountDeletionGrace: 30 * 24 * time.Hour,
+		AccessTokenTTL: accessTokenTTL, RefreshTokenTTL: refreshTokenTTL}
+}
+
+func mustHash(t *testing.T, alg hasher.Algorithm, password string) string {
+	t.Helper()
+	h, err := alg.Hash(password)
+	assert.NoError(t, err)
+	return h
+}
+
+// testDeps are what the services are built from in tests. newTestDeps
+// fills them with working fakes and empty mocks; a test replaces the ones
+// it sets expectations on before building the service under test.
+type testDeps struct {
+	users        *MockUserStorage
+	tokens       *MockTokenStorage
+	sessions     *MockSessionStorage
+	hasher       app.AppPasswordHasher
+	keys         *keyring.KeyRing
+	loginGuard   app.AppLoginGuard
+	accountGuard app.AppAccountGuard
+	actionTokens *testActionTokens
+	mailer       *testMailer
+	cfg          *config.Config
+	mfaStore     *testMFAStore
+	oauthStates  *testOAuthStates
+	identities   *testIdentities
+	providers    *oidc.Registry
+	roles        *testRoles
+	clients      *testClients
+	audit        *testAudit
+	auditStore   *testAuditStorage
+	passwords    *password.Checker
+}
+
+func newTestDeps(t *testing.T) *testDeps {
+	return &testDeps{
+		users:        &MockUserStorage{},
+		tokens:       &MockTokenStorage{},
+		sessions:     &MockSessionStorage{},
+		hasher:       newTestHasher(),
+		keys:         newTestKeyRing(t),
+		loginGuard:   newTestLockout(),
+		accountGuard: newTestAccountLockout(),
+		actionTokens: newTestActionTokens(),
+		mailer:       &testMailer{},
+		cfg:          testConfig(),
+		mfaStore:     newTestMFAStore(),
+		oauthStates:  newTestOAuthStates(),
+		identities:   newTestIdentities(),
+		providers:    oidc.NewRegistry(),
+		roles:        newTestRoles(),
+		clients:      newTestClients(),
+		audit:        newTestAudit(),
+		auditStore:   newTestAuditStorage(),
+		passwords:    newTestPasswordPolicy(),
+	}
+}
+
+func (d *testDeps) newSessions() *Sessions {
+	return NewSessions(d.users, d.tokens, d.sessions, d.keys, d.actionTokens, d.roles, d.mailer, d.cfg, d.audit)
+}
+
+func (d *testDeps) newAuthService() *AuthService {
+	return AuthNew(d.newSessions(), d.users, d.tokens, d.sessions, d.hasher, d.keys, d.loginGuard, d.accountGuard, d.mailer, d.audit, d.passwords)
 }
 
 type MockConfig struct {
@@ -63,6 +275,21 @@ func (m *MockTokenStorage) RemoveToken(ctx context.Context, refreshToken string,
 	return args.Error(0)
 }
 
+func (m *MockTokenStorage) SaveRefreshFamily(ctx context.Context, family, jti string, ttl time.Duration) error {
+	args := m.Called(ctx, family, jti, ttl)
+	return args.Error(0)
+}
+
+func (m *MockTokenStorage) RotateRefreshFamily(ctx context.Context, family, oldJti, newJti string) error {
+	args := m.Called(ctx, family, oldJti, newJti)
+	return args.Error(0)
+}
+
+func (m *MockTokenStorage) RevokeRefreshFamily(ctx context.Context, family string) error {
+	args := m.Called(ctx, family)
+	return args.Error(0)
+}
+
 func (m *MockTokenStorage) GetTokens(ctx context.Context, refreshToken string) (*models.TokenDto, error) {
 	args := m.Called(ctx, refreshToken)
 	return args.Get(0).(*models.TokenDto), args.Error(1)
@@ -75,6 +302,8 @@ func TestAuthService_Create(t *testing.T) {
 	testContainer := dig.New()
 	app.AppContainer = testContainer
 
+	keys := newTestKeyRing(t)
+
 	tests := []struct {
 		name          string
 		input         models.UserCreateReq
@@ -86,26 +315,23 @@ func TestAuthService_Create(t *testing.T) {
 			name: "successful user creation",
 			input: models.UserCreateReq{
 				Login:    "testuser",
-				Password: "password123",
+				Password: "correct horse 42",
 			},
 			mockSetup: func(mus *MockUserStorage, mc *MockConfig) {
-				mus.On("Get", mock.Anything).Return(
-					(*models.UserCreateRes)(nil),
-					nil,
+				mus.On("GetByLogin", "testuser").Return(
+					(*models.User)(nil),
+					storage.ErrUserNotFound,
 				)
 
-				mus.On("Save", mock.Anything).Return(
+				mus.On("Save", mock.MatchedBy(func(u models.UserCreateDto) bool {
+					return u.Login == "testuser" && u.PasswordHash != "" && u.PasswordHash != "correct horse 42"
+				})).Return(
 					models.UserCreateRes{
 						Login: "testuser",
 						Id:    "123",
 					}, nil,
 				)
 
-				mc.On("GetConfig").Return(
-					&config.Config{
-						Secret: "test-secret",
-					},
-				)
 			},
 			expectedError: nil,
 			wantToken:     true,
@@ -124,11 +350,11 @@ func TestAuthService_Create(t *testing.T) {
 			name: "user already exists",
 			input: models.UserCreateReq{
 				Login:    "existing",
-				Password: "password",
+				Password: "correct horse 42",
 			},
 			mockSetup: func(mus *MockUserStorage, mc *MockConfig) {
-				mus.On("Get", mock.Anything).Return(
-					&models.UserCreateRes{
+				mus.On("GetByLogin", "existing").Return(
+					&models.User{
 						Login: "existing",
 						Id:    "456",
 					},
@@ -142,11 +368,11 @@ func TestAuthService_Create(t *testing.T) {
 			name: "error getting user",
 			input: models.UserCreateReq{
 				Login:    "erroruser",
-				Password: "password",
+				Password: "correct horse 42",
 			},
 			mockSetup: func(mus *MockUserStorage, mc *MockConfig) {
-				mus.On("Get", mock.Anything).Return(
-					(*models.UserCreateRes)(nil),
+				mus.On("GetByLogin", "erroruser").Return(
+					(*models.User)(nil),
 					errors.New("database error"),
 				)
 			},
@@ -160,8 +386,13 @@ func TestAuthService_Create(t *testing.T) {
 			mockUserStorage := &MockUserStorage{}
 			mockConfig := &MockConfig{}
 			mockTokenStorage := &MockTokenStorage{}
+			mockSessionStorage := &MockSessionStorage{}
+			mockSessionStorage.On("Delete", mock.Anything, "test-user-id", "family-1").Return(nil).Maybe()
+			mockSessionStorage.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil).Maybe()
+			mockSessionStorage.On("Touch", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
 
 			tt.mockSetup(mockUserStorage, mockConfig)
+			mockTokenStorage.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).Return(nil).Maybe()
 
 			// Очищаем контейнер перед каждым тестом
 			testContainer = dig.New()
@@ -172,9 +403,11 @@ func TestAuthService_Create(t *testing.T) {
 			})
 			assert.NoError(t, err)
 
-			service := AuthNew(mockUserStorage, mockTokenStorage)
+			d := newTestDeps(t)
+			d.users, d.tokens, d.sessions, d.keys = mockUserStorage, mockTokenStorage, mockSessionStorage, keys
+			service := d.newAuthService()
 
-			token, err := service.Create(tt.input)
+			token, err := service.Create(tt.input, testClient)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
@@ -204,6 +437,10 @@ func TestAuthService_Login(t *testing.T) {
 	testContainer := dig.New()
 	app.AppContainer = testContainer
 
+	keys := newTestKeyRing(t)
+
+	storedHash := mustHash(t, hasher.NewArgon2id(testArgon2Params), "password123")
+
 	tests := []struct {
 		name          string
 		input         models.UserCreateReq
@@ -218,23 +455,15 @@ func TestAuthService_Login(t *testing.T) {
 				Password: "password123",
 			},
 			mockSetup: func(mus *MockUserStorage, mc *MockConfig) {
-				pswdHash, _ := getHash("password123")
-				mus.On("Get", models.UserCreateDto{
-					Login:        "testuser",
-					PasswordHash: pswdHash,
-				}).Return(
-					&models.UserCreateRes{
-						Login: "testuser",
-						Id:    "123",
+				mus.On("GetByLogin", "testuser").Return(
+					&models.User{
+						Login:        "testuser",
+						Id:           "123",
+						PasswordHash: storedHash,
 					},
 					nil,
 				)
 
-				mc.On("GetConfig").Return(
-					&config.Config{
-						Secret: "test-secret",
-					},
-				)
 			},
 			expectedError: nil,
 			wantToken:     true,
@@ -249,6 +478,25 @@ func TestAuthService_Login(t *testing.T) {
 			expectedError: errors.New("login and password are required"),
 			wantToken:     false,
 		},
+		{
+			name: "wrong password",
+			input: models.UserCreateReq{
+				Login:    "testuser",
+				Password: "password124",
+			},
+			mockSetup: func(mus *MockUserStorage, mc *MockConfig) {
+				mus.On("GetByLogin", "testuser").Return(
+					&models.User{
+						Login:        "testuser",
+						Id:           "123",
+						PasswordHash: storedHash,
+					},
+					nil,
+				)
+			},
+			expectedError: ErrWrongLoginOrPassword,
+			wantToken:     false,
+		},
 		{
 			name: "user not found",
 			input: models.UserCreateReq{
@@ -256,12 +504,27 @@ func TestAuthService_Login(t *testing.T) {
 				Password: "password",
 			},
 			mockSetup: func(mus *MockUserStorage, mc *MockConfig) {
-				pswdHash, _ := getHash("password")
-				mus.On("Get", models.UserCreateDto{
-					Login:        "nonexistent",
-					PasswordHash: pswdHash,
-				}).Return(
-					(*models.UserCreateRes)(nil),
+				mus.On("GetByLogin", "nonexistent").Return(
+					(*models.User)(nil),
+					storage.ErrUserNotFound,
+				)
+			},
+			expectedError: ErrWrongLoginOrPassword,
+			wantToken:     false,
+		},
+		{
+			name: "malformed stored hash",
+			input: models.UserCreateReq{
+				Login:    "testuser",
+				Password: "password123",
+			},
+			mockSetup: func(mus *MockUserStorage, mc *MockConfig) {
+				mus.On("GetByLogin", "testuser").Return(
+					&models.User{
+						Login:        "testuser",
+						Id:           "123",
+						PasswordHash: "plaintext",
+					},
 					nil,
 				)
 			},
@@ -275,12 +538,8 @@ func TestAuthService_Login(t *testing.T) {
 				Password: "password",
 			},
 			mockSetup: func(mus *MockUserStorage, mc *MockConfig) {
-				pswdHash, _ := getHash("password")
-				mus.On("Get", models.UserCreateDto{
-					Login:        "erroruser",
-					PasswordHash: pswdHash,
-				}).Return(
-					(*models.UserCreateRes)(nil),
+				mus.On("GetByLogin", "erroruser").Return(
+					(*models.User)(nil),
 					errors.New("database error"),
 				)
 			},
@@ -294,8 +553,13 @@ func TestAuthService_Login(t *testing.T) {
 			mockUserStorage := &MockUserStorage{}
 			mockConfig := &MockConfig{}
 			mockTokenStorage := &MockTokenStorage{}
+			mockSessionStorage := &MockSessionStorage{}
+			mockSessionStorage.On("Delete", mock.Anything, "test-user-id", "family-1").Return(nil).Maybe()
+			mockSessionStorage.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil).Maybe()
+			mockSessionStorage.On("Touch", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
 
 			tt.mockSetup(mockUserStorage, mockConfig)
+			mockTokenStorage.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).Return(nil).Maybe()
 
 			// Очищаем контейнер перед каждым тестом
 			testContainer = dig.New()
@@ -306,9 +570,11 @@ func TestAuthService_Login(t *testing.T) {
 			})
 			assert.NoError(t, err)
 
-			service := AuthNew(mockUserStorage, mockTokenStorage)
+			d := newTestDeps(t)
+			d.users, d.tokens, d.sessions, d.keys = mockUserStorage, mockTokenStorage, mockSessionStorage, keys
+			service := d.newAuthService()
 
-			token, err := service.Login(tt.input)
+			token, err := service.Login(tt.input, testClient)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
@@ -331,6 +597,139 @@ func TestAuthService_Login(t *testing.T) {
 	}
 }
 
+func TestAuthService_LoginRehash(t *testing.T) {
+	originalContainer := app.AppContainer
+	defer func() { app.AppContainer = originalContainer }()
+
+	weakArgon2 := testArgon2Params
+	weakArgon2.Memory = 512
+
+	tests := []struct {
+		name       string
+		storedHash string
+		wantRehash bool
+	}{
+		{
+			name:       "current parameters are kept",
+			storedHash: mustHash(t, hasher.NewArgon2id(testArgon2Params), "password123"),
+			wantRehash: false,
+		},
+		{
+			name:       "weaker argon2 parameters are upgraded",
+			storedHash: mustHash(t, hasher.NewArgon2id(weakArgon2), "password123"),
+			wantRehash: true,
+		},
+		{
+			name:       "bcrypt hash is migrated to argon2id",
+			storedHash: mustHash(t, hasher.NewBcrypt(4), "password123"),
+			wantRehash: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserStorage := &MockUserStorage{}
+			mockConfig := &MockConfig{}
+			mockTokenStorage := &MockTokenStorage{}
+			mockSessionStorage := &MockSessionStorage{}
+			mockSessionStorage.On("Delete", mock.Anything, "test-user-id", "family-1").Return(nil).Maybe()
+			mockSessionStorage.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil).Maybe()
+			mockSessionStorage.On("Touch", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+			policy := newTestHasher()
+
+			mockUserStorage.On("GetByLogin", "testuser").Return(
+				&models.User{Login: "testuser", Id: "123", PasswordHash: tt.storedHash},
+				nil,
+			)
+			var upgraded string
+			if tt.wantRehash {
+				mockUserStorage.On("Update", mock.AnythingOfType("models.UserCreateDto")).
+					Run(func(args mock.Arguments) {
+						upgraded = args.Get(0).(models.UserCreateDto).PasswordHash
+					}).
+					Return(nil)
+			}
+			mockTokenStorage.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+
+			app.AppContainer = dig.New()
+			err := app.AppContainer.Provide(func() app.AppConfig {
+				return mockConfig
+			})
+			assert.NoError(t, err)
+
+			d := newTestDeps(t)
+			d.users, d.tokens, d.sessions, d.hasher = mockUserStorage, mockTokenStorage, mockSessionStorage, policy
+			service := d.newAuthService()
+
+			token, err := service.Login(models.UserCreateReq{Login: "testuser", Password: "password123"}, testClient)
+			assert.NoError(t, err)
+			assert.NotNil(t, token)
+
+			if tt.wantRehash {
+				assert.NotEqual(t, tt.storedHash, upgraded)
+				assert.False(t, policy.NeedsRehash(upgraded))
+				ok, err := policy.Verify("password123", upgraded)
+				assert.NoError(t, err)
+				assert.True(t, ok)
+			} else {
+				mockUserStorage.AssertNotCalled(t, "Update", mock.Anything)
+			}
+			mockUserStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_TokenTypes(t *testing.T) {
+	originalContainer := app.AppContainer
+	defer func() { app.AppContainer = originalContainer }()
+
+	mockUserStorage := &MockUserStorage{}
+	mockConfig := &MockConfig{}
+	mockTokenStorage := &MockTokenStorage{}
+	mockSessionStorage := &MockSessionStorage{}
+	mockSessionStorage.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil).Maybe()
+	mockSessionStorage.On("Touch", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	mockUserStorage.On("GetByLogin", "testuser").Return(
+		&models.User{Login: "testuser", Id: "123", PasswordHash: mustHash(t, hasher.NewArgon2id(testArgon2Params), "password123")},
+		nil,
+	)
+	var family, familyJti string
+	mockTokenStorage.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).
+		Run(func(args mock.Arguments) {
+			family = args.String(1)
+			familyJti = args.String(2)
+		}).
+		Return(nil)
+
+	app.AppContainer = dig.New()
+	assert.NoError(t, app.AppContainer.Provide(func() app.AppConfig { return mockConfig }))
+
+	keys := newTestKeyRing(t)
+	d := newTestDeps(t)
+	d.users, d.tokens, d.sessions, d.keys = mockUserStorage, mockTokenStorage, mockSessionStorage, keys
+	service := d.newAuthService()
+	tokens, err := service.Login(models.UserCreateReq{Login: "testuser", Password: "password123"}, testClient)
+	assert.NoError(t, err)
+
+	access, err := service.sessions.parseToken(tokens.Access)
+	assert.NoError(t, err)
+	refresh, err := service.sessions.parseToken(tokens.Refresh)
+	assert.NoError(t, err)
+
+	assert.Equal(t, models.TokenTypeAccess, access.Type)
+	assert.Equal(t, models.TokenTypeRefresh, refresh.Type)
+	assert.Equal(t, "123", access.Subject)
+	assert.Equal(t, "123", refresh.Subject)
+	assert.Equal(t, family, access.Family)
+	assert.Equal(t, family, refresh.Family)
+	assert.Equal(t, familyJti, refresh.ID)
+	assert.NotEqual(t, access.ID, refresh.ID)
+	assert.WithinDuration(t, time.Now().Add(accessTokenTTL), access.ExpiresAt.Time, time.Minute)
+	assert.WithinDuration(t, time.Now().Add(refreshTokenTTL), refresh.ExpiresAt.Time, time.Minute)
+	mockTokenStorage.AssertExpectations(t)
+}
+
 func TestAuthService_RefreshToken(t *testing.T) {
 	originalContainer := app.AppContainer
 	defer func() { app.AppContainer = originalContainer }()
@@ -338,24 +737,43 @@ func TestAuthService_RefreshToken(t *testing.T) {
 	testContainer := dig.New()
 	app.AppContainer = testContainer
 
+	keys := newTestKeyRing(t)
+
 	// Создаем тестовый JWT токен для использования в тестах
-	testUser := models.UserCreateRes{
+	testUser := models.User{
 		Id:    "test-user-id",
 		Login: "testuser",
 	}
 
-	createTestToken := func(exp time.Time) string {
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	createTestToken := func(typ string, exp time.Time) string {
+		signedToken, _ := keys.Sign(jwt.MapClaims{
 			"sub": testUser.Id,
 			"exp": exp.Unix(),
 			"iat": time.Now().Unix(),
+			"typ": typ,
+			"fam": "family-1",
+			"jti": "jti-1",
 		})
-		signedToken, _ := token.SignedString([]byte("test-secret"))
 		return signedToken
 	}
 
-	validRefreshToken := createTestToken(time.Now().Add(time.Hour))
-	expiredRefreshToken := createTestToken(time.Now().Add(-time.Hour))
+	validRefreshToken := createTestToken(models.TokenTypeRefresh, time.Now().Add(time.Hour))
+	expiredRefreshToken := createTestToken(models.TokenTypeRefresh, time.Now().Add(-time.Hour))
+	accessToken := createTestToken(models.TokenTypeAccess, time.Now().Add(time.Hour))
+	foreignToken, _ := newTestKeyRing(t).Sign(jwt.MapClaims{
+		"sub": testUser.Id,
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"typ": models.TokenTypeRefresh,
+		"fam": "family-1",
+		"jti": "jti-1",
+	})
+	hmacToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": testUser.Id,
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"typ": models.TokenTypeRefresh,
+		"fam": "family-1",
+		"jti": "jti-1",
+	}).SignedString([]byte("test-secret"))
 
 	tests := []struct {
 		name           string
@@ -368,19 +786,48 @@ func TestAuthService_RefreshToken(t *testing.T) {
 			name:         "successful token refresh",
 			refreshToken: validRefreshToken,
 			mockSetup: func(mus *MockUserStorage, mc *MockConfig, mts *MockTokenStorage) {
-				mc.On("GetConfig").Return(&config.Config{Secret: "test-secret"})
 				mus.On("GetById", "test-user-id").Return(&testUser, nil)
+				mts.On("RotateRefreshFamily", mock.Anything, "family-1", "jti-1", mock.Anything).Return(nil)
 				mts.On("SetTokens", mock.Anything, mock.Anything).Return(nil)
 				mts.On("RemoveToken", mock.Anything, validRefreshToken, "").Return(nil)
 			},
 			expectedError:  nil,
 			expectedTokens: true,
 		},
+		{
+			name:         "access token used as refresh token",
+			refreshToken: accessToken,
+			mockSetup: func(mus *MockUserStorage, mc *MockConfig, mts *MockTokenStorage) {
+			},
+			expectedError:  ErrInvalidTokenType,
+			expectedTokens: false,
+		},
+		{
+			name:         "reused refresh token",
+			refreshToken: validRefreshToken,
+			mockSetup: func(mus *MockUserStorage, mc *MockConfig, mts *MockTokenStorage) {
+				mus.On("GetById", "test-user-id").Return(&testUser, nil)
+				mts.On("RotateRefreshFamily", mock.Anything, "family-1", "jti-1", mock.Anything).
+					Return(storage.ErrRefreshTokenReused)
+			},
+			expectedError:  ErrRefreshTokenReused,
+			expectedTokens: false,
+		},
+		{
+			name:         "revoked refresh family",
+			refreshToken: validRefreshToken,
+			mockSetup: func(mus *MockUserStorage, mc *MockConfig, mts *MockTokenStorage) {
+				mus.On("GetById", "test-user-id").Return(&testUser, nil)
+				mts.On("RotateRefreshFamily", mock.Anything, "family-1", "jti-1", mock.Anything).
+					Return(storage.ErrRefreshFamilyNotFound)
+			},
+			expectedError:  ErrRefreshTokenRevoked,
+			expectedTokens: false,
+		},
 		{
 			name:           "empty refresh token",
 			refreshToken:   "",
 			mockSetup:      func(mus *MockUserStorage, mc *MockConfig, mts *MockTokenStorage) {
-				mc.On("GetConfig").Return(&config.Config{Secret: "test-secret"})
 
 			},
 			expectedError:  errors.New("invalid refresh token: token is malformed: token contains an invalid number of segments"),
@@ -390,53 +837,60 @@ func TestAuthService_RefreshToken(t *testing.T) {
 			name:         "expired refresh token",
 			refreshToken: expiredRefreshToken,
 			mockSetup: func(mus *MockUserStorage, mc *MockConfig, mts *MockTokenStorage) {
-				mc.On("GetConfig").Return(&config.Config{Secret: "test-secret"})
 			},
-			expectedError:  errors.New("invalid refresh token: token has invalid claims: token is expired"),
+			expectedError:  ErrTokenExpired,
 			expectedTokens: false,
 		},
 		{
 			name:         "invalid token signature",
 			refreshToken: "invalid.token.signature",
 			mockSetup: func(mus *MockUserStorage, mc *MockConfig, mts *MockTokenStorage) {
-				mc.On("GetConfig").Return(&config.Config{Secret: "test-secret"})
 			},
-			expectedError:  errors.New("invalid refresh token: token is malformed: could not JSON decode header: invalid character '\\u008a' looking for beginning of value"),
+			expectedError:  errors.New("invalid refresh token: token is malformed: could not JSON decode header"),
+			expectedTokens: false,
+		},
+		{
+			name:           "token signed by unknown key",
+			refreshToken:   foreignToken,
+			mockSetup:      func(mus *MockUserStorage, mc *MockConfig, mts *MockTokenStorage) {},
+			expectedError:  keyring.ErrUnknownKey,
+			expectedTokens: false,
+		},
+		{
+			name:           "symmetric token rejected",
+			refreshToken:   hmacToken,
+			mockSetup:      func(mus *MockUserStorage, mc *MockConfig, mts *MockTokenStorage) {},
+			expectedError:  keyring.ErrUnknownKey,
 			expectedTokens: false,
 		},
 		{
 			name:         "user not found",
 			refreshToken: validRefreshToken,
 			mockSetup: func(mus *MockUserStorage, mc *MockConfig, mts *MockTokenStorage) {
-				mc.On("GetConfig").Return(&config.Config{Secret: "test-secret"})
-				mus.On("GetById", "test-user-id").Return((*models.UserCreateRes)(nil), errors.New("user not found"))
+				mus.On("GetById", "test-user-id").Return((*models.User)(nil), storage.ErrUserNotFound)
 			},
-			expectedError:  errors.New("user not found: user not found"),
+			expectedError:  ErrSessionRevoked,
 			expectedTokens: false,
 		},
 		{
-			name:         "failed to store new tokens",
+			name:         "error getting user",
 			refreshToken: validRefreshToken,
 			mockSetup: func(mus *MockUserStorage, mc *MockConfig, mts *MockTokenStorage) {
-				mc.On("GetConfig").Return(&config.Config{Secret: "test-secret"})
-				mus.On("GetById", "test-user-id").Return(&testUser, nil)
-				mts.On("SetTokens", mock.Anything, mock.Anything).Return(errors.New("storage error"))
+				mus.On("GetById", "test-user-id").Return((*models.User)(nil), errors.New("database error"))
 			},
-			expectedError:  errors.New("failed to store tokens: storage error"),
+			expectedError:  errors.New("AuthService RefreshToken get user: database error"),
 			expectedTokens: false,
 		},
 		{
-			name:         "success without token store",
+			name:         "failed to store new tokens",
 			refreshToken: validRefreshToken,
 			mockSetup: func(mus *MockUserStorage, mc *MockConfig, mts *MockTokenStorage) {
-				mc.On("GetConfig").Return(&config.Config{Secret: "test-secret"})
 				mus.On("GetById", "test-user-id").Return(&testUser, nil)
-				mts.On("SetTokens", mock.Anything, mock.Anything).Return(nil)
-				mts.On("RemoveToken", mock.Anything, validRefreshToken, "").Return(nil)
-
+				mts.On("RotateRefreshFamily", mock.Anything, "family-1", "jti-1", mock.Anything).Return(nil)
+				mts.On("SetTokens", mock.Anything, mock.Anything).Return(errors.New("storage error"))
 			},
-			expectedError:  nil,
-			expectedTokens: true,
+			expectedError:  errors.New("failed to store tokens: storage error"),
+			expectedTokens: false,
 		},
 	}
 
@@ -445,6 +899,10 @@ func TestAuthService_RefreshToken(t *testing.T) {
 			mockUserStorage := &MockUserStorage{}
 			mockConfig := &MockConfig{}
 			mockTokenStorage := &MockTokenStorage{}
+			mockSessionStorage := &MockSessionStorage{}
+			mockSessionStorage.On("Delete", mock.Anything, "test-user-id", "family-1").Return(nil).Maybe()
+			mockSessionStorage.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil).Maybe()
+			mockSessionStorage.On("Touch", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
 
 			tt.mockSetup(mockUserStorage, mockConfig, mockTokenStorage)
 
@@ -457,8 +915,9 @@ func TestAuthService_RefreshToken(t *testing.T) {
 			})
 			assert.NoError(t, err)
 
-			service := AuthNew(mockUserStorage, mockTokenStorage)
-			service.tokenStore = mockTokenStorage
+			d := newTestDeps(t)
+			d.users, d.tokens, d.sessions, d.keys = mockUserStorage, mockTokenStorage, mockSessionStorage, keys
+			service := d.newAuthService()
 
 			tokens, err := service.RefreshToken(tt.refreshToken)
 
@@ -483,3 +942,464 @@ func TestAuthService_RefreshToken(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthService_RefreshTokenReuseEndsSession(t *testing.T) {
+	mockUserStorage := &MockUserStorage{}
+	mockTokenStorage := &MockTokenStorage{}
+	mockSessionStorage := &MockSessionStorage{}
+	d := newTestDeps(t)
+	d.users, d.tokens, d.sessions = mockUserStorage, mockTokenStorage, mockSessionStorage
+	service := d.newAuthService()
+
+	ctx := context.Background()
+	mockTokenStorage.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+	mockSessionStorage.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+	tokens, err := service.sessions.startSession(ctx, models.UserCreateRes{Id: "123", Login: "testuser"}, testClient)
+	require.NoError(t, err)
+	claims, err := service.sessions.parseToken(tokens.Refresh)
+	require.NoError(t, err)
+
+	mockSessionStorage.On("Get", mock.Anything, claims.Family).
+		Return(&models.Session{Id: claims.Family, UserId: "123", LastSeen: time.Now()}, nil).Once()
+	_, err = service.Authenticate(ctx, tokens.Access)
+	require.NoError(t, err)
+
+	mockUserStorage.On("GetById", "123").Return(&models.User{Id: "123", Login: "testuser"}, nil)
+	mockTokenStorage.On("RotateRefreshFamily", mock.Anything, claims.Family, claims.ID, mock.Anything).
+		Return(storage.ErrRefreshTokenReused)
+	mockSessionStorage.On("Delete", mock.Anything, "123", claims.Family).Return(nil)
+	_, err = service.RefreshToken(tokens.Refresh)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	mockSessionStorage.On("Get", mock.Anything, claims.Family).
+		Return((*models.Session)(nil), storage.ErrSessionNotFound)
+	_, err = service.Authenticate(ctx, tokens.Access)
+	assert.ErrorIs(t, err, ErrSessionRevoked, "the access token dies with the session")
+	mockSessionStorage.AssertExpectations(t)
+}
+
+func TestAuthService_Logout(t *testing.T) {
+	originalContainer := app.AppContainer
+	defer func() { app.AppContainer = originalContainer }()
+
+	keys := newTestKeyRing(t)
+
+	validRefreshToken, _ := keys.Sign(jwt.MapClaims{
+		"sub": "test-user-id",
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"typ": models.TokenTypeRefresh,
+		"fam": "family-1",
+		"jti": "jti-1",
+	})
+	validAccessToken, _ := keys.Sign(jwt.MapClaims{
+		"sub": "test-user-id",
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"typ": models.TokenTypeAccess,
+		"fam": "family-1",
+		"jti": "jti-2",
+	})
+
+	tests := []struct {
+		name          string
+		refreshToken  string
+		accessToken   string
+		mockSetup     func(*MockTokenStorage)
+		expectedError error
+	}{
+		{
+			name:         "revokes refresh family",
+			refreshToken: validRefreshToken,
+			mockSetup: func(mts *MockTokenStorage) {
+				mts.On("RevokeRefreshFamily", mock.Anything, "family-1").Return(nil)
+				mts.On("RemoveToken", mock.Anything, validRefreshToken, "access").Return(nil)
+			},
+		},
+		{
+			name:         "invalid refresh token still removes stored tokens",
+			refreshToken: "garbage",
+			mockSetup: func(mts *MockTokenStorage) {
+				mts.On("RemoveToken", mock.Anything, "garbage", "access").Return(nil)
+			},
+		},
+		{
+			name:        "access token alone ends its session",
+			accessToken: validAccessToken,
+			mockSetup: func(mts *MockTokenStorage) {
+				mts.On("RevokeRefreshFamily", mock.Anything, "family-1").Return(nil)
+				mts.On("RemoveToken", mock.Anything, "", validAccessToken).Return(nil)
+			},
+		},
+		{
+			name:         "revoke error",
+			refreshToken: validRefreshToken,
+			mockSetup: func(mts *MockTokenStorage) {
+				mts.On("RevokeRefreshFamily", mock.Anything, "family-1").Return(errors.New("redis down"))
+			},
+			expectedError: errors.New("AuthService Logout revoke family: redis down"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConfig := &MockConfig{}
+			mockTokenStorage := &MockTokenStorage{}
+			mockSessionStorage := &MockSessionStorage{}
+			mockSessionStorage.On("Delete", mock.Anything, "test-user-id", "family-1").Return(nil).Maybe()
+			mockSessionStorage.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil).Maybe()
+			mockSessionStorage.On("Touch", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+			tt.mockSetup(mockTokenStorage)
+
+			app.AppContainer = dig.New()
+			assert.NoError(t, app.AppContainer.Provide(func() app.AppConfig { return mockConfig }))
+
+			d := newTestDeps(t)
+			d.tokens, d.sessions, d.keys = mockTokenStorage, mockSessionStorage, keys
+			service := d.newAuthService()
+			access := tt.accessToken
+			if access == "" {
+				access = "access"
+			}
+			err := service.Logout(context.Background(), tt.refreshToken, access)
+
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			mockTokenStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_KeyRotation(t *testing.T) {
+	keys := newTestKeyRing(t)
+	mockTokenStorage := &MockTokenStorage{}
+	mockSessionStorage := &MockSessionStorage{}
+	mockSessionStorage.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil).Maybe()
+	mockSessionStorage.On("Touch", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockTokenStorage.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+	d := newTestDeps(t)
+	d.tokens, d.sessions, d.keys = mockTokenStorage, mockSessionStorage, keys
+	service := d.newAuthService()
+
+	before, err := service.sessions.startSession(context.Background(), models.UserCreateRes{Id: "123", Login: "testuser"}, testClient)
+	assert.NoError(t, err)
+
+	assert.NoError(t, keys.Rotate(context.Background()))
+
+	after, err := service.sessions.startSession(context.Background(), models.UserCreateRes{Id: "123", Login: "testuser"}, testClient)
+	assert.NoError(t, err)
+
+	oldToken, _, err := jwt.NewParser().ParseUnverified(before.Access, &models.TokenClaims{})
+	assert.NoError(t, err)
+	newToken, _, err := jwt.NewParser().ParseUnverified(after.Access, &models.TokenClaims{})
+	assert.NoError(t, err)
+	// the new key is published before it signs
+	assert.Equal(t, oldToken.Header["kid"], newToken.Header["kid"])
+	assert.Equal(t, "EdDSA", newToken.Header["alg"])
+	_, err = service.sessions.parseToken(after.Access)
+	assert.NoError(t, err)
+
+	jwks := keys.JWKS()
+	assert.Len(t, jwks.Keys, 2)
+	assert.NotEqual(t, newToken.Header["kid"], jwks.Keys[0].Kid)
+	assert.Equal(t, newToken.Header["kid"], jwks.Keys[1].Kid)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "Ed25519", jwks.Keys[0].Crv)
+	assert.NotEmpty(t, jwks.Keys[0].X)
+}
+
+func TestAuthService_SessionCreatedOnLogin(t *testing.T) {
+	mockUserStorage := &MockUserStorage{}
+	mockTokenStorage := &MockTokenStorage{}
+	mockSessionStorage := &MockSessionStorage{}
+
+	mockUserStorage.On("GetByLogin", "testuser").Return(
+		&models.User{Login: "testuser", Id: "123", PasswordHash: mustHash(t, hasher.NewArgon2id(testArgon2Params), "password123")},
+		nil,
+	)
+	var family string
+	mockTokenStorage.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).
+		Run(func(args mock.Arguments) { family = args.String(1) }).
+		Return(nil)
+	mockSessionStorage.On("Save", mock.Anything, mock.MatchedBy(func(s models.Session) bool {
+		return s.Id == family &&
+			s.UserId == "123" &&
+			s.Device == testClient.Device &&
+			s.UserAgent == testClient.UserAgent &&
+			s.IP == testClient.IP &&
+			!s.CreatedAt.IsZero() &&
+			s.LastSeen.Equal(s.CreatedAt)
+	}), refreshTokenTTL).Return(nil)
+
+	d := newTestDeps(t)
+	d.users, d.tokens, d.sessions = mockUserStorage, mockTokenStorage, mockSessionStorage
+	service := d.newAuthService()
+	_, err := service.Login(models.UserCreateReq{Login: "testuser", Password: "password123"}, testClient)
+	assert.NoError(t, err)
+	mockSessionStorage.AssertExpectations(t)
+}
+
+func TestAuthService_Authenticate(t *testing.T) {
+	keys := newTestKeyRing(t)
+	foreignKeys := newTestKeyRing(t)
+	signWith := func(kr *keyring.KeyRing, typ, sub string, exp time.Time) string {
+		token, _ := kr.Sign(jwt.MapClaims{
+			"sub": sub,
+			"exp": exp.Unix(),
+			"typ": typ,
+			"fam": "session-1",
+			"jti": "jti-1",
+		})
+		return token
+	}
+	sign := func(typ, sub string, exp time.Time) string {
+		return signWith(keys, typ, sub, exp)
+	}
+	noExp, _ := keys.Sign(jwt.MapClaims{"sub": "123", "typ": models.TokenTypeAccess, "fam": "session-1"})
+	activeSession := &models.Session{Id: "session-1", UserId: "123", LastSeen: time.Now()}
+
+	tests := []struct {
+		name          string
+		token         string
+		mockSetup     func(*MockSessionStorage)
+		expectedError error
+		// rejected is set when any error other than expectedError will do.
+		rejected bool
+	}{
+		{
+			name:  "active session",
+			token: sign(models.TokenTypeAccess, "123", time.Now().Add(time.Minute)),
+			mockSetup: func(mss *MockSessionStorage) {
+				mss.On("Get", mock.Anything, "session-1").Return(activeSession, nil)
+			},
+		},
+		{
+			name:  "stale last seen is touched",
+			token: sign(models.TokenTypeAccess, "123", time.Now().Add(time.Minute)),
+			mockSetup: func(mss *MockSessionStorage) {
+				mss.On("Get", mock.Anything, "session-1").Return(
+					&models.Session{Id: "session-1", UserId: "123", LastSeen: time.Now().Add(-time.Hour)}, nil,
+				)
+				mss.On("Touch", mock.Anything, "session-1", mock.AnythingOfType("time.Time")).Return(nil)
+			},
+		},
+		{
+			name:  "revoked session",
+			token: sign(models.TokenTypeAccess, "123", time.Now().Add(time.Minute)),
+			mockSetup: func(mss *MockSessionStorage) {
+				mss.On("Get", mock.Anything, "session-1").Return((*models.Session)(nil), storage.ErrSessionNotFound)
+			},
+			expectedError: ErrSessionRevoked,
+		},
+		{
+			name:  "session of another user",
+			token: sign(models.TokenTypeAccess, "456", time.Now().Add(time.Minute)),
+			mockSetup: func(mss *MockSessionStorage) {
+				mss.On("Get", mock.Anything, "session-1").Return(activeSession, nil)
+			},
+			expectedError: ErrSessionRevoked,
+		},
+		{
+			name:          "refresh token is not an access token",
+			token:         sign(models.TokenTypeRefresh, "123", time.Now().Add(time.Minute)),
+			mockSetup:     func(mss *MockSessionStorage) {},
+			expectedError: ErrInvalidTokenType,
+		},
+		{
+			name:          "expired token",
+			token:         sign(models.TokenTypeAccess, "123", time.Now().Add(-time.Minute)),
+			mockSetup:     func(mss *MockSessionStorage) {},
+			expectedError: ErrTokenExpired,
+		},
+		{
+			name:      "expired token signed by another key is not refreshable",
+			token:     signWith(foreignKeys, models.TokenTypeAccess, "123", time.Now().Add(-time.Minute)),
+			mockSetup: func(mss *MockSessionStorage) {},
+			rejected:  true,
+		},
+		{
+			name:      "token without exp",
+			token:     noExp,
+			mockSetup: func(mss *MockSessionStorage) {},
+			rejected:  true,
+		},
+		{
+			name:      "garbage",
+			token:     "not-a-jwt",
+			mockSetup: func(mss *MockSessionStorage) {},
+			rejected:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSessionStorage := &MockSessionStorage{}
+			tt.mockSetup(mockSessionStorage)
+			d := newTestDeps(t)
+			d.sessions, d.keys = mockSessionStorage, keys
+			service := d.newAuthService()
+
+			claims, err := service.Authenticate(context.Background(), tt.token)
+			if tt.rejected {
+				assert.Error(t, err)
+				assert.NotErrorIs(t, err, ErrTokenExpired)
+				assert.Nil(t, claims)
+			} else if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, claims)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "session-1", claims.Family)
+			}
+			mockSessionStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_RevokeSession(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockSetup     func(*MockTokenStorage, *MockSessionStorage)
+		expectedError error
+	}{
+		{
+			name: "own session",
+			mockSetup: func(mts *MockTokenStorage, mss *MockSessionStorage) {
+				mss.On("Get", mock.Anything, "session-1").Return(&models.Session{Id: "session-1", UserId: "123"}, nil)
+				mts.On("RevokeRefreshFamily", mock.Anything, "session-1").Return(nil)
+				mss.On("Delete", mock.Anything, "123", "session-1").Return(nil)
+			},
+		},
+		{
+			name: "session of another user",
+			mockSetup: func(mts *MockTokenStorage, mss *MockSessionStorage) {
+				mss.On("Get", mock.Anything, "session-1").Return(&models.Session{Id: "session-1", UserId: "456"}, nil)
+			},
+			expectedError: ErrSessionNotFound,
+		},
+		{
+			name: "missing session",
+			mockSetup: func(mts *MockTokenStorage, mss *MockSessionStorage) {
+				mss.On("Get", mock.Anything, "session-1").Return((*models.Session)(nil), storage.ErrSessionNotFound)
+			},
+			expectedError: ErrSessionNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTokenStorage := &MockTokenStorage{}
+			mockSessionStorage := &MockSessionStorage{}
+			tt.mockSetup(mockTokenStorage, mockSessionStorage)
+			d := newTestDeps(t)
+			d.tokens, d.sessions = mockTokenStorage, mockSessionStorage
+			service := d.newAuthService()
+
+			err := service.RevokeSession(context.Background(), "123", "session-1")
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockTokenStorage.AssertExpectations(t)
+			mockSessionStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_RevokeAllSessions(t *testing.T) {
+	mockTokenStorage := &MockTokenStorage{}
+	mockSessionStorage := &MockSessionStorage{}
+	mockSessionStorage.On("ListByUser", mock.Anything, "123").Return([]models.Session{
+		{Id: "session-1", UserId: "123"},
+		{Id: "session-2", UserId: "123"},
+	}, nil)
+	for _, id := range []string{"session-1", "session-2"} {
+		mockTokenStorage.On("RevokeRefreshFamily", mock.Anything, id).Return(nil)
+		mockSessionStorage.On("Delete", mock.Anything, "123", id).Return(nil)
+	}
+	d := newTestDeps(t)
+	d.tokens, d.sessions = mockTokenStorage, mockSessionStorage
+	service := d.newAuthService()
+
+	assert.NoError(t, service.RevokeAllSessions(context.Background(), "123"))
+	mockTokenStorage.AssertExpectations(t)
+	mockSessionStorage.AssertExpectations(t)
+}
+
+func TestAuthService_LoginLockout(t *testing.T) {
+	mockUserStorage := &MockUserStorage{}
+	mockTokenStorage := &MockTokenStorage{}
+	mockSessionStorage := &MockSessionStorage{}
+	mockUserStorage.On("GetByLogin", "testuser").Return(
+		&models.User{Login: "testuser", Id: "123", PasswordHash: mustHash(t, hasher.NewArgon2id(testArgon2Params), "password123")},
+		nil,
+	)
+	mockTokenStorage.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+	mockSessionStorage.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+
+	guard := newTestLockout()
+	d := newTestDeps(t)
+	d.users, d.tokens, d.sessions, d.loginGuard = mockUserStorage, mockTokenStorage, mockSessionStorage, guard
+	service := d.newAuthService()
+	wrong := models.UserCreateReq{Login: "testuser", Password: "wrong"}
+	right := models.UserCreateReq{Login: "testuser", Password: "password123"}
+
+	// a success in between clears earlier failures
+	for i := 0; i < config.DefaultLoginLockout.MaxFailures-1; i++ {
+		_, err := service.Login(wrong, testClient)
+		assert.ErrorIs(t, err, ErrWrongLoginOrPassword)
+	}
+	_, err := service.Login(right, testClient)
+	assert.NoError(t, err)
+
+	for i := 0; i < config.DefaultLoginLockout.MaxFailures; i++ {
+		_, err := service.Login(wrong, testClient)
+		assert.ErrorIs(t, err, ErrWrongLoginOrPassword)
+	}
+
+	// even the right password is rejected while locked
+	_, err = service.Login(right, testClient)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	var tooMany *RetryAfterError
+	assert.ErrorAs(t, err, &tooMany)
+	assert.InDelta(t, config.DefaultLoginLockout.BaseLock.Seconds(), tooMany.RetryAfter.Seconds(), 1)
+
+	// the lock is scoped to the client IP, so the owner can still log in
+	// from elsewhere
+	elsewhere := testClient
+	elsewhere.IP = "203.0.113.7"
+	_, err = service.Login(right, elsewhere)
+	assert.NoError(t, err)
+}
+
+func TestAuthService_LoginAccountLockout(t *testing.T) {
+	mockUserStorage := &MockUserStorage{}
+	mockUserStorage.On("GetByLogin", "testuser").Return(
+		&models.User{Login: "testuser", Id: "123", PasswordHash: mustHash(t, hasher.NewArgon2id(testArgon2Params), "password123")},
+		nil,
+	)
+
+	policy := config.Lockout{MaxFailures: 6, Window: time.Hour, BaseLock: 5 * time.Minute, MaxLock: time.Hour, Memory: 24 * time.Hour}
+	d := newTestDeps(t)
+	d.users, d.accountGuard = mockUserStorage, ratelimit.NewLockout(ratelimit.NewMemoryStore(), policy)
+	service := d.newAuthService()
+	wrong := models.UserCreateReq{Login: "testuser", Password: "wrong"}
+	right := models.UserCreateReq{Login: "testuser", Password: "password123"}
+
+	// every address stays below the per-IP threshold
+	for i := 0; i < policy.MaxFailures; i++ {
+		client := testClient
+		client.IP = fmt.Sprintf("203.0.113.%d", i/2)
+		_, err := service.Login(wrong, client)
+		assert.ErrorIs(t, err, ErrWrongLoginOrPassword)
+	}
+
+	elsewhere := testClient
+	elsewhere.IP = "198.51.100.1"
+	_, err := service.Login(right, elsewhere)
+	var tooMany *RetryAfterError
+	assert.ErrorAs(t, err, &tooMany)
+	assert.InDelta(t, policy.BaseLock.Seconds(), tooMany.RetryAfter.Seconds(), 1)
+}
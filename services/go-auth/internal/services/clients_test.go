@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+	"go-auth/pkg/authz"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testClients is an in-memory AppClientStorage that knows the permissions
+// seeded by the migrations.
+type testClients struct {
+	clients map[string]models.APIClient
+}
+
+func newTestClients() *testClients {
+	return &testClients{clients: map[string]models.APIClient{}}
+}
+
+var testPermissions = []string{
+	authz.PermAuditRead, authz.PermChatModerate, authz.PermClientsManage,
+	authz.PermGameAdmin, authz.PermGamePlay, authz.PermRolesManage, authz.PermUsersRead,
+}
+
+func (s *testClients) Save(client models.APIClient) error {
+	for _, scope := range client.Scopes {
+		if !slices.Contains(testPermissions, scope) {
+			return storage.ErrScopeNotFound
+		}
+	}
+	client.CreatedAt = time.Now()
+	s.clients[client.Id] = client
+	return nil
+}
+
+func (s *testClients) Get(id string) (*models.APIClient, error) {
+	client, ok := s.clients[id]
+	if !ok {
+		return nil, storage.ErrClientNotFound
+	}
+	return &client, nil
+}
+
+func (s *testClients) List() ([]models.APIClient, error) {
+	clients := []models.APIClient{}
+	for _, c := range s.clients {
+		clients = append(clients, c)
+	}
+	return clients, nil
+}
+
+func (s *testClients) Revoke(id string) error {
+	client, ok := s.clients[id]
+	if !ok {
+		return storage.ErrClientNotFound
+	}
+	now := time.Now()
+	client.RevokedAt = &now
+	s.clients[id] = client
+	return nil
+}
+
+func (d *testDeps) newClientService() *ClientService {
+	return NewClientService(d.newSessions(), d.clients, d.keys, d.cfg)
+}
+
+func newClientTestService(t *testing.T) (*ClientService, *testClients) {
+	d := newTestDeps(t)
+	return d.newClientService(), d.clients
+}
+
+func TestClientService_RegisterClient(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           models.APIClientCreateReq
+		expectedError error
+	}{
+		{name: "valid", req: models.APIClientCreateReq{Name: " nest ", Scopes: []string{authz.PermUsersRead, authz.PermGameAdmin, authz.PermUsersRead}}},
+		{name: "no name", req: models.APIClientCreateReq{Name: " ", Scopes: []string{authz.PermUsersRead}}, expectedError: ErrInvalidClientName},
+		{name: "no scopes", req: models.APIClientCreateReq{Name: "nest"}, expectedError: ErrInvalidScope},
+		{name: "unknown scope", req: models.APIClientCreateReq{Name: "nest", Scopes: []string{"everything"}}, expectedError: ErrInvalidScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, clients := newClientTestService(t)
+
+			res, err := service.RegisterClient(context.Background(), tt.req)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, res)
+				assert.Empty(t, clients.clients)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(res.Id, "cl_"))
+			assert.Equal(t, "nest", res.Name)
+			assert.Equal(t, []string{authz.PermGameAdmin, authz.PermUsersRead}, res.Scopes)
+			assert.NotEmpty(t, res.ClientSecret)
+			assert.Equal(t, hashClientSecret(res.ClientSecret), clients.clients[res.Id].SecretHash, "only the hash is stored")
+		})
+	}
+}
+
+func TestClientService_ClientToken(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newClientTestService(t)
+	client, err := service.RegisterClient(ctx, models.APIClientCreateReq{Name: "nest", Scopes: []string{authz.PermUsersRead, authz.PermGameAdmin}})
+	assert.NoError(t, err)
+	revoked, err := service.RegisterClient(ctx, models.APIClientCreateReq{Name: "old job", Scopes: []string{authz.PermUsersRead}})
+	assert.NoError(t, err)
+	assert.NoError(t, service.RevokeClient(ctx, revoked.Id))
+
+	tests := []struct {
+		name          string
+		clientId      string
+		secret        string
+		scope         string
+		expectedScope string
+		expectedError error
+	}{
+		{name: "all scopes by default", clientId: client.Id, secret: client.ClientSecret, expectedScope: "game:admin users:read"},
+		{name: "requested subset", clientId: client.Id, secret: client.ClientSecret, scope: " users:read ", expectedScope: "users:read"},
+		{name: "scope not allowed", clientId: client.Id, secret: client.ClientSecret, scope: "users:read roles:manage", expectedError: ErrInvalidScope},
+		{name: "wrong secret", clientId: client.Id, secret: revoked.ClientSecret, expectedError: ErrInvalidClient},
+		{name: "revoked client", clientId: revoked.Id, secret: revoked.ClientSecret, expectedError: ErrInvalidClient},
+		{name: "unknown client", clientId: "cl_nope", secret: client.ClientSecret, expectedError: ErrInvalidClient},
+		{name: "no client id", secret: client.ClientSecret, expectedError: ErrInvalidClient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := service.ClientToken(ctx, tt.clientId, tt.secret, tt.scope)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, res)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "Bearer", res.TokenType)
+			assert.Equal(t, int(accessTokenTTL.Seconds()), res.ExpiresIn)
+			assert.Equal(t, tt.expectedScope, res.Scope)
+
+			claims, err := service.AuthenticateClient(ctx, res.AccessToken)
+			assert.NoError(t, err)
+			assert.Equal(t, client.Id, claims.Subject)
+			assert.Equal(t, tt.expectedScope, claims.Scope)
+		})
+	}
+}
+
+func TestClientService_AuthenticateClient(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDeps(t)
+	service := d.newClientService()
+	introspection := d.newIntrospectionService()
+	client, err := service.RegisterClient(ctx, models.APIClientCreateReq{Name: "nest", Scopes: []string{authz.PermUsersRead}})
+	assert.NoError(t, err)
+	token, err := service.ClientToken(ctx, client.Id, client.ClientSecret, "")
+	assert.NoError(t, err)
+
+	userToken, _, err := d.newSessions().generateJWT(models.UserCreateRes{Id: "123"}, models.TokenTypeAccess, "session-1", time.Minute)
+	assert.NoError(t, err)
+	_, err = service.AuthenticateClient(ctx, userToken)
+	assert.ErrorIs(t, err, ErrInvalidTokenType)
+	_, err = d.newAuthService().Authenticate(ctx, token.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidTokenType, "client token is not a user token")
+
+	res, err := introspection.Introspect(ctx, token.AccessToken)
+	assert.NoError(t, err)
+	assert.True(t, res.Active)
+	assert.Equal(t, client.Id, res.Subject)
+	assert.Equal(t, []string{authz.PermUsersRead}, res.Permissions)
+	assert.Equal(t, models.TokenTypeClient, res.TokenType)
+
+	assert.NoError(t, service.RevokeClient(ctx, client.Id))
+	_, err = service.AuthenticateClient(ctx, token.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidClient, "tokens die with their client")
+	res, err = introspection.Introspect(ctx, token.AccessToken)
+	assert.NoError(t, err)
+	assert.False(t, res.Active)
+
+	assert.ErrorIs(t, service.RevokeClient(ctx, "cl_nope"), ErrClientNotFound)
+}
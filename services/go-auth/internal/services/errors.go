@@ -1,7 +1,84 @@
 package services
 
-import "errors"
+import (
+	"errors"
+	"go-auth/internal/models"
+	"time"
+)
 
 var ErrUserExists = errors.New("user allready exists")
 var ErrWrongLoginOrPassword = errors.New("wrong password or login")
 var ErrLoginAndPasswordAreRequired = errors.New("login and password are required")
+var ErrInvalidTokenType = errors.New("invalid token type")
+var ErrTokenExpired = errors.New("token expired")
+var ErrInvalidToken = errors.New("invalid token")
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+var ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+var ErrSessionRevoked = errors.New("session revoked")
+var ErrSessionNotFound = errors.New("session not found")
+var ErrTooManyAttempts = errors.New("too many attempts")
+var ErrInvalidEmail = errors.New("invalid email")
+var ErrEmailExists = errors.New("email allready in use")
+var ErrPasswordRequired = errors.New("password is required")
+var ErrInvalidActionToken = errors.New("invalid or expired token")
+var ErrMFARequired = errors.New("second factor required")
+var ErrInvalidMFACode = errors.New("invalid second factor code")
+var ErrMFANotSetUp = errors.New("totp is not set up")
+var ErrMFANotEnabled = errors.New("totp is not enabled")
+var ErrMFAAlreadyEnabled = errors.New("totp is already enabled")
+var ErrUnknownOAuthProvider = errors.New("unknown oauth provider")
+var ErrInvalidOAuthState = errors.New("invalid or expired oauth state")
+var ErrOAuthEmailConflict = errors.New("email belongs to an account with unverified email")
+var ErrIdentityLinked = errors.New("identity is linked to another user")
+var ErrRoleNotFound = errors.New("role not found")
+var ErrUserNotFound = errors.New("user not found")
+var ErrWrongPassword = errors.New("wrong password")
+var ErrInvalidDisplayName = errors.New("display name is too long")
+var ErrInvalidAvatarURL = errors.New("avatar url must be an http(s) url")
+var ErrInvalidClient = errors.New("invalid client credentials")
+var ErrInvalidScope = errors.New("invalid scope")
+var ErrInvalidClientName = errors.New("client name is required")
+var ErrClientNotFound = errors.New("api client not found")
+var ErrInvalidEventFilter = errors.New("invalid event filter")
+var ErrValidation = errors.New("validation failed")
+
+// RetryAfterError is ErrTooManyAttempts with the time left until the next
+// attempt is allowed.
+type RetryAfterError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *RetryAfterError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// ValidationError is ErrValidation with the fields that were rejected.
+type ValidationError struct {
+	Fields []models.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return ErrValidation.Error()
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// MFARequiredError is returned by Login instead of tokens when the user has
+// 2FA enabled. Challenge is exchanged for tokens by LoginMFA.
+type MFARequiredError struct {
+	Challenge string
+}
+
+func (e *MFARequiredError) Error() string {
+	return ErrMFARequired.Error()
+}
+
+func (e *MFARequiredError) Is(target error) bool {
+	return target == ErrMFARequired
+}
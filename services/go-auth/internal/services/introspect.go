@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+)
+
+// IntrospectionService answers other services asking about tokens.
+type IntrospectionService struct {
+	auth    app.AppAuthService
+	clients app.AppClientService
+}
+
+func NewIntrospectionService(auth app.AppAuthService, clients app.AppClientService) *IntrospectionService {
+	return &IntrospectionService{auth: auth, clients: clients}
+}
+
+// Introspect tells other services whether accessToken is active: genuine,
+// unexpired and with its session still present in Redis, or, for a client
+// token, with its client not revoked. Only access and client tokens are
+// ever active; a client's scopes are reported as its permissions. An error
+// means we couldn't tell, not that the token is bad.
+func (s IntrospectionService) Introspect(ctx context.Context, accessToken string) (*models.Introspection, error) {
+	claims, err := s.auth.Authenticate(ctx, accessToken)
+	if errors.Is(err, ErrInvalidTokenType) {
+		claims, err = s.clients.AuthenticateClient(ctx, accessToken)
+	}
+	switch {
+	case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrTokenExpired),
+		errors.Is(err, ErrInvalidTokenType), errors.Is(err, ErrSessionRevoked),
+		errors.Is(err, ErrInvalidClient):
+		return &models.Introspection{Active: false}, nil
+	case err != nil:
+		return nil, fmt.Errorf("IntrospectionService Introspect: %w", err)
+	}
+
+	res := &models.Introspection{
+		Active:      true,
+		Subject:     claims.Subject,
+		SessionId:   claims.Family,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+		Id:          claims.ID,
+		TokenType:   claims.Type,
+	}
+	if claims.Type == models.TokenTypeClient {
+		res.Permissions = strings.Fields(claims.Scope)
+	}
+	if claims.ExpiresAt != nil {
+		res.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		res.IssuedAt = claims.IssuedAt.Unix()
+	}
+	return res, nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func (d *testDeps) newIntrospectionService() *IntrospectionService {
+	return NewIntrospectionService(d.newAuthService(), d.newClientService())
+}
+
+func TestIntrospectionService_Introspect(t *testing.T) {
+	keys := newTestKeyRing(t)
+	exp := time.Now().Add(time.Minute).Truncate(time.Second)
+	sign := func(typ string, exp time.Time) string {
+		token, _ := keys.Sign(jwt.MapClaims{
+			"sub":   "123",
+			"exp":   exp.Unix(),
+			"iat":   exp.Add(-accessTokenTTL).Unix(),
+			"typ":   typ,
+			"fam":   "session-1",
+			"jti":   "jti-1",
+			"roles": []string{"player"},
+			"perms": []string{"game:play"},
+		})
+		return token
+	}
+	activeSession := &models.Session{Id: "session-1", UserId: "123", LastSeen: time.Now()}
+
+	tests := []struct {
+		name          string
+		token         string
+		mockSetup     func(*MockSessionStorage)
+		expected      *models.Introspection
+		expectedError bool
+	}{
+		{
+			name:  "active token",
+			token: sign(models.TokenTypeAccess, exp),
+			mockSetup: func(mss *MockSessionStorage) {
+				mss.On("Get", mock.Anything, "session-1").Return(activeSession, nil)
+			},
+			expected: &models.Introspection{
+				Active:      true,
+				Subject:     "123",
+				SessionId:   "session-1",
+				Roles:       []string{"player"},
+				Permissions: []string{"game:play"},
+				ExpiresAt:   exp.Unix(),
+				IssuedAt:    exp.Add(-accessTokenTTL).Unix(),
+				Id:          "jti-1",
+				TokenType:   models.TokenTypeAccess,
+			},
+		},
+		{
+			name:  "revoked session",
+			token: sign(models.TokenTypeAccess, exp),
+			mockSetup: func(mss *MockSessionStorage) {
+				mss.On("Get", mock.Anything, "session-1").Return((*models.Session)(nil), storage.ErrSessionNotFound)
+			},
+			expected: &models.Introspection{},
+		},
+		{
+			name:      "expired token",
+			token:     sign(models.TokenTypeAccess, time.Now().Add(-time.Minute)),
+			mockSetup: func(mss *MockSessionStorage) {},
+			expected:  &models.Introspection{},
+		},
+		{
+			name:      "refresh token",
+			token:     sign(models.TokenTypeRefresh, exp),
+			mockSetup: func(mss *MockSessionStorage) {},
+			expected:  &models.Introspection{},
+		},
+		{
+			name:      "garbage",
+			token:     "not-a-jwt",
+			mockSetup: func(mss *MockSessionStorage) {},
+			expected:  &models.Introspection{},
+		},
+		{
+			name:  "redis down is an error, not an inactive token",
+			token: sign(models.TokenTypeAccess, exp),
+			mockSetup: func(mss *MockSessionStorage) {
+				mss.On("Get", mock.Anything, "session-1").Return((*models.Session)(nil), errors.New("connection refused"))
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSessionStorage := &MockSessionStorage{}
+			tt.mockSetup(mockSessionStorage)
+			d := newTestDeps(t)
+			d.sessions, d.keys = mockSessionStorage, keys
+			service := d.newIntrospectionService()
+
+			res, err := service.Introspect(context.Background(), tt.token)
+			if tt.expectedError {
+				assert.Error(t, err)
+				assert.Nil(t, res)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, res)
+			}
+			mockSessionStorage.AssertExpectations(t)
+		})
+	}
+}
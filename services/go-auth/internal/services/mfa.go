@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+	"go-auth/pkg/totp"
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+	// recoveryCodeSize is the number of base32 characters (48 random
+	// bits) in a code, rendered as two groups of five.
+	recoveryCodeSize = 10
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFAService manages the second factor of users and completes the logins
+// that require it.
+type MFAService struct {
+	sessions    *Sessions
+	userStorage app.AppUserStorage
+	mfaStore    app.AppMFAStorage
+	loginGuard  app.AppLoginGuard
+	cfg         app.AppConfig
+	audit       app.AppAuditLog
+}
+
+func NewMFAService(
+	sessions *Sessions,
+	userStorage app.AppUserStorage,
+	mfaStore app.AppMFAStorage,
+	loginGuard app.AppLoginGuard,
+	cfg app.AppConfig,
+	audit app.AppAuditLog,
+) *MFAService {
+	return &MFAService{
+		sessions:    sessions,
+		userStorage: userStorage,
+		mfaStore:    mfaStore,
+		loginGuard:  loginGuard,
+		cfg:         cfg,
+		audit:       audit,
+	}
+}
+
+// LoginMFA completes a login that Login answered with MFARequiredError.
+// The challenge survives wrong codes so a typo doesn't restart the login;
+// attempts count towards the same lockout as passwords.
+func (s MFAService) LoginMFA(ctx context.Context, challenge, code string, client models.ClientInfo) (*models.TokenDto, error) {
+	claims, err := s.sessions.parseToken(challenge)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidActionToken, err)
+	}
+	if claims.Type != models.TokenTypeMFAChallenge {
+		return nil, ErrInvalidTokenType
+	}
+	state, err := s.mfaStore.GetTOTP(claims.Subject)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil, ErrInvalidActionToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("MFAService LoginMFA: %w", err)
+	}
+	if err := s.verifySecondFactor(ctx, claims.Subject, state, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			event := clientEvent(models.AuthEventLoginFailed, claims.Subject, client)
+			event.Details = map[string]string{"reason": "invalid_mfa_code"}
+			s.audit.Record(ctx, event)
+		}
+		return nil, err
+	}
+	if _, err := s.sessions.consumeActionToken(ctx, models.TokenTypeMFAChallenge, challenge); err != nil {
+		return nil, err
+	}
+	user, err := s.userStorage.GetById(claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("MFAService LoginMFA: %w", err)
+	}
+	// The grace period may have run out since the challenge was issued.
+	if active, err := s.sessions.restoreIfDeleted(user); err != nil {
+		return nil, err
+	} else if !active {
+		return nil, ErrInvalidActionToken
+	}
+	return s.sessions.startSession(ctx, models.UserCreateRes{Id: claims.Subject, Login: state.Login}, client)
+}
+
+// SetupTOTP starts enrollment with a fresh secret. Nothing changes for the
+// user until ConfirmTOTP proves the authenticator app has it.
+func (s MFAService) SetupTOTP(ctx context.Context, userId string) (*models.TOTPSetupRes, error) {
+	state, err := s.mfaStore.GetTOTP(userId)
+	if err != nil {
+		return nil, fmt.Errorf("MFAService SetupTOTP: %w", err)
+	}
+	if state.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("MFAService SetupTOTP: %w", err)
+	}
+	if err := s.mfaStore.SetPendingTOTP(userId, secret); err != nil {
+		return nil, fmt.Errorf("MFAService SetupTOTP: %w", err)
+	}
+	return &models.TOTPSetupRes{
+		Secret: secret,
+		Uri:    totp.URI(s.cfg.GetConfig().MFAIssuer, state.Login, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables 2FA and returns the recovery codes, which are shown
+// only this once.
+func (s MFAService) ConfirmTOTP(ctx context.Context, userId, code string) ([]string, error) {
+	state, err := s.mfaStore.GetTOTP(userId)
+	if err != nil {
+		return nil, fmt.Errorf("MFAService ConfirmTOTP: %w", err)
+	}
+	if state.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if state.Secret == "" {
+		return nil, ErrMFANotSetUp
+	}
+	step, ok := totp.Validate(state.Secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("MFAService ConfirmTOTP: %w", err)
+	}
+	if err := s.mfaStore.EnableTOTP(userId, step, hashes); err != nil {
+		return nil, fmt.Errorf("MFAService ConfirmTOTP: %w", err)
+	}
+	slog.Info("MFAService totp enabled", "user_id", userId)
+	return codes, nil
+}
+
+func (s MFAService) DisableTOTP(ctx context.Context, userId, code string) error {
+	state, err := s.enabledTOTP(userId)
+	if err != nil {
+		return err
+	}
+	if err := s.verifySecondFactor(ctx, userId, state, code); err != nil {
+		return err
+	}
+	if err := s.mfaStore.DisableTOTP(userId); err != nil {
+		return fmt.Errorf("MFAService DisableTOTP: %w", err)
+	}
+	slog.Info("MFAService totp disabled", "user_id", userId)
+	return nil
+}
+
+func (s MFAService) RegenerateRecoveryCodes(ctx context.Context, userId, code string) ([]string, error) {
+	state, err := s.enabledTOTP(userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(ctx, userId, state, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("MFAService RegenerateRecoveryCodes: %w", err)
+	}
+	if err := s.mfaStore.ReplaceRecoveryCodes(userId, hashes); err != nil {
+		return nil, fmt.Errorf("MFAService RegenerateRecoveryCodes: %w", err)
+	}
+	return codes, nil
+}
+
+func (s MFAService) enabledTOTP(userId string) (*models.TOTPState, error) {
+	state, err := s.mfaStore.GetTOTP(userId)
+	if err != nil {
+		return nil, fmt.Errorf("MFAService get totp: %w", err)
+	}
+	if !state.Enabled {
+		return nil, ErrMFANotEnabled
+	}
+	return state, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused
+// recovery code. Each is good for one use only.
+func (s MFAService) verifySecondFactor(ctx context.Context, userId string, state *models.TOTPState, code string) error {
+	guardKey := "mfa:" + userId
+	if retryAfter, err := s.loginGuard.Check(ctx, guardKey); err != nil {
+		slog.Error("MFAService mfa lockout check: " + err.Error())
+	} else if retryAfter > 0 {
+		return &RetryAfterError{RetryAfter: retryAfter}
+	}
+
+	ok, err := s.checkSecondFactor(userId, state, code)
+	if err != nil {
+		return fmt.Errorf("MFAService verify second factor: %w", err)
+	}
+	if !ok {
+		slog.Info("MFAService failed second factor", "user_id", userId)
+		if _, err := s.loginGuard.Fail(ctx, guardKey); err != nil {
+			slog.Error("MFAService mfa lockout fail: " + err.Error())
+		}
+		return ErrInvalidMFACode
+	}
+	if err := s.loginGuard.Reset(ctx, guardKey); err != nil {
+		slog.Error("MFAService mfa lockout reset: " + err.Error())
+	}
+	return nil
+}
+
+func (s MFAService) checkSecondFactor(userId string, state *models.TOTPState, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(state.Secret, code, time.Now())
+		if !ok || step <= state.LastStep {
+			return false, nil
+		}
+		return s.mfaStore.UseTOTPStep(userId, step)
+	}
+	hash, ok := hashRecoveryCode(code)
+	if !ok {
+		return false, nil
+	}
+	return s.mfaStore.UseRecoveryCode(userId, hash)
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeSize*5/8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		code := raw[:recoveryCodeSize/2] + "-" + raw[recoveryCodeSize/2:]
+		hash, _ := hashRecoveryCode(code)
+		codes = append(codes, code)
+		hashes = append(hashes, hash)
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode normalizes how a code may be typed back and hashes it.
+// Codes are random, not user chosen, so a fast hash is enough and lets the
+// database look them up directly.
+func hashRecoveryCode(code string) (string, bool) {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != recoveryCodeSize {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:]), true
+}
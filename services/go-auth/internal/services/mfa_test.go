@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+	"go-auth/pkg/hasher"
+	"go-auth/pkg/totp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testMFAStore is an in-memory AppMFAStorage for a single user.
+type testMFAStore struct {
+	state models.TOTPState
+	codes map[string]bool
+}
+
+func newTestMFAStore() *testMFAStore {
+	return &testMFAStore{state: models.TOTPState{Login: "testuser"}, codes: map[string]bool{}}
+}
+
+func (s *testMFAStore) GetTOTP(userId string) (*models.TOTPState, error) {
+	state := s.state
+	return &state, nil
+}
+
+func (s *testMFAStore) SetPendingTOTP(userId, secret string) error {
+	if s.state.Enabled {
+		return storage.ErrUserNotFound
+	}
+	s.state.Secret = secret
+	return nil
+}
+
+func (s *testMFAStore) EnableTOTP(userId string, step int64, codeHashes []string) error {
+	s.state.Enabled = true
+	s.state.LastStep = step
+	return s.ReplaceRecoveryCodes(userId, codeHashes)
+}
+
+func (s *testMFAStore) DisableTOTP(userId string) error {
+	s.state = models.TOTPState{Login: s.state.Login}
+	s.codes = map[string]bool{}
+	return nil
+}
+
+func (s *testMFAStore) ReplaceRecoveryCodes(userId string, codeHashes []string) error {
+	s.codes = map[string]bool{}
+	for _, h := range codeHashes {
+		s.codes[h] = true
+	}
+	return nil
+}
+
+func (s *testMFAStore) UseTOTPStep(userId string, step int64) (bool, error) {
+	if !s.state.Enabled || step <= s.state.LastStep {
+		return false, nil
+	}
+	s.state.LastStep = step
+	return true, nil
+}
+
+func (s *testMFAStore) UseRecoveryCode(userId, codeHash string) (bool, error) {
+	if !s.codes[codeHash] {
+		return false, nil
+	}
+	delete(s.codes, codeHash)
+	return true, nil
+}
+
+func currentCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(time.Now())+offset)
+	assert.NoError(t, err)
+	return code
+}
+
+func (d *testDeps) newMFAService() *MFAService {
+	return NewMFAService(d.newSessions(), d.users, d.mfaStore, d.loginGuard, d.cfg, d.audit)
+}
+
+// mfaTestEnv is "testuser" (id 123, password "password123") with TOTP
+// enrolled. auth is for getting login challenges.
+type mfaTestEnv struct {
+	deps    *testDeps
+	service *MFAService
+	auth    *AuthService
+	codes   []string
+}
+
+func newMFATestEnv(t *testing.T) *mfaTestEnv {
+	t.Helper()
+	return newMFATestEnvFor(t, &models.User{
+		Id:           "123",
+		Login:        "testuser",
+		PasswordHash: mustHash(t, hasher.NewArgon2id(testArgon2Params), "password123"),
+		MFAEnabled:   true,
+	})
+}
+
+func newMFATestEnvFor(t *testing.T, user *models.User) *mfaTestEnv {
+	t.Helper()
+	d := newTestDeps(t)
+	d.users.On("GetByLogin", "testuser").Return(user, nil)
+	d.users.On("GetById", "123").Return(user, nil)
+	d.tokens.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+	d.sessions.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+	service := d.newMFAService()
+
+	ctx := context.Background()
+	_, err := service.SetupTOTP(ctx, "123")
+	assert.NoError(t, err)
+	// Enroll with the previous step so the current one is still unused.
+	codes, err := service.ConfirmTOTP(ctx, "123", currentCode(t, d.mfaStore.state.Secret, -1))
+	assert.NoError(t, err)
+	return &mfaTestEnv{deps: d, service: service, auth: d.newAuthService(), codes: codes}
+}
+
+func (e *mfaTestEnv) secret() string { return e.deps.mfaStore.state.Secret }
+
+func loginChallenge(t *testing.T, service *AuthService) string {
+	t.Helper()
+	tokens, err := service.Login(models.UserCreateReq{Login: "testuser", Password: "password123"}, testClient)
+	assert.Nil(t, tokens)
+	var mfa *MFARequiredError
+	if !assert.ErrorAs(t, err, &mfa) {
+		return ""
+	}
+	assert.ErrorIs(t, err, ErrMFARequired)
+	return mfa.Challenge
+}
+
+func TestMFAService_TOTPEnrollment(t *testing.T) {
+	mfaStore := newTestMFAStore()
+	d := newTestDeps(t)
+	d.mfaStore = mfaStore
+	service := d.newMFAService()
+	ctx := context.Background()
+
+	_, err := service.ConfirmTOTP(ctx, "123", "123456")
+	assert.ErrorIs(t, err, ErrMFANotSetUp)
+	assert.ErrorIs(t, service.DisableTOTP(ctx, "123", "123456"), ErrMFANotEnabled)
+
+	setup, err := service.SetupTOTP(ctx, "123")
+	assert.NoError(t, err)
+	assert.Equal(t, mfaStore.state.Secret, setup.Secret)
+	assert.Contains(t, setup.Uri, "otpauth://totp/go-auth:testuser?")
+	assert.False(t, mfaStore.state.Enabled, "setup alone doesn't enable 2FA")
+
+	_, err = service.ConfirmTOTP(ctx, "123", currentCode(t, setup.Secret, -5))
+	assert.ErrorIs(t, err, ErrInvalidMFACode, "code outside the skew window")
+
+	codes, err := service.ConfirmTOTP(ctx, "123", currentCode(t, setup.Secret, 0))
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.Len(t, mfaStore.codes, recoveryCodeCount)
+	for _, code := range codes {
+		hash, _ := hashRecoveryCode(code)
+		assert.True(t, mfaStore.codes[hash], "only hashes are stored")
+		assert.NotContains(t, mfaStore.codes, code)
+	}
+
+	_, err = service.SetupTOTP(ctx, "123")
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+	_, err = service.ConfirmTOTP(ctx, "123", currentCode(t, setup.Secret, 0))
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+}
+
+func TestMFAService_LoginMFA(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("totp code", func(t *testing.T) {
+		e := newMFATestEnv(t)
+		challenge := loginChallenge(t, e.auth)
+		code := currentCode(t, e.secret(), 0)
+
+		tokens, err := e.service.LoginMFA(ctx, challenge, code, testClient)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, tokens.Access)
+
+		_, err = e.service.LoginMFA(ctx, challenge, code, testClient)
+		assert.Error(t, err, "challenge is one-time")
+
+		_, err = e.service.LoginMFA(ctx, loginChallenge(t, e.auth), code, testClient)
+		assert.ErrorIs(t, err, ErrInvalidMFACode, "totp code can't be replayed")
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		e := newMFATestEnv(t)
+
+		tokens, err := e.service.LoginMFA(ctx, loginChallenge(t, e.auth), " "+e.codes[0]+" ", testClient)
+		assert.NoError(t, err)
+		assert.NotNil(t, tokens)
+
+		_, err = e.service.LoginMFA(ctx, loginChallenge(t, e.auth), e.codes[0], testClient)
+		assert.ErrorIs(t, err, ErrInvalidMFACode, "recovery code is one-time")
+	})
+
+	t.Run("wrong code keeps the challenge until lockout", func(t *testing.T) {
+		e := newMFATestEnv(t)
+		challenge := loginChallenge(t, e.auth)
+
+		for i := 0; i < 4; i++ {
+			_, err := e.service.LoginMFA(ctx, challenge, "aaaaa-aaaaa", testClient)
+			assert.ErrorIs(t, err, ErrInvalidMFACode)
+		}
+		tokens, err := e.service.LoginMFA(ctx, challenge, currentCode(t, e.secret(), 0), testClient)
+		assert.NoError(t, err)
+		assert.NotNil(t, tokens)
+
+		challenge = loginChallenge(t, e.auth)
+		for i := 0; i < 5; i++ {
+			_, err = e.service.LoginMFA(ctx, challenge, "aaaaa-aaaaa", testClient)
+			assert.ErrorIs(t, err, ErrInvalidMFACode)
+		}
+		_, err = e.service.LoginMFA(ctx, challenge, currentCode(t, e.secret(), 1), testClient)
+		var tooMany *RetryAfterError
+		assert.ErrorAs(t, err, &tooMany)
+	})
+
+	t.Run("access token is not a challenge", func(t *testing.T) {
+		e := newMFATestEnv(t)
+		access, _, err := e.deps.newSessions().generateJWT(models.UserCreateRes{Id: "123"}, models.TokenTypeAccess, "fam", time.Minute)
+		assert.NoError(t, err)
+		_, err = e.service.LoginMFA(ctx, access, currentCode(t, e.secret(), 0), testClient)
+		assert.ErrorIs(t, err, ErrInvalidTokenType)
+	})
+
+	t.Run("deleted account is restored only after the second factor", func(t *testing.T) {
+		deletedAt := time.Now().Add(-time.Hour)
+		e := newMFATestEnvFor(t, &models.User{
+			Id:           "123",
+			Login:        "testuser",
+			PasswordHash: mustHash(t, hasher.NewArgon2id(testArgon2Params), "password123"),
+			MFAEnabled:   true,
+			DeletedAt:    &deletedAt,
+		})
+		users := e.deps.users
+		challenge := loginChallenge(t, e.auth)
+		users.AssertNotCalled(t, "Restore", mock.Anything)
+
+		_, err := e.service.LoginMFA(ctx, challenge, "aaaaa-aaaaa", testClient)
+		assert.ErrorIs(t, err, ErrInvalidMFACode)
+		users.AssertNotCalled(t, "Restore", mock.Anything)
+
+		users.On("Restore", "123").Return(nil).Once()
+		tokens, err := e.service.LoginMFA(ctx, challenge, currentCode(t, e.secret(), 0), testClient)
+		assert.NoError(t, err)
+		assert.NotNil(t, tokens)
+		users.AssertExpectations(t)
+	})
+}
+
+func TestMFAService_DisableTOTP(t *testing.T) {
+	ctx := context.Background()
+	e := newMFATestEnv(t)
+	service, mfaStore, codes := e.service, e.deps.mfaStore, e.codes
+
+	assert.ErrorIs(t, service.DisableTOTP(ctx, "123", "bbbbb-bbbbb"), ErrInvalidMFACode)
+	assert.True(t, mfaStore.state.Enabled)
+
+	newCodes, err := service.RegenerateRecoveryCodes(ctx, "123", codes[0])
+	assert.NoError(t, err)
+	assert.Len(t, newCodes, recoveryCodeCount)
+	assert.ErrorIs(t, service.DisableTOTP(ctx, "123", codes[1]), ErrInvalidMFACode, "old codes are replaced")
+
+	assert.NoError(t, service.DisableTOTP(ctx, "123", newCodes[0]))
+	assert.False(t, mfaStore.state.Enabled)
+	assert.Empty(t, mfaStore.state.Secret)
+	assert.Empty(t, mfaStore.codes)
+}
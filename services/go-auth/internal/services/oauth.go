@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+	"go-auth/pkg/oidc"
+)
+
+// OAuthStateTTL is how long the user has to sign in at the provider.
+const OAuthStateTTL = 10 * time.Minute
+
+// OAuthService signs users in with external identity providers and links
+// their accounts there to local users.
+type OAuthService struct {
+	sessions    *Sessions
+	userStorage app.AppUserStorage
+	oauthStates app.AppOAuthStateStorage
+	identities  app.AppIdentityStorage
+	providers   app.AppOAuthProviders
+}
+
+func NewOAuthService(
+	sessions *Sessions,
+	userStorage app.AppUserStorage,
+	oauthStates app.AppOAuthStateStorage,
+	identities app.AppIdentityStorage,
+	providers app.AppOAuthProviders,
+) *OAuthService {
+	return &OAuthService{
+		sessions:    sessions,
+		userStorage: userStorage,
+		oauthStates: oauthStates,
+		identities:  identities,
+		providers:   providers,
+	}
+}
+
+// OAuthStart begins a login with provider and returns the URL to redirect
+// the browser to, along with the state of the flow for the caller to bind
+// to the browser. With linkUserId set the callback links the external
+// account to that user instead of signing in.
+func (s OAuthService) OAuthStart(ctx context.Context, provider, linkUserId string) (string, string, error) {
+	p, ok := s.providers.Provider(provider)
+	if !ok {
+		return "", "", ErrUnknownOAuthProvider
+	}
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, oidc.S256Challenge(verifier))
+	if err != nil {
+		return "", "", fmt.Errorf("OAuthService OAuthStart: %w", err)
+	}
+	data := models.OAuthState{Provider: provider, Nonce: nonce, Verifier: verifier, UserId: linkUserId}
+	if err := s.oauthStates.Save(ctx, state, data, OAuthStateTTL); err != nil {
+		return "", "", fmt.Errorf("OAuthService OAuthStart: %w", err)
+	}
+	return authURL, state, nil
+}
+
+// OAuthCallback finishes the flow started by OAuthStart. It signs the user
+// in like Login does, so it may return MFARequiredError. When the flow was
+// started for linking, the identity is linked and no tokens are returned.
+//
+// A new external identity is attached to the local user with the same
+// email only if both sides have verified it; otherwise a new user is
+// created.
+func (s OAuthService) OAuthCallback(ctx context.Context, provider, state, code string, client models.ClientInfo) (*models.TokenDto, error) {
+	data, err := s.oauthStates.Consume(ctx, state)
+	if errors.Is(err, storage.ErrOAuthStateNotFound) {
+		return nil, ErrInvalidOAuthState
+	}
+	if err != nil {
+		return nil, fmt.Errorf("OAuthService OAuthCallback: %w", err)
+	}
+	if data.Provider != provider {
+		return nil, ErrInvalidOAuthState
+	}
+	p, ok := s.providers.Provider(provider)
+	if !ok {
+		return nil, ErrUnknownOAuthProvider
+	}
+	identity, err := p.Exchange(ctx, code, data.Verifier, data.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("OAuthService OAuthCallback: %w", err)
+	}
+
+	if data.UserId != "" {
+		return nil, s.linkIdentity(data.UserId, *identity)
+	}
+
+	user, err := s.oauthUser(ctx, *identity)
+	if err != nil {
+		return nil, err
+	}
+	if !s.sessions.restorable(user) {
+		return nil, ErrUserNotFound
+	}
+	if user.MFAEnabled {
+		challenge, err := s.sessions.issueActionToken(ctx, models.TokenTypeMFAChallenge, user.Id, "", mfaChallengeTTL)
+		if err != nil {
+			return nil, fmt.Errorf("OAuthService OAuthCallback mfa challenge: %w", err)
+		}
+		return nil, &MFARequiredError{Challenge: challenge}
+	}
+	if _, err := s.sessions.restoreIfDeleted(user); err != nil {
+		return nil, err
+	}
+	return s.sessions.startSession(ctx, models.UserCreateRes{Id: user.Id, Login: user.Login}, client)
+}
+
+// linkIdentity links identity to userId. Linking an identity the user
+// already has is a no-op.
+func (s OAuthService) linkIdentity(userId string, identity models.Identity) error {
+	err := s.identities.Link(userId, identity)
+	if !errors.Is(err, storage.ErrIdentityExists) {
+		return err
+	}
+	owner, err := s.identities.GetUser(identity.Provider, identity.Subject)
+	if err != nil {
+		return fmt.Errorf("OAuthService linkIdentity: %w", err)
+	}
+	if owner.Id != userId {
+		return ErrIdentityLinked
+	}
+	return nil
+}
+
+// oauthUser finds the user identity belongs to, linking or creating one on
+// first login.
+func (s OAuthService) oauthUser(ctx context.Context, identity models.Identity) (*models.User, error) {
+	user, err := s.identities.GetUser(identity.Provider, identity.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		return nil, fmt.Errorf("OAuthService oauthUser: %w", err)
+	}
+
+	email, err := normalizeEmail(identity.Email)
+	if err != nil || !identity.EmailVerified {
+		// An address the provider didn't verify proves nothing.
+		email = ""
+	}
+	if email != "" {
+		existing, err := s.userStorage.GetByEmail(email)
+		if err == nil {
+			// Without a verified local address anyone could have
+			// registered it to take over the external account's owner.
+			if !existing.EmailVerified {
+				return nil, ErrOAuthEmailConflict
+			}
+			if err := s.identities.Link(existing.Id, identity); err != nil {
+				return nil, fmt.Errorf("OAuthService oauthUser link: %w", err)
+			}
+			slog.Info("OAuthService linked identity by email", "user_id", existing.Id, "provider", identity.Provider)
+			return existing, nil
+		}
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			return nil, fmt.Errorf("OAuthService oauthUser: %w", err)
+		}
+	}
+
+	login, err := oauthLogin(identity.Provider)
+	if err != nil {
+		return nil, err
+	}
+	// The user has no password until they reset it. It is created along
+	// with its identity, so a failure can't leave an account behind that
+	// no one can sign in to.
+	user, err = s.identities.CreateUser(models.UserCreateDto{Login: login, Email: email}, identity)
+	if err != nil {
+		return nil, fmt.Errorf("OAuthService oauthUser create: %w", err)
+	}
+	return user, nil
+}
+
+func oauthLogin(provider string) (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("OAuthService oauthLogin: %w", err)
+	}
+	return provider + "_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+	"go-auth/pkg/oidc"
+	"go-auth/pkg/oidc/oidctest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type testOAuthStates struct {
+	states map[string]models.OAuthState
+}
+
+func newTestOAuthStates() *testOAuthStates {
+	return &testOAuthStates{states: map[string]models.OAuthState{}}
+}
+
+func (s *testOAuthStates) Save(ctx context.Context, state string, data models.OAuthState, ttl time.Duration) error {
+	s.states[state] = data
+	return nil
+}
+
+func (s *testOAuthStates) Consume(ctx context.Context, state string) (*models.OAuthState, error) {
+	data, ok := s.states[state]
+	if !ok {
+		return nil, storage.ErrOAuthStateNotFound
+	}
+	delete(s.states, state)
+	return &data, nil
+}
+
+// testIdentities is an in-memory AppIdentityStorage. users resolves linked
+// user ids to users; created records the users CreateUser made.
+type testIdentities struct {
+	links   map[string]string
+	users   map[string]*models.User
+	created []models.UserCreateDto
+}
+
+func newTestIdentities() *testIdentities {
+	return &testIdentities{links: map[string]string{}, users: map[string]*models.User{}}
+}
+
+func (s *testIdentities) GetUser(provider, subject string) (*models.User, error) {
+	userId, ok := s.links[provider+":"+subject]
+	if !ok {
+		return nil, storage.ErrUserNotFound
+	}
+	if user, ok := s.users[userId]; ok {
+		return user, nil
+	}
+	return &models.User{Id: userId}, nil
+}
+
+func (s *testIdentities) Link(userId string, identity models.Identity) error {
+	key := identity.Provider + ":" + identity.Subject
+	if _, ok := s.links[key]; ok {
+		return storage.ErrIdentityExists
+	}
+	s.links[key] = userId
+	return nil
+}
+
+// CreateUser creates users with id "456".
+func (s *testIdentities) CreateUser(user models.UserCreateDto, identity models.Identity) (*models.User, error) {
+	if err := s.Link("456", identity); err != nil {
+		return nil, err
+	}
+	s.created = append(s.created, user)
+	created := &models.User{Id: "456", Login: user.Login, Email: user.Email, EmailVerified: user.Email != ""}
+	s.users[created.Id] = created
+	return created, nil
+}
+
+func (d *testDeps) newOAuthService() *OAuthService {
+	return NewOAuthService(d.newSessions(), d.users, d.oauthStates, d.identities, d.providers)
+}
+
+type oauthTestEnv struct {
+	srv        *oidctest.Server
+	service    *OAuthService
+	users      *MockUserStorage
+	identities *testIdentities
+}
+
+func newOAuthTestEnv(t *testing.T) *oauthTestEnv {
+	t.Helper()
+	srv := oidctest.NewServer()
+	t.Cleanup(srv.Close)
+	provider := oidc.NewProvider(oidc.ProviderConfig{
+		Name:         "fake",
+		Issuer:       srv.Issuer(),
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  "http://localhost:8080/oauth/fake/callback",
+	}, srv.Client())
+
+	users := &MockUserStorage{}
+	mockTokenStorage := &MockTokenStorage{}
+	mockTokenStorage.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+	mockSessionStorage := &MockSessionStorage{}
+	mockSessionStorage.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+	identities := newTestIdentities()
+	d := newTestDeps(t)
+	d.users, d.tokens, d.sessions, d.identities, d.providers = users, mockTokenStorage, mockSessionStorage, identities, oidc.NewRegistry(provider)
+	service := d.newOAuthService()
+	return &oauthTestEnv{srv: srv, service: service, users: users, identities: identities}
+}
+
+// signIn runs the flow through the fake provider up to the callback.
+func (e *oauthTestEnv) signIn(t *testing.T, linkUserId string) (*models.TokenDto, error) {
+	t.Helper()
+	ctx := context.Background()
+	authURL, _, err := e.service.OAuthStart(ctx, "fake", linkUserId)
+	if !assert.NoError(t, err) {
+		return nil, err
+	}
+	code, state, err := e.srv.Authorize(authURL)
+	if !assert.NoError(t, err) {
+		return nil, err
+	}
+	return e.service.OAuthCallback(ctx, "fake", state, code, testClient)
+}
+
+func TestOAuthService_OAuthStart(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	ctx := context.Background()
+
+	_, _, err := env.service.OAuthStart(ctx, "unknown", "")
+	assert.ErrorIs(t, err, ErrUnknownOAuthProvider)
+
+	authURL, state, err := env.service.OAuthStart(ctx, "fake", "")
+	assert.NoError(t, err)
+	u, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	assert.NotEmpty(t, state)
+	assert.Equal(t, state, q.Get("state"))
+	assert.NotEmpty(t, q.Get("nonce"))
+	assert.Equal(t, "openid email profile", q.Get("scope"))
+}
+
+func TestOAuthService_OAuthCallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		user          oidctest.User
+		mockSetup     func(*MockUserStorage)
+		linked        map[string]string
+		expectedError error
+		expectedLink  string
+		// created is set when the first login creates a user with
+		// createdEmail.
+		created      bool
+		createdEmail string
+	}{
+		{
+			name:         "known identity signs in",
+			user:         oidctest.User{Subject: "sub-1", Email: "fake@example.com", EmailVerified: true},
+			mockSetup:    func(mus *MockUserStorage) {},
+			linked:       map[string]string{"fake:sub-1": "123"},
+			expectedLink: "123",
+		},
+		{
+			name: "verified email links to verified user",
+			user: oidctest.User{Subject: "sub-1", Email: "User@Example.com", EmailVerified: true},
+			mockSetup: func(mus *MockUserStorage) {
+				mus.On("GetByEmail", "user@example.com").Return(&models.User{Id: "123", Login: "testuser", EmailVerified: true}, nil)
+			},
+			expectedLink: "123",
+		},
+		{
+			name: "unverified local email is not taken over",
+			user: oidctest.User{Subject: "sub-1", Email: "user@example.com", EmailVerified: true},
+			mockSetup: func(mus *MockUserStorage) {
+				mus.On("GetByEmail", "user@example.com").Return(&models.User{Id: "123", Login: "testuser"}, nil)
+			},
+			expectedError: ErrOAuthEmailConflict,
+		},
+		{
+			name: "new user with verified email",
+			user: oidctest.User{Subject: "sub-1", Email: "new@example.com", EmailVerified: true},
+			mockSetup: func(mus *MockUserStorage) {
+				mus.On("GetByEmail", "new@example.com").Return((*models.User)(nil), storage.ErrUserNotFound)
+			},
+			expectedLink: "456",
+			created:      true,
+			createdEmail: "new@example.com",
+		},
+		{
+			name:         "unverified provider email is ignored",
+			user:         oidctest.User{Subject: "sub-1", Email: "user@example.com"},
+			mockSetup:    func(mus *MockUserStorage) {},
+			expectedLink: "456",
+			created:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOAuthTestEnv(t)
+			env.srv.User = tt.user
+			for k, v := range tt.linked {
+				env.identities.links[k] = v
+			}
+			tt.mockSetup(env.users)
+
+			tokens, err := env.signIn(t, "")
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, tokens)
+				assert.Empty(t, env.identities.links)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotEmpty(t, tokens.Access)
+			assert.Equal(t, tt.expectedLink, env.identities.links["fake:sub-1"])
+			if !tt.created {
+				assert.Empty(t, env.identities.created)
+			} else if assert.Len(t, env.identities.created, 1) {
+				created := env.identities.created[0]
+				assert.Equal(t, tt.createdEmail, created.Email)
+				assert.Empty(t, created.PasswordHash)
+				assert.Greater(t, len(created.Login), len("fake_"))
+			}
+			env.users.AssertExpectations(t)
+		})
+	}
+}
+
+func TestOAuthService_OAuthCallbackState(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	env.identities.links["fake:fake-sub-1"] = "123"
+	ctx := context.Background()
+
+	authURL, _, err := env.service.OAuthStart(ctx, "fake", "")
+	assert.NoError(t, err)
+	code, state, err := env.srv.Authorize(authURL)
+	assert.NoError(t, err)
+
+	_, err = env.service.OAuthCallback(ctx, "fake", "forged", code, testClient)
+	assert.ErrorIs(t, err, ErrInvalidOAuthState)
+	_, err = env.service.OAuthCallback(ctx, "other", state, code, testClient)
+	assert.ErrorIs(t, err, ErrInvalidOAuthState, "state is bound to its provider")
+	_, err = env.service.OAuthCallback(ctx, "fake", state, code, testClient)
+	assert.ErrorIs(t, err, ErrInvalidOAuthState, "state is one-time even after a mismatch")
+}
+
+func TestOAuthService_OAuthCallbackMFA(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	env.identities.links["fake:fake-sub-1"] = "123"
+	env.identities.users["123"] = &models.User{Id: "123", Login: "testuser", MFAEnabled: true}
+
+	tokens, err := env.signIn(t, "")
+	assert.Nil(t, tokens)
+	var mfa *MFARequiredError
+	assert.ErrorAs(t, err, &mfa)
+	assert.NotEmpty(t, mfa.Challenge)
+}
+
+func TestOAuthService_OAuthLink(t *testing.T) {
+	env := newOAuthTestEnv(t)
+
+	tokens, err := env.signIn(t, "123")
+	assert.NoError(t, err)
+	assert.Nil(t, tokens, "linking doesn't start a session")
+	assert.Equal(t, "123", env.identities.links["fake:fake-sub-1"])
+
+	_, err = env.signIn(t, "123")
+	assert.NoError(t, err, "linking twice is a no-op")
+
+	_, err = env.signIn(t, "456")
+	assert.ErrorIs(t, err, ErrIdentityLinked)
+}
+
+func TestAuthService_LoginWithoutPassword(t *testing.T) {
+	mockUserStorage := &MockUserStorage{}
+	mockUserStorage.On("GetByLogin", "fake_abc").Return(&models.User{Id: "456", Login: "fake_abc"}, nil)
+	service := newAccountTestService(t, mockUserStorage, &MockTokenStorage{}, &MockSessionStorage{}, &testMailer{})
+
+	_, err := service.Login(models.UserCreateReq{Login: "fake_abc", Password: "anything"}, testClient)
+	assert.ErrorIs(t, err, ErrWrongLoginOrPassword)
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+)
+
+const (
+	minLoginLength = 3
+	maxLoginLength = 20
+)
+
+// checkLogin returns what is wrong with a new login, or nil.
+func checkLogin(login string) []models.FieldError {
+	switch n := utf8.RuneCountInString(login); {
+	case n < minLoginLength:
+		return []models.FieldError{{Field: "login", Code: "too_short", Message: fmt.Sprintf("must be at least %d characters", minLoginLength)}}
+	case n > maxLoginLength:
+		return []models.FieldError{{Field: "login", Code: "too_long", Message: fmt.Sprintf("must be at most %d characters", maxLoginLength)}}
+	}
+	return nil
+}
+
+// checkNewPassword applies the password policy to a password being set
+// for login.
+func checkNewPassword(passwords app.AppPasswordPolicy, login, password string) error {
+	if fields := passwords.Check(login, password); len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go-auth/internal/models"
+	"go-auth/pkg/password"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// breachedPassword is on the breached list of newTestPasswordPolicy.
+const breachedPassword = "Tr0ub4dor&3"
+
+func newTestPasswordPolicy() *password.Checker {
+	// SHA-1 of breachedPassword.
+	list, err := password.LoadHashList(strings.NewReader("874572E7A5AE6A49466A6AC578B98ADBA78C6AA6:3\n"))
+	if err != nil {
+		panic(err)
+	}
+	return password.NewChecker(password.DefaultRules, list)
+}
+
+func TestAuthService_CreateValidation(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          models.UserCreateReq
+		expectedFields []string
+	}{
+		{
+			name:           "short login and password",
+			input:          models.UserCreateReq{Login: "ab", Password: "Ab1"},
+			expectedFields: []string{"login:too_short", "password:too_short"},
+		},
+		{
+			name:           "long login",
+			input:          models.UserCreateReq{Login: strings.Repeat("a", maxLoginLength+1), Password: "correct horse 42"},
+			expectedFields: []string{"login:too_long"},
+		},
+		{
+			name:           "common password",
+			input:          models.UserCreateReq{Login: "testuser", Password: "Password123"},
+			expectedFields: []string{"password:common"},
+		},
+		{
+			name:           "password contains login",
+			input:          models.UserCreateReq{Login: "testuser", Password: "TestUser2024"},
+			expectedFields: []string{"password:contains_login"},
+		},
+		{
+			name:           "single character class",
+			input:          models.UserCreateReq{Login: "testuser", Password: "abcdefghij"},
+			expectedFields: []string{"password:too_few_classes"},
+		},
+		{
+			name:           "breached password",
+			input:          models.UserCreateReq{Login: "testuser", Password: breachedPassword},
+			expectedFields: []string{"password:breached"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mus := &MockUserStorage{}
+			service := newAccountTestService(t, mus, &MockTokenStorage{}, &MockSessionStorage{}, &testMailer{})
+
+			_, err := service.Create(tt.input, testClient)
+
+			var verr *ValidationError
+			if assert.ErrorAs(t, err, &verr) {
+				var fields []string
+				for _, f := range verr.Fields {
+					fields = append(fields, f.Field+":"+f.Code)
+				}
+				assert.Equal(t, tt.expectedFields, fields)
+			}
+			assert.ErrorIs(t, err, ErrValidation)
+			mus.AssertNotCalled(t, "Save", mock.Anything)
+		})
+	}
+}
+
+func TestAuthService_ResetPasswordWeakKeepsLink(t *testing.T) {
+	user := &models.User{Id: "123", Login: "testuser", Email: "user@example.com", EmailVerified: true}
+	mockUserStorage := &MockUserStorage{}
+	mockUserStorage.On("GetByEmail", "user@example.com").Return(user, nil)
+	mockUserStorage.On("GetById", "123").Return(user, nil)
+	mockSessionStorage := &MockSessionStorage{}
+	mailer := &testMailer{}
+	service := newAccountTestService(t, mockUserStorage, &MockTokenStorage{}, mockSessionStorage, mailer)
+	ctx := context.Background()
+
+	assert.NoError(t, service.ForgotPassword(ctx, "user@example.com"))
+	token := mailedToken(t, mailer)
+
+	assert.ErrorIs(t, service.ResetPassword(ctx, token, "qwerty"), ErrValidation)
+	assert.ErrorIs(t, service.ResetPassword(ctx, token, "testuser-2024"), ErrValidation)
+
+	mockUserStorage.On("Update", mock.Anything).Return(nil).Once()
+	mockSessionStorage.On("ListByUser", mock.Anything, "123").Return([]models.Session{}, nil)
+	assert.NoError(t, service.ResetPassword(ctx, token, "correct horse 42"), "rejected passwords don't use up the link")
+	mockUserStorage.AssertExpectations(t)
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+)
+
+const (
+	maxDisplayNameLength = 64
+	maxAvatarURLLength   = 2048
+)
+
+// ProfileService lets users see and change their account, its password
+// included, and delete it.
+type ProfileService struct {
+	sessions     *Sessions
+	userStorage  app.AppUserStorage
+	sessionStore app.AppSessionStorage
+	hasher       app.AppPasswordHasher
+	loginGuard   app.AppLoginGuard
+	passwords    app.AppPasswordPolicy
+	cfg          app.AppConfig
+	audit        app.AppAuditLog
+}
+
+func NewProfileService(
+	sessions *Sessions,
+	userStorage app.AppUserStorage,
+	sessionStore app.AppSessionStorage,
+	hasher app.AppPasswordHasher,
+	loginGuard app.AppLoginGuard,
+	passwords app.AppPasswordPolicy,
+	cfg app.AppConfig,
+	audit app.AppAuditLog,
+) *ProfileService {
+	return &ProfileService{
+		sessions:     sessions,
+		userStorage:  userStorage,
+		sessionStore: sessionStore,
+		hasher:       hasher,
+		loginGuard:   loginGuard,
+		passwords:    passwords,
+		cfg:          cfg,
+		audit:        audit,
+	}
+}
+
+func (s ProfileService) GetProfile(ctx context.Context, userId string) (*models.Profile, error) {
+	user, err := s.userStorage.GetById(userId)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ProfileService GetProfile: %w", err)
+	}
+	return &models.Profile{
+		Id:            user.Id,
+		Login:         user.Login,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		DisplayName:   user.DisplayName,
+		AvatarUrl:     user.AvatarUrl,
+		MfaEnabled:    user.MFAEnabled,
+		HasPassword:   user.PasswordHash != "",
+		CreatedAt:     user.CreatedAt,
+	}, nil
+}
+
+// UpdateProfile changes the fields set in req. A new email has to be
+// verified again; the link is sent to it.
+func (s ProfileService) UpdateProfile(ctx context.Context, userId string, req models.ProfileUpdateReq) (*models.Profile, error) {
+	var dto models.ProfileUpdateDto
+	if req.DisplayName != nil {
+		name := strings.TrimSpace(*req.DisplayName)
+		if utf8.RuneCountInString(name) > maxDisplayNameLength {
+			return nil, ErrInvalidDisplayName
+		}
+		dto.DisplayName = &name
+	}
+	if req.AvatarUrl != nil {
+		avatar := strings.TrimSpace(*req.AvatarUrl)
+		if avatar != "" && !validAvatarURL(avatar) {
+			return nil, ErrInvalidAvatarURL
+		}
+		dto.AvatarUrl = &avatar
+	}
+
+	current, err := s.GetProfile(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	var newEmail string
+	if req.Email != nil {
+		email, err := normalizeEmail(*req.Email)
+		if err != nil {
+			return nil, err
+		}
+		if email != current.Email {
+			if email != "" {
+				_, err := s.userStorage.GetByEmail(email)
+				if err == nil {
+					return nil, ErrEmailExists
+				}
+				if !errors.Is(err, storage.ErrUserNotFound) {
+					return nil, fmt.Errorf("ProfileService UpdateProfile check email: %w", err)
+				}
+			}
+			dto.Email = &email
+			newEmail = email
+		}
+	}
+
+	err = s.userStorage.UpdateProfile(userId, dto)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ProfileService UpdateProfile: %w", err)
+	}
+	if newEmail != "" {
+		if err := s.sessions.sendVerification(ctx, models.UserCreateRes{Id: userId, Login: current.Login}, newEmail); err != nil {
+			slog.Error("ProfileService UpdateProfile send verification: " + err.Error())
+		}
+	}
+	return s.GetProfile(ctx, userId)
+}
+
+func validAvatarURL(raw string) bool {
+	if len(raw) > maxAvatarURLLength {
+		return false
+	}
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// ChangePassword sets a new password and ends every session but the one
+// the change was made from. Users created by social login have no password
+// yet and may set one without currentPassword.
+func (s ProfileService) ChangePassword(ctx context.Context, userId, sessionId, currentPassword, newPassword string) error {
+	if newPassword == "" {
+		return ErrPasswordRequired
+	}
+	user, err := s.checkPassword(ctx, userId, currentPassword)
+	if err != nil {
+		return err
+	}
+	if err := checkNewPassword(s.passwords, user.Login, newPassword); err != nil {
+		return err
+	}
+	hash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return fmt.Errorf("ProfileService ChangePassword hash password: %w", err)
+	}
+	if err := s.userStorage.Update(models.UserCreateDto{Login: user.Login, PasswordHash: hash}); err != nil {
+		return fmt.Errorf("ProfileService ChangePassword: %w", err)
+	}
+
+	sessions, err := s.sessionStore.ListByUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("ProfileService ChangePassword: %w", err)
+	}
+	for _, session := range sessions {
+		if session.Id == sessionId {
+			continue
+		}
+		if err := s.sessions.endSession(ctx, userId, session.Id); err != nil {
+			return fmt.Errorf("ProfileService ChangePassword: %w", err)
+		}
+	}
+	slog.Info("ProfileService password changed", "user_id", userId)
+	s.audit.Record(ctx, models.AuthEvent{Type: models.AuthEventPasswordChanged, UserId: userId, SessionId: sessionId})
+	return nil
+}
+
+// DeleteAccount signs the user out everywhere and schedules the account
+// for deletion. Logging in during the grace period restores it.
+func (s ProfileService) DeleteAccount(ctx context.Context, userId, password string) error {
+	if _, err := s.checkPassword(ctx, userId, password); err != nil {
+		return err
+	}
+	err := s.userStorage.SoftDelete(userId)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("ProfileService DeleteAccount: %w", err)
+	}
+	if err := s.sessions.revokeAllSessions(ctx, userId); err != nil {
+		return fmt.Errorf("ProfileService DeleteAccount: %w", err)
+	}
+	slog.Info("ProfileService account deleted", "user_id", userId)
+	return nil
+}
+
+// PurgeDeletedAccounts removes accounts whose grace period is over.
+func (s ProfileService) PurgeDeletedAccounts(ctx context.Context) (int64, error) {
+	n, err := s.userStorage.PurgeDeleted(time.Now().Add(-s.cfg.GetConfig().AccountDeletionGrace))
+	if err != nil {
+		return 0, fmt.Errorf("ProfileService PurgeDeletedAccounts: %w", err)
+	}
+	return n, nil
+}
+
+// checkPassword confirms a sensitive change with the user's password, if
+// they have one. Wrong passwords lock the confirmation for the account; only
+// the session holder can get it locked.
+func (s ProfileService) checkPassword(ctx context.Context, userId, password string) (*models.User, error) {
+	user, err := s.userStorage.GetById(userId)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ProfileService checkPassword: %w", err)
+	}
+	if user.PasswordHash == "" {
+		return user, nil
+	}
+	if retryAfter, err := s.loginGuard.Check(ctx, passwordGuardKey(user.Id)); err != nil {
+		slog.Error("ProfileService checkPassword lockout check: " + err.Error())
+	} else if retryAfter > 0 {
+		return nil, &RetryAfterError{RetryAfter: retryAfter}
+	}
+	ok, err := s.hasher.Verify(password, user.PasswordHash)
+	if err != nil {
+		return nil, fmt.Errorf("ProfileService checkPassword verify password: %w", err)
+	}
+	if !ok {
+		if _, err := s.loginGuard.Fail(ctx, passwordGuardKey(user.Id)); err != nil {
+			slog.Error("ProfileService checkPassword lockout fail: " + err.Error())
+		}
+		return nil, ErrWrongPassword
+	}
+	return user, nil
+}
+
+func passwordGuardKey(userId string) string { return "password:" + userId }
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+	"go-auth/pkg/hasher"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func (d *testDeps) newProfileService() *ProfileService {
+	return NewProfileService(d.newSessions(), d.users, d.sessions, d.hasher, d.loginGuard, d.passwords, d.cfg, d.audit)
+}
+
+func newProfileTestService(t *testing.T, mus *MockUserStorage, mts *MockTokenStorage, mss *MockSessionStorage, mailer *testMailer) *ProfileService {
+	d := newTestDeps(t)
+	d.users, d.tokens, d.sessions, d.mailer = mus, mts, mss, mailer
+	return d.newProfileService()
+}
+
+func TestProfileService_UpdateProfile(t *testing.T) {
+	user := &models.User{Id: "123", Login: "testuser", Email: "old@example.com", EmailVerified: true}
+
+	tests := []struct {
+		name          string
+		req           models.ProfileUpdateReq
+		mockSetup     func(*MockUserStorage)
+		expectedError error
+		wantMail      string
+	}{
+		{
+			name: "display name and avatar",
+			req:  models.ProfileUpdateReq{DisplayName: strPtr("  New Name "), AvatarUrl: strPtr("https://cdn.example.com/a.png")},
+			mockSetup: func(mus *MockUserStorage) {
+				mus.On("UpdateProfile", "123", models.ProfileUpdateDto{
+					DisplayName: strPtr("New Name"),
+					AvatarUrl:   strPtr("https://cdn.example.com/a.png"),
+				}).Return(nil)
+			},
+		},
+		{
+			name: "new email is verified again",
+			req:  models.ProfileUpdateReq{Email: strPtr("New@Example.com")},
+			mockSetup: func(mus *MockUserStorage) {
+				mus.On("GetByEmail", "new@example.com").Return((*models.User)(nil), storage.ErrUserNotFound)
+				mus.On("UpdateProfile", "123", models.ProfileUpdateDto{Email: strPtr("new@example.com")}).Return(nil)
+			},
+			wantMail: "new@example.com",
+		},
+		{
+			name: "same email is not changed",
+			req:  models.ProfileUpdateReq{Email: strPtr("old@example.com")},
+			mockSetup: func(mus *MockUserStorage) {
+				mus.On("UpdateProfile", "123", models.ProfileUpdateDto{}).Return(nil)
+			},
+		},
+		{
+			name: "email is cleared",
+			req:  models.ProfileUpdateReq{Email: strPtr("")},
+			mockSetup: func(mus *MockUserStorage) {
+				mus.On("UpdateProfile", "123", models.ProfileUpdateDto{Email: strPtr("")}).Return(nil)
+			},
+		},
+		{
+			name: "email in use",
+			req:  models.ProfileUpdateReq{Email: strPtr("taken@example.com")},
+			mockSetup: func(mus *MockUserStorage) {
+				mus.On("GetByEmail", "taken@example.com").Return(&models.User{Id: "456"}, nil)
+			},
+			expectedError: ErrEmailExists,
+		},
+		{
+			name:          "invalid email",
+			req:           models.ProfileUpdateReq{Email: strPtr("nope")},
+			mockSetup:     func(mus *MockUserStorage) {},
+			expectedError: ErrInvalidEmail,
+		},
+		{
+			name:          "javascript avatar",
+			req:           models.ProfileUpdateReq{AvatarUrl: strPtr("javascript:alert(1)")},
+			mockSetup:     func(mus *MockUserStorage) {},
+			expectedError: ErrInvalidAvatarURL,
+		},
+		{
+			name:          "display name too long",
+			req:           models.ProfileUpdateReq{DisplayName: strPtr(string(make([]rune, maxDisplayNameLength+1)))},
+			mockSetup:     func(mus *MockUserStorage) {},
+			expectedError: ErrInvalidDisplayName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserStorage := &MockUserStorage{}
+			mockUserStorage.On("GetById", "123").Return(user, nil)
+			tt.mockSetup(mockUserStorage)
+			mailer := &testMailer{}
+			service := newProfileTestService(t, mockUserStorage, &MockTokenStorage{}, &MockSessionStorage{}, mailer)
+
+			profile, err := service.UpdateProfile(context.Background(), "123", tt.req)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, profile)
+				mockUserStorage.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "123", profile.Id)
+			if tt.wantMail != "" {
+				assert.Len(t, mailer.sent, 1)
+				assert.Equal(t, tt.wantMail, mailer.sent[0].To)
+			} else {
+				assert.Empty(t, mailer.sent)
+			}
+			mockUserStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProfileService_ChangePassword(t *testing.T) {
+	ctx := context.Background()
+	hash := mustHash(t, hasher.NewArgon2id(testArgon2Params), "password123")
+
+	t.Run("ends the other sessions", func(t *testing.T) {
+		mockUserStorage := &MockUserStorage{}
+		mockUserStorage.On("GetById", "123").Return(&models.User{Id: "123", Login: "testuser", PasswordHash: hash}, nil)
+		var newHash string
+		mockUserStorage.On("Update", mock.MatchedBy(func(u models.UserCreateDto) bool {
+			newHash = u.PasswordHash
+			return u.Login == "testuser"
+		})).Return(nil)
+		mockTokenStorage := &MockTokenStorage{}
+		mockTokenStorage.On("RevokeRefreshFamily", mock.Anything, "s2").Return(nil).Once()
+		mockSessionStorage := &MockSessionStorage{}
+		mockSessionStorage.On("ListByUser", mock.Anything, "123").Return([]models.Session{{Id: "s1", UserId: "123"}, {Id: "s2", UserId: "123"}}, nil)
+		mockSessionStorage.On("Delete", mock.Anything, "123", "s2").Return(nil).Once()
+		service := newProfileTestService(t, mockUserStorage, mockTokenStorage, mockSessionStorage, &testMailer{})
+
+		assert.NoError(t, service.ChangePassword(ctx, "123", "s1", "password123", "newPassword123"))
+		ok, err := newTestHasher().Verify("newPassword123", newHash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		mockTokenStorage.AssertExpectations(t)
+		mockSessionStorage.AssertExpectations(t)
+	})
+
+	t.Run("wrong current password", func(t *testing.T) {
+		mockUserStorage := &MockUserStorage{}
+		mockUserStorage.On("GetById", "123").Return(&models.User{Id: "123", Login: "testuser", PasswordHash: hash}, nil)
+		service := newProfileTestService(t, mockUserStorage, &MockTokenStorage{}, &MockSessionStorage{}, &testMailer{})
+
+		for i := 0; i < 5; i++ {
+			assert.ErrorIs(t, service.ChangePassword(ctx, "123", "s1", "wrong", "newPassword123"), ErrWrongPassword)
+		}
+		var tooMany *RetryAfterError
+		assert.ErrorAs(t, service.ChangePassword(ctx, "123", "s1", "password123", "newPassword123"), &tooMany,
+			"wrong passwords lock the confirmation")
+		mockUserStorage.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("first password of a social login user", func(t *testing.T) {
+		mockUserStorage := &MockUserStorage{}
+		mockUserStorage.On("GetById", "123").Return(&models.User{Id: "123", Login: "fake_abc"}, nil)
+		mockUserStorage.On("Update", mock.Anything).Return(nil)
+		mockSessionStorage := &MockSessionStorage{}
+		mockSessionStorage.On("ListByUser", mock.Anything, "123").Return([]models.Session{{Id: "s1", UserId: "123"}}, nil)
+		service := newProfileTestService(t, mockUserStorage, &MockTokenStorage{}, mockSessionStorage, &testMailer{})
+
+		assert.NoError(t, service.ChangePassword(ctx, "123", "s1", "", "newPassword123"))
+	})
+
+	t.Run("weak new password", func(t *testing.T) {
+		mockUserStorage := &MockUserStorage{}
+		mockUserStorage.On("GetById", "123").Return(&models.User{Id: "123", Login: "testuser", PasswordHash: hash}, nil)
+		service := newProfileTestService(t, mockUserStorage, &MockTokenStorage{}, &MockSessionStorage{}, &testMailer{})
+
+		assert.ErrorIs(t, service.ChangePassword(ctx, "123", "s1", "password123", "qwerty123"), ErrValidation)
+		mockUserStorage.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("empty new password", func(t *testing.T) {
+		service := newProfileTestService(t, &MockUserStorage{}, &MockTokenStorage{}, &MockSessionStorage{}, &testMailer{})
+		assert.ErrorIs(t, service.ChangePassword(ctx, "123", "s1", "password123", ""), ErrPasswordRequired)
+	})
+}
+
+func TestProfileService_DeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	hash := mustHash(t, hasher.NewArgon2id(testArgon2Params), "password123")
+	user := &models.User{Id: "123", Login: "testuser", PasswordHash: hash}
+
+	mockUserStorage := &MockUserStorage{}
+	mockUserStorage.On("GetById", "123").Return(user, nil)
+	mockTokenStorage := &MockTokenStorage{}
+	mockTokenStorage.On("RevokeRefreshFamily", mock.Anything, "s1").Return(nil)
+	mockSessionStorage := &MockSessionStorage{}
+	mockSessionStorage.On("ListByUser", mock.Anything, "123").Return([]models.Session{{Id: "s1", UserId: "123"}}, nil)
+	mockSessionStorage.On("Delete", mock.Anything, "123", "s1").Return(nil)
+	service := newProfileTestService(t, mockUserStorage, mockTokenStorage, mockSessionStorage, &testMailer{})
+
+	assert.ErrorIs(t, service.DeleteAccount(ctx, "123", "wrong"), ErrWrongPassword)
+	mockUserStorage.AssertNotCalled(t, "SoftDelete", mock.Anything)
+
+	mockUserStorage.On("SoftDelete", "123").Return(nil).Once()
+	assert.NoError(t, service.DeleteAccount(ctx, "123", "password123"))
+	mockUserStorage.AssertExpectations(t)
+	mockSessionStorage.AssertExpectations(t)
+}
+
+func TestAuthService_LoginRestoresDeletedAccount(t *testing.T) {
+	hash := mustHash(t, hasher.NewArgon2id(testArgon2Params), "password123")
+
+	tests := []struct {
+		name          string
+		deletedAgo    time.Duration
+		expectedError error
+	}{
+		{
+			name:       "within grace period",
+			deletedAgo: 24 * time.Hour,
+		},
+		{
+			name:          "after grace period",
+			deletedAgo:    31 * 24 * time.Hour,
+			expectedError: ErrWrongLoginOrPassword,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deletedAt := time.Now().Add(-tt.deletedAgo)
+			mockUserStorage := &MockUserStorage{}
+			mockUserStorage.On("GetByLogin", "testuser").Return(&models.User{Id: "123", Login: "testuser", PasswordHash: hash, DeletedAt: &deletedAt}, nil)
+			mockTokenStorage := &MockTokenStorage{}
+			mockTokenStorage.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+			mockSessionStorage := &MockSessionStorage{}
+			mockSessionStorage.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+			if tt.expectedError == nil {
+				mockUserStorage.On("Restore", "123").Return(nil).Once()
+			}
+			service := newAccountTestService(t, mockUserStorage, mockTokenStorage, mockSessionStorage, &testMailer{})
+
+			tokens, err := service.Login(models.UserCreateReq{Login: "testuser", Password: "password123"}, testClient)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, tokens)
+				mockUserStorage.AssertNotCalled(t, "Restore", mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, tokens)
+			mockUserStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProfileService_PurgeDeletedAccounts(t *testing.T) {
+	mockUserStorage := &MockUserStorage{}
+	mockUserStorage.On("PurgeDeleted", mock.MatchedBy(func(before time.Time) bool {
+		return time.Until(before.Add(30*24*time.Hour)).Abs() < time.Minute
+	})).Return(int64(2), nil)
+	service := newProfileTestService(t, mockUserStorage, &MockTokenStorage{}, &MockSessionStorage{}, &testMailer{})
+
+	n, err := service.PurgeDeletedAccounts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+)
+
+// RoleService manages the roles of users. Their tokens pick the changes up
+// on the next refresh.
+type RoleService struct {
+	roles app.AppRoleStorage
+}
+
+func NewRoleService(roles app.AppRoleStorage) *RoleService {
+	return &RoleService{roles: roles}
+}
+
+func (s RoleService) ListRoles(ctx context.Context) ([]models.Role, error) {
+	roles, err := s.roles.ListRoles()
+	if err != nil {
+		return nil, fmt.Errorf("RoleService ListRoles: %w", err)
+	}
+	return roles, nil
+}
+
+func (s RoleService) UserGrants(ctx context.Context, userId string) (*models.Grants, error) {
+	grants, err := s.roles.GetGrants(userId)
+	if err != nil {
+		return nil, fmt.Errorf("RoleService UserGrants: %w", err)
+	}
+	return grants, nil
+}
+
+// AssignRole gives role to the user. Like RevokeRole it takes effect in
+// the user's tokens on their next refresh, i.e. within the access token TTL.
+func (s RoleService) AssignRole(ctx context.Context, userId, role string) error {
+	err := s.roles.AssignRole(userId, role)
+	switch {
+	case errors.Is(err, storage.ErrRoleNotFound):
+		return ErrRoleNotFound
+	case errors.Is(err, storage.ErrUserNotFound):
+		return ErrUserNotFound
+	case err != nil:
+		return fmt.Errorf("RoleService AssignRole: %w", err)
+	}
+	slog.Info("RoleService role assigned", "user_id", userId, "role", role)
+	return nil
+}
+
+func (s RoleService) RevokeRole(ctx context.Context, userId, role string) error {
+	if err := s.roles.RevokeRole(userId, role); err != nil {
+		return fmt.Errorf("RoleService RevokeRole: %w", err)
+	}
+	slog.Info("RoleService role revoked", "user_id", userId, "role", role)
+	return nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+	"go-auth/pkg/authz"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testRoles is an in-memory AppRoleStorage with the roles seeded by the
+// migration. Unknown users have the default role.
+type testRoles struct {
+	permissions map[string][]string
+	users       map[string][]string
+}
+
+func newTestRoles() *testRoles {
+	return &testRoles{
+		permissions: map[string][]string{
+			authz.RoleAdmin:     {authz.PermAuditRead, authz.PermChatModerate, authz.PermClientsManage, authz.PermGameAdmin, authz.PermGamePlay, authz.PermRolesManage, authz.PermUsersRead},
+			authz.RoleModerator: {authz.PermAuditRead, authz.PermChatModerate, authz.PermGamePlay, authz.PermUsersRead},
+			authz.RolePlayer:    {authz.PermGamePlay},
+		},
+		users: map[string][]string{},
+	}
+}
+
+func (s *testRoles) rolesOf(userId string) []string {
+	if roles, ok := s.users[userId]; ok {
+		return roles
+	}
+	return []string{authz.DefaultRole}
+}
+
+func (s *testRoles) GetGrants(userId string) (*models.Grants, error) {
+	grants := &models.Grants{Roles: slices.Sorted(slices.Values(s.rolesOf(userId))), Permissions: []string{}}
+	for _, role := range grants.Roles {
+		for _, p := range s.permissions[role] {
+			if !slices.Contains(grants.Permissions, p) {
+				grants.Permissions = append(grants.Permissions, p)
+			}
+		}
+	}
+	slices.Sort(grants.Permissions)
+	return grants, nil
+}
+
+func (s *testRoles) ListRoles() ([]models.Role, error) {
+	var roles []models.Role
+	for name, perms := range s.permissions {
+		roles = append(roles, models.Role{Name: name, Permissions: perms})
+	}
+	return roles, nil
+}
+
+func (s *testRoles) AssignRole(userId, role string) error {
+	if _, ok := s.permissions[role]; !ok {
+		return storage.ErrRoleNotFound
+	}
+	if userId == "missing" {
+		return storage.ErrUserNotFound
+	}
+	roles := s.rolesOf(userId)
+	if !slices.Contains(roles, role) {
+		s.users[userId] = append(slices.Clone(roles), role)
+	}
+	return nil
+}
+
+func (s *testRoles) RevokeRole(userId, role string) error {
+	s.users[userId] = slices.DeleteFunc(slices.Clone(s.rolesOf(userId)), func(r string) bool { return r == role })
+	return nil
+}
+
+func (d *testDeps) newRoleService() *RoleService {
+	return NewRoleService(d.roles)
+}
+
+func TestSessions_TokensCarryGrants(t *testing.T) {
+	mockTokenStorage := &MockTokenStorage{}
+	mockTokenStorage.On("SaveRefreshFamily", mock.Anything, mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+	mockSessionStorage := &MockSessionStorage{}
+	mockSessionStorage.On("Save", mock.Anything, mock.Anything, refreshTokenTTL).Return(nil)
+	d := newTestDeps(t)
+	d.tokens, d.sessions = mockTokenStorage, mockSessionStorage
+	service := d.newSessions()
+	ctx := context.Background()
+
+	assert.NoError(t, d.newRoleService().AssignRole(ctx, "123", authz.RoleModerator))
+	tokens, err := service.startSession(ctx, models.UserCreateRes{Id: "123", Login: "testuser"}, testClient)
+	assert.NoError(t, err)
+
+	access, err := service.parseToken(tokens.Access)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{authz.RoleModerator, authz.RolePlayer}, access.Roles)
+	assert.Equal(t, []string{authz.PermAuditRead, authz.PermChatModerate, authz.PermGamePlay, authz.PermUsersRead}, access.Permissions)
+
+	refresh, err := service.parseToken(tokens.Refresh)
+	assert.NoError(t, err)
+	assert.Empty(t, refresh.Roles, "only access tokens carry grants")
+	assert.Empty(t, refresh.Permissions)
+}
+
+func TestRoleService_AssignRole(t *testing.T) {
+	tests := []struct {
+		name          string
+		userId        string
+		role          string
+		expectedError error
+		expectedRoles []string
+	}{
+		{
+			name:          "assign",
+			userId:        "123",
+			role:          authz.RoleAdmin,
+			expectedRoles: []string{authz.RoleAdmin, authz.RolePlayer},
+		},
+		{
+			name:          "assign twice is a no-op",
+			userId:        "123",
+			role:          authz.RolePlayer,
+			expectedRoles: []string{authz.RolePlayer},
+		},
+		{
+			name:          "unknown role",
+			userId:        "123",
+			role:          "superuser",
+			expectedError: ErrRoleNotFound,
+		},
+		{
+			name:          "unknown user",
+			userId:        "missing",
+			role:          authz.RoleAdmin,
+			expectedError: ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestDeps(t).newRoleService()
+			ctx := context.Background()
+
+			err := service.AssignRole(ctx, tt.userId, tt.role)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			grants, err := service.UserGrants(ctx, tt.userId)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRoles, grants.Roles)
+		})
+	}
+}
+
+func TestRoleService_RevokeRole(t *testing.T) {
+	service := newTestDeps(t).newRoleService()
+	ctx := context.Background()
+
+	assert.NoError(t, service.AssignRole(ctx, "123", authz.RoleAdmin))
+	assert.NoError(t, service.RevokeRole(ctx, "123", authz.RoleAdmin))
+	grants, err := service.UserGrants(ctx, "123")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{authz.RolePlayer}, grants.Roles)
+	assert.Equal(t, []string{authz.PermGamePlay}, grants.Permissions)
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"go-auth/internal/storage"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Sessions is what the auth services have in common: it starts and ends
+// sessions, signs and parses tokens and issues the one-time tokens whose
+// links are mailed to users. It is provided to the services only, not to
+// handlers.
+type Sessions struct {
+	userStorage  app.AppUserStorage
+	tokenStore   app.AppTokenStorage
+	sessionStore app.AppSessionStorage
+	keys         app.AppKeyRing
+	actionTokens app.AppActionTokenStorage
+	roles        app.AppRoleStorage
+	mailer       app.AppMailer
+	cfg          app.AppConfig
+	audit        app.AppAuditLog
+}
+
+func NewSessions(
+	userStorage app.AppUserStorage,
+	tokenStore app.AppTokenStorage,
+	sessionStore app.AppSessionStorage,
+	keys app.AppKeyRing,
+	actionTokens app.AppActionTokenStorage,
+	roles app.AppRoleStorage,
+	mailer app.AppMailer,
+	cfg app.AppConfig,
+	audit app.AppAuditLog,
+) *Sessions {
+	return &Sessions{
+		userStorage:  userStorage,
+		tokenStore:   tokenStore,
+		sessionStore: sessionStore,
+		keys:         keys,
+		actionTokens: actionTokens,
+		roles:        roles,
+		mailer:       mailer,
+		cfg:          cfg,
+		audit:        audit,
+	}
+}
+
+// revokeAllSessions ends every session of the user.
+func (s Sessions) revokeAllSessions(ctx context.Context, userId string) error {
+	sessions, err := s.sessionStore.ListByUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("Sessions revokeAllSessions: %w", err)
+	}
+	for _, session := range sessions {
+		if err := s.endSession(ctx, userId, session.Id); err != nil {
+			return fmt.Errorf("Sessions revokeAllSessions: %w", err)
+		}
+	}
+	return nil
+}
+
+// endSession drops the refresh family first so the session can't be
+// resurrected by a refresh racing with the revocation.
+func (s Sessions) endSession(ctx context.Context, userId, sessionId string) error {
+	if err := s.tokenStore.RevokeRefreshFamily(ctx, sessionId); err != nil {
+		return err
+	}
+	return s.sessionStore.Delete(ctx, userId, sessionId)
+}
+
+// startSession issues the first token pair of a new refresh family and
+// records the session it belongs to.
+func (s Sessions) startSession(ctx context.Context, user models.UserCreateRes, client models.ClientInfo) (*models.TokenDto, error) {
+	family := uuid.NewString()
+	tokens, jti, err := s.issueTokens(user, family)
+	if err != nil {
+		return nil, err
+	}
+	refreshTokenTTL := s.cfg.GetConfig().RefreshTokenTTL
+	if err := s.tokenStore.SaveRefreshFamily(ctx, family, jti, refreshTokenTTL); err != nil {
+		return nil, fmt.Errorf("Sessions save refresh family: %w", err)
+	}
+	now := time.Now()
+	session := models.Session{
+		Id:        family,
+		UserId:    user.Id,
+		Device:    client.Device,
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+		CreatedAt: now,
+		LastSeen:  now,
+	}
+	if err := s.sessionStore.Save(ctx, session, refreshTokenTTL); err != nil {
+		return nil, fmt.Errorf("Sessions save session: %w", err)
+	}
+	event := clientEvent(models.AuthEventLoginSucceeded, user.Id, client)
+	event.SessionId = family
+	s.audit.Record(ctx, event)
+	return tokens, nil
+}
+
+// issueTokens returns an access/refresh pair and the jti of the refresh token.
+// The access token carries the user's current roles, so role changes reach
+// clients with the next refresh.
+func (s Sessions) issueTokens(user models.UserCreateRes, family string) (*models.TokenDto, string, error) {
+	grants, err := s.roles.GetGrants(user.Id)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get user grants: %w", err)
+	}
+	cfg := s.cfg.GetConfig()
+	access, _, err := s.signJWT(models.TokenClaims{
+		Type:        models.TokenTypeAccess,
+		Family:      family,
+		Roles:       grants.Roles,
+		Permissions: grants.Permissions,
+	}, user.Id, cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate access token: %w", err)
+	}
+	refresh, jti, err := s.generateJWT(user, models.TokenTypeRefresh, family, cfg.RefreshTokenTTL)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return &models.TokenDto{Access: access, Refresh: refresh, ExpiresIn: cfg.AccessTokenTTL}, jti, nil
+}
+
+func (s Sessions) generateJWT(user models.UserCreateRes, typ, family string, ttl time.Duration) (string, string, error) {
+	return s.signJWT(models.TokenClaims{Type: typ, Family: family}, user.Id, ttl)
+}
+
+// signJWT fills in the registered claims and signs claims. It returns the
+// token and its jti.
+func (s Sessions) signJWT(claims models.TokenClaims, subject string, ttl time.Duration) (string, string, error) {
+	now := time.Now()
+	jti := uuid.NewString()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti,
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+	signed, err := s.keys.Sign(claims)
+	if err != nil {
+		return "", "", err
+	}
+	return signed, jti, nil
+}
+
+func (s Sessions) parseToken(tokenString string) (*models.TokenClaims, error) {
+	claims := &models.TokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, s.keys.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// sendVerification mails a confirmation link for email.
+func (s Sessions) sendVerification(ctx context.Context, user models.UserCreateRes, email string) error {
+	token, err := s.issueActionToken(ctx, models.TokenTypeEmailVerify, user.Id, email, emailVerifyTokenTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, models.Mail{
+		To:      email,
+		Subject: "Confirm your email",
+		Body: "Hi " + user.Login + ",\n\n" +
+			"please confirm your email address by opening the link below:\n" +
+			s.publicLink("/email/verify", token),
+	})
+}
+
+// issueActionToken signs a one-time token and remembers its jti, replacing
+// any earlier token of the same purpose.
+func (s Sessions) issueActionToken(ctx context.Context, typ, userId, email string, ttl time.Duration) (string, error) {
+	token, jti, err := s.generateJWT(models.UserCreateRes{Id: userId}, typ, "", ttl)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate %s token: %w", typ, err)
+	}
+	err = s.actionTokens.Save(ctx, typ, models.ActionToken{Jti: jti, UserId: userId, Email: email}, ttl)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s Sessions) consumeActionToken(ctx context.Context, typ, token string) (*models.ActionToken, error) {
+	claims, err := s.parseToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidActionToken, err)
+	}
+	if claims.Type != typ {
+		return nil, ErrInvalidTokenType
+	}
+	action, err := s.actionTokens.Consume(ctx, typ, claims.Subject, claims.ID)
+	if errors.Is(err, storage.ErrActionTokenNotFound) {
+		return nil, ErrInvalidActionToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Sessions consume %s token: %w", typ, err)
+	}
+	return action, nil
+}
+
+func (s Sessions) publicLink(path, token string) string {
+	return strings.TrimRight(s.cfg.GetConfig().PublicURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// restorable reports whether user may still sign in: the account is not
+// deleted, or its deletion is within the grace period.
+func (s Sessions) restorable(user *models.User) bool {
+	return user.DeletedAt == nil || time.Since(*user.DeletedAt) <= s.cfg.GetConfig().AccountDeletionGrace
+}
+
+// restoreIfDeleted cancels a pending deletion when the user signs in
+// during the grace period. After it the account is as good as gone. It is
+// called only once a session is issued, so a password alone, without the
+// second factor, doesn't cancel the deletion.
+func (s Sessions) restoreIfDeleted(user *models.User) (bool, error) {
+	if user.DeletedAt == nil {
+		return true, nil
+	}
+	if !s.restorable(user) {
+		return false, nil
+	}
+	if err := s.userStorage.Restore(user.Id); err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		return false, fmt.Errorf("Sessions restore account: %w", err)
+	}
+	slog.Info("Sessions account restored", "user_id", user.Id)
+	user.DeletedAt = nil
+	return true, nil
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ActionTokenStorage keeps the one outstanding email token per user and
+// purpose. Issuing a new token replaces the previous one.
+type ActionTokenStorage struct {
+	redisDB app.AppRedis
+}
+
+func NewActionTokenStorage(redisDB app.AppRedis) *ActionTokenStorage {
+	return &ActionTokenStorage{redisDB: redisDB}
+}
+
+// consumeActionScript deletes the stored token only if its jti matches, so
+// each token can be redeemed once.
+const consumeActionScript = `
+local raw = redis.call("GET", KEYS[1])
+if not raw then
+	return false
+end
+if cjson.decode(raw).jti ~= ARGV[1] then
+	return false
+end
+redis.call("DEL", KEYS[1])
+return raw
+`
+
+func actionTokenKey(purpose, userId string) string {
+	return "action_token:" + purpose + ":" + userId
+}
+
+func (s *ActionTokenStorage) Save(ctx context.Context, purpose string, token models.ActionToken, ttl time.Duration) error {
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("ActionTokenStorage Save: %w", err)
+	}
+	if err := s.redisDB.Set(ctx, actionTokenKey(purpose, token.UserId), raw, ttl).Err(); err != nil {
+		return fmt.Errorf("ActionTokenStorage Save: %w", err)
+	}
+	return nil
+}
+
+func (s *ActionTokenStorage) Consume(ctx context.Context, purpose, userId, jti string) (*models.ActionToken, error) {
+	raw, err := s.redisDB.Eval(ctx, consumeActionScript, []string{actionTokenKey(purpose, userId)}, jti).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrActionTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ActionTokenStorage Consume: %w", err)
+	}
+	var token models.ActionToken
+	if err := json.Unmarshal([]byte(raw), &token); err != nil {
+		return nil, fmt.Errorf("ActionTokenStorage Consume: %w", err)
+	}
+	return &token, nil
+}
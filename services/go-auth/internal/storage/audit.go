@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"strconv"
+	"strings"
+)
+
+// AuditStorage keeps the trail of security events.
+type AuditStorage struct {
+	db *sql.DB
+}
+
+func NewAuditStorage(db app.DB) *AuditStorage {
+	return &AuditStorage{db: db.GetConnection()}
+}
+
+// Save stores event. Kafka delivers at least once, so saving an event
+// again is a no-op.
+func (s *AuditStorage) Save(event models.AuthEvent) error {
+	if event.Details == nil {
+		event.Details = map[string]string{}
+	}
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event details: %w", err)
+	}
+
+	query := `
+		INSERT INTO auth_events (id, type, user_id, login, session_id, ip, user_agent, details, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, $9)
+		ON CONFLICT (id) DO NOTHING
+	`
+
+	_, err = s.db.Exec(query, event.Id, event.Type, event.UserId, event.Login, event.SessionId,
+		event.IP, event.UserAgent, details, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save auth event: %w", err)
+	}
+	return nil
+}
+
+// List returns the events matching filter, newest first.
+func (s *AuditStorage) List(filter models.AuthEventFilter) ([]models.AuthEvent, error) {
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if filter.UserId != "" {
+		where = append(where, "user_id = "+arg(filter.UserId))
+	}
+	if filter.Type != "" {
+		where = append(where, "type = "+arg(filter.Type))
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "created_at >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "created_at < "+arg(filter.To))
+	}
+	query := `
+		SELECT id, type, COALESCE(user_id, ''), COALESCE(login, ''), COALESCE(session_id, ''),
+			COALESCE(ip, ''), COALESCE(user_agent, ''), details, created_at
+		FROM auth_events
+	`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id LIMIT " + arg(filter.Limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list auth events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AuthEvent{}
+	for rows.Next() {
+		var e models.AuthEvent
+		var details []byte
+		if err := rows.Scan(&e.Id, &e.Type, &e.UserId, &e.Login, &e.SessionId, &e.IP, &e.UserAgent, &details, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan auth event: %w", err)
+		}
+		if err := json.Unmarshal(details, &e.Details); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event details: %w", err)
+		}
+		if len(e.Details) == 0 {
+			e.Details = nil
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list auth events: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+)
+
+// ClientStorage keeps the API clients that use the client-credentials grant.
+type ClientStorage struct {
+	db *sql.DB
+}
+
+func NewClientStorage(db app.DB) *ClientStorage {
+	return &ClientStorage{db: db.GetConnection()}
+}
+
+// Save registers client with its scopes. A scope that is not a known
+// permission yields ErrScopeNotFound and nothing is saved.
+func (s *ClientStorage) Save(client models.APIClient) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO api_clients (id, name, secret_hash)
+		VALUES ($1, $2, $3)
+	`
+	if _, err := tx.Exec(query, client.Id, client.Name, client.SecretHash); err != nil {
+		return fmt.Errorf("failed to save client: %w", err)
+	}
+
+	query = `
+		INSERT INTO api_client_scopes (client_id, scope)
+		SELECT $1, name FROM permissions WHERE name = $2
+		ON CONFLICT (client_id, scope) DO NOTHING
+	`
+	for _, scope := range client.Scopes {
+		ok, err := affected(tx.Exec(query, client.Id, scope))
+		if err != nil {
+			return fmt.Errorf("failed to save client scope: %w", err)
+		}
+		if !ok {
+			var exists bool
+			if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM permissions WHERE name = $1)`, scope).Scan(&exists); err != nil {
+				return fmt.Errorf("failed to save client scope: %w", err)
+			}
+			if !exists {
+				return ErrScopeNotFound
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// Get returns the client, revoked or not.
+func (s *ClientStorage) Get(id string) (*models.APIClient, error) {
+	clients, err := s.list(`WHERE c.id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(clients) == 0 {
+		return nil, ErrClientNotFound
+	}
+	return &clients[0], nil
+}
+
+func (s *ClientStorage) List() ([]models.APIClient, error) {
+	return s.list("")
+}
+
+func (s *ClientStorage) list(where string, args ...interface{}) ([]models.APIClient, error) {
+	query := `
+		SELECT c.id, c.name, c.secret_hash, c.created_at, c.revoked_at, COALESCE(cs.scope, '')
+		FROM api_clients c
+		LEFT JOIN api_client_scopes cs ON cs.client_id = c.id
+		` + where + `
+		ORDER BY c.created_at, c.id, cs.scope
+	`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clients: %w", err)
+	}
+	defer rows.Close()
+
+	clients := []models.APIClient{}
+	for rows.Next() {
+		var c models.APIClient
+		var revokedAt sql.NullTime
+		var scope string
+		if err := rows.Scan(&c.Id, &c.Name, &c.SecretHash, &c.CreatedAt, &revokedAt, &scope); err != nil {
+			return nil, fmt.Errorf("failed to scan client: %w", err)
+		}
+		if len(clients) == 0 || clients[len(clients)-1].Id != c.Id {
+			if revokedAt.Valid {
+				c.RevokedAt = &revokedAt.Time
+			}
+			c.Scopes = []string{}
+			clients = append(clients, c)
+		}
+		if scope != "" {
+			last := &clients[len(clients)-1]
+			last.Scopes = append(last.Scopes, scope)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list clients: %w", err)
+	}
+	return clients, nil
+}
+
+// Revoke stops the client from getting new tokens. Revoking it again is a
+// no-op.
+func (s *ClientStorage) Revoke(id string) error {
+	query := `
+		UPDATE api_clients
+		SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1
+	`
+
+	err := expectRow(s.db.Exec(query, id))
+	if errors.Is(err, ErrUserNotFound) {
+		return ErrClientNotFound
+	}
+	return err
+}
@@ -3,3 +3,12 @@ package storage
 import "errors"
 
 var ErrUserNotFound = errors.New("user not found")
+var ErrRefreshFamilyNotFound = errors.New("refresh token family not found")
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+var ErrSessionNotFound = errors.New("session not found")
+var ErrActionTokenNotFound = errors.New("action token not found")
+var ErrOAuthStateNotFound = errors.New("oauth state not found")
+var ErrIdentityExists = errors.New("identity already linked")
+var ErrRoleNotFound = errors.New("role not found")
+var ErrClientNotFound = errors.New("api client not found")
+var ErrScopeNotFound = errors.New("scope not found")
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+)
+
+// IdentityStorage links accounts at external identity providers to users.
+type IdentityStorage struct {
+	db *sql.DB
+}
+
+func NewIdentityStorage(db app.DB) *IdentityStorage {
+	return &IdentityStorage{db: db.GetConnection()}
+}
+
+func (s *IdentityStorage) GetUser(provider, subject string) (*models.User, error) {
+	var res models.User
+
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = (SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2)
+	`
+
+	err := scanUser(s.db.QueryRow(query, provider, subject), &res)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by identity: %w", err)
+	}
+
+	return &res, nil
+}
+
+// Link attaches identity to the user. An identity belongs to one user only;
+// linking it again yields ErrIdentityExists.
+func (s *IdentityStorage) Link(userId string, identity models.Identity) error {
+	return linkIdentity(s.db, userId, identity)
+}
+
+// CreateUser creates the user of a first external login, with its email
+// verified, and links identity to it in one transaction.
+func (s *IdentityStorage) CreateUser(user models.UserCreateDto, identity models.Identity) (*models.User, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	u, err := insertUser(tx, user, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := linkIdentity(tx, u.Id, identity); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to save user: %w", err)
+	}
+	return u, nil
+}
+
+// execer is what *sql.DB and *sql.Tx have in common.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func linkIdentity(db execer, userId string, identity models.Identity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		ON CONFLICT (provider, subject) DO NOTHING
+	`
+
+	ok, err := affected(db.Exec(query, userId, identity.Provider, identity.Subject, identity.Email))
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	if !ok {
+		return ErrIdentityExists
+	}
+	return nil
+}
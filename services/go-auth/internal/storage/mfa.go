@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+)
+
+type MFAStorage struct {
+	db *sql.DB
+}
+
+func NewMFAStorage(db app.DB) *MFAStorage {
+	return &MFAStorage{db: db.GetConnection()}
+}
+
+func (s *MFAStorage) GetTOTP(userId string) (*models.TOTPState, error) {
+	var res models.TOTPState
+
+	query := `
+		SELECT login, COALESCE(totp_secret, ''), totp_enabled_at IS NOT NULL, totp_last_step
+		FROM users
+		WHERE id = $1
+	`
+
+	err := s.db.QueryRow(query, userId).Scan(&res.Login, &res.Secret, &res.Enabled, &res.LastStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get totp: %w", err)
+	}
+
+	return &res, nil
+}
+
+// SetPendingTOTP stores a secret that is not in use until EnableTOTP. An
+// enabled secret is never overwritten.
+func (s *MFAStorage) SetPendingTOTP(userId, secret string) error {
+	query := `
+		UPDATE users
+		SET totp_secret = $2
+		WHERE id = $1 AND totp_enabled_at IS NULL
+	`
+
+	return expectRow(s.db.Exec(query, userId, secret))
+}
+
+// EnableTOTP turns the pending secret on, marks step as used and replaces
+// the recovery codes, all at once.
+func (s *MFAStorage) EnableTOTP(userId string, step int64, codeHashes []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE users
+		SET totp_enabled_at = NOW(), totp_last_step = $2
+		WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL
+	`
+	if err := expectRow(tx.Exec(query, userId, step)); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(tx, userId, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *MFAStorage) DisableTOTP(userId string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE users
+		SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0
+		WHERE id = $1
+	`
+	if err := expectRow(tx.Exec(query, userId)); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(tx, userId, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *MFAStorage) ReplaceRecoveryCodes(userId string, codeHashes []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userId, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep records step as the last accepted one. It returns false if
+// the step (or a later one) was already used, i.e. the code is replayed.
+func (s *MFAStorage) UseTOTPStep(userId string, step int64) (bool, error) {
+	query := `
+		UPDATE users
+		SET totp_last_step = $2
+		WHERE id = $1 AND totp_enabled_at IS NOT NULL AND totp_last_step < $2
+	`
+
+	return affected(s.db.Exec(query, userId, step))
+}
+
+// UseRecoveryCode burns an unused code. It returns false if there is none.
+func (s *MFAStorage) UseRecoveryCode(userId, codeHash string) (bool, error) {
+	query := `
+		UPDATE recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	return affected(s.db.Exec(query, userId, codeHash))
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userId string, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userId); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, hash := range codeHashes {
+		query := `
+			INSERT INTO recovery_codes (user_id, code_hash)
+			VALUES ($1, $2)
+		`
+		if _, err := tx.Exec(query, userId, hash); err != nil {
+			return fmt.Errorf("failed to save recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+func affected(result sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, fmt.Errorf("failed to update: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+func expectRow(result sql.Result, err error) error {
+	ok, err := affected(result, err)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+	return nil
+}
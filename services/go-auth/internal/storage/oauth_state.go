@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OAuthStateStorage keeps the PKCE verifier and nonce of a pending OAuth
+// login under its state parameter until the callback consumes it.
+type OAuthStateStorage struct {
+	redisDB app.AppRedis
+}
+
+func NewOAuthStateStorage(redisDB app.AppRedis) *OAuthStateStorage {
+	return &OAuthStateStorage{redisDB: redisDB}
+}
+
+func oauthStateKey(state string) string {
+	return "oauth_state:" + state
+}
+
+func (s *OAuthStateStorage) Save(ctx context.Context, state string, data models.OAuthState, ttl time.Duration) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("OAuthStateStorage Save: %w", err)
+	}
+	if err := s.redisDB.Set(ctx, oauthStateKey(state), raw, ttl).Err(); err != nil {
+		return fmt.Errorf("OAuthStateStorage Save: %w", err)
+	}
+	return nil
+}
+
+func (s *OAuthStateStorage) Consume(ctx context.Context, state string) (*models.OAuthState, error) {
+	raw, err := s.redisDB.GetDel(ctx, oauthStateKey(state)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrOAuthStateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("OAuthStateStorage Consume: %w", err)
+	}
+	var data models.OAuthState
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("OAuthStateStorage Consume: %w", err)
+	}
+	return &data, nil
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// outboxLockKey is the advisory lock held by the instance relaying the
+// outbox, so events of a user are published in order.
+const outboxLockKey = 0x6f7574626f78
+
+// OutboxStorage hands user_outbox rows to the relay.
+type OutboxStorage struct {
+	db *sql.DB
+}
+
+func NewOutboxStorage(db app.DB) *OutboxStorage {
+	return &OutboxStorage{db: db.GetConnection()}
+}
+
+// Relay passes up to limit pending messages, oldest first, to send and
+// marks the sent ones. It stops at the first failure, which is returned
+// together with the number sent. While another instance relays, it sends
+// nothing.
+func (s *OutboxStorage) Relay(limit int, send func(models.OutboxMessage) error) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRow(`SELECT pg_try_advisory_xact_lock($1)`, outboxLockKey).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	query := `
+		SELECT id, event_type, user_id, payload
+		FROM user_outbox
+		WHERE sent_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`
+	rows, err := tx.Query(query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	var pending []models.OutboxMessage
+	for rows.Next() {
+		var m models.OutboxMessage
+		if err := rows.Scan(&m.Id, &m.Type, &m.UserId, &m.Payload); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		pending = append(pending, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	var sent []int64
+	var sendErr error
+	for _, m := range pending {
+		if sendErr = send(m); sendErr != nil {
+			break
+		}
+		sent = append(sent, m.Id)
+	}
+	if len(sent) > 0 {
+		if _, err := tx.Exec(`UPDATE user_outbox SET sent_at = NOW() WHERE id = ANY($1)`, sent); err != nil {
+			return 0, fmt.Errorf("failed to mark outbox messages sent: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, fmt.Errorf("failed to mark outbox messages sent: %w", err)
+		}
+	}
+	return len(sent), sendErr
+}
+
+// PurgeSent removes messages sent before the given time.
+func (s *OutboxStorage) PurgeSent(before time.Time) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM user_outbox WHERE sent_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge outbox: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return n, nil
+}
+
+// writeUserEvent queues an event about u in tx.
+func writeUserEvent(tx *sql.Tx, typ string, u *models.User) error {
+	event := models.UserEvent{
+		Id:         uuid.NewString(),
+		Type:       typ,
+		UserId:     u.Id,
+		OccurredAt: time.Now().UTC(),
+	}
+	if typ != models.UserEventDeleted {
+		createdAt := u.CreatedAt
+		event.Login = u.Login
+		event.Email = u.Email
+		event.EmailVerified = u.EmailVerified
+		event.DisplayName = u.DisplayName
+		event.AvatarUrl = u.AvatarUrl
+		event.CreatedAt = &createdAt
+		event.DeletedAt = u.DeletedAt
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user event: %w", err)
+	}
+	query := `
+		INSERT INTO user_outbox (event_type, user_id, payload)
+		VALUES ($1, $2, $3)
+	`
+	if _, err := tx.Exec(query, typ, u.Id, payload); err != nil {
+		return fmt.Errorf("failed to write user event: %w", err)
+	}
+	return nil
+}
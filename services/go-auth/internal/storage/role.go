@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"slices"
+)
+
+// RoleStorage keeps the roles of users and the permissions of roles.
+type RoleStorage struct {
+	db *sql.DB
+}
+
+func NewRoleStorage(db app.DB) *RoleStorage {
+	return &RoleStorage{db: db.GetConnection()}
+}
+
+// GetGrants returns the user's roles and the union of their permissions,
+// both sorted.
+func (s *RoleStorage) GetGrants(userId string) (*models.Grants, error) {
+	query := `
+		SELECT ur.role, COALESCE(rp.permission, '')
+		FROM user_roles ur
+		LEFT JOIN role_permissions rp ON rp.role = ur.role
+		WHERE ur.user_id = $1
+		ORDER BY ur.role, rp.permission
+	`
+
+	rows, err := s.db.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get grants: %w", err)
+	}
+	defer rows.Close()
+
+	grants := &models.Grants{Roles: []string{}, Permissions: []string{}}
+	for rows.Next() {
+		var role, permission string
+		if err := rows.Scan(&role, &permission); err != nil {
+			return nil, fmt.Errorf("failed to scan grants: %w", err)
+		}
+		if !slices.Contains(grants.Roles, role) {
+			grants.Roles = append(grants.Roles, role)
+		}
+		if permission != "" && !slices.Contains(grants.Permissions, permission) {
+			grants.Permissions = append(grants.Permissions, permission)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get grants: %w", err)
+	}
+	slices.Sort(grants.Permissions)
+	return grants, nil
+}
+
+func (s *RoleStorage) ListRoles() ([]models.Role, error) {
+	query := `
+		SELECT r.name, r.description, COALESCE(rp.permission, '')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role = r.name
+		ORDER BY r.name, rp.permission
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var name, description, permission string
+		if err := rows.Scan(&name, &description, &permission); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		if len(roles) == 0 || roles[len(roles)-1].Name != name {
+			roles = append(roles, models.Role{Name: name, Description: description, Permissions: []string{}})
+		}
+		if permission != "" {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, permission)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	return roles, nil
+}
+
+// AssignRole gives role to the user. Assigning a role the user already has
+// is a no-op.
+func (s *RoleStorage) AssignRole(userId, role string) error {
+	query := `
+		INSERT INTO user_roles (user_id, role)
+		SELECT u.id, r.name
+		FROM users u, roles r
+		WHERE u.id = $1 AND r.name = $2
+		ON CONFLICT (user_id, role) DO NOTHING
+	`
+
+	if _, err := s.db.Exec(query, userId, role); err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	// Nothing inserted is either an existing assignment or a missing user
+	// or role; tell which.
+	var userExists, roleExists bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM users WHERE id = $1), EXISTS (SELECT 1 FROM roles WHERE name = $2)
+	`, userId, role).Scan(&userExists, &roleExists)
+	if err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	if !roleExists {
+		return ErrRoleNotFound
+	}
+	if !userExists {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// RevokeRole takes role from the user. Revoking a role the user doesn't
+// have is a no-op.
+func (s *RoleStorage) RevokeRole(userId, role string) error {
+	query := `
+		DELETE FROM user_roles
+		WHERE user_id = $1 AND role = $2
+	`
+
+	if _, err := s.db.Exec(query, userId, role); err != nil {
+		return fmt.Errorf("failed to revoke role: %w", err)
+	}
+	return nil
+}
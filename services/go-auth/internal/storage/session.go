@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type SessionStorage struct {
+	redisDB app.AppRedis
+}
+
+func NewSessionStorage(redisDB app.AppRedis) *SessionStorage {
+	return &SessionStorage{redisDB: redisDB}
+}
+
+func sessionKey(id string) string {
+	return "session:" + id
+}
+
+func userSessionsKey(userId string) string {
+	return "user_sessions:" + userId
+}
+
+func (s *SessionStorage) Save(ctx context.Context, session models.Session, ttl time.Duration) error {
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("SessionStorage Save: %w", err)
+	}
+	pipe := s.redisDB.Pipeline()
+	pipe.Set(ctx, sessionKey(session.Id), raw, ttl)
+	pipe.SAdd(ctx, userSessionsKey(session.UserId), session.Id)
+	pipe.Expire(ctx, userSessionsKey(session.UserId), ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("SessionStorage Save: %w", err)
+	}
+	return nil
+}
+
+func (s *SessionStorage) Get(ctx context.Context, id string) (*models.Session, error) {
+	raw, err := s.redisDB.Get(ctx, sessionKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("SessionStorage Get: %w", err)
+	}
+	var session models.Session
+	if err := json.Unmarshal(raw, &session); err != nil {
+		return nil, fmt.Errorf("SessionStorage Get: %w", err)
+	}
+	return &session, nil
+}
+
+func (s *SessionStorage) Touch(ctx context.Context, id string, lastSeen time.Time) error {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	session.LastSeen = lastSeen
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("SessionStorage Touch: %w", err)
+	}
+	if err := s.redisDB.Set(ctx, sessionKey(id), raw, redis.KeepTTL).Err(); err != nil {
+		return fmt.Errorf("SessionStorage Touch: %w", err)
+	}
+	return nil
+}
+
+// ListByUser returns the live sessions of a user and drops ids whose
+// session key has already expired.
+func (s *SessionStorage) ListByUser(ctx context.Context, userId string) ([]models.Session, error) {
+	ids, err := s.redisDB.SMembers(ctx, userSessionsKey(userId)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("SessionStorage ListByUser: %w", err)
+	}
+	if len(ids) == 0 {
+		return []models.Session{}, nil
+	}
+
+	pipe := s.redisDB.Pipeline()
+	cmds := make([]*redis.StringCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.Get(ctx, sessionKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("SessionStorage ListByUser: %w", err)
+	}
+
+	sessions := make([]models.Session, 0, len(ids))
+	var stale []interface{}
+	for i, cmd := range cmds {
+		raw, err := cmd.Bytes()
+		if errors.Is(err, redis.Nil) {
+			stale = append(stale, ids[i])
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("SessionStorage ListByUser: %w", err)
+		}
+		var session models.Session
+		if err := json.Unmarshal(raw, &session); err != nil {
+			return nil, fmt.Errorf("SessionStorage ListByUser: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if len(stale) > 0 {
+		if err := s.redisDB.SRem(ctx, userSessionsKey(userId), stale...).Err(); err != nil {
+			return nil, fmt.Errorf("SessionStorage ListByUser cleanup: %w", err)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *SessionStorage) Delete(ctx context.Context, userId, id string) error {
+	pipe := s.redisDB.Pipeline()
+	pipe.Del(ctx, sessionKey(id))
+	pipe.SRem(ctx, userSessionsKey(userId), id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("SessionStorage Delete: %w", err)
+	}
+	return nil
+}
@@ -11,10 +11,11 @@ import (
 
 type TokenStorage struct {
 	redisDB app.AppRedis
+	cfg     app.AppConfig
 }
 
-func NewTokenStorage(redisDB app.AppRedis) *TokenStorage {
-	return &TokenStorage{redisDB: redisDB}
+func NewTokenStorage(redisDB app.AppRedis, cfg app.AppConfig) *TokenStorage {
+	return &TokenStorage{redisDB: redisDB, cfg: cfg}
 }
 
 func (s *TokenStorage) SetTokens(ctx context.Context, jwt *models.TokenDto) error {
@@ -26,9 +27,10 @@ func (s *TokenStorage) SetTokens(ctx context.Context, jwt *models.TokenDto) erro
 		)
 		// return fmt.Errorf("empty tokens")
 	}
+	cfg := s.cfg.GetConfig()
 	pipe := s.redisDB.Pipeline()
-	pipe.Set(ctx, "access:"+jwt.Access, jwt.Access, 30* time.Second)
-	pipe.Set(ctx, "refresh:"+jwt.Refresh, jwt.Refresh, 30*24*3600 * time.Second)
+	pipe.Set(ctx, "access:"+jwt.Access, jwt.Access, cfg.AccessTokenTTL)
+	pipe.Set(ctx, "refresh:"+jwt.Refresh, jwt.Refresh, cfg.RefreshTokenTTL)
 	cmds, err := pipe.Exec(ctx)
 	if err != nil {
 		slog.Info("TokenStorage SetTokens Redis pipeline failed " + err.Error())
@@ -51,3 +53,51 @@ func (s *TokenStorage) RemoveToken(ctx context.Context, refresh, access string)
 	}
 	return nil
 }
+
+// rotateRefreshScript atomically swaps the current jti of a refresh family.
+// Presenting anything but the current jti means an already rotated token was
+// replayed, so the whole family is dropped.
+const rotateRefreshScript = `
+local current = redis.call("GET", KEYS[1])
+if not current then
+	return 0
+end
+if current ~= ARGV[1] then
+	redis.call("DEL", KEYS[1])
+	return -1
+end
+redis.call("SET", KEYS[1], ARGV[2], "KEEPTTL")
+return 1
+`
+
+func refreshFamilyKey(family string) string {
+	return "refresh_family:" + family
+}
+
+func (s *TokenStorage) SaveRefreshFamily(ctx context.Context, family, jti string, ttl time.Duration) error {
+	if err := s.redisDB.Set(ctx, refreshFamilyKey(family), jti, ttl).Err(); err != nil {
+		return fmt.Errorf("TokenStorage SaveRefreshFamily: %w", err)
+	}
+	return nil
+}
+
+func (s *TokenStorage) RotateRefreshFamily(ctx context.Context, family, oldJti, newJti string) error {
+	res, err := s.redisDB.Eval(ctx, rotateRefreshScript, []string{refreshFamilyKey(family)}, oldJti, newJti).Int()
+	if err != nil {
+		return fmt.Errorf("TokenStorage RotateRefreshFamily: %w", err)
+	}
+	switch res {
+	case 0:
+		return ErrRefreshFamilyNotFound
+	case -1:
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+func (s *TokenStorage) RevokeRefreshFamily(ctx context.Context, family string) error {
+	if err := s.redisDB.Del(ctx, refreshFamilyKey(family)).Err(); err != nil {
+		return fmt.Errorf("TokenStorage RevokeRefreshFamily: %w", err)
+	}
+	return nil
+}
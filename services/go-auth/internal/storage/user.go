@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"go-auth/internal/app"
 	"go-auth/internal/models"
+	"go-auth/pkg/authz"
+	"time"
 )
 
 type UserStorage struct {
@@ -17,46 +19,116 @@ func NewUserStorage( db app.DB) *UserStorage {
 	return &UserStorage{db: db.GetConnection()}
 }
 
+// Save creates the user with the default role and queues user.created in
+// the same transaction.
 func (s *UserStorage) Save(user models.UserCreateDto) (models.UserCreateRes, error) {
+	var res models.UserCreateRes
+	tx, err := s.db.Begin()
+	if err != nil {
+		return res, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
 
-var res models.UserCreateRes
-	
+	u, err := insertUser(tx, user, false)
+	if err != nil {
+		return res, err
+	}
+	if err := tx.Commit(); err != nil {
+		return res, fmt.Errorf("failed to save user: %w", err)
+	}
+	return models.UserCreateRes{Id: u.Id, Login: u.Login}, nil
+}
 
+// insertUser creates the user in tx with the default role and queues
+// user.created. emailVerified stores the email as already confirmed.
+func insertUser(tx *sql.Tx, user models.UserCreateDto, emailVerified bool) (*models.User, error) {
+	query := `
+		INSERT INTO users (login, password_hash, email, email_verified_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), CASE WHEN $4 AND $3 <> '' THEN NOW() END)
+		RETURNING ` + userColumns
+
+	var u models.User
+	if err := scanUser(tx.QueryRow(query, user.Login, user.PasswordHash, user.Email, emailVerified), &u); err != nil {
+		return nil, fmt.Errorf("failed to save user: %w", err)
+	}
+	// Every user starts with the default role.
+	if _, err := tx.Exec(`INSERT INTO user_roles (user_id, role) VALUES ($1, $2)`, u.Id, authz.DefaultRole); err != nil {
+		return nil, fmt.Errorf("failed to save user role: %w", err)
+	}
+	if err := writeUserEvent(tx, models.UserEventCreated, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *UserStorage) GetByLogin(login string) (*models.User, error) {
+	var res models.User
 
 	query := `
-		INSERT INTO users (login, password_hash)
-		VALUES ($1, $2)
-		RETURNING id, login
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE login = $1
 	`
 
-	err := s.db.QueryRow(query, user.Login,  user.PasswordHash).Scan(&res.Id, &res.Login)
+	err := scanUser(s.db.QueryRow(query, login), &res)
 	if err != nil {
-		return res, fmt.Errorf("failed to save user: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	return res, nil
+	return &res, nil
 }
 
-func (s *UserStorage) Get(user models.UserCreateDto) (*models.UserCreateRes, error) {
-	var res models.UserCreateRes
+func (s *UserStorage) GetByEmail(email string) (*models.User, error) {
+	var res models.User
 
 	query := `
-		SELECT id, login
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE login = $1 AND password_hash = $2
+		WHERE email = $1
 	`
 
-	err := s.db.QueryRow(query, user.Login, user.PasswordHash).Scan(&res.Id, &res.Login)
+	err := scanUser(s.db.QueryRow(query, email), &res)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
 		}
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
 
 	return &res, nil
 }
 
+// VerifyEmail marks email as confirmed, provided it is still the user's
+// current address.
+func (s *UserStorage) VerifyEmail(userId, email string) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW())
+		WHERE id = $1 AND email = $2
+	`
+
+	return s.updateUser(query, userId, email)
+}
+
+const userColumns = `id, login, COALESCE(password_hash, ''), COALESCE(email, ''), email_verified_at IS NOT NULL, totp_enabled_at IS NOT NULL,
+	COALESCE(display_name, ''), COALESCE(avatar_url, ''), created_at, deleted_at`
+
+func scanUser(row *sql.Row, u *models.User) error {
+	var deletedAt sql.NullTime
+	err := row.Scan(&u.Id, &u.Login, &u.PasswordHash, &u.Email, &u.EmailVerified, &u.MFAEnabled,
+		&u.DisplayName, &u.AvatarUrl, &u.CreatedAt, &deletedAt)
+	if err != nil {
+		return err
+	}
+	if deletedAt.Valid {
+		u.DeletedAt = &deletedAt.Time
+	}
+	return nil
+}
+
 func (s *UserStorage) Update(user models.UserCreateDto) error {
 	query := `
 		UPDATE users
@@ -80,16 +152,16 @@ func (s *UserStorage) Update(user models.UserCreateDto) error {
 
 	return nil
 }
-func (s *UserStorage) GetById(userId string) (*models.UserCreateRes, error) {
-var res models.UserCreateRes
+func (s *UserStorage) GetById(userId string) (*models.User, error) {
+	var res models.User
 
 	query := `
-		SELECT id
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE password_hash = $1
+		WHERE id = $1
 	`
 
-	err := s.db.QueryRow(query, userId).Scan(&res.Id, &res.Login)
+	err := scanUser(s.db.QueryRow(query, userId), &res)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
@@ -99,3 +171,104 @@ var res models.UserCreateRes
 
 	return &res, nil
 }
+
+// UpdateProfile applies the non-nil fields of p. An empty string clears the
+// field. A new email starts out unverified.
+func (s *UserStorage) UpdateProfile(userId string, p models.ProfileUpdateDto) error {
+	query := `
+		UPDATE users
+		SET display_name = CASE WHEN $2::text IS NULL THEN display_name ELSE NULLIF($2, '') END,
+			avatar_url = CASE WHEN $3::text IS NULL THEN avatar_url ELSE NULLIF($3, '') END,
+			email_verified_at = CASE WHEN $4::text IS NULL OR $4 = email THEN email_verified_at ELSE NULL END,
+			email = CASE WHEN $4::text IS NULL THEN email ELSE NULLIF($4, '') END
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	return s.updateUser(query, userId, p.DisplayName, p.AvatarUrl, p.Email)
+}
+
+// SoftDelete marks the user deleted. The row is removed by PurgeDeleted
+// once the grace period is over.
+func (s *UserStorage) SoftDelete(userId string) error {
+	query := `
+		UPDATE users
+		SET deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	return s.updateUser(query, userId)
+}
+
+func (s *UserStorage) Restore(userId string) error {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+
+	return s.updateUser(query, userId)
+}
+
+// PurgeDeleted removes users soft deleted before the given time and
+// returns how many there were. Each gets a user.deleted event.
+func (s *UserStorage) PurgeDeleted(before time.Time) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`DELETE FROM users WHERE deleted_at < $1 RETURNING id`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted users: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan purged user: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to purge deleted users: %w", err)
+	}
+
+	for _, id := range ids {
+		if err := writeUserEvent(tx, models.UserEventDeleted, &models.User{Id: id}); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to purge deleted users: %w", err)
+	}
+	return int64(len(ids)), nil
+}
+
+// updateUser runs an UPDATE of a single user and queues user.updated with
+// the result in the same transaction. No matching row is ErrUserNotFound.
+func (s *UserStorage) updateUser(query string, args ...interface{}) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var u models.User
+	err = scanUser(tx.QueryRow(query+" RETURNING "+userColumns, args...), &u)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if err := writeUserEvent(tx, models.UserEventUpdated, &u); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+CREATE TABLE roles (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE permissions (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE role_permissions (
+    role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE user_roles (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE INDEX idx_user_roles_role ON user_roles(role);
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full access'),
+    ('moderator', 'Moderates chat and sees users'),
+    ('player', 'Default role of every user');
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:read', 'See users and their roles'),
+    ('roles:manage', 'Assign and revoke roles'),
+    ('chat:moderate', 'Delete messages and mute users'),
+    ('game:admin', 'Manage rooms and matches'),
+    ('game:play', 'Join matches');
+
+INSERT INTO role_permissions (role, permission)
+SELECT 'admin', name FROM permissions;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('moderator', 'users:read'),
+    ('moderator', 'chat:moderate'),
+    ('moderator', 'game:play'),
+    ('player', 'game:play');
+
+INSERT INTO user_roles (user_id, role)
+SELECT id, 'player' FROM users;
+
+-- The first admin is granted by hand:
+-- INSERT INTO user_roles (user_id, role) SELECT id, 'admin' FROM users WHERE login = '...';
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN display_name TEXT,
+    ADD COLUMN avatar_url TEXT,
+    ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
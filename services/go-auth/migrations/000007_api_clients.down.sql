@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE name = 'clients:manage';
+DROP TABLE IF EXISTS api_client_scopes;
+DROP TABLE IF EXISTS api_clients;
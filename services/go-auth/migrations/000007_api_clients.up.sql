@@ -0,0 +1,21 @@
+CREATE TABLE api_clients (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    secret_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP
+);
+
+-- Scopes a client may request. They are permission names, so a client
+-- token passes the same permission checks as a user with those permissions.
+CREATE TABLE api_client_scopes (
+    client_id TEXT NOT NULL REFERENCES api_clients(id) ON DELETE CASCADE,
+    scope TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (client_id, scope)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('clients:manage', 'Register and revoke API clients');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'clients:manage');
@@ -0,0 +1,4 @@
+DELETE FROM permissions WHERE name = 'audit:read';
+DROP INDEX IF EXISTS idx_auth_events_created;
+DROP INDEX IF EXISTS idx_auth_events_user_created;
+DROP TABLE IF EXISTS auth_events;
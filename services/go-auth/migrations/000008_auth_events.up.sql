@@ -0,0 +1,22 @@
+-- No foreign key to users: the trail has to outlive purged accounts.
+CREATE TABLE auth_events (
+    id UUID PRIMARY KEY,
+    type TEXT NOT NULL,
+    user_id TEXT,
+    login TEXT,
+    session_id TEXT,
+    ip TEXT,
+    user_agent TEXT,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_auth_events_user_created ON auth_events(user_id, created_at DESC);
+CREATE INDEX idx_auth_events_created ON auth_events(created_at DESC);
+
+INSERT INTO permissions (name, description) VALUES
+    ('audit:read', 'See the security events of users');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'audit:read'),
+    ('moderator', 'audit:read');
@@ -0,0 +1,13 @@
+-- Account lifecycle events waiting to be published. Rows are written in the
+-- same transaction as the change they describe, so no event is lost or
+-- published for a change that was rolled back.
+CREATE TABLE user_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP
+);
+
+CREATE INDEX idx_user_outbox_pending ON user_outbox(id) WHERE sent_at IS NULL;
@@ -0,0 +1,88 @@
+// Package audit ships security events to Kafka, from where they are stored
+// in auth_events, without slowing down the requests that cause them.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// bufferSize is how many events may wait for Kafka before new ones are
+// dropped.
+const bufferSize = 1024
+
+type Log struct {
+	producer app.AppProducer
+	topic    string
+	now      func() time.Time
+
+	events    chan models.AuthEvent
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func New(producer app.AppProducer, cfg app.AppConfig) *Log {
+	return NewLog(producer, cfg.GetConfig().AuditTopic)
+}
+
+func NewLog(producer app.AppProducer, topic string) *Log {
+	l := &Log{
+		producer: producer,
+		topic:    topic,
+		now:      time.Now,
+		events:   make(chan models.AuthEvent, bufferSize),
+		done:     make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Record fills in the id and time of event and queues it. When the queue
+// is full the event only goes to the service log.
+func (l *Log) Record(ctx context.Context, event models.AuthEvent) {
+	if event.Id == "" {
+		event.Id = uuid.NewString()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = l.now().UTC()
+	}
+	select {
+	case l.events <- event:
+	default:
+		slog.Error("audit queue is full, event dropped", "event", event)
+	}
+}
+
+// Close sends the queued events and stops.
+func (l *Log) Close() {
+	l.closeOnce.Do(func() {
+		close(l.events)
+		<-l.done
+	})
+}
+
+func (l *Log) run() {
+	defer close(l.done)
+	for event := range l.events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			slog.Error("audit marshal event: " + err.Error())
+			continue
+		}
+		// Keyed by user so a user's events stay in order.
+		key := event.UserId
+		if key == "" {
+			key = event.Login
+		}
+		if err := l.producer.Produce(l.topic, key, string(value)); err != nil {
+			slog.Error("audit produce event: "+err.Error(), "event", string(value))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-auth/internal/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type message struct {
+	topic, key, value string
+}
+
+type fakeProducer struct {
+	mu       sync.Mutex
+	messages []message
+	err      error
+	block    chan struct{}
+}
+
+func (p *fakeProducer) Produce(topic, key, value string) error {
+	if p.block != nil {
+		<-p.block
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, message{topic, key, value})
+	return p.err
+}
+
+func (p *fakeProducer) Close() {}
+
+func TestLog_Record(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		event       models.AuthEvent
+		expectedKey string
+	}{
+		{
+			name:        "keyed by user",
+			event:       models.AuthEvent{Type: models.AuthEventLoggedOut, UserId: "123"},
+			expectedKey: "123",
+		},
+		{
+			name:        "keyed by login without user",
+			event:       models.AuthEvent{Type: models.AuthEventLoginFailed, Login: "nobody"},
+			expectedKey: "nobody",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			producer := &fakeProducer{}
+			log := NewLog(producer, "auth_events")
+			log.now = func() time.Time { return now }
+
+			log.Record(context.Background(), tt.event)
+			log.Close()
+
+			assert.Len(t, producer.messages, 1)
+			msg := producer.messages[0]
+			assert.Equal(t, "auth_events", msg.topic)
+			assert.Equal(t, tt.expectedKey, msg.key)
+			var event models.AuthEvent
+			assert.NoError(t, json.Unmarshal([]byte(msg.value), &event))
+			assert.NotEmpty(t, event.Id)
+			assert.Equal(t, tt.event.Type, event.Type)
+			assert.True(t, now.Equal(event.CreatedAt))
+		})
+	}
+}
+
+func TestLog_ProduceErrorDoesNotStop(t *testing.T) {
+	producer := &fakeProducer{err: errors.New("broker down")}
+	log := NewLog(producer, "auth_events")
+
+	log.Record(context.Background(), models.AuthEvent{Type: models.AuthEventLoggedOut, UserId: "1"})
+	log.Record(context.Background(), models.AuthEvent{Type: models.AuthEventLoggedOut, UserId: "2"})
+	log.Close()
+
+	assert.Len(t, producer.messages, 2)
+}
+
+func TestLog_RecordDoesNotBlock(t *testing.T) {
+	producer := &fakeProducer{block: make(chan struct{})}
+	log := NewLog(producer, "auth_events")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < bufferSize+10; i++ {
+			log.Record(context.Background(), models.AuthEvent{Type: models.AuthEventLoggedOut})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Record blocked on a stuck producer")
+	}
+
+	close(producer.block)
+	log.Close()
+	assert.LessOrEqual(t, len(producer.messages), bufferSize+1, "overflow is dropped")
+}
@@ -0,0 +1,79 @@
+// Package authz checks the roles and permissions carried in access tokens.
+package authz
+
+import (
+	"context"
+	"go-auth/internal/router/apierror"
+	"net/http"
+	"slices"
+)
+
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RolePlayer    = "player"
+	// DefaultRole is given to every new user.
+	DefaultRole = RolePlayer
+)
+
+const (
+	PermUsersRead     = "users:read"
+	PermRolesManage   = "roles:manage"
+	PermChatModerate  = "chat:moderate"
+	PermGameAdmin     = "game:admin"
+	PermGamePlay      = "game:play"
+	PermClientsManage = "clients:manage"
+	PermAuditRead     = "audit:read"
+)
+
+// Subject is the caller of a request as far as authorization is concerned.
+type Subject struct {
+	Id          string
+	Roles       []string
+	Permissions []string
+}
+
+func (s Subject) HasRole(role string) bool {
+	return slices.Contains(s.Roles, role)
+}
+
+// Can reports whether the subject has every one of permissions.
+func (s Subject) Can(permissions ...string) bool {
+	for _, p := range permissions {
+		if !slices.Contains(s.Permissions, p) {
+			return false
+		}
+	}
+	return true
+}
+
+type subjectKey struct{}
+
+func WithSubject(ctx context.Context, s Subject) context.Context {
+	return context.WithValue(ctx, subjectKey{}, s)
+}
+
+func SubjectFromContext(ctx context.Context) (Subject, bool) {
+	s, ok := ctx.Value(subjectKey{}).(Subject)
+	return s, ok
+}
+
+// RequirePermission lets the request through only if the subject put in
+// the context by the authentication middleware has all of permissions. It
+// answers 401 without a subject and 403 when a permission is missing.
+func RequirePermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, ok := SubjectFromContext(r.Context())
+			if !ok {
+				apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized")
+				return
+			}
+			if !s.Can(permissions...) {
+				apierror.Write(w, r, http.StatusForbidden, apierror.CodeForbidden, "Forbidden")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
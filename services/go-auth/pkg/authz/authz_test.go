@@ -0,0 +1,88 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequirePermission(t *testing.T) {
+	moderator := Subject{Id: "123", Roles: []string{RoleModerator}, Permissions: []string{PermUsersRead, PermChatModerate}}
+
+	tests := []struct {
+		name           string
+		subject        *Subject
+		required       []string
+		expectedStatus int
+	}{
+		{
+			name:           "no subject",
+			required:       []string{PermChatModerate},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "has permission",
+			subject:        &moderator,
+			required:       []string{PermChatModerate},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "has all permissions",
+			subject:        &moderator,
+			required:       []string{PermChatModerate, PermUsersRead},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing one permission",
+			subject:        &moderator,
+			required:       []string{PermChatModerate, PermRolesManage},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "no permissions at all",
+			subject:        &Subject{Id: "123"},
+			required:       []string{PermGamePlay},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.subject != nil {
+				req = req.WithContext(WithSubject(context.Background(), *tt.subject))
+			}
+			rr := httptest.NewRecorder()
+
+			RequirePermission(tt.required...)(next).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedStatus == http.StatusOK, called)
+		})
+	}
+}
+
+func TestRequirePermission_ErrorBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "req-1"))
+	rr := httptest.NewRecorder()
+
+	RequirePermission(PermGameAdmin)(http.NotFoundHandler()).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.JSONEq(t, `{"code":"unauthorized","message":"Unauthorized","traceId":"req-1"}`, rr.Body.String())
+}
+
+func TestSubject_HasRole(t *testing.T) {
+	s := Subject{Roles: []string{RolePlayer}}
+	assert.True(t, s.HasRole(RolePlayer))
+	assert.False(t, s.HasRole(RoleAdmin))
+}
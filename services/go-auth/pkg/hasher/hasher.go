@@ -0,0 +1,114 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-auth/internal/app"
+)
+
+const (
+	AlgArgon2id = "argon2id"
+	AlgBcrypt   = "bcrypt"
+)
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// Algorithm is a single password hashing scheme. Encoded hashes carry their
+// own parameters so they can be verified after the policy changes.
+type Algorithm interface {
+	Name() string
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash reports whether encoded was produced with weaker
+	// parameters than the ones the algorithm is configured with.
+	NeedsRehash(encoded string) bool
+}
+
+// Policy hashes new passwords with the current algorithm and verifies
+// hashes produced by any supported one.
+type Policy struct {
+	current    Algorithm
+	algorithms map[string]Algorithm
+}
+
+func New(cfg app.AppConfig) *Policy {
+	name := cfg.GetConfig().PasswordHasher
+	if name == "" {
+		name = AlgArgon2id
+	}
+	argon := NewArgon2id(DefaultArgon2Params)
+	bcrypt := NewBcrypt(DefaultBcryptCost)
+
+	var current Algorithm
+	switch name {
+	case AlgArgon2id:
+		current = argon
+	case AlgBcrypt:
+		current = bcrypt
+	default:
+		panic(fmt.Sprintf("unsupported password hasher: %s", name))
+	}
+	return NewPolicy(current, argon, bcrypt)
+}
+
+// NewPolicy builds a policy hashing with current and verifying with current
+// plus every algorithm in supported.
+func NewPolicy(current Algorithm, supported ...Algorithm) *Policy {
+	p := &Policy{
+		current:    current,
+		algorithms: map[string]Algorithm{current.Name(): current},
+	}
+	for _, a := range supported {
+		if _, ok := p.algorithms[a.Name()]; !ok {
+			p.algorithms[a.Name()] = a
+		}
+	}
+	return p
+}
+
+func (p *Policy) Hash(password string) (string, error) {
+	return p.current.Hash(password)
+}
+
+func (p *Policy) Verify(password, encoded string) (bool, error) {
+	alg, err := p.algorithmFor(encoded)
+	if err != nil {
+		return false, err
+	}
+	return alg.Verify(password, encoded)
+}
+
+func (p *Policy) NeedsRehash(encoded string) bool {
+	name, err := algorithmName(encoded)
+	if err != nil || name != p.current.Name() {
+		return true
+	}
+	return p.current.NeedsRehash(encoded)
+}
+
+func (p *Policy) algorithmFor(encoded string) (Algorithm, error) {
+	name, err := algorithmName(encoded)
+	if err != nil {
+		return nil, err
+	}
+	alg, ok := p.algorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownHashFormat, name)
+	}
+	return alg, nil
+}
+
+func algorithmName(encoded string) (string, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return AlgArgon2id, nil
+	case strings.HasPrefix(encoded, "$2a$"),
+		strings.HasPrefix(encoded, "$2b$"),
+		strings.HasPrefix(encoded, "$2y$"):
+		return AlgBcrypt, nil
+	}
+	return "", ErrUnknownHashFormat
+}
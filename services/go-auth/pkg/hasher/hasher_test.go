@@ -0,0 +1,129 @@
+package hasher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testArgon2Params keeps argon2 cheap enough for unit tests.
+var testArgon2Params = Argon2Params{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func mustHash(t *testing.T, alg Algorithm, password string) string {
+	t.Helper()
+	h, err := alg.Hash(password)
+	require.NoError(t, err)
+	return h
+}
+
+func TestArgon2id_RoundTrip(t *testing.T) {
+	a := NewArgon2id(testArgon2Params)
+	hash := mustHash(t, a, "password123")
+	assert.Regexp(t, `^\$argon2id\$v=19\$m=1024,t=1,p=1\$[^$]+\$[^$]+$`, hash)
+	assert.NotEqual(t, hash, mustHash(t, a, "password123"), "salted")
+
+	ok, err := a.Verify("password123", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = a.Verify("password124", hash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestBcrypt_Verify(t *testing.T) {
+	b := NewBcrypt(4)
+	hash := mustHash(t, b, "password123")
+
+	ok, err := b.Verify("password123", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = b.Verify("password124", hash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestPolicy_Verify(t *testing.T) {
+	p := NewPolicy(NewArgon2id(testArgon2Params), NewBcrypt(4))
+
+	for name, hash := range map[string]string{
+		"argon2id": mustHash(t, NewArgon2id(testArgon2Params), "password123"),
+		"bcrypt":   mustHash(t, NewBcrypt(4), "password123"),
+	} {
+		ok, err := p.Verify("password123", hash)
+		require.NoError(t, err, name)
+		assert.True(t, ok, name)
+	}
+}
+
+func TestPolicy_VerifyMalformed(t *testing.T) {
+	p := NewPolicy(NewArgon2id(testArgon2Params), NewBcrypt(4))
+
+	tests := []struct {
+		name    string
+		encoded string
+		wantErr error
+	}{
+		{name: "empty", encoded: "", wantErr: ErrUnknownHashFormat},
+		{name: "plaintext", encoded: "password123", wantErr: ErrUnknownHashFormat},
+		{name: "unsupported scheme", encoded: "$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5", wantErr: ErrUnknownHashFormat},
+		{name: "argon2id missing key", encoded: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA", wantErr: ErrInvalidHash},
+		{name: "argon2id wrong version", encoded: "$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5", wantErr: ErrInvalidHash},
+		{name: "argon2id bad params", encoded: "$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5", wantErr: ErrInvalidHash},
+		{name: "argon2id bad salt", encoded: "$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5", wantErr: ErrInvalidHash},
+		{name: "argon2id empty key", encoded: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$", wantErr: ErrInvalidHash},
+		{name: "argon2id empty salt", encoded: "$argon2id$v=19$m=1024,t=1,p=1$$a2V5", wantErr: ErrInvalidHash},
+		{name: "argon2id zero parallelism", encoded: "$argon2id$v=19$m=1024,t=1,p=0$c2FsdA$a2V5", wantErr: ErrInvalidHash},
+		{name: "argon2id zero iterations", encoded: "$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5", wantErr: ErrInvalidHash},
+		{name: "argon2id too many iterations", encoded: "$argon2id$v=19$m=1024,t=100000,p=1$c2FsdA$a2V5", wantErr: ErrInvalidHash},
+		{name: "argon2id too much memory", encoded: "$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdA$a2V5", wantErr: ErrInvalidHash},
+		{name: "bcrypt truncated", encoded: "$2a$04$short", wantErr: ErrInvalidHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := p.Verify("password123", tt.encoded)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestPolicy_NeedsRehash(t *testing.T) {
+	weak := testArgon2Params
+	weak.Memory = 512
+	longerKey := testArgon2Params
+	longerKey.KeyLength = 64
+
+	argonPolicy := NewPolicy(NewArgon2id(testArgon2Params), NewBcrypt(4))
+	bcryptPolicy := NewPolicy(NewBcrypt(5), NewArgon2id(testArgon2Params))
+
+	tests := []struct {
+		name    string
+		policy  *Policy
+		encoded string
+		want    bool
+	}{
+		{name: "current argon2id", policy: argonPolicy, encoded: mustHash(t, NewArgon2id(testArgon2Params), "pw"), want: false},
+		{name: "stronger argon2id", policy: argonPolicy, encoded: mustHash(t, NewArgon2id(longerKey), "pw"), want: false},
+		{name: "weaker argon2id", policy: argonPolicy, encoded: mustHash(t, NewArgon2id(weak), "pw"), want: true},
+		{name: "bcrypt under argon2id", policy: argonPolicy, encoded: mustHash(t, NewBcrypt(4), "pw"), want: true},
+		{name: "argon2id under bcrypt", policy: bcryptPolicy, encoded: mustHash(t, NewArgon2id(testArgon2Params), "pw"), want: true},
+		{name: "cheaper bcrypt", policy: bcryptPolicy, encoded: mustHash(t, NewBcrypt(4), "pw"), want: true},
+		{name: "current bcrypt", policy: bcryptPolicy, encoded: mustHash(t, NewBcrypt(5), "pw"), want: false},
+		{name: "malformed", policy: argonPolicy, encoded: "$argon2id$broken", want: true},
+		{name: "empty", policy: argonPolicy, encoded: "", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.NeedsRehash(tt.encoded))
+		})
+	}
+}
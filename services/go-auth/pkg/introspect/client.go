@@ -0,0 +1,122 @@
+package introspect
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// Options tune the cache. Zero fields take the defaults.
+type Options struct {
+	// TTL is how long an active answer is reused, so a revoked session may
+	// be accepted for up to this long. It is also capped by the token's exp.
+	TTL time.Duration
+	// NegativeTTL is how long an inactive answer is reused; it keeps a
+	// client retrying a bad token from reaching go-auth every time.
+	NegativeTTL time.Duration
+	// MaxEntries bounds the cache.
+	MaxEntries int
+}
+
+var DefaultOptions = Options{
+	TTL:         30 * time.Second,
+	NegativeTTL: 5 * time.Second,
+	MaxEntries:  10000,
+}
+
+type entry struct {
+	result  Result
+	expires time.Time
+}
+
+// Client answers from its cache and asks go-auth through transport on a
+// miss. Errors are never cached. Tokens are kept only as SHA-256 hashes.
+type Client struct {
+	transport Transport
+	opts      Options
+	now       func() time.Time
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]entry
+}
+
+func New(transport Transport, opts Options) *Client {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultOptions.TTL
+	}
+	if opts.NegativeTTL <= 0 {
+		opts.NegativeTTL = DefaultOptions.NegativeTTL
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultOptions.MaxEntries
+	}
+	return &Client{
+		transport: transport,
+		opts:      opts,
+		now:       time.Now,
+		entries:   make(map[[sha256.Size]byte]entry),
+	}
+}
+
+func (c *Client) Introspect(ctx context.Context, token string) (*Result, error) {
+	key := sha256.Sum256([]byte(token))
+	now := c.now()
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		res := e.result
+		return &res, nil
+	}
+
+	res, err := c.transport.Introspect(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Active {
+		// Drop any fields a misbehaving server may have set.
+		res = &Result{}
+	}
+	c.store(key, *res, now)
+	return res, nil
+}
+
+func (c *Client) store(key [sha256.Size]byte, res Result, now time.Time) {
+	ttl := c.opts.NegativeTTL
+	if res.Active {
+		ttl = c.opts.TTL
+		if exp := res.Expiry(); !exp.IsZero() && exp.Sub(now) < ttl {
+			ttl = exp.Sub(now)
+		}
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.opts.MaxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = entry{result: res, expires: now.Add(ttl)}
+}
+
+// evict drops expired entries and, if that frees nothing, an arbitrary one.
+func (c *Client) evict(now time.Time) {
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	if len(c.entries) < c.opts.MaxEntries {
+		return
+	}
+	for k := range c.entries {
+		delete(c.entries, k)
+		if len(c.entries) < c.opts.MaxEntries {
+			return
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package introspect
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-auth/pkg/authz"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTransport answers from results and counts the calls.
+type fakeTransport struct {
+	results map[string]*Result
+	err     error
+	calls   int
+}
+
+func (f *fakeTransport) Introspect(ctx context.Context, token string) (*Result, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if res, ok := f.results[token]; ok {
+		out := *res
+		return &out, nil
+	}
+	return &Result{}, nil
+}
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestClient(transport Transport, opts Options) (*Client, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	c := New(transport, opts)
+	c.now = clock.now
+	return c, clock
+}
+
+func TestClient_Cache(t *testing.T) {
+	ctx := context.Background()
+	start := time.Unix(1700000000, 0)
+
+	t.Run("active answer is reused for TTL", func(t *testing.T) {
+		transport := &fakeTransport{results: map[string]*Result{
+			"token": {Active: true, Subject: "123", ExpiresAt: start.Add(time.Hour).Unix()},
+		}}
+		c, clock := newTestClient(transport, Options{TTL: 30 * time.Second})
+
+		for i := 0; i < 3; i++ {
+			res, err := c.Introspect(ctx, "token")
+			assert.NoError(t, err)
+			assert.Equal(t, "123", res.Subject)
+		}
+		assert.Equal(t, 1, transport.calls)
+
+		clock.advance(30 * time.Second)
+		_, err := c.Introspect(ctx, "token")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, transport.calls)
+	})
+
+	t.Run("TTL is capped by exp", func(t *testing.T) {
+		transport := &fakeTransport{results: map[string]*Result{
+			"token": {Active: true, Subject: "123", ExpiresAt: start.Add(10 * time.Second).Unix()},
+		}}
+		c, clock := newTestClient(transport, Options{TTL: time.Minute})
+
+		_, _ = c.Introspect(ctx, "token")
+		clock.advance(10 * time.Second)
+		_, _ = c.Introspect(ctx, "token")
+		assert.Equal(t, 2, transport.calls)
+	})
+
+	t.Run("inactive answer is reused for NegativeTTL", func(t *testing.T) {
+		transport := &fakeTransport{}
+		c, clock := newTestClient(transport, Options{NegativeTTL: 5 * time.Second})
+
+		res, err := c.Introspect(ctx, "revoked")
+		assert.NoError(t, err)
+		assert.False(t, res.Active)
+		clock.advance(4 * time.Second)
+		_, _ = c.Introspect(ctx, "revoked")
+		assert.Equal(t, 1, transport.calls)
+		clock.advance(time.Second)
+		_, _ = c.Introspect(ctx, "revoked")
+		assert.Equal(t, 2, transport.calls)
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		transport := &fakeTransport{err: errors.New("connection refused")}
+		c, _ := newTestClient(transport, Options{})
+
+		_, err := c.Introspect(ctx, "token")
+		assert.Error(t, err)
+		_, err = c.Introspect(ctx, "token")
+		assert.Error(t, err)
+		assert.Equal(t, 2, transport.calls)
+	})
+
+	t.Run("cache is bounded", func(t *testing.T) {
+		transport := &fakeTransport{}
+		c, _ := newTestClient(transport, Options{MaxEntries: 2})
+
+		for _, token := range []string{"a", "b", "c", "d"} {
+			_, err := c.Introspect(ctx, token)
+			assert.NoError(t, err)
+		}
+		assert.Len(t, c.entries, 2)
+	})
+
+	t.Run("callers can't modify cached answers", func(t *testing.T) {
+		transport := &fakeTransport{results: map[string]*Result{"token": {Active: true, Subject: "123"}}}
+		c, _ := newTestClient(transport, Options{})
+
+		res, _ := c.Introspect(ctx, "token")
+		res.Subject = "456"
+		res, _ = c.Introspect(ctx, "token")
+		assert.Equal(t, "123", res.Subject)
+	})
+}
+
+func TestClient_Middleware(t *testing.T) {
+	transport := &fakeTransport{results: map[string]*Result{
+		"good": {Active: true, Subject: "123", Permissions: []string{authz.PermGamePlay}},
+	}}
+	c, _ := newTestClient(transport, Options{})
+	failing, _ := newTestClient(&fakeTransport{err: errors.New("connection refused")}, Options{})
+
+	tests := []struct {
+		name           string
+		client         *Client
+		header         string
+		cookie         string
+		expectedStatus int
+		expectedUserId string
+	}{
+		{name: "bearer token", client: c, header: "Bearer good", expectedStatus: http.StatusOK, expectedUserId: "123"},
+		{name: "cookie", client: c, cookie: "good", expectedStatus: http.StatusOK, expectedUserId: "123"},
+		{name: "inactive token", client: c, header: "Bearer bad", expectedStatus: http.StatusUnauthorized},
+		{name: "no token", client: c, expectedStatus: http.StatusUnauthorized},
+		{name: "go-auth unavailable", client: failing, header: "Bearer good", expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var userId string
+			handler := tt.client.Middleware(authz.RequirePermission(authz.PermGamePlay)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				s, _ := authz.SubjectFromContext(r.Context())
+				userId = s.Id
+				res, ok := FromContext(r.Context())
+				assert.True(t, ok)
+				assert.True(t, res.Active)
+			})))
+
+			req := httptest.NewRequest(http.MethodGet, "/ws/player", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: AccessTokenCookie, Value: tt.cookie})
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedUserId, userId)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package introspect
+
+import (
+	"context"
+	"fmt"
+
+	"go-auth/pkg/introspect/introspectpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCTransport calls the IntrospectionService.
+type GRPCTransport struct {
+	client introspectpb.IntrospectionServiceClient
+	secret string
+}
+
+// NewGRPCTransport uses conn, which the caller dials and closes.
+func NewGRPCTransport(conn grpc.ClientConnInterface, secret string) *GRPCTransport {
+	return &GRPCTransport{client: introspectpb.NewIntrospectionServiceClient(conn), secret: secret}
+}
+
+func (t *GRPCTransport) Introspect(ctx context.Context, token string) (*Result, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+t.secret)
+	res, err := t.client.Introspect(ctx, &introspectpb.IntrospectRequest{Token: token, TokenTypeHint: "access_token"})
+	if status.Code(err) == codes.Unauthenticated {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, fmt.Errorf("introspect: %w", err)
+	}
+	return &Result{
+		Active:      res.GetActive(),
+		Subject:     res.GetSub(),
+		SessionId:   res.GetSid(),
+		Roles:       res.GetRoles(),
+		Permissions: res.GetPermissions(),
+		ExpiresAt:   res.GetExp(),
+		IssuedAt:    res.GetIat(),
+		Id:          res.GetJti(),
+		TokenType:   res.GetTokenType(),
+	}, nil
+}
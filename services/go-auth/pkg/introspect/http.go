@@ -0,0 +1,60 @@
+package introspect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTPTransport calls POST /introspect.
+type HTTPTransport struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewHTTPTransport talks to go-auth at baseURL. With a nil client requests
+// time out after 5 seconds.
+func NewHTTPTransport(baseURL, secret string, client *http.Client) *HTTPTransport {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &HTTPTransport{
+		url:    strings.TrimSuffix(baseURL, "/") + "/introspect",
+		secret: secret,
+		client: client,
+	}
+}
+
+func (t *HTTPTransport) Introspect(ctx context.Context, token string) (*Result, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.secret)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspect: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return nil, ErrUnauthorized
+	default:
+		return nil, fmt.Errorf("introspect: POST %s: %s", t.url, resp.Status)
+	}
+	var res Result
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("introspect: decode response: %w", err)
+	}
+	return &res, nil
+}
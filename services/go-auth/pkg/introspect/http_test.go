@@ -0,0 +1,51 @@
+package introspect
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/introspect", r.URL.Path)
+		switch {
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.PostFormValue("token") == "good":
+			w.Write([]byte(`{"active":true,"sub":"123","sid":"session-1","roles":["player"],"exp":1700000000,"token_type":"access"}`))
+		case r.PostFormValue("token") == "boom":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"active":false}`))
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	transport := NewHTTPTransport(server.URL+"/", "secret", server.Client())
+	res, err := transport.Introspect(ctx, "good")
+	assert.NoError(t, err)
+	assert.Equal(t, &Result{
+		Active:    true,
+		Subject:   "123",
+		SessionId: "session-1",
+		Roles:     []string{"player"},
+		ExpiresAt: 1700000000,
+		TokenType: "access",
+	}, res)
+
+	res, err = transport.Introspect(ctx, "bad")
+	assert.NoError(t, err)
+	assert.False(t, res.Active)
+
+	_, err = transport.Introspect(ctx, "boom")
+	assert.Error(t, err)
+
+	_, err = NewHTTPTransport(server.URL, "wrong", server.Client()).Introspect(ctx, "good")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
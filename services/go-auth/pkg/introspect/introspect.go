@@ -0,0 +1,53 @@
+// Package introspect lets other services check go-auth access tokens
+// through POST /introspect or the gRPC IntrospectionService, with a small
+// cache in front so that not every request costs a round trip.
+//
+//	client := introspect.New(introspect.NewHTTPTransport("http://go-auth:8080", secret, nil), introspect.Options{})
+//	router.Use(client.Middleware)
+//
+// Handlers behind Middleware find the caller with authz.SubjectFromContext,
+// so authz.RequirePermission works as it does in go-auth itself.
+package introspect
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-auth/pkg/authz"
+)
+
+// ErrUnauthorized means go-auth didn't accept our shared secret.
+var ErrUnauthorized = errors.New("introspect: caller rejected")
+
+// Result is go-auth's answer about a token. When Active is false the other
+// fields are empty.
+type Result struct {
+	Active      bool     `json:"active"`
+	Subject     string   `json:"sub,omitempty"`
+	SessionId   string   `json:"sid,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	ExpiresAt   int64    `json:"exp,omitempty"`
+	IssuedAt    int64    `json:"iat,omitempty"`
+	Id          string   `json:"jti,omitempty"`
+	TokenType   string   `json:"token_type,omitempty"`
+}
+
+// Expiry returns when the token expires, zero if unknown.
+func (r Result) Expiry() time.Time {
+	if r.ExpiresAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(r.ExpiresAt, 0)
+}
+
+// AuthzSubject returns the subject authz.RequirePermission checks.
+func (r Result) AuthzSubject() authz.Subject {
+	return authz.Subject{Id: r.Subject, Roles: r.Roles, Permissions: r.Permissions}
+}
+
+// Transport asks go-auth about a token.
+type Transport interface {
+	Introspect(ctx context.Context, token string) (*Result, error)
+}
@@ -0,0 +1,63 @@
+package introspect
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go-auth/pkg/authz"
+)
+
+// AccessTokenCookie is where browsers carry the access token go-auth sets.
+const AccessTokenCookie = "access_token"
+
+type resultKey struct{}
+
+// FromContext returns the introspection result put there by Middleware.
+func FromContext(ctx context.Context) (*Result, bool) {
+	res, ok := ctx.Value(resultKey{}).(*Result)
+	return res, ok
+}
+
+// Middleware admits requests with an active access token, taken from the
+// Authorization bearer header or the access_token cookie, and puts the
+// caller into the context for authz. It answers 401 otherwise and 503 when
+// go-auth can't be asked.
+func (c *Client) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		res, err := c.Introspect(r.Context(), token)
+		if err != nil {
+			slog.Error("introspect Middleware: " + err.Error())
+			if errors.Is(err, ErrUnauthorized) {
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if !res.Active {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		ctx := authz.WithSubject(r.Context(), res.AuthzSubject())
+		ctx = context.WithValue(ctx, resultKey{}, res)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func bearerToken(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return token
+	}
+	if cookie, err := r.Cookie(AccessTokenCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
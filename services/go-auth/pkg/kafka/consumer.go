@@ -0,0 +1,103 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"log/slog"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// retryInterval keeps a failing database from turning Run into a busy loop.
+const retryInterval = 5 * time.Second
+
+// AuditConsumer moves security events from the audit topic into the
+// auth_events table.
+type AuditConsumer struct {
+	consumerGroup sarama.ConsumerGroup
+	store         app.AppAuditStorage
+	topics        []string
+}
+
+func NewAuditConsumer(cfg app.AppConfig, store app.AppAuditStorage) (*AuditConsumer, error) {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_8_0_0
+	config.Consumer.Return.Errors = true
+	c := cfg.GetConfig()
+
+	// Offsets are committed by hand once an event is stored.
+	config.Consumer.Offsets.AutoCommit.Enable = false
+	// The trail must not lose events produced while no consumer ran.
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	consumerGroup, err := sarama.NewConsumerGroup(c.KafkaBrokers, c.KafkaGroupId, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditConsumer{
+		consumerGroup: consumerGroup,
+		store:         store,
+		topics:        []string{c.AuditTopic},
+	}, nil
+}
+
+func (c *AuditConsumer) Close() {
+	if err := c.consumerGroup.Close(); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// Run consumes until ctx is done. Rebalances end Consume, so it is called
+// again in a loop.
+func (c *AuditConsumer) Run(ctx context.Context) {
+	go func() {
+		for err := range c.consumerGroup.Errors() {
+			slog.Error("AuditConsumer: " + err.Error())
+		}
+	}()
+	for {
+		if err := c.consumerGroup.Consume(ctx, c.topics, c); err != nil {
+			slog.Error("AuditConsumer consume: " + err.Error())
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryInterval):
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (c *AuditConsumer) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (c *AuditConsumer) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (c *AuditConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		var event models.AuthEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil || event.Id == "" {
+			// A malformed event will never become valid; skip it.
+			slog.Error("AuditConsumer bad event", "offset", msg.Offset, "value", string(msg.Value))
+			session.MarkMessage(msg, "")
+			continue
+		}
+		if err := c.store.Save(event); err != nil {
+			// Leave the offset so the event is redelivered after a restart
+			// or rebalance.
+			slog.Error("AuditConsumer save: " + err.Error())
+			return err
+		}
+		session.MarkMessage(msg, "")
+		session.Commit()
+	}
+	return nil
+}
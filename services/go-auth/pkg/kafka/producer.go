@@ -0,0 +1,50 @@
+package kafka
+
+import (
+	"go-auth/internal/app"
+	"log/slog"
+
+	"github.com/IBM/sarama"
+)
+
+type Producer struct {
+	producer sarama.SyncProducer
+}
+
+func NewProducer(cfg app.AppConfig) (*Producer, error) {
+	config := sarama.NewConfig()
+	config.Producer.Return.Successes = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 5
+
+	p, err := sarama.NewSyncProducer(cfg.GetConfig().KafkaBrokers, config)
+	if err != nil {
+		return nil, err
+	}
+	return &Producer{producer: p}, nil
+}
+
+func (p *Producer) Close() {
+	if err := p.producer.Close(); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// Produce sends value to topic. Messages with the same key go to the same
+// partition, so they are consumed in order.
+func (p *Producer) Produce(topic, key, value string) error {
+	msg := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.StringEncoder(value),
+	}
+	if key != "" {
+		msg.Key = sarama.StringEncoder(key)
+	}
+
+	partition, offset, err := p.producer.SendMessage(msg)
+	if err != nil {
+		return err
+	}
+	slog.Debug("Message sent", "topic", topic, "partition", partition, "offset", offset)
+	return nil
+}
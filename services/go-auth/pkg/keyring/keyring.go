@@ -0,0 +1,430 @@
+package keyring
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	rsaKeyBits = 2048
+	// syncInterval is how often the ring reloads the shared store, picking
+	// up rotations made by other instances.
+	syncInterval = time.Minute
+	// publishDelay is how long a new key is in the JWKS before it signs, so
+	// that the other instances have loaded it by the time they see tokens
+	// signed with it.
+	publishDelay = syncInterval
+	// reloadInterval bounds how often tokens with an unknown kid reload the
+	// store, so made-up kids can't keep the ring reloading.
+	reloadInterval = 10 * time.Second
+	// syncAttempts bounds the retries of a sync that lost a race with
+	// another instance saving the ring.
+	syncAttempts = 3
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+var ErrNoActiveKey = errors.New("no active signing key")
+
+type Options struct {
+	Algorithm        string
+	RotationInterval time.Duration
+	// Retention is how long a retired key keeps verifying tokens. It must
+	// cover the longest token lifetime.
+	Retention time.Duration
+	// KeyEncryptionKey is the AES-256 key the private keys are sealed with
+	// in the store. Without it they are stored in the clear; keys stored in
+	// the clear are sealed on the next sync once it is set.
+	KeyEncryptionKey []byte
+}
+
+type Key struct {
+	ID        string
+	Algorithm string
+	CreatedAt time.Time
+	RetiredAt time.Time
+	private   crypto.Signer
+}
+
+func (k *Key) Public() crypto.PublicKey {
+	return k.private.Public()
+}
+
+// KeyRing signs with the newest key and verifies with every key that has
+// not yet passed its retention period.
+type KeyRing struct {
+	mu     sync.RWMutex
+	store  Store
+	opts   Options
+	keys   map[string]*Key
+	active *Key
+	now    func() time.Time
+	// aead seals the private keys in the store; nil stores them in the clear.
+	aead cipher.AEAD
+	// reloadMu serializes reloads for unknown kids, lastReload is when the
+	// last of them ran.
+	reloadMu   sync.Mutex
+	lastReload time.Time
+}
+
+func New(cfg app.AppConfig, rds app.AppRedis) *KeyRing {
+	c := cfg.GetConfig()
+	kek, err := c.KeyEncryptionKey()
+	if err != nil {
+		panic(err.Error())
+	}
+	if kek == nil {
+		slog.Warn("KeyRing JWT_KEY_ENCRYPTION_KEY is not set, signing keys are stored in Redis unencrypted")
+	}
+	kr, err := NewKeyRing(context.Background(), NewRedisStore(rds), Options{
+		Algorithm:        c.JWTSigningAlg,
+		RotationInterval: c.JWTKeyRotation,
+		Retention:        c.JWTKeyRetention,
+		KeyEncryptionKey: kek,
+	})
+	if err != nil {
+		panic(err.Error())
+	}
+	go kr.Run(context.Background())
+	return kr
+}
+
+func NewKeyRing(ctx context.Context, store Store, opts Options) (*KeyRing, error) {
+	if opts.Algorithm != AlgRS256 && opts.Algorithm != AlgEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", opts.Algorithm)
+	}
+	kr := &KeyRing{store: store, opts: opts, keys: map[string]*Key{}, now: time.Now}
+	if opts.KeyEncryptionKey != nil {
+		block, err := aes.NewCipher(opts.KeyEncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("key encryption key: %w", err)
+		}
+		if kr.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, fmt.Errorf("key encryption key: %w", err)
+		}
+	}
+	if err := kr.sync(ctx, false); err != nil {
+		return nil, err
+	}
+	return kr, nil
+}
+
+// Run keeps the ring in sync with the store and rotates on schedule.
+func (kr *KeyRing) Run(ctx context.Context) {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := kr.sync(ctx, false); err != nil {
+				slog.Error("KeyRing sync: " + err.Error())
+			}
+		}
+	}
+}
+
+// Rotate publishes a new key. It replaces the active key once it has been
+// published for publishDelay.
+func (kr *KeyRing) Rotate(ctx context.Context) error {
+	return kr.sync(ctx, true)
+}
+
+func (kr *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	kr.mu.RLock()
+	key := kr.active
+	kr.mu.RUnlock()
+	if key == nil {
+		return "", ErrNoActiveKey
+	}
+	token := jwt.NewWithClaims(signingMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// Keyfunc resolves the verification key by the token's kid header.
+func (kr *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing kid", ErrUnknownKey)
+	}
+	key, ok := kr.key(kid)
+	if !ok {
+		key, ok = kr.reloadFor(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.Public(), nil
+}
+
+func (kr *KeyRing) key(kid string) (*Key, bool) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	key, ok := kr.keys[kid]
+	return key, ok
+}
+
+// reloadFor syncs with the store to pick up kid from a key another instance
+// has published since the last sync, at most once per reloadInterval.
+func (kr *KeyRing) reloadFor(kid string) (*Key, bool) {
+	kr.reloadMu.Lock()
+	defer kr.reloadMu.Unlock()
+	// A reload we waited for may have loaded it.
+	if key, ok := kr.key(kid); ok {
+		return key, true
+	}
+	now := kr.now()
+	if now.Sub(kr.lastReload) < reloadInterval {
+		return nil, false
+	}
+	kr.lastReload = now
+	if err := kr.sync(context.Background(), false); err != nil {
+		slog.Error("KeyRing reload: " + err.Error())
+		return nil, false
+	}
+	return kr.key(kid)
+}
+
+func (kr *KeyRing) JWKS() models.JWKSet {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	set := models.JWKSet{Keys: make([]models.JWK, 0, len(kr.keys))}
+	for _, key := range sortedKeys(kr.keys) {
+		set.Keys = append(set.Keys, toJWK(key))
+	}
+	return set
+}
+
+// sync loads the ring, rotates and expires keys as due and saves the
+// result. Losing the race to save to another instance reloads its ring and
+// starts over.
+func (kr *KeyRing) sync(ctx context.Context, force bool) error {
+	for attempt := 1; ; attempt++ {
+		err := kr.syncOnce(ctx, force)
+		if !errors.Is(err, ErrConflict) || attempt == syncAttempts {
+			return err
+		}
+		slog.Info("KeyRing changed by another instance, reloading")
+	}
+}
+
+func (kr *KeyRing) syncOnce(ctx context.Context, force bool) error {
+	stored, revision, err := kr.store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("KeyRing load: %w", err)
+	}
+	keys := make(map[string]*Key, len(stored))
+	// Keys stored in the clear are sealed once there is a key to seal with.
+	changed := false
+	for _, sk := range stored {
+		key, err := kr.decodeKey(sk)
+		if err != nil {
+			return fmt.Errorf("KeyRing decode %s: %w", sk.ID, err)
+		}
+		keys[key.ID] = key
+		changed = changed || (kr.aead != nil && !sk.Encrypted)
+	}
+
+	now := kr.now()
+	newest := newestActive(keys)
+	if force || newest == nil ||
+		now.Sub(newest.CreatedAt) >= kr.opts.RotationInterval ||
+		newest.Algorithm != kr.opts.Algorithm {
+		next, err := generateKey(kr.opts.Algorithm, now)
+		if err != nil {
+			return fmt.Errorf("KeyRing generate: %w", err)
+		}
+		keys[next.ID] = next
+		changed = true
+		slog.Info("KeyRing published signing key", "kid", next.ID, "alg", next.Algorithm)
+	}
+	// The keys a published key replaces are retired once it signs.
+	active := signingKey(keys, now)
+	for _, k := range keys {
+		if k.RetiredAt.IsZero() && k.CreatedAt.Before(active.CreatedAt) {
+			k.RetiredAt = now
+			changed = true
+			slog.Info("KeyRing rotated signing key", "kid", active.ID, "retired", k.ID)
+		}
+	}
+	for id, k := range keys {
+		if !k.RetiredAt.IsZero() && now.Sub(k.RetiredAt) > kr.opts.Retention {
+			delete(keys, id)
+			changed = true
+		}
+	}
+
+	if changed {
+		toStore := make([]StoredKey, 0, len(keys))
+		for _, k := range sortedKeys(keys) {
+			sk, err := kr.encodeKey(k)
+			if err != nil {
+				return fmt.Errorf("KeyRing encode %s: %w", k.ID, err)
+			}
+			toStore = append(toStore, sk)
+		}
+		if err := kr.store.Save(ctx, toStore, revision); err != nil {
+			return fmt.Errorf("KeyRing save: %w", err)
+		}
+	}
+
+	kr.mu.Lock()
+	kr.keys = keys
+	kr.active = active
+	kr.mu.Unlock()
+	return nil
+}
+
+func newestActive(keys map[string]*Key) *Key {
+	var active *Key
+	for _, k := range keys {
+		if k.RetiredAt.IsZero() && (active == nil || k.CreatedAt.After(active.CreatedAt)) {
+			active = k
+		}
+	}
+	return active
+}
+
+// signingKey is the newest unretired key published for publishDelay. Until
+// one is, as on first start, it is the oldest unretired key.
+func signingKey(keys map[string]*Key, now time.Time) *Key {
+	var signing, oldest *Key
+	for _, k := range keys {
+		if !k.RetiredAt.IsZero() {
+			continue
+		}
+		if oldest == nil || k.CreatedAt.Before(oldest.CreatedAt) {
+			oldest = k
+		}
+		if now.Sub(k.CreatedAt) >= publishDelay && (signing == nil || k.CreatedAt.After(signing.CreatedAt)) {
+			signing = k
+		}
+	}
+	if signing == nil {
+		return oldest
+	}
+	return signing
+}
+
+func sortedKeys(keys map[string]*Key) []*Key {
+	res := make([]*Key, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, k)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.After(res[j].CreatedAt)
+	})
+	return res
+}
+
+func signingMethod(alg string) jwt.SigningMethod {
+	if alg == AlgRS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+func generateKey(alg string, now time.Time) (*Key, error) {
+	var signer crypto.Signer
+	switch alg {
+	case AlgRS256:
+		k, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		signer = k
+	case AlgEdDSA:
+		_, k, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = k
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", alg)
+	}
+	return &Key{ID: uuid.NewString(), Algorithm: alg, CreatedAt: now, private: signer}, nil
+}
+
+// encodeKey marshals k for the store, sealing the private key when the
+// ring has a key encryption key. The kid is authenticated along with it so
+// sealed keys can't be swapped between entries.
+func (kr *KeyRing) encodeKey(k *Key) (StoredKey, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.private)
+	if err != nil {
+		return StoredKey{}, err
+	}
+	sk := StoredKey{
+		ID:         k.ID,
+		Algorithm:  k.Algorithm,
+		PrivateKey: der,
+		CreatedAt:  k.CreatedAt,
+		RetiredAt:  k.RetiredAt,
+	}
+	if kr.aead != nil {
+		nonce := make([]byte, kr.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return StoredKey{}, err
+		}
+		sk.PrivateKey = kr.aead.Seal(nonce, nonce, der, []byte(k.ID))
+		sk.Encrypted = true
+	}
+	return sk, nil
+}
+
+func (kr *KeyRing) decodeKey(sk StoredKey) (*Key, error) {
+	der := sk.PrivateKey
+	if sk.Encrypted {
+		if kr.aead == nil {
+			return nil, errors.New("key is encrypted but no key encryption key is configured")
+		}
+		size := kr.aead.NonceSize()
+		if len(der) < size {
+			return nil, errors.New("sealed key is truncated")
+		}
+		var err error
+		if der, err = kr.aead.Open(nil, der[:size], der[size:], []byte(sk.ID)); err != nil {
+			return nil, fmt.Errorf("open sealed key: %w", err)
+		}
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+	return &Key{
+		ID:        sk.ID,
+		Algorithm: sk.Algorithm,
+		CreatedAt: sk.CreatedAt,
+		RetiredAt: sk.RetiredAt,
+		private:   signer,
+	}, nil
+}
@@ -0,0 +1,217 @@
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKEK = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestRing(t *testing.T, store Store, kek []byte) *KeyRing {
+	t.Helper()
+	kr, err := NewKeyRing(context.Background(), store, Options{
+		Algorithm:        AlgEdDSA,
+		RotationInterval: time.Hour,
+		Retention:        time.Hour,
+		KeyEncryptionKey: kek,
+	})
+	require.NoError(t, err)
+	return kr
+}
+
+func storedIDs(t *testing.T, store Store) []string {
+	t.Helper()
+	keys, _, err := store.Load(context.Background())
+	require.NoError(t, err)
+	ids := make([]string, 0, len(keys))
+	for _, k := range keys {
+		ids = append(ids, k.ID)
+	}
+	return ids
+}
+
+// racingStore runs race before its first Save, like another instance
+// saving the ring between this one's load and save.
+type racingStore struct {
+	*MemoryStore
+	race func()
+}
+
+func (s *racingStore) Save(ctx context.Context, keys []StoredKey, revision string) error {
+	if race := s.race; race != nil {
+		s.race = nil
+		race()
+	}
+	return s.MemoryStore.Save(ctx, keys, revision)
+}
+
+func TestKeyRing_ConcurrentRotation(t *testing.T) {
+	ctx := context.Background()
+	store := &racingStore{MemoryStore: NewMemoryStore()}
+	a := newTestRing(t, store, nil)
+	b := newTestRing(t, store.MemoryStore, nil)
+
+	store.race = func() { require.NoError(t, b.Rotate(ctx)) }
+	require.NoError(t, a.Rotate(ctx))
+
+	ids := storedIDs(t, store)
+	assert.Len(t, ids, 3, "the initial key and one key per rotation")
+	assert.Contains(t, ids, newestActive(b.keys).ID, "the other instance's key survives")
+	assert.Contains(t, ids, newestActive(a.keys).ID)
+}
+
+// countingStore counts loads, each one a round trip to Redis.
+type countingStore struct {
+	*MemoryStore
+	loads int
+}
+
+func (s *countingStore) Load(ctx context.Context) ([]StoredKey, string, error) {
+	s.loads++
+	return s.MemoryStore.Load(ctx)
+}
+
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time          { return c.t }
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func sign(t *testing.T, kr *KeyRing) (string, string) {
+	t.Helper()
+	signed, err := kr.Sign(jwt.RegisteredClaims{Subject: "123"})
+	require.NoError(t, err)
+	token, _, err := jwt.NewParser().ParseUnverified(signed, &jwt.RegisteredClaims{})
+	require.NoError(t, err)
+	return signed, token.Header["kid"].(string)
+}
+
+func TestKeyRing_PublishesBeforeSigning(t *testing.T) {
+	ctx := context.Background()
+	kr := newTestRing(t, NewMemoryStore(), nil)
+	clock := &testClock{t: time.Now()}
+	kr.now = clock.now
+	_, first := sign(t, kr)
+
+	require.NoError(t, kr.Rotate(ctx))
+	next := newestActive(kr.keys).ID
+	assert.NotEqual(t, first, next)
+	assert.Equal(t, next, kr.JWKS().Keys[0].Kid, "the new key is published")
+	_, kid := sign(t, kr)
+	assert.Equal(t, first, kid, "the published key doesn't sign yet")
+
+	clock.advance(publishDelay)
+	require.NoError(t, kr.sync(ctx, false))
+	_, kid = sign(t, kr)
+	assert.Equal(t, next, kid)
+	assert.False(t, kr.keys[first].RetiredAt.IsZero(), "the replaced key is retired")
+}
+
+func TestKeyRing_VerifiesKeysOfOtherInstances(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	a := newTestRing(t, store, nil)
+	b := newTestRing(t, store, nil)
+	clock := &testClock{t: time.Now()}
+	a.now = clock.now
+
+	require.NoError(t, a.Rotate(ctx))
+	clock.advance(publishDelay)
+	require.NoError(t, a.sync(ctx, false))
+	signed, kid := sign(t, a)
+	_, known := b.key(kid)
+	require.False(t, known, "b hasn't synced since a rotated")
+
+	_, err := jwt.Parse(signed, b.Keyfunc)
+	assert.NoError(t, err, "b reloads the store for the unknown kid")
+}
+
+func TestKeyRing_UnknownKidReloadsAreLimited(t *testing.T) {
+	store := &countingStore{MemoryStore: NewMemoryStore()}
+	kr := newTestRing(t, store, nil)
+	clock := &testClock{t: time.Now()}
+	kr.now = clock.now
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.RegisteredClaims{})
+	token.Header["kid"] = "made-up"
+	loads := store.loads
+
+	for i := 0; i < 3; i++ {
+		_, err := kr.Keyfunc(token)
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	}
+	assert.Equal(t, loads+1, store.loads)
+
+	clock.advance(reloadInterval)
+	_, err := kr.Keyfunc(token)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, loads+2, store.loads)
+}
+
+func TestKeyRing_Encryption(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("keys are sealed", func(t *testing.T) {
+		store := NewMemoryStore()
+		kr := newTestRing(t, store, testKEK)
+
+		keys, _, err := store.Load(ctx)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.True(t, keys[0].Encrypted)
+		_, err = x509.ParsePKCS8PrivateKey(keys[0].PrivateKey)
+		assert.Error(t, err, "private key is not stored in the clear")
+
+		other := newTestRing(t, store, testKEK)
+		assert.Equal(t, kr.active.ID, other.active.ID)
+		assert.Equal(t, kr.active.Public(), other.active.Public())
+	})
+
+	t.Run("plaintext keys are sealed once a key is configured", func(t *testing.T) {
+		store := NewMemoryStore()
+		plain := newTestRing(t, store, nil)
+
+		sealed := newTestRing(t, store, testKEK)
+		assert.Equal(t, plain.active.ID, sealed.active.ID)
+		keys, _, err := store.Load(ctx)
+		require.NoError(t, err)
+		assert.True(t, keys[0].Encrypted)
+	})
+
+	t.Run("wrong or missing key", func(t *testing.T) {
+		store := NewMemoryStore()
+		newTestRing(t, store, testKEK)
+
+		_, err := NewKeyRing(ctx, store, Options{Algorithm: AlgEdDSA, RotationInterval: time.Hour, Retention: time.Hour})
+		assert.Error(t, err)
+		_, err = NewKeyRing(ctx, store, Options{
+			Algorithm:        AlgEdDSA,
+			RotationInterval: time.Hour,
+			Retention:        time.Hour,
+			KeyEncryptionKey: bytes.Repeat([]byte{1}, 32),
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("sealed key is bound to its kid", func(t *testing.T) {
+		store := NewMemoryStore()
+		newTestRing(t, store, testKEK)
+		keys, revision, err := store.Load(ctx)
+		require.NoError(t, err)
+		keys[0].ID = "swapped"
+		require.NoError(t, store.Save(ctx, keys, revision))
+
+		_, err = NewKeyRing(ctx, store, Options{
+			Algorithm:        AlgEdDSA,
+			RotationInterval: time.Hour,
+			Retention:        time.Hour,
+			KeyEncryptionKey: testKEK,
+		})
+		assert.Error(t, err)
+	})
+}
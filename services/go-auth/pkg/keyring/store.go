@@ -0,0 +1,124 @@
+package keyring
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-auth/internal/app"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyRing = "jwt:keyring"
+
+// ErrConflict is returned by Store.Save when another instance changed the
+// ring since it was loaded.
+var ErrConflict = errors.New("key ring changed concurrently")
+
+// StoredKey is the persisted form of a signing key. PrivateKey is PKCS#8
+// DER, sealed with the key encryption key when Encrypted is set.
+type StoredKey struct {
+	ID         string    `json:"kid"`
+	Algorithm  string    `json:"alg"`
+	PrivateKey []byte    `json:"key"`
+	Encrypted  bool      `json:"enc,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	RetiredAt  time.Time `json:"retired_at"`
+}
+
+// Store persists the whole ring so every instance signs with the same key.
+// Load returns a revision of the ring; Save replaces the ring only if it is
+// still at that revision, so two instances rotating at once can't overwrite
+// each other's keys.
+type Store interface {
+	Load(ctx context.Context) ([]StoredKey, string, error)
+	Save(ctx context.Context, keys []StoredKey, revision string) error
+}
+
+// saveKeyRingScript sets KEYS[1] to ARGV[2] if the SHA-1 of its current
+// value, "" when there is none, is ARGV[1].
+const saveKeyRingScript = `
+local current = redis.call("GET", KEYS[1])
+local revision = ""
+if current then
+	revision = redis.sha1hex(current)
+end
+if revision ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2])
+return 1
+`
+
+type RedisStore struct {
+	redisDB app.AppRedis
+}
+
+func NewRedisStore(redisDB app.AppRedis) *RedisStore {
+	return &RedisStore{redisDB: redisDB}
+}
+
+func (s *RedisStore) Load(ctx context.Context) ([]StoredKey, string, error) {
+	raw, err := s.redisDB.Get(ctx, redisKeyRing).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("RedisStore Load: %w", err)
+	}
+	var keys []StoredKey
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return nil, "", fmt.Errorf("RedisStore Load: %w", err)
+	}
+	sum := sha1.Sum(raw)
+	return keys, hex.EncodeToString(sum[:]), nil
+}
+
+func (s *RedisStore) Save(ctx context.Context, keys []StoredKey, revision string) error {
+	raw, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("RedisStore Save: %w", err)
+	}
+	saved, err := s.redisDB.Eval(ctx, saveKeyRingScript, []string{redisKeyRing}, revision, raw).Int()
+	if err != nil {
+		return fmt.Errorf("RedisStore Save: %w", err)
+	}
+	if saved == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+type MemoryStore struct {
+	mu       sync.Mutex
+	keys     []StoredKey
+	revision int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Load(ctx context.Context) ([]StoredKey, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]StoredKey(nil), s.keys...), strconv.Itoa(s.revision), nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, keys []StoredKey, revision string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if revision != strconv.Itoa(s.revision) {
+		return ErrConflict
+	}
+	s.keys = append([]StoredKey(nil), keys...)
+	s.revision++
+	return nil
+}
@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"go-auth/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// File stores every message as an .eml file in dir, so a local run can be
+// inspected without a mail server.
+type File struct {
+	dir  string
+	from string
+	seq  atomic.Uint64
+}
+
+func NewFile(dir, from string) *File {
+	return &File{dir: dir, from: from}
+}
+
+func (f *File) Send(ctx context.Context, mail models.Mail) error {
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		return fmt.Errorf("File Send: %w", err)
+	}
+	name := fmt.Sprintf("%s-%d-%s.eml",
+		time.Now().UTC().Format("20060102T150405"),
+		f.seq.Add(1),
+		strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(mail.To),
+	)
+	if err := os.WriteFile(filepath.Join(f.dir, name), compose(f.from, mail), 0o644); err != nil {
+		return fmt.Errorf("File Send: %w", err)
+	}
+	return nil
+}
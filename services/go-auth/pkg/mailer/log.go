@@ -0,0 +1,19 @@
+package mailer
+
+import (
+	"context"
+	"go-auth/internal/models"
+	"log/slog"
+)
+
+// Log writes messages to the service log instead of sending them.
+type Log struct{}
+
+func NewLog() *Log {
+	return &Log{}
+}
+
+func (l *Log) Send(ctx context.Context, mail models.Mail) error {
+	slog.Info("Mailer message", "to", mail.To, "subject", mail.Subject, "body", mail.Body)
+	return nil
+}
@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+)
+
+const (
+	KindLog  = "log"
+	KindFile = "file"
+	KindSMTP = "smtp"
+)
+
+// Sender delivers a single message.
+type Sender interface {
+	Send(ctx context.Context, mail models.Mail) error
+}
+
+// New picks the implementation configured by MAILER. The log and file
+// mailers are meant for local runs and tests; production uses smtp.
+func New(cfg app.AppConfig) Sender {
+	c := cfg.GetConfig()
+	switch c.Mailer {
+	case KindSMTP:
+		return NewSMTP(SMTPOptions{
+			Host:     c.SMTPHost,
+			Port:     c.SMTPPort,
+			User:     c.SMTPUser,
+			Password: c.SMTPPassword,
+			From:     c.MailFrom,
+		})
+	case KindFile:
+		return NewFile(c.MailerDir, c.MailFrom)
+	case KindLog, "":
+		return NewLog()
+	default:
+		panic(fmt.Sprintf("unknown mailer: %s", c.Mailer))
+	}
+}
@@ -0,0 +1,69 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-auth/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testMail = models.Mail{To: "user@example.com", Subject: "Сброс пароля", Body: "line one\nline two"}
+
+func TestFile_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := NewFile(dir, "no-reply@localhost")
+
+	assert.NoError(t, m.Send(context.Background(), testMail))
+	assert.NoError(t, m.Send(context.Background(), testMail))
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2, "each message gets its own file")
+
+	raw, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	assert.NoError(t, err)
+	msg := string(raw)
+	assert.Contains(t, entries[0].Name(), "user_at_example.com")
+	assert.Contains(t, msg, "From: no-reply@localhost\r\n")
+	assert.Contains(t, msg, "To: user@example.com\r\n")
+	assert.Contains(t, msg, "Subject: =?utf-8?q?")
+	assert.True(t, strings.HasSuffix(msg, "\r\n\r\nline one\r\nline two"))
+}
+
+func TestSMTP_Send(t *testing.T) {
+	m := NewSMTP(SMTPOptions{Host: "smtp.example.com", Port: "587", User: "user", Password: "secret", From: "no-reply@example.com"})
+
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotAuth smtp.Auth
+	m.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotAuth, gotFrom, gotTo = addr, a, from, to
+		return nil
+	}
+	assert.NoError(t, m.Send(context.Background(), testMail))
+	assert.Equal(t, "smtp.example.com:587", gotAddr)
+	assert.NotNil(t, gotAuth)
+	assert.Equal(t, "no-reply@example.com", gotFrom)
+	assert.Equal(t, []string{"user@example.com"}, gotTo)
+
+	m.send = func(string, smtp.Auth, string, []string, []byte) error {
+		return errors.New("connection refused")
+	}
+	assert.ErrorContains(t, m.Send(context.Background(), testMail), "connection refused")
+}
+
+func TestSMTP_NoAuthWithoutUser(t *testing.T) {
+	m := NewSMTP(SMTPOptions{Host: "localhost", Port: "25", From: "no-reply@localhost"})
+	m.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		assert.Nil(t, a)
+		return nil
+	}
+	assert.NoError(t, m.Send(context.Background(), testMail))
+}
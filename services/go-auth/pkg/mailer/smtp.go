@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go-auth/internal/models"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type SMTPOptions struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	From     string
+}
+
+// SMTP sends through a relay, authenticating with PLAIN when a user is set.
+// net/smtp upgrades to STARTTLS whenever the server offers it.
+type SMTP struct {
+	opts SMTPOptions
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewSMTP(opts SMTPOptions) *SMTP {
+	return &SMTP{opts: opts, send: smtp.SendMail}
+}
+
+func (s *SMTP) Send(ctx context.Context, mail models.Mail) error {
+	var auth smtp.Auth
+	if s.opts.User != "" {
+		auth = smtp.PlainAuth("", s.opts.User, s.opts.Password, s.opts.Host)
+	}
+	addr := net.JoinHostPort(s.opts.Host, s.opts.Port)
+	if err := s.send(addr, auth, s.opts.From, []string{mail.To}, compose(s.opts.From, mail)); err != nil {
+		return fmt.Errorf("SMTP Send: %w", err)
+	}
+	return nil
+}
+
+// compose renders a minimal RFC 5322 message with a UTF-8 plain text body.
+func compose(from string, mail models.Mail) []byte {
+	var b bytes.Buffer
+	header := func(k, v string) {
+		b.WriteString(k + ": " + v + "\r\n")
+	}
+	header("From", from)
+	header("To", mail.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", mail.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(mail.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes()
+}
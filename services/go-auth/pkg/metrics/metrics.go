@@ -24,11 +24,24 @@ var (
 		},
 		[]string{"method", "path"},
 	)
+	blockedAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_blocked_attempts_total",
+			Help: "Requests rejected by rate limiting or login lockout",
+		},
+		[]string{"scope", "reason"},
+	)
 )
 
 func init() {
 	prometheus.MustRegister(requestsTotal)
 	prometheus.MustRegister(requestDuration)
+	prometheus.MustRegister(blockedAttempts)
+}
+
+// RecordBlocked counts a rejected attempt; reason is "rate_limit" or "lockout".
+func RecordBlocked(scope, reason string) {
+	blockedAttempts.WithLabelValues(scope, reason).Inc()
 }
 
 func Start(addr string) {
@@ -0,0 +1,174 @@
+package oidc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-auth/pkg/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRedirect = "http://localhost:8080/oauth/fake/callback"
+
+func newTestProvider(srv *oidctest.Server) *Provider {
+	return NewProvider(ProviderConfig{
+		Name:         "fake",
+		Issuer:       srv.Issuer(),
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  testRedirect,
+	}, srv.Client())
+}
+
+// login runs the browser part of the flow and returns the code.
+func login(t *testing.T, srv *oidctest.Server, p *Provider, state, nonce, verifier string) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, S256Challenge(verifier))
+	assert.NoError(t, err)
+	code, gotState, err := srv.Authorize(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, state, gotState)
+	return code
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	srv := oidctest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		tamper        func(jwt.MapClaims)
+		verifier      string
+		nonce         string
+		expectedError error
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name:          "wrong code verifier",
+			verifier:      "not-the-verifier",
+			expectedError: ErrExchange,
+		},
+		{
+			name:          "wrong nonce",
+			nonce:         "another-nonce",
+			expectedError: ErrInvalidIDToken,
+		},
+		{
+			name:          "wrong audience",
+			tamper:        func(c jwt.MapClaims) { c["aud"] = "another-client" },
+			expectedError: ErrInvalidIDToken,
+		},
+		{
+			name:          "wrong issuer",
+			tamper:        func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+			expectedError: ErrInvalidIDToken,
+		},
+		{
+			name:          "expired",
+			tamper:        func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+			expectedError: ErrInvalidIDToken,
+		},
+		{
+			name: "azp required with several audiences",
+			tamper: func(c jwt.MapClaims) {
+				c["aud"] = []string{oidctest.ClientID, "another-client"}
+			},
+			expectedError: ErrInvalidIDToken,
+		},
+		{
+			name:          "missing sub",
+			tamper:        func(c jwt.MapClaims) { delete(c, "sub") },
+			expectedError: ErrInvalidIDToken,
+		},
+		{
+			name:   "email_verified as string",
+			tamper: func(c jwt.MapClaims) { c["email_verified"] = "true" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv.Tamper(tt.tamper)
+			p := newTestProvider(srv)
+			code := login(t, srv, p, "state-1", "nonce-1", "verifier-1")
+
+			verifier, nonce := "verifier-1", "nonce-1"
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			identity, err := p.Exchange(ctx, code, verifier, nonce)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, identity)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "fake", identity.Provider)
+			assert.Equal(t, "fake-sub-1", identity.Subject)
+			assert.Equal(t, "fake@example.com", identity.Email)
+			assert.True(t, identity.EmailVerified)
+		})
+	}
+	srv.Tamper(nil)
+
+	t.Run("code is one-time", func(t *testing.T) {
+		p := newTestProvider(srv)
+		code := login(t, srv, p, "state-1", "nonce-1", "verifier-1")
+		_, err := p.Exchange(ctx, code, "verifier-1", "nonce-1")
+		assert.NoError(t, err)
+		_, err = p.Exchange(ctx, code, "verifier-1", "nonce-1")
+		assert.ErrorIs(t, err, ErrExchange)
+	})
+}
+
+func TestProvider_KeyRotation(t *testing.T) {
+	srv := oidctest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+	p := newTestProvider(srv)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	_, err := p.Exchange(ctx, login(t, srv, p, "s", "n", "v"), "v", "n")
+	assert.NoError(t, err)
+
+	// A new kid is fetched, but not more often than minRefreshInterval.
+	srv.RotateKey()
+	_, err = p.Exchange(ctx, login(t, srv, p, "s", "n", "v"), "v", "n")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	now = now.Add(minRefreshInterval)
+	_, err = p.Exchange(ctx, login(t, srv, p, "s", "n", "v"), "v", "n")
+	assert.NoError(t, err)
+}
+
+func TestProvider_DiscoveryIssuerMismatch(t *testing.T) {
+	srv := oidctest.NewServer()
+	defer srv.Close()
+	p := NewProvider(ProviderConfig{Name: "fake", Issuer: srv.Issuer() + "/", ClientID: oidctest.ClientID}, srv.Client())
+
+	_, err := p.AuthCodeURL(context.Background(), "s", "n", "c")
+	assert.ErrorContains(t, err, "issuer mismatch")
+}
+
+func TestRegistry(t *testing.T) {
+	srv := oidctest.NewServer()
+	defer srv.Close()
+	r := NewRegistry(newTestProvider(srv))
+
+	p, ok := r.Provider("fake")
+	assert.True(t, ok)
+	assert.Equal(t, "fake", p.Name())
+	_, ok = r.Provider("unknown")
+	assert.False(t, ok)
+	assert.Equal(t, testRedirect, CallbackURL("http://localhost:8080/", "fake"))
+}
@@ -0,0 +1,47 @@
+package oidc
+
+import (
+	"go-auth/internal/app"
+	"strings"
+)
+
+// Registry holds the configured providers by name.
+type Registry struct {
+	providers map[string]app.AppOAuthProvider
+}
+
+func NewRegistry(providers ...app.AppOAuthProvider) *Registry {
+	r := &Registry{providers: make(map[string]app.AppOAuthProvider, len(providers))}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+	return r
+}
+
+// New builds the providers from OAUTH_PROVIDERS. Callbacks go to
+// AUTH_PUBLIC_URL/oauth/{name}/callback, which must be registered with
+// each provider.
+func New(cfg app.AppConfig) *Registry {
+	c := cfg.GetConfig()
+	providers := make([]app.AppOAuthProvider, 0, len(c.OAuthProviders))
+	for _, p := range c.OAuthProviders {
+		providers = append(providers, NewProvider(ProviderConfig{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			Scopes:       p.Scopes,
+			RedirectURL:  CallbackURL(c.AuthPublicURL, p.Name),
+		}, nil))
+	}
+	return NewRegistry(providers...)
+}
+
+func CallbackURL(base, provider string) string {
+	return strings.TrimRight(base, "/") + "/oauth/" + provider + "/callback"
+}
+
+func (r *Registry) Provider(name string) (app.AppOAuthProvider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
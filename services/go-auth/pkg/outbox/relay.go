@@ -0,0 +1,84 @@
+// Package outbox publishes the account lifecycle events queued in
+// user_outbox to Kafka.
+package outbox
+
+import (
+	"context"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"log/slog"
+	"time"
+)
+
+const (
+	pollInterval = time.Second
+	batchSize    = 100
+	// retention is how long sent messages are kept for debugging.
+	retention     = 7 * 24 * time.Hour
+	purgeInterval = time.Hour
+)
+
+// Relay publishes outbox messages in the order they were written. A message
+// is published at least once: if marking it sent fails it goes out again.
+type Relay struct {
+	store    app.AppOutboxStorage
+	producer app.AppProducer
+	topic    string
+	interval time.Duration
+	now      func() time.Time
+}
+
+func New(store app.AppOutboxStorage, producer app.AppProducer, cfg app.AppConfig) *Relay {
+	return NewRelay(store, producer, cfg.GetConfig().UserEventsTopic)
+}
+
+func NewRelay(store app.AppOutboxStorage, producer app.AppProducer, topic string) *Relay {
+	return &Relay{
+		store:    store,
+		producer: producer,
+		topic:    topic,
+		interval: pollInterval,
+		now:      time.Now,
+	}
+}
+
+// Run relays until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	var purged time.Time
+	for {
+		r.Flush()
+		if now := r.now(); now.Sub(purged) >= purgeInterval {
+			if _, err := r.store.PurgeSent(now.Add(-retention)); err != nil {
+				slog.Error("outbox relay purge: " + err.Error())
+			}
+			purged = now
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush publishes everything pending. On a failure the rest waits for the
+// next call.
+func (r *Relay) Flush() {
+	for {
+		n, err := r.store.Relay(batchSize, r.send)
+		if err != nil {
+			slog.Error("outbox relay: " + err.Error())
+			return
+		}
+		if n < batchSize {
+			return
+		}
+	}
+}
+
+// send keys messages by user so a user's events stay in order.
+func (r *Relay) send(m models.OutboxMessage) error {
+	return r.producer.Produce(r.topic, m.UserId, m.Payload)
+}
@@ -0,0 +1,115 @@
+package outbox
+
+import (
+	"errors"
+	"go-auth/internal/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStore behaves like OutboxStorage.Relay over an in-memory table.
+type fakeStore struct {
+	pending []models.OutboxMessage
+	sent    []int64
+	purged  []time.Time
+}
+
+func (s *fakeStore) Relay(limit int, send func(models.OutboxMessage) error) (int, error) {
+	n := 0
+	for n < limit && len(s.pending) > 0 {
+		if err := send(s.pending[0]); err != nil {
+			return n, err
+		}
+		s.sent = append(s.sent, s.pending[0].Id)
+		s.pending = s.pending[1:]
+		n++
+	}
+	return n, nil
+}
+
+func (s *fakeStore) PurgeSent(before time.Time) (int64, error) {
+	s.purged = append(s.purged, before)
+	return 0, nil
+}
+
+type message struct {
+	topic, key, value string
+}
+
+type fakeProducer struct {
+	messages []message
+	failAt   int
+}
+
+func (p *fakeProducer) Produce(topic, key, value string) error {
+	if p.failAt > 0 && len(p.messages)+1 == p.failAt {
+		p.failAt = 0
+		return errors.New("broker down")
+	}
+	p.messages = append(p.messages, message{topic, key, value})
+	return nil
+}
+
+func (p *fakeProducer) Close() {}
+
+func pending(n int) []models.OutboxMessage {
+	var messages []models.OutboxMessage
+	for i := 1; i <= n; i++ {
+		messages = append(messages, models.OutboxMessage{
+			Id:      int64(i),
+			Type:    models.UserEventUpdated,
+			UserId:  "user-" + strconv.Itoa(i%3),
+			Payload: `{"id":"` + strconv.Itoa(i) + `"}`,
+		})
+	}
+	return messages
+}
+
+func TestRelay_Flush(t *testing.T) {
+	tests := []struct {
+		name            string
+		pending         int
+		failAt          int
+		expectedSent    int
+		expectedPending int
+	}{
+		{name: "nothing pending"},
+		{name: "one batch", pending: 3, expectedSent: 3},
+		{name: "several batches", pending: batchSize*2 + 5, expectedSent: batchSize*2 + 5},
+		{name: "stops at failure", pending: 5, failAt: 3, expectedSent: 2, expectedPending: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{pending: pending(tt.pending)}
+			producer := &fakeProducer{failAt: tt.failAt}
+			relay := NewRelay(store, producer, "user_events")
+
+			relay.Flush()
+
+			assert.Len(t, store.sent, tt.expectedSent)
+			assert.Len(t, store.pending, tt.expectedPending)
+			assert.Len(t, producer.messages, tt.expectedSent)
+			for i, msg := range producer.messages {
+				assert.Equal(t, "user_events", msg.topic)
+				assert.Equal(t, "user-"+strconv.Itoa((i+1)%3), msg.key, "keyed by user")
+				assert.Equal(t, `{"id":"`+strconv.Itoa(i+1)+`"}`, msg.value, "in outbox order")
+			}
+		})
+	}
+}
+
+func TestRelay_FailedMessageIsRetried(t *testing.T) {
+	store := &fakeStore{pending: pending(3)}
+	producer := &fakeProducer{failAt: 2}
+	relay := NewRelay(store, producer, "user_events")
+
+	relay.Flush()
+	relay.Flush()
+
+	assert.Equal(t, []int64{1, 2, 3}, store.sent)
+	assert.Len(t, producer.messages, 3)
+}
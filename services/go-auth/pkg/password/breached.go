@@ -0,0 +1,81 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// prefixLength is the length of the hash prefix buckets are keyed by, as
+// in the k-anonymity range API of Have I Been Pwned.
+const prefixLength = 5
+
+// HashList is an offline list of SHA-1 hashes of breached passwords. It is
+// laid out like the k-anonymity range API: hashes are grouped by their
+// first five hex digits and a lookup only searches the suffixes of one
+// bucket.
+type HashList struct {
+	buckets map[string][]string
+	size    int
+}
+
+// LoadHashFile loads a hash list from path, see LoadHashList.
+func LoadHashFile(path string) (*HashList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadHashFile: %w", err)
+	}
+	defer f.Close()
+	return LoadHashList(f)
+}
+
+// LoadHashList reads one SHA-1 hex digest per line, optionally followed by
+// ":<count>" as in the Pwned Passwords downloads. Blank lines and lines
+// starting with # are skipped.
+func LoadHashList(r io.Reader) (*HashList, error) {
+	l := &HashList{buckets: map[string][]string{}}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("LoadHashList line %d: not a SHA-1 digest", n)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("LoadHashList line %d: %w", n, err)
+		}
+		prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+		l.buckets[prefix] = append(l.buckets[prefix], suffix)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("LoadHashList: %w", err)
+	}
+	for prefix, suffixes := range l.buckets {
+		slices.Sort(suffixes)
+		l.buckets[prefix] = slices.Compact(suffixes)
+		l.size += len(l.buckets[prefix])
+	}
+	return l, nil
+}
+
+// Contains reports whether password is on the list.
+func (l *HashList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, found := slices.BinarySearch(l.buckets[hash[:prefixLength]], hash[prefixLength:])
+	return found
+}
+
+// Len is the number of distinct hashes on the list.
+func (l *HashList) Len() int {
+	return l.size
+}
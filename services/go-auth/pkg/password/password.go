@@ -0,0 +1,129 @@
+// Package password decides whether a new password is good enough: long
+// enough, mixing character classes, not a well-known one and not found in
+// a list of breached passwords.
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"go-auth/internal/app"
+	"go-auth/internal/models"
+	"log/slog"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Violation codes, stable for clients to translate.
+const (
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeTooFewClasses = "too_few_classes"
+	CodeCommon        = "common"
+	CodeContainsLogin = "contains_login"
+	CodeBreached      = "breached"
+)
+
+// Rules are the requirements for new passwords. Lengths count characters,
+// not bytes.
+type Rules struct {
+	MinLength int
+	MaxLength int
+	// MinClasses is how many of lower case, upper case, digits and other
+	// characters a password has to mix.
+	MinClasses int
+}
+
+var DefaultRules = Rules{MinLength: 8, MaxLength: 64, MinClasses: 2}
+
+//go:embed common.txt
+var commonList string
+
+// Checker applies Rules, the built-in blocklist and, if loaded, a list of
+// breached password hashes.
+type Checker struct {
+	rules     Rules
+	blocklist map[string]struct{}
+	breached  *HashList
+}
+
+// New builds the checker from the config and loads the breached password
+// list, if one is configured. A list that can't be loaded stops startup:
+// silently running without it would weaken the policy.
+func New(cfg app.AppConfig) *Checker {
+	c := cfg.GetConfig()
+	rules := Rules{MinLength: c.PasswordMinLength, MaxLength: c.PasswordMaxLength, MinClasses: c.PasswordMinClasses}
+	var breached *HashList
+	if c.BreachedHashesFile != "" {
+		list, err := LoadHashFile(c.BreachedHashesFile)
+		if err != nil {
+			panic(fmt.Sprintf("breached password list can not be loaded: %s", err.Error()))
+		}
+		slog.Info("breached password list loaded", "hashes", list.Len())
+		breached = list
+	}
+	return NewChecker(rules, breached)
+}
+
+// NewChecker returns a checker of rules; breached may be nil.
+func NewChecker(rules Rules, breached *HashList) *Checker {
+	blocklist := map[string]struct{}{}
+	scanner := bufio.NewScanner(strings.NewReader(commonList))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blocklist[strings.ToLower(line)] = struct{}{}
+	}
+	return &Checker{rules: rules, blocklist: blocklist, breached: breached}
+}
+
+// Check returns every rule password breaks for the account login, or nil.
+func (c *Checker) Check(login, password string) []models.FieldError {
+	var violations []models.FieldError
+	length := utf8.RuneCountInString(password)
+	if length < c.rules.MinLength {
+		violations = append(violations, violation(CodeTooShort, fmt.Sprintf("must be at least %d characters", c.rules.MinLength)))
+	}
+	if c.rules.MaxLength > 0 && length > c.rules.MaxLength {
+		violations = append(violations, violation(CodeTooLong, fmt.Sprintf("must be at most %d characters", c.rules.MaxLength)))
+	}
+	if classes(password) < c.rules.MinClasses {
+		violations = append(violations, violation(CodeTooFewClasses,
+			fmt.Sprintf("must mix at least %d of lower case, upper case, digits and symbols", c.rules.MinClasses)))
+	}
+	lower := strings.ToLower(password)
+	if _, ok := c.blocklist[lower]; ok {
+		violations = append(violations, violation(CodeCommon, "is too common"))
+	}
+	if login = strings.ToLower(login); len(login) >= 3 && strings.Contains(lower, login) {
+		violations = append(violations, violation(CodeContainsLogin, "must not contain the login"))
+	}
+	if c.breached != nil && c.breached.Contains(password) {
+		violations = append(violations, violation(CodeBreached, "has appeared in a data breach"))
+	}
+	return violations
+}
+
+func violation(code, message string) models.FieldError {
+	return models.FieldError{Field: "password", Code: code, Message: message}
+}
+
+func classes(password string) int {
+	var lower, upper, digit, other int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	return lower + upper + digit + other
+}
@@ -0,0 +1,97 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// SHA-1 of "Tr0ub4dor&3".
+const breachedHash = "874572E7A5AE6A49466A6AC578B98ADBA78C6AA6"
+
+func codes(t *testing.T, c *Checker, login, password string) []string {
+	t.Helper()
+	var codes []string
+	for _, v := range c.Check(login, password) {
+		assert.Equal(t, "password", v.Field)
+		assert.NotEmpty(t, v.Message)
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func TestChecker_Check(t *testing.T) {
+	list, err := LoadHashList(strings.NewReader(breachedHash + ":42\n"))
+	assert.NoError(t, err)
+	checker := NewChecker(DefaultRules, list)
+
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		expected []string
+	}{
+		{name: "good", login: "alice", password: "correct horse 42"},
+		{name: "too short", login: "alice", password: "Ab1", expected: []string{CodeTooShort}},
+		{name: "too long", login: "alice", password: strings.Repeat("aB1", 22), expected: []string{CodeTooLong}},
+		{name: "length counts characters", login: "alice", password: "пароль-пароль"},
+		{name: "one class", login: "alice", password: "abcdefghijk", expected: []string{CodeTooFewClasses}},
+		{name: "common in any case", login: "alice", password: "QWERTY123", expected: []string{CodeCommon}},
+		{name: "contains login", login: "Alice", password: "xx-alice-2024", expected: []string{CodeContainsLogin}},
+		{name: "short login is not matched", login: "al", password: "xx-al-2024"},
+		{name: "breached", login: "alice", password: "Tr0ub4dor&3", expected: []string{CodeBreached}},
+		{name: "several", login: "alice", password: "alice", expected: []string{CodeTooShort, CodeTooFewClasses, CodeContainsLogin}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, codes(t, checker, tt.login, tt.password))
+		})
+	}
+}
+
+func TestChecker_WithoutBreachedList(t *testing.T) {
+	checker := NewChecker(DefaultRules, nil)
+	assert.Empty(t, codes(t, checker, "alice", "Tr0ub4dor&3"))
+}
+
+func TestLoadHashList(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedLen   int
+		expectedError string
+	}{
+		{
+			name:        "pwned passwords format",
+			input:       "# comment\n\n" + breachedHash + ":3\n" + strings.ToLower(breachedHash) + "\n5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:1\n",
+			expectedLen: 2,
+		},
+		{
+			name:          "not a digest",
+			input:         breachedHash + "\nnope\n",
+			expectedError: "LoadHashList line 2: not a SHA-1 digest",
+		},
+		{
+			name:          "not hex",
+			input:         strings.Repeat("Z", 40),
+			expectedError: "LoadHashList line 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := LoadHashList(strings.NewReader(tt.input))
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedLen, list.Len())
+			assert.True(t, list.Contains("Tr0ub4dor&3"))
+			assert.True(t, list.Contains("password"))
+			assert.False(t, list.Contains("correct horse 42"))
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"go-auth/internal/app"
+)
+
+// Limiter is a sliding-window log limiter. Rejected requests are recorded
+// too, so a client that keeps hammering stays blocked.
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+func New(rds app.AppRedis) *Limiter {
+	return NewLimiter(NewRedisStore(rds))
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{store: store, now: time.Now}
+}
+
+// Allow reports whether another request under key fits into limit per
+// window; if not, it also returns when the oldest request leaves the window.
+func (l *Limiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	now := l.now()
+	count, oldest, err := l.store.Hit(ctx, "ratelimit:"+key, now, window)
+	if err != nil {
+		return false, 0, err
+	}
+	if count <= limit {
+		return true, 0, nil
+	}
+	return false, oldest.Add(window).Sub(now), nil
+}
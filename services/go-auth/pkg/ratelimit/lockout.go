@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"go-auth/internal/app"
+	"go-auth/internal/config"
+)
+
+// Lockout implements progressive lockout after repeated failures.
+type Lockout struct {
+	store  Store
+	policy config.Lockout
+	// scope keeps the keys of lockouts sharing a store apart.
+	scope string
+	now   func() time.Time
+}
+
+// NewLoginLockout locks a login out from one client IP.
+func NewLoginLockout(rds app.AppRedis, cfg app.AppConfig) *Lockout {
+	return NewLockout(NewRedisStore(rds), cfg.GetConfig().LoginLockout)
+}
+
+// NewAccountLockout locks a login out from every address.
+func NewAccountLockout(rds app.AppRedis, cfg app.AppConfig) *Lockout {
+	l := NewLockout(NewRedisStore(rds), cfg.GetConfig().AccountLockout)
+	l.scope = "account:"
+	return l
+}
+
+func NewLockout(store Store, policy config.Lockout) *Lockout {
+	return &Lockout{store: store, policy: policy, now: time.Now}
+}
+
+// Check returns how long key stays locked, zero if it isn't.
+func (l *Lockout) Check(ctx context.Context, key string) (time.Duration, error) {
+	return l.store.LockTTL(ctx, l.lockKey(key), l.now())
+}
+
+// Fail records a failure and returns the lock duration if it locked key.
+func (l *Lockout) Fail(ctx context.Context, key string) (time.Duration, error) {
+	now := l.now()
+	failures, _, err := l.store.Hit(ctx, l.failuresKey(key), now, l.policy.Window)
+	if err != nil {
+		return 0, err
+	}
+	if failures < l.policy.MaxFailures {
+		return 0, nil
+	}
+
+	lockouts, _, err := l.store.Hit(ctx, l.lockoutsKey(key), now, l.policy.Memory)
+	if err != nil {
+		return 0, err
+	}
+	d := l.policy.BaseLock
+	for i := 1; i < lockouts && d < l.policy.MaxLock; i++ {
+		d *= 2
+	}
+	if d > l.policy.MaxLock {
+		d = l.policy.MaxLock
+	}
+	if err := l.store.Lock(ctx, l.lockKey(key), now, d); err != nil {
+		return 0, err
+	}
+	if err := l.store.Reset(ctx, l.failuresKey(key)); err != nil {
+		return 0, err
+	}
+	return d, nil
+}
+
+// Reset forgets failures after a successful attempt. Escalation history is
+// kept so a lucky guess between locks doesn't reset the penalty.
+func (l *Lockout) Reset(ctx context.Context, key string) error {
+	return l.store.Reset(ctx, l.failuresKey(key))
+}
+
+func (l *Lockout) failuresKey(key string) string { return "lockout:" + l.scope + "failures:" + key }
+func (l *Lockout) lockoutsKey(key string) string { return "lockout:" + l.scope + "count:" + key }
+func (l *Lockout) lockKey(key string) string     { return "lockout:" + l.scope + "lock:" + key }
@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-auth/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestLimiter_SlidingWindow(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	l := NewLimiter(NewMemoryStore())
+	l.now = clock.now
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		ok, _, err := l.Allow(ctx, "login:ip:1.2.3.4", 3, time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		clock.advance(10 * time.Second)
+	}
+
+	ok, retryAfter, err := l.Allow(ctx, "login:ip:1.2.3.4", 3, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	// the first hit was 30s ago and leaves the window in another 30s
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	// other keys are independent
+	ok, _, err = l.Allow(ctx, "login:ip:5.6.7.8", 3, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	clock.advance(time.Minute)
+	ok, _, err = l.Allow(ctx, "login:ip:1.2.3.4", 3, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestLockout_Progressive(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	policy := config.Lockout{
+		MaxFailures: 3,
+		Window:      10 * time.Minute,
+		BaseLock:    time.Minute,
+		MaxLock:     3 * time.Minute,
+		Memory:      24 * time.Hour,
+	}
+	l := NewLockout(NewMemoryStore(), policy)
+	l.now = clock.now
+	ctx := context.Background()
+
+	lockAfterFailures := func() time.Duration {
+		var locked time.Duration
+		for i := 0; i < policy.MaxFailures; i++ {
+			d, err := l.Fail(ctx, "user")
+			assert.NoError(t, err)
+			locked = d
+		}
+		return locked
+	}
+
+	assert.Equal(t, time.Minute, lockAfterFailures())
+	retry, err := l.Check(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, retry)
+
+	clock.advance(time.Minute)
+	retry, err = l.Check(ctx, "user")
+	assert.NoError(t, err)
+	assert.Zero(t, retry)
+
+	assert.Equal(t, 2*time.Minute, lockAfterFailures())
+	clock.advance(2 * time.Minute)
+	// capped at MaxLock
+	assert.Equal(t, 3*time.Minute, lockAfterFailures())
+	clock.advance(3 * time.Minute)
+	assert.Equal(t, 3*time.Minute, lockAfterFailures())
+
+	// other keys are not affected
+	retry, err = l.Check(ctx, "other")
+	assert.NoError(t, err)
+	assert.Zero(t, retry)
+}
+
+func TestLockout_ResetClearsFailures(t *testing.T) {
+	l := NewLockout(NewMemoryStore(), config.Lockout{
+		MaxFailures: 2,
+		Window:      time.Minute,
+		BaseLock:    time.Minute,
+		MaxLock:     time.Minute,
+		Memory:      time.Hour,
+	})
+	ctx := context.Background()
+
+	d, err := l.Fail(ctx, "user")
+	assert.NoError(t, err)
+	assert.Zero(t, d)
+	assert.NoError(t, l.Reset(ctx, "user"))
+
+	d, err = l.Fail(ctx, "user")
+	assert.NoError(t, err)
+	assert.Zero(t, d)
+}
+
+func TestLockout_ScopesShareStore(t *testing.T) {
+	store := NewMemoryStore()
+	policy := config.Lockout{MaxFailures: 1, Window: time.Minute, BaseLock: time.Minute, MaxLock: time.Minute, Memory: time.Hour}
+	login := NewLockout(store, policy)
+	account := NewLockout(store, policy)
+	account.scope = "account:"
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	login.now, account.now = clock.now, clock.now
+	ctx := context.Background()
+
+	d, err := account.Fail(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, d)
+
+	retry, err := login.Check(ctx, "user")
+	assert.NoError(t, err)
+	assert.Zero(t, retry, "the account lock is kept apart from the per-IP one")
+	retry, err = account.Check(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, retry)
+}
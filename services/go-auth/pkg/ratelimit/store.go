@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-auth/internal/app"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Store keeps sliding-window event logs and lock markers. The caller passes
+// the current time so limits can be tested with a fake clock.
+type Store interface {
+	// Hit records an event at now and returns how many events fall within
+	// window together with the time of the oldest of them.
+	Hit(ctx context.Context, key string, now time.Time, window time.Duration) (int, time.Time, error)
+	Reset(ctx context.Context, key string) error
+	Lock(ctx context.Context, key string, now time.Time, ttl time.Duration) error
+	// LockTTL returns how long key stays locked, zero if it isn't.
+	LockTTL(ctx context.Context, key string, now time.Time) (time.Duration, error)
+}
+
+const hitScript = `
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+redis.call("ZADD", KEYS[1], now, ARGV[3])
+redis.call("PEXPIRE", KEYS[1], window)
+local count = redis.call("ZCARD", KEYS[1])
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+return {count, oldest[2]}
+`
+
+type RedisStore struct {
+	redisDB app.AppRedis
+}
+
+func NewRedisStore(redisDB app.AppRedis) *RedisStore {
+	return &RedisStore{redisDB: redisDB}
+}
+
+func (s *RedisStore) Hit(ctx context.Context, key string, now time.Time, window time.Duration) (int, time.Time, error) {
+	res, err := s.redisDB.Eval(ctx, hitScript, []string{key},
+		now.UnixMilli(), window.Milliseconds(), strconv.FormatInt(now.UnixNano(), 10)+"-"+uuid.NewString(),
+	).Slice()
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("RedisStore Hit: %w", err)
+	}
+	if len(res) != 2 {
+		return 0, time.Time{}, fmt.Errorf("RedisStore Hit: unexpected reply %v", res)
+	}
+	count, ok := res[0].(int64)
+	if !ok {
+		return 0, time.Time{}, fmt.Errorf("RedisStore Hit: unexpected count %v", res[0])
+	}
+	oldestStr, _ := res[1].(string)
+	oldestMs, err := strconv.ParseInt(oldestStr, 10, 64)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("RedisStore Hit: %w", err)
+	}
+	return int(count), time.UnixMilli(oldestMs), nil
+}
+
+func (s *RedisStore) Reset(ctx context.Context, key string) error {
+	if err := s.redisDB.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("RedisStore Reset: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Lock(ctx context.Context, key string, now time.Time, ttl time.Duration) error {
+	if err := s.redisDB.Set(ctx, key, now.UnixMilli(), ttl).Err(); err != nil {
+		return fmt.Errorf("RedisStore Lock: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) LockTTL(ctx context.Context, key string, now time.Time) (time.Duration, error) {
+	ttl, err := s.redisDB.PTTL(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("RedisStore LockTTL: %w", err)
+	}
+	// -2: no key, -1: no expiry (never set by Lock)
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+type MemoryStore struct {
+	mu    sync.Mutex
+	hits  map[string][]time.Time
+	locks map[string]time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{hits: map[string][]time.Time{}, locks: map[string]time.Time{}}
+}
+
+func (s *MemoryStore) Hit(ctx context.Context, key string, now time.Time, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from := now.Add(-window)
+	kept := s.hits[key][:0]
+	for _, t := range s.hits[key] {
+		if t.After(from) {
+			kept = append(kept, t)
+		}
+	}
+	kept = append(kept, now)
+	s.hits[key] = kept
+	return len(kept), kept[0], nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.hits, key)
+	return nil
+}
+
+func (s *MemoryStore) Lock(ctx context.Context, key string, now time.Time, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locks[key] = now.Add(ttl)
+	return nil
+}
+
+func (s *MemoryStore) LockTTL(ctx context.Context, key string, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	until, ok := s.locks[key]
+	if !ok || !until.After(now) {
+		delete(s.locks, key)
+		return 0, nil
+	}
+	return until.Sub(now), nil
+}
@@ -32,6 +32,26 @@ func (r *Redis) Pipeline() redis.Pipeliner {
 	return r.client.Pipeline()
 }
 
+func (r *Redis) SMembers(ctx context.Context, key string) *redis.StringSliceCmd {
+	return r.client.SMembers(ctx, key)
+}
+
+func (r *Redis) SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	return r.client.SRem(ctx, key, members...)
+}
+
+func (r *Redis) PTTL(ctx context.Context, key string) *redis.DurationCmd {
+	return r.client.PTTL(ctx, key)
+}
+
+func (r *Redis) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	return r.client.Eval(ctx, script, keys, args...)
+}
+
+func (r *Redis) GetDel(ctx context.Context, key string) *redis.StringCmd {
+	return r.client.GetDel(ctx, key)
+}
+
 func New(cfg app.AppConfig) *Redis {
 	var redisAddr = cfg.GetConfig().RedisAddr
 
@@ -0,0 +1,72 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the SHA-1 seed from RFC 6238 Appendix B.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; we use the last 6 of them.
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for unix, want := range vectors {
+		code, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, want[2:], code, "time %d", unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	step := Step(now)
+
+	tests := []struct {
+		name     string
+		codeStep int64
+		wantOk   bool
+	}{
+		{name: "current step", codeStep: step, wantOk: true},
+		{name: "previous step within skew", codeStep: step - 1, wantOk: true},
+		{name: "next step within skew", codeStep: step + 1, wantOk: true},
+		{name: "outside skew", codeStep: step - 2, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Code(secret, tt.codeStep)
+			assert.NoError(t, err)
+			got, ok := Validate(secret, code, now)
+			assert.Equal(t, tt.wantOk, ok)
+			if tt.wantOk {
+				assert.Equal(t, tt.codeStep, got)
+			}
+		})
+	}
+
+	_, ok := Validate(secret, "12345", now)
+	assert.False(t, ok, "wrong length")
+	_, ok = Validate("not base32!", "123456", now)
+	assert.False(t, ok, "invalid secret")
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("go-auth", "player one", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/go-auth:player%20one?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=go-auth")
+	assert.Contains(t, uri, "digits=6")
+}
@@ -4,15 +4,13 @@ import (
 	"context"
 	"fmt"
 	"go-game/cmd/wire"
-	"go-game/internal/models"
 	"go-game/internal/server"
-	"go-game/internal/services"
+	"go-game/pkg/metrics"
 	"log/slog"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
-	"time"
 )
 
 func main() {
@@ -27,32 +25,22 @@ func main() {
 		panic(fmt.Sprintf("Error on wire.Initialize() %v", err))
 	}
 
+	metrics.Start(deps.Config.GetConfig().MetricsAddr)
+
 	var wg sync.WaitGroup
 go deps.Consumer.StartRead()
 
-    gameService := services.NewGameService(deps.RTCManager)
-    
-    go func() {
-        ticker := time.NewTicker(100 * time.Millisecond)
-        defer ticker.Stop()
-        
-        for range ticker.C {
-            if err := gameService.BroadcastGameState(ctx, "game1", models.GameState{
-                
-            }); err != nil {
-                slog.Error("Failed to broadcast game state", "error", err)
-            }
-        }
-    }()
-
-
 	select {
 	case <-exit:
 	case <-ctx.Done():
 	}
 
 	deps.Consumer.Close()
+	deps.SearchGameService.Close()
+	deps.GameService.Close()
 	deps.Producer.Close()
+	deps.MatchQueue.Close()
+	deps.DB.Close()
 	if err := server.Inst.Close(); err != nil {
 		slog.Error(err.Error())
 	}
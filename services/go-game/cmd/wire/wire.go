@@ -7,17 +7,25 @@ import (
 	"go-game/internal/app"
 	"go-game/internal/config"
 	"go-game/internal/services"
+	"go-game/pkg/clock"
+	"go-game/pkg/db"
 	"go-game/pkg/kafka"
+	"go-game/pkg/matchqueue"
 	"go-game/pkg/webrtc"
 
 	"github.com/google/wire"
 )
 
 type Dependenсies struct {
-	Producer       app.KProducer
-	Consumer       app.KConsumer
-	MessageService app.MessageService
-	RTCManager     *webrtc.RTCManager
+	Config            app.AppConfig
+	Producer          app.KProducer
+	Consumer          app.KConsumer
+	MessageService    app.MessageService
+	RTCManager        *webrtc.RTCManager
+	GameService       *services.GameService
+	SearchGameService *services.SearchGameService
+	DB                *db.DB
+	MatchQueue        *matchqueue.RedisQueue
 }
 
 func Initialize() (*Dependenсies, error) {
@@ -32,6 +40,16 @@ func Initialize() (*Dependenсies, error) {
 		webrtc.NewRTCManager,
 		services.NewMessageService,
 		wire.Bind(new(app.MessageService), new(*services.MessageService)),
+		clock.New,
+		wire.Bind(new(app.Clock), new(clock.Real)),
+		services.NewRoomRegistry,
+		services.NewGameService,
+		db.New,
+		wire.Bind(new(app.CharacterStore), new(*db.DB)),
+		matchqueue.NewRedisQueue,
+		wire.Bind(new(app.MatchQueue), new(*matchqueue.RedisQueue)),
+		wire.Bind(new(app.RoomDirectory), new(*matchqueue.RedisQueue)),
+		services.NewSearchGameService,
 		wire.Struct(new(Dependenсies), "*"),
 	)
 	return &Dependenсies{}, nil
@@ -10,7 +10,10 @@ import (
 	"go-game/internal/app"
 	"go-game/internal/config"
 	"go-game/internal/services"
+	"go-game/pkg/clock"
+	"go-game/pkg/db"
 	"go-game/pkg/kafka"
+	"go-game/pkg/matchqueue"
 	"go-game/pkg/webrtc"
 )
 
@@ -20,16 +23,27 @@ func Initialize() (*Dependenсies, error) {
 	configConfig := config.New()
 	producer := kafka.NewProducer(configConfig)
 	rtcManager := webrtc.NewRTCManager(producer, configConfig)
-	messageService := services.NewMessageService(rtcManager)
+	dbDB := db.New(configConfig)
+	redisQueue := matchqueue.NewRedisQueue(configConfig)
+	clockReal := clock.New()
+	roomRegistry := services.NewRoomRegistry(configConfig, producer, clockReal)
+	searchGameService := services.NewSearchGameService(redisQueue, redisQueue, dbDB, roomRegistry, producer, configConfig, clockReal)
+	messageService := services.NewMessageService(rtcManager, searchGameService, redisQueue, producer, configConfig)
 	consumer, err := kafka.NewConsumer(configConfig, messageService)
 	if err != nil {
 		return nil, err
 	}
+	gameService := services.NewGameService(rtcManager, roomRegistry, configConfig, clockReal)
 	dependenсies := &Dependenсies{
-		Producer:       producer,
-		Consumer:       consumer,
-		MessageService: messageService,
-		RTCManager:     rtcManager,
+		Config:            configConfig,
+		Producer:          producer,
+		Consumer:          consumer,
+		MessageService:    messageService,
+		RTCManager:        rtcManager,
+		GameService:       gameService,
+		SearchGameService: searchGameService,
+		DB:                dbDB,
+		MatchQueue:        redisQueue,
 	}
 	return dependenсies, nil
 }
@@ -37,8 +51,13 @@ func Initialize() (*Dependenсies, error) {
 // wire.go:
 
 type Dependenсies struct {
-	Producer       app.KProducer
-	Consumer       app.KConsumer
-	MessageService app.MessageService
-	RTCManager     *webrtc.RTCManager
+	Config            app.AppConfig
+	Producer          app.KProducer
+	Consumer          app.KConsumer
+	MessageService    app.MessageService
+	RTCManager        *webrtc.RTCManager
+	GameService       *services.GameService
+	SearchGameService *services.SearchGameService
+	DB                *db.DB
+	MatchQueue        *matchqueue.RedisQueue
 }
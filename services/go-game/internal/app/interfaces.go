@@ -4,6 +4,7 @@ import (
 	"context"
 	"go-game/internal/config"
 	"go-game/internal/models"
+	"time"
 )
 
 type AppConfig interface {
@@ -22,3 +23,47 @@ type KConsumer interface {
 	Close()
 }
 
+// Clock is time as the simulation sees it; tests substitute a fake one to
+// drive ticks by hand.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// GameInput receives what happens on players' data channels. A player
+// PlayerConnected fails for is to be disconnected.
+type GameInput interface {
+	PlayerConnected(gameID, playerID string) error
+	PlayerDisconnected(gameID, playerID string)
+	HandleInput(gameID, playerID string, data []byte)
+}
+
+// MatchQueue holds the players searching for a game. A player has at most
+// one ticket; enqueueing again replaces it.
+type MatchQueue interface {
+	Enqueue(ctx context.Context, ticket models.MatchTicket) error
+	Remove(ctx context.Context, playerID string) error
+	Tickets(ctx context.Context) ([]models.MatchTicket, error)
+	// Take removes the tickets of all the players or, if any of them is
+	// gone already, none of them and reports false.
+	Take(ctx context.Context, playerIDs []string) (bool, error)
+}
+
+// RoomDirectory records which instance of the service owns a room. The
+// room's peers and simulation live on its owner, so signals for it are
+// handled there whichever instance they reach first.
+type RoomDirectory interface {
+	SetRoomOwner(ctx context.Context, gameID, instanceID string) error
+	// RoomOwner returns an empty ID for a room no instance owns.
+	RoomOwner(ctx context.Context, gameID string) (string, error)
+}
+
+type CharacterStore interface {
+	// CharacterLevel fails for a character that isn't accountID's.
+	CharacterLevel(ctx context.Context, accountID, characterID string) (int32, error)
+}
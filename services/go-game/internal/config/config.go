@@ -1,6 +1,11 @@
 package config
 
-import "strings"
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
 
 type Config struct {
 	RedisAddr              string
@@ -18,9 +23,21 @@ type Config struct {
 	KafkaGroupId           string
 	RTCSignalTopic         string
 	RTCResponseTopic       string
+	InstanceID             string   // Уникален для каждого экземпляра сервиса
 	WebRTCIceServers       []string // STUN/TURN серверы
 	WebRTCSignalingTimeout int32    // Таймаут сигналинга в секундах
 	ExternalIP             string
+	GameTickRate           int            // Шагов симуляции в секунду
+	GameRoomCapacity       int            // Игроков в комнате по умолчанию
+	GameRoomStartDelay     int            // Отсчёт перед стартом полной комнаты в секундах
+	GameRoomIdleTimeout    int            // Через сколько секунд удаляется пустая или завершённая комната
+	GameRoomEventsTopic    string         // Топик событий комнат
+	MatchModes             map[string]int // Режим -> игроков в матче
+	MatchTolerance         int            // Допустимая разница уровней в начале поиска
+	MatchToleranceStep     int            // На сколько уровней расширяется допуск
+	MatchWidenInterval     int            // Раз в сколько секунд ожидания расширяется допуск
+	MatchMaxTolerance      int            // Предел допуска
+	MatchTimeout           int            // Через сколько секунд поиск прекращается
 }
 
 func New() *Config {
@@ -44,11 +61,56 @@ func New() *Config {
 		KafkaGroupId:           cfg.KafkaGroupId,
 		RTCSignalTopic:         cfg.RTCSignalTopic,
 		RTCResponseTopic:       cfg.RTCResponseTopic,
+		InstanceID:             instanceID(cfg.InstanceID),
 		WebRTCIceServers:       cfg.WebRTCIceServers,
 		WebRTCSignalingTimeout: cfg.WebRTCSignalingTimeout,
 		ExternalIP:             cfg.ExternalIP,
+		GameTickRate:           cfg.GameTickRate,
+		GameRoomCapacity:       cfg.GameRoomCapacity,
+		GameRoomStartDelay:     cfg.GameRoomStartDelay,
+		GameRoomIdleTimeout:    cfg.GameRoomIdleTimeout,
+		GameRoomEventsTopic:    cfg.GameRoomEventsTopic,
+		MatchModes:             parseModes(cfg.MatchModes),
+		MatchTolerance:         cfg.MatchTolerance,
+		MatchToleranceStep:     cfg.MatchToleranceStep,
+		MatchWidenInterval:     cfg.MatchWidenInterval,
+		MatchMaxTolerance:      cfg.MatchMaxTolerance,
+		MatchTimeout:           cfg.MatchTimeout,
 	}
 }
+
+// parseModes reads "mode:players,..." pairs.
+func parseModes(s string) map[string]int {
+	modes := map[string]int{}
+	for _, pair := range strings.Split(s, ",") {
+		name, size, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		n, err := strconv.Atoi(size)
+		if !ok || name == "" || err != nil || n < 1 {
+			panic(fmt.Sprintf("invalid MATCH_MODES entry %q", pair))
+		}
+		modes[name] = n
+	}
+	return modes
+}
+
+// instanceID defaults to the host name, which is unique per container.
+func instanceID(id string) string {
+	if id != "" {
+		return id
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		panic(fmt.Sprintf("INSTANCE_ID is not set and the host name is unknown: %v", err))
+	}
+	return host
+}
+
+// InstanceSignalTopic is where the other instances forward the signals
+// for the rooms instanceID owns.
+func (cfg *Config) InstanceSignalTopic(instanceID string) string {
+	return cfg.RTCSignalTopic + "." + instanceID
+}
+
 func (cfg *Config) GetConfig() *Config {
 	return cfg
 }
@@ -6,7 +6,7 @@ import (
 
 type Envs struct {
 	RedisAddr                   string   `env:"REDIS_ADDRESS"`
-	MetricsAddr                 string   `env:"METRICS_ADDRESS"`
+	MetricsAddr                 string   `env:"METRICS_ADDRESS" envDefault:":8081"`
 	ServerAddr                  string   `env:"SERVER_ADDRESS"`
 	JaegerAddr                  string   `env:"JEAGER_ADDRESS"`
 	PostgresHost                string   `env:"POSTGRES_HOST"`
@@ -20,11 +20,23 @@ type Envs struct {
 	KafkaGroupId                string   `env:"KAFKA_GROUP_ID"`
 	RTCSignalTopic              string   `env:"RTC_SIGNAL_TOPIC"`
 	RTCResponseTopic            string   `env:"RTC_RESPONSE_TOPIC"`
+	InstanceID                  string   `env:"INSTANCE_ID"`
 	MESSAGE_TOPIC               string   `env:"MESSAGE_TOPIC"`
 	MESSAGE_CONFIRMATIONS_TOPIC string   `env:"MESSAGE_CONFIRMATIONS_TOPIC"`
 	WebRTCIceServers            []string `env:"RTC_ICE_SERVERS" envSeparator:","`
 	WebRTCSignalingTimeout      int32    `env:"RTC_SIGNAL_TIMEOUT"`
 	ExternalIP                  string   `env:"EXTERNAL_IP"`
+	GameTickRate                int      `env:"GAME_TICK_RATE" envDefault:"10"`
+	GameRoomCapacity            int      `env:"GAME_ROOM_CAPACITY" envDefault:"4"`
+	GameRoomStartDelay          int      `env:"GAME_ROOM_START_DELAY" envDefault:"3"`
+	GameRoomIdleTimeout         int      `env:"GAME_ROOM_IDLE_TIMEOUT" envDefault:"60"`
+	GameRoomEventsTopic         string   `env:"GAME_ROOM_EVENTS_TOPIC" envDefault:"game_room_events"`
+	MatchModes                  string   `env:"MATCH_MODES" envDefault:"duel:2,ffa:4"`
+	MatchTolerance              int      `env:"MATCH_TOLERANCE" envDefault:"2"`
+	MatchToleranceStep          int      `env:"MATCH_TOLERANCE_STEP" envDefault:"2"`
+	MatchWidenInterval          int      `env:"MATCH_WIDEN_INTERVAL" envDefault:"10"`
+	MatchMaxTolerance           int      `env:"MATCH_MAX_TOLERANCE" envDefault:"20"`
+	MatchTimeout                int      `env:"MATCH_TIMEOUT" envDefault:"300"`
 }
 
 func ParseEnv() (*Envs, error) {
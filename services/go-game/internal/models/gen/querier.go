@@ -15,6 +15,7 @@ type Querier interface {
 	CreateCharacter(ctx context.Context, arg CreateCharacterParams) (Character, error)
 	GetAccountByID(ctx context.Context, id uuid.UUID) (Account, error)
 	GetAccountByLogin(ctx context.Context, login string) (Account, error)
+	GetAccountCharacter(ctx context.Context, arg GetAccountCharacterParams) (Character, error)
 	GetCharacterByID(ctx context.Context, id uuid.UUID) (Character, error)
 	GetCharacterWithDetails(ctx context.Context, id uuid.UUID) (GetCharacterWithDetailsRow, error)
 	ListCharactersByAccount(ctx context.Context, accountID *uuid.UUID) ([]Character, error)
@@ -102,6 +102,30 @@ func (q *Queries) GetAccountByLogin(ctx context.Context, login string) (Account,
 	return i, err
 }
 
+const getAccountCharacter = `-- name: GetAccountCharacter :one
+SELECT id, account_id, class_id, name, created_at, level, last_played_at FROM character WHERE id = $1 AND account_id = $2
+`
+
+type GetAccountCharacterParams struct {
+	ID        uuid.UUID  `json:"id"`
+	AccountID *uuid.UUID `json:"accountId"`
+}
+
+func (q *Queries) GetAccountCharacter(ctx context.Context, arg GetAccountCharacterParams) (Character, error) {
+	row := q.db.QueryRow(ctx, getAccountCharacter, arg.ID, arg.AccountID)
+	var i Character
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ClassID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.Level,
+		&i.LastPlayedAt,
+	)
+	return i, err
+}
+
 const getCharacterByID = `-- name: GetCharacterByID :one
 SELECT id, account_id, class_id, name, created_at, level, last_played_at FROM character WHERE id = $1
 `
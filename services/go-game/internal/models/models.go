@@ -39,12 +39,96 @@ type WebRTCSignal struct {
 	Payload json.RawMessage `json:"payload"`
 }
 
+type Vec2 struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
 type Player struct {
+	Id       string `json:"id"`
+	Position Vec2   `json:"position"`
+	Velocity Vec2   `json:"velocity"`
+	// LastInput is the seq of the last input applied, so the client can
+	// drop the inputs the server has already seen when reconciling.
+	LastInput uint32 `json:"lastInput"`
 }
 
 type Object struct {
+	Id       string `json:"id"`
+	Kind     string `json:"kind"`
+	Position Vec2   `json:"position"`
 }
+
+// GameState is a snapshot of a room after Tick simulation steps.
 type GameState struct {
+	Tick    uint64   `json:"tick"`
 	Players []Player `json:"players"`
 	Objects []Object `json:"objects"`
 }
+
+// PlayerInput is what a player wants to do during one tick. MoveX and MoveY
+// are the stick direction, each in [-1, 1].
+type PlayerInput struct {
+	Seq   uint32  `json:"seq"`
+	MoveX float64 `json:"moveX"`
+	MoveY float64 `json:"moveY"`
+}
+
+// GameCommand is a message a client sends over its data channel.
+type GameCommand struct {
+	Type string `json:"type"` // "input", "ack"
+	PlayerInput
+	// Tick is the snapshot an "ack" acknowledges.
+	Tick uint64 `json:"tick,omitempty"`
+}
+
+type RoomState string
+
+const (
+	RoomLobby    RoomState = "lobby"
+	RoomStarting RoomState = "starting"
+	RoomRunning  RoomState = "running"
+	RoomFinished RoomState = "finished"
+)
+
+// Room is a game as the registry sees it: who is in it and how far along
+// it is. Id is the game_id clients put in their offers.
+type Room struct {
+	Id        string    `json:"id"`
+	State     RoomState `json:"state"`
+	Capacity  int       `json:"capacity"`
+	Players   []string  `json:"players"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// RoomEvent is published on the room events topic after every change of a
+// room, with the room as it is after the change.
+type RoomEvent struct {
+	Type     string    `json:"type"` // "created", "joined", "left", "state", "removed"
+	PlayerId string    `json:"playerId,omitempty"`
+	Room     Room      `json:"room"`
+	At       time.Time `json:"at"`
+}
+
+// SearchRequest is the payload of a "search" signal.
+type SearchRequest struct {
+	CharacterID string `json:"character_id"`
+	Mode        string `json:"mode"`
+}
+
+// MatchTicket is a player waiting in the matchmaking queue.
+type MatchTicket struct {
+	PlayerID    string    `json:"player_id"`
+	CharacterID string    `json:"character_id"`
+	Mode        string    `json:"mode"`
+	Level       int32     `json:"level"`
+	EnqueuedAt  time.Time `json:"enqueued_at"`
+}
+
+// MatchFound is sent to every player of a match; they join it by sending
+// an offer for GameID.
+type MatchFound struct {
+	GameID  string   `json:"game_id"`
+	Mode    string   `json:"mode"`
+	Players []string `json:"players"`
+}
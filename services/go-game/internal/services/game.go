@@ -3,28 +3,168 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"go-game/internal/app"
 	"go-game/internal/models"
 	"go-game/pkg/webrtc"
+	"log/slog"
+	"sync"
+	"time"
 )
 
+// roomSweepInterval is how often the registry's countdowns and idle rooms
+// are checked.
+const roomSweepInterval = time.Second
+
+// GameService seats connecting players in their registry rooms and runs a
+// GameRoom, one goroutine each, for every room that is running, feeding it
+// what players send over their data channels.
 type GameService struct {
-    rtcManager *webrtc.RTCManager
+	rtcManager *webrtc.RTCManager
+	registry   *RoomRegistry
+	clock      app.Clock
+	tickRate   int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu    sync.Mutex
+	rooms map[string]*GameRoom
 }
 
-func NewGameService(rtcManager *webrtc.RTCManager) *GameService {
-    return &GameService{
-        rtcManager: rtcManager,
-    }
+// NewGameService makes the service the receiver of rtcManager's data
+// channel events and starts sweeping the registry.
+func NewGameService(rtcManager *webrtc.RTCManager, registry *RoomRegistry, cfg app.AppConfig, clock app.Clock) *GameService {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &GameService{
+		rtcManager: rtcManager,
+		registry:   registry,
+		clock:      clock,
+		tickRate:   cfg.GetConfig().GameTickRate,
+		ctx:        ctx,
+		cancel:     cancel,
+		rooms:      map[string]*GameRoom{},
+	}
+	rtcManager.SetGameInput(s)
+	s.wg.Add(1)
+	go s.sweep()
+	return s
 }
 
 func (s *GameService) BroadcastGameState(ctx context.Context, gameID string, state models.GameState) error {
-    data, err := json.Marshal(state)
-    if err != nil {
-        return err
-    }
-
-    
-    
-    s.rtcManager.BroadcastToGame(gameID, data)
-    return nil
+	s.rtcManager.BroadcastSnapshot(gameID, state)
+	return nil
+}
+
+// PlayerConnected seats the player in the registry room gameID and, if
+// the room is running, in its simulation. It fails if the room doesn't
+// exist, is full or is over.
+func (s *GameService) PlayerConnected(gameID, playerID string) error {
+	room, err := s.registry.Join(gameID, playerID)
+	if err != nil {
+		return err
+	}
+	if room.State == models.RoomRunning {
+		s.play(gameID, playerID)
+	}
+	return nil
+}
+
+func (s *GameService) PlayerDisconnected(gameID, playerID string) {
+	if _, err := s.registry.Leave(gameID, playerID); err != nil && !errors.Is(err, ErrRoomNotFound) {
+		slog.Error("GameService PlayerDisconnected", "gameID", gameID, "playerID", playerID, "error", err)
+	}
+	if room := s.room(gameID); room != nil {
+		room.Leave(playerID)
+	}
+}
+
+// HandleInput handles a command a player sent: inputs are queued for the
+// next tick of its room, acks move its snapshot base.
+func (s *GameService) HandleInput(gameID, playerID string, data []byte) {
+	var cmd models.GameCommand
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		slog.Debug("GameService HandleInput bad command", "playerID", playerID, "error", err)
+		return
+	}
+	switch cmd.Type {
+	case "input":
+		room := s.room(gameID)
+		if room == nil || !room.Enqueue(playerID, cmd.PlayerInput) {
+			slog.Debug("GameService HandleInput input dropped", "gameID", gameID, "playerID", playerID)
+		}
+	case "ack":
+		s.rtcManager.AckSnapshot(gameID, playerID, cmd.Tick)
+	default:
+		slog.Debug("GameService HandleInput unknown command", "playerID", playerID, "type", cmd.Type)
+	}
+}
+
+// play joins the players to the simulation of gameID, starting it if it
+// isn't running.
+func (s *GameService) play(gameID string, players ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if room, ok := s.rooms[gameID]; ok && joinAll(room, players) {
+		return
+	}
+	if s.ctx.Err() != nil {
+		return
+	}
+	room := NewGameRoom(gameID, s.tickRate, s.clock, s)
+	joinAll(room, players)
+	s.rooms[gameID] = room
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		room.Run(s.ctx)
+		s.mu.Lock()
+		if s.rooms[gameID] == room {
+			delete(s.rooms, gameID)
+		}
+		s.mu.Unlock()
+		if err := s.registry.End(gameID); err != nil {
+			slog.Error("GameService registry.End", "gameID", gameID, "error", err)
+		}
+	}()
+	slog.Info("GameService room started", "gameID", gameID)
+}
+
+func joinAll(room *GameRoom, players []string) bool {
+	for _, p := range players {
+		if !room.Join(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// sweep starts the simulation of the rooms whose countdown is over.
+func (s *GameService) sweep() {
+	defer s.wg.Done()
+	ticker := s.clock.NewTicker(roomSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C():
+			for _, room := range s.registry.Sweep() {
+				s.play(room.Id, room.Players...)
+			}
+		}
+	}
+}
+
+// Close stops every room and waits for their goroutines.
+func (s *GameService) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *GameService) room(gameID string) *GameRoom {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rooms[gameID]
 }
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"go-game/internal/config"
+	"go-game/pkg/webrtc"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGameService_RoomLifecycle(t *testing.T) {
+	registry, clock, _ := newTestRegistry()
+	s := NewGameService(&webrtc.RTCManager{}, registry, &config.Config{GameTickRate: 10}, clock)
+	defer s.Close()
+	clock.WaitTickers(t, 1)
+
+	assert.ErrorIs(t, s.PlayerConnected("nope", "alice"), ErrRoomNotFound)
+
+	game := registry.Create(2)
+	require.NoError(t, s.PlayerConnected(game.Id, "alice"))
+	require.NoError(t, s.PlayerConnected(game.Id, "bob"))
+	assert.ErrorIs(t, s.PlayerConnected(game.Id, "carol"), ErrRoomFull)
+	assert.Nil(t, s.room(game.Id), "the game waits for the countdown")
+
+	clock.Advance(t, 3*time.Second)
+	require.Eventually(t, func() bool { return s.room(game.Id) != nil }, time.Second, time.Millisecond)
+	clock.WaitTickers(t, 2)
+
+	s.HandleInput(game.Id, "alice", []byte(`{"type":"input","seq":1,"moveX":1}`))
+	s.HandleInput(game.Id, "alice", []byte(`not json`))
+	// The second tick is taken only once the first step is over.
+	clock.Tick(t)
+	clock.Tick(t)
+
+	s.PlayerDisconnected(game.Id, "alice")
+	s.PlayerDisconnected(game.Id, "bob")
+	clock.Tick(t)
+	require.Eventually(t, func() bool { return s.room(game.Id) == nil }, time.Second, time.Millisecond)
+	assert.ErrorIs(t, s.PlayerConnected(game.Id, "alice"), ErrRoomFinished)
+}
@@ -3,19 +3,36 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go-game/internal/app"
+	"go-game/internal/config"
 	"go-game/internal/models"
 	"go-game/pkg/webrtc"
 	"log/slog"
 )
 
+// ErrPlayerMismatch is returned for a signal naming a player other than the
+// one who sent it.
+var ErrPlayerMismatch = errors.New("signal is for another player")
+
+// MessageService handles the signals players send through go-websocket.
+// Signals for a room owned by another instance are forwarded to it.
 type MessageService struct {
-	rtcManager *webrtc.RTCManager
+	rtcManager    *webrtc.RTCManager
+	searchService *SearchGameService
+	directory     app.RoomDirectory
+	producer      app.KProducer
+	cfg           *config.Config
 }
 
-func NewMessageService(rtcManager *webrtc.RTCManager) *MessageService {
+func NewMessageService(rtcManager *webrtc.RTCManager, searchService *SearchGameService, directory app.RoomDirectory, producer app.KProducer, cfg app.AppConfig) *MessageService {
 	return &MessageService{
-		rtcManager: rtcManager,
+		rtcManager:    rtcManager,
+		searchService: searchService,
+		directory:     directory,
+		producer:      producer,
+		cfg:           cfg.GetConfig(),
 	}
 }
 
@@ -33,21 +50,69 @@ func (s *MessageService) HandleMessage(ctx context.Context, msg models.MessageDT
 		if err := json.Unmarshal((signal.Payload), &offer); err != nil {
 			return fmt.Errorf("MessageService HandleMessage case offer json.Unmarshal %w", err)
 		}
+		if offer.PlayerID != msg.Producer {
+			return fmt.Errorf("MessageService HandleMessage case offer: %w", ErrPlayerMismatch)
+		}
+		if forwarded, err := s.forward(ctx, offer.GameID, msg); forwarded || err != nil {
+			return err
+		}
 		return s.rtcManager.HandleOffer(ctx, offer)
 	case "answer":
 		var answer models.WebRTCAnswer
 		if err := json.Unmarshal((signal.Payload), &answer); err != nil {
 			return fmt.Errorf("MessageService HandleMessage case answer json.Unmarshal %w", err)
 		}
+		if answer.PlayerID != msg.Producer {
+			return fmt.Errorf("MessageService HandleMessage case answer: %w", ErrPlayerMismatch)
+		}
+		if forwarded, err := s.forward(ctx, answer.GameID, msg); forwarded || err != nil {
+			return err
+		}
 		return s.rtcManager.HandleAnswer(ctx, answer)
 	case "candidate":
 		var candidate models.ICECandidate
 		if err := json.Unmarshal((signal.Payload), &candidate); err != nil {
 			return fmt.Errorf("MessageService HandleMessage case candidate json.Unmarshal %w", err)
 		}
+		if candidate.PlayerID != msg.Producer {
+			return fmt.Errorf("MessageService HandleMessage case candidate: %w", ErrPlayerMismatch)
+		}
+		if forwarded, err := s.forward(ctx, candidate.GameID, msg); forwarded || err != nil {
+			return err
+		}
 		return s.rtcManager.HandleICECandidate(ctx, candidate)
+	case "search":
+		var req models.SearchRequest
+		if err := json.Unmarshal((signal.Payload), &req); err != nil {
+			return fmt.Errorf("MessageService HandleMessage case search json.Unmarshal %w", err)
+		}
+		return s.searchService.Search(ctx, msg.Producer, req)
+	case "cancel_search":
+		return s.searchService.CancelSearch(ctx, msg.Producer)
 	default:
 		slog.Warn("Unknown WebRTC signal type", "type", signal.Type)
 		return nil
 	}
 }
+
+// forward passes msg on to the instance owning the room gameID. It reports
+// false if msg is to be handled here: this instance owns the room, or no
+// instance does.
+func (s *MessageService) forward(ctx context.Context, gameID string, msg models.MessageDTO) (bool, error) {
+	owner, err := s.directory.RoomOwner(ctx, gameID)
+	if err != nil {
+		return false, fmt.Errorf("MessageService forward RoomOwner: %w", err)
+	}
+	if owner == "" || owner == s.cfg.InstanceID {
+		return false, nil
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return false, fmt.Errorf("MessageService forward json.Marshal: %w", err)
+	}
+	if err := s.producer.Produce(s.cfg.InstanceSignalTopic(owner), string(data)); err != nil {
+		return false, fmt.Errorf("MessageService forward Produce: %w", err)
+	}
+	slog.Debug("MessageService signal forwarded", "gameID", gameID, "owner", owner)
+	return true, nil
+}
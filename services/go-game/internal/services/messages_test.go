@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"go-game/internal/config"
+	"go-game/internal/models"
+	"go-game/pkg/matchqueue"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageService_RefusesSignalsForOtherPlayers(t *testing.T) {
+	ctx := context.Background()
+	directory := matchqueue.NewMemoryQueue()
+	// A signal that got through would be forwarded to the owner.
+	require.NoError(t, directory.SetRoomOwner(ctx, "g1", "game-b"))
+	messages := &messagesRecorder{}
+	cfg := &config.Config{InstanceID: "game-a", RTCSignalTopic: "rtc_signal"}
+	s := NewMessageService(nil, nil, directory, messages, cfg)
+
+	payloads := map[string]any{
+		"offer":     models.WebRTCOffer{SDP: "sdp", PlayerID: "bob", GameID: "g1", SessionID: "s1"},
+		"answer":    models.WebRTCAnswer{SDP: "sdp", PlayerID: "bob", GameID: "g1", SessionID: "s1"},
+		"candidate": models.ICECandidate{Candidate: "candidate", PlayerID: "bob", GameID: "g1", SessionID: "s1"},
+	}
+	for signalType, payload := range payloads {
+		t.Run(signalType, func(t *testing.T) {
+			data, err := json.Marshal(payload)
+			require.NoError(t, err)
+			signal, err := json.Marshal(models.WebRTCSignal{Type: signalType, Payload: data})
+			require.NoError(t, err)
+
+			err = s.HandleMessage(ctx, models.MessageDTO{Action: "webrtc", Payload: string(signal), Producer: "alice"})
+			assert.ErrorIs(t, err, ErrPlayerMismatch)
+			assert.Empty(t, messages.topics)
+		})
+	}
+}
@@ -1 +1,243 @@
 package services
+
+import (
+	"context"
+	"go-game/internal/app"
+	"go-game/internal/models"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTickRate = 10
+
+	arenaWidth  = 1000.0
+	arenaHeight = 1000.0
+	// playerSpeed is in arena units per second.
+	playerSpeed = 200.0
+	// maxQueuedInputs bounds the inputs waiting for the next tick, so a
+	// flooding client can't grow the queue without limit.
+	maxQueuedInputs = 1024
+)
+
+// spawnPoints are taken in turn by joining players.
+var spawnPoints = []models.Vec2{{X: 100, Y: 100}, {X: 900, Y: 900}, {X: 900, Y: 100}, {X: 100, Y: 900}}
+
+// statePublisher sends snapshots to the players of a room.
+type statePublisher interface {
+	BroadcastGameState(ctx context.Context, gameID string, state models.GameState) error
+}
+
+type queuedInput struct {
+	playerID string
+	input    models.PlayerInput
+}
+
+type membership struct {
+	playerID string
+	join     bool
+}
+
+type playerState struct {
+	models.Player
+	move models.Vec2
+}
+
+// GameRoom runs the authoritative simulation of one game. State changes
+// only on the goroutine running Run, once per tick: joins, leaves and
+// inputs that arrive in between are queued and applied at the next tick.
+// Inputs are applied in player and seq order rather than arrival order,
+// and every step advances time by the same amount, so the same inputs
+// always produce the same states.
+type GameRoom struct {
+	id        string
+	tickRate  int
+	clock     app.Clock
+	publisher statePublisher
+
+	mu      sync.Mutex
+	members map[string]struct{}
+	changes []membership
+	inputs  []queuedInput
+	closed  bool
+
+	// Owned by the Run goroutine.
+	tick    uint64
+	players map[string]*playerState
+	spawned int
+}
+
+func NewGameRoom(id string, tickRate int, clock app.Clock, publisher statePublisher) *GameRoom {
+	if tickRate <= 0 {
+		tickRate = DefaultTickRate
+	}
+	return &GameRoom{
+		id:        id,
+		tickRate:  tickRate,
+		clock:     clock,
+		publisher: publisher,
+		members:   map[string]struct{}{},
+		players:   map[string]*playerState{},
+	}
+}
+
+func (r *GameRoom) ID() string {
+	return r.id
+}
+
+// Join adds the player at the next tick. It returns false once the room
+// has stopped.
+func (r *GameRoom) Join(playerID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return false
+	}
+	r.members[playerID] = struct{}{}
+	r.changes = append(r.changes, membership{playerID: playerID, join: true})
+	return true
+}
+
+// Leave removes the player at the next tick. The room stops at the first
+// tick it has no players left.
+func (r *GameRoom) Leave(playerID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.members[playerID]; !ok {
+		return
+	}
+	delete(r.members, playerID)
+	r.changes = append(r.changes, membership{playerID: playerID})
+}
+
+// Enqueue queues input of a member for the next tick. It returns false if
+// the input was dropped.
+func (r *GameRoom) Enqueue(playerID string, input models.PlayerInput) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.members[playerID]; !ok || r.closed || len(r.inputs) >= maxQueuedInputs {
+		return false
+	}
+	r.inputs = append(r.inputs, queuedInput{playerID: playerID, input: input})
+	return true
+}
+
+// Run steps the simulation tickRate times a second and publishes a snapshot
+// after every step. It returns when ctx is done or the room is empty.
+func (r *GameRoom) Run(ctx context.Context) {
+	ticker := r.clock.NewTicker(time.Second / time.Duration(r.tickRate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.mu.Lock()
+			r.closed = true
+			r.mu.Unlock()
+			return
+		case <-ticker.C():
+			state, ok := r.step()
+			if !ok {
+				slog.Info("GameRoom stopped, no players left", "gameID", r.id)
+				return
+			}
+			if err := r.publisher.BroadcastGameState(ctx, r.id, state); err != nil {
+				slog.Error("GameRoom BroadcastGameState", "gameID", r.id, "error", err)
+			}
+		}
+	}
+}
+
+// step advances the room by one tick. It returns false, and closes the
+// room, if nobody is in it.
+func (r *GameRoom) step() (models.GameState, bool) {
+	r.mu.Lock()
+	changes, inputs := r.changes, r.inputs
+	r.changes, r.inputs = nil, nil
+	if len(r.members) == 0 {
+		r.closed = true
+		r.mu.Unlock()
+		return models.GameState{}, false
+	}
+	r.mu.Unlock()
+
+	for _, c := range changes {
+		if !c.join {
+			delete(r.players, c.playerID)
+			continue
+		}
+		if _, ok := r.players[c.playerID]; !ok {
+			r.players[c.playerID] = &playerState{Player: models.Player{
+				Id:       c.playerID,
+				Position: spawnPoints[r.spawned%len(spawnPoints)],
+			}}
+			r.spawned++
+		}
+	}
+
+	sort.SliceStable(inputs, func(i, j int) bool {
+		if inputs[i].playerID != inputs[j].playerID {
+			return inputs[i].playerID < inputs[j].playerID
+		}
+		return inputs[i].input.Seq < inputs[j].input.Seq
+	})
+	for _, in := range inputs {
+		p, ok := r.players[in.playerID]
+		// Inputs are resent until acknowledged, so old ones are expected.
+		if !ok || in.input.Seq <= p.LastInput {
+			continue
+		}
+		p.move = direction(in.input.MoveX, in.input.MoveY)
+		p.LastInput = in.input.Seq
+	}
+
+	r.tick++
+	dt := 1 / float64(r.tickRate)
+	state := models.GameState{
+		Tick:    r.tick,
+		Players: make([]models.Player, 0, len(r.players)),
+		Objects: []models.Object{},
+	}
+	for _, id := range r.playerIDs() {
+		p := r.players[id]
+		p.Velocity = models.Vec2{X: p.move.X * playerSpeed, Y: p.move.Y * playerSpeed}
+		p.Position.X, p.Velocity.X = clampAxis(p.Position.X+p.Velocity.X*dt, p.Velocity.X, arenaWidth)
+		p.Position.Y, p.Velocity.Y = clampAxis(p.Position.Y+p.Velocity.Y*dt, p.Velocity.Y, arenaHeight)
+		state.Players = append(state.Players, p.Player)
+	}
+	return state, true
+}
+
+func (r *GameRoom) playerIDs() []string {
+	ids := make([]string, 0, len(r.players))
+	for id := range r.players {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// direction turns a stick position into a vector no longer than 1.
+// Garbage from the client means standing still.
+func direction(x, y float64) models.Vec2 {
+	if math.IsNaN(x) || math.IsNaN(y) || math.IsInf(x, 0) || math.IsInf(y, 0) {
+		return models.Vec2{}
+	}
+	if l := math.Hypot(x, y); l > 1 {
+		x, y = x/l, y/l
+	}
+	return models.Vec2{X: x, Y: y}
+}
+
+// clampAxis keeps pos within [0, limit], stopping the movement into a wall.
+func clampAxis(pos, velocity, limit float64) (float64, float64) {
+	switch {
+	case pos < 0:
+		return 0, 0
+	case pos > limit:
+		return limit, 0
+	}
+	return pos, velocity
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"go-game/internal/app"
+	"go-game/internal/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock moves only when told to.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+type fakeTicker struct {
+	c       chan time.Time
+	d       time.Duration
+	next    time.Time
+	stopped chan struct{}
+	stop    sync.Once
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) app.Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTicker{c: make(chan time.Time), d: d, next: c.now.Add(d), stopped: make(chan struct{})}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Advance moves time on by d and fires every ticker that is due, once,
+// blocking until its goroutine has received the tick or stopped the
+// ticker. The work a tick triggers may still be running when Advance
+// returns.
+func (c *fakeClock) Advance(t *testing.T, d time.Duration) {
+	t.Helper()
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	var due []*fakeTicker
+	for _, ticker := range c.tickers {
+		if !ticker.next.After(now) {
+			due = append(due, ticker)
+			ticker.next = now.Add(ticker.d)
+		}
+	}
+	c.mu.Unlock()
+	for _, ticker := range due {
+		select {
+		case ticker.c <- now:
+		case <-ticker.stopped:
+		case <-time.After(time.Second):
+			t.Fatal("tick not received")
+		}
+	}
+}
+
+// WaitTickers waits until n tickers have been made, so that a goroutine
+// started with one is ready for Advance.
+func (c *fakeClock) WaitTickers(t *testing.T, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.tickers) >= n
+	}, time.Second, time.Millisecond, "ticker not started")
+}
+
+// Tick is one step of a room at 10 ticks a second.
+func (c *fakeClock) Tick(t *testing.T) {
+	t.Helper()
+	c.Advance(t, 100*time.Millisecond)
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+func (t *fakeTicker) Stop()               { t.stop.Do(func() { close(t.stopped) }) }
+
+// statesRecorder collects published snapshots.
+type statesRecorder struct {
+	states chan models.GameState
+}
+
+func newStatesRecorder() *statesRecorder {
+	return &statesRecorder{states: make(chan models.GameState, 16)}
+}
+
+func (r *statesRecorder) BroadcastGameState(ctx context.Context, gameID string, state models.GameState) error {
+	r.states <- state
+	return nil
+}
+
+func (r *statesRecorder) next(t *testing.T) models.GameState {
+	t.Helper()
+	select {
+	case s := <-r.states:
+		return s
+	case <-time.After(time.Second):
+		t.Fatal("no state published")
+		return models.GameState{}
+	}
+}
+
+func startRoom(t *testing.T, players ...string) (*GameRoom, *fakeClock, *statesRecorder, chan struct{}) {
+	t.Helper()
+	clock, recorder := newFakeClock(), newStatesRecorder()
+	room := NewGameRoom("game1", 10, clock, recorder)
+	for _, p := range players {
+		require.True(t, room.Join(p))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		room.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	clock.WaitTickers(t, 1)
+	return room, clock, recorder, done
+}
+
+func TestGameRoom_Movement(t *testing.T) {
+	room, clock, recorder, _ := startRoom(t, "alice")
+
+	clock.Tick(t)
+	state := recorder.next(t)
+	assert.Equal(t, uint64(1), state.Tick)
+	assert.Equal(t, []models.Player{{Id: "alice", Position: models.Vec2{X: 100, Y: 100}}}, state.Players)
+	assert.Equal(t, []models.Object{}, state.Objects)
+
+	// 200 units/s at 10 ticks/s is 20 units a tick.
+	require.True(t, room.Enqueue("alice", models.PlayerInput{Seq: 1, MoveX: 1}))
+	clock.Tick(t)
+	state = recorder.next(t)
+	assert.Equal(t, models.Player{
+		Id:        "alice",
+		Position:  models.Vec2{X: 120, Y: 100},
+		Velocity:  models.Vec2{X: 200},
+		LastInput: 1,
+	}, state.Players[0])
+
+	// The last input keeps applying until another one arrives.
+	clock.Tick(t)
+	assert.Equal(t, models.Vec2{X: 140, Y: 100}, recorder.next(t).Players[0].Position)
+}
+
+func TestGameRoom_Inputs(t *testing.T) {
+	tests := []struct {
+		name             string
+		inputs           []models.PlayerInput
+		expectedPosition models.Vec2
+		expectedLast     uint32
+	}{
+		{
+			name:             "latest seq wins whatever the arrival order",
+			inputs:           []models.PlayerInput{{Seq: 3, MoveY: 1}, {Seq: 2, MoveX: 1}},
+			expectedPosition: models.Vec2{X: 100, Y: 120},
+			expectedLast:     3,
+		},
+		{
+			name:             "diagonal is not faster",
+			inputs:           []models.PlayerInput{{Seq: 1, MoveX: 1, MoveY: 1}},
+			expectedPosition: models.Vec2{X: 100 + 20/1.4142135623730951, Y: 100 + 20/1.4142135623730951},
+			expectedLast:     1,
+		},
+		{
+			name:             "seq zero is never newer",
+			inputs:           []models.PlayerInput{{Seq: 0, MoveX: 1}},
+			expectedPosition: models.Vec2{X: 100, Y: 100},
+		},
+		{
+			name:             "stick is clamped",
+			inputs:           []models.PlayerInput{{Seq: 1, MoveX: -50}},
+			expectedPosition: models.Vec2{X: 80, Y: 100},
+			expectedLast:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room, clock, recorder, _ := startRoom(t, "alice")
+			clock.Tick(t)
+			recorder.next(t)
+
+			for _, in := range tt.inputs {
+				room.Enqueue("alice", in)
+			}
+			clock.Tick(t)
+			p := recorder.next(t).Players[0]
+			assert.InDelta(t, tt.expectedPosition.X, p.Position.X, 1e-9)
+			assert.InDelta(t, tt.expectedPosition.Y, p.Position.Y, 1e-9)
+			assert.Equal(t, tt.expectedLast, p.LastInput)
+		})
+	}
+}
+
+func TestGameRoom_ClampsToArena(t *testing.T) {
+	room, clock, recorder, _ := startRoom(t, "alice")
+	room.Enqueue("alice", models.PlayerInput{Seq: 1, MoveX: -1})
+	for i := 0; i < 6; i++ {
+		clock.Tick(t)
+		recorder.next(t)
+	}
+	clock.Tick(t)
+	p := recorder.next(t).Players[0]
+	assert.Equal(t, models.Vec2{X: 0, Y: 100}, p.Position)
+	assert.Equal(t, models.Vec2{}, p.Velocity)
+}
+
+func TestGameRoom_Deterministic(t *testing.T) {
+	run := func(order []string) models.GameState {
+		room, clock, recorder, _ := startRoom(t, "alice", "bob")
+		inputs := map[string]models.PlayerInput{
+			"alice": {Seq: 1, MoveX: 0.5, MoveY: -0.25},
+			"bob":   {Seq: 1, MoveX: -1},
+		}
+		var state models.GameState
+		for tick := 0; tick < 5; tick++ {
+			for _, p := range order {
+				in := inputs[p]
+				in.Seq += uint32(tick)
+				room.Enqueue(p, in)
+			}
+			clock.Tick(t)
+			state = recorder.next(t)
+		}
+		return state
+	}
+
+	assert.Equal(t, run([]string{"alice", "bob"}), run([]string{"bob", "alice"}))
+}
+
+func TestGameRoom_JoinLeave(t *testing.T) {
+	room, clock, recorder, done := startRoom(t, "alice")
+	assert.False(t, room.Enqueue("mallory", models.PlayerInput{Seq: 1}), "non-members can't send input")
+
+	require.True(t, room.Join("bob"))
+	clock.Tick(t)
+	state := recorder.next(t)
+	require.Len(t, state.Players, 2)
+	assert.Equal(t, models.Vec2{X: 900, Y: 900}, state.Players[1].Position)
+
+	room.Leave("alice")
+	clock.Tick(t)
+	state = recorder.next(t)
+	require.Len(t, state.Players, 1)
+	assert.Equal(t, "bob", state.Players[0].Id)
+
+	room.Leave("bob")
+	clock.Tick(t)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("empty room kept running")
+	}
+	assert.False(t, room.Join("alice"), "a stopped room can't be joined")
+}
+
+func TestGameRoom_InputQueueIsBounded(t *testing.T) {
+	room, _, _, _ := startRoom(t, "alice")
+	for i := 0; i < maxQueuedInputs; i++ {
+		require.True(t, room.Enqueue("alice", models.PlayerInput{Seq: uint32(i + 1)}))
+	}
+	assert.False(t, room.Enqueue("alice", models.PlayerInput{Seq: maxQueuedInputs + 1}))
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"go-game/internal/app"
+	"go-game/internal/models"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRoomNotFound = errors.New("room not found")
+	ErrRoomFull     = errors.New("room is full")
+	ErrRoomFinished = errors.New("room is finished")
+)
+
+const (
+	DefaultRoomCapacity    = 4
+	DefaultRoomStartDelay  = 3 * time.Second
+	DefaultRoomIdleTimeout = time.Minute
+)
+
+type roomEntry struct {
+	models.Room
+	// updatedAt is the time of the last join, leave or state change.
+	updatedAt time.Time
+	startAt   time.Time
+}
+
+// RoomRegistry keeps the rooms of this instance and moves them through
+// their states: a room waits in the lobby until it is full, counts down in
+// starting for startDelay, and is running until everyone has left or it is
+// ended. Empty and finished rooms are removed by Sweep once they have been
+// idle for idleTimeout. Every change is published on the room events topic.
+type RoomRegistry struct {
+	producer    app.KProducer
+	topic       string
+	clock       app.Clock
+	capacity    int
+	startDelay  time.Duration
+	idleTimeout time.Duration
+
+	mu    sync.Mutex
+	rooms map[string]*roomEntry
+}
+
+func NewRoomRegistry(cfg app.AppConfig, producer app.KProducer, clock app.Clock) *RoomRegistry {
+	c := cfg.GetConfig()
+	r := &RoomRegistry{
+		producer:    producer,
+		topic:       c.GameRoomEventsTopic,
+		clock:       clock,
+		capacity:    c.GameRoomCapacity,
+		startDelay:  time.Duration(c.GameRoomStartDelay) * time.Second,
+		idleTimeout: time.Duration(c.GameRoomIdleTimeout) * time.Second,
+		rooms:       map[string]*roomEntry{},
+	}
+	if r.capacity <= 0 {
+		r.capacity = DefaultRoomCapacity
+	}
+	if r.startDelay < 0 {
+		r.startDelay = DefaultRoomStartDelay
+	}
+	if r.idleTimeout <= 0 {
+		r.idleTimeout = DefaultRoomIdleTimeout
+	}
+	return r
+}
+
+// Create opens a room in the lobby. A capacity of zero or less means the
+// configured default.
+func (r *RoomRegistry) Create(capacity int) models.Room {
+	if capacity <= 0 {
+		capacity = r.capacity
+	}
+	now := r.clock.Now()
+	e := &roomEntry{
+		Room: models.Room{
+			Id:        uuid.NewString(),
+			State:     models.RoomLobby,
+			Capacity:  capacity,
+			Players:   []string{},
+			CreatedAt: now,
+		},
+		updatedAt: now,
+	}
+
+	r.mu.Lock()
+	r.rooms[e.Id] = e
+	room := e.snapshot()
+	r.mu.Unlock()
+
+	r.publish(models.RoomEvent{Type: "created", Room: room, At: now})
+	return room
+}
+
+func (r *RoomRegistry) Get(id string) (models.Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.rooms[id]
+	if !ok {
+		return models.Room{}, ErrRoomNotFound
+	}
+	return e.snapshot(), nil
+}
+
+// Join adds the player to the room. Joining a room the player is already
+// in succeeds without changing anything, so a reconnecting player gets
+// its seat back. The room starts counting down once it is full.
+func (r *RoomRegistry) Join(id, playerID string) (models.Room, error) {
+	now := r.clock.Now()
+	var events []models.RoomEvent
+
+	r.mu.Lock()
+	e, ok := r.rooms[id]
+	switch {
+	case !ok:
+		r.mu.Unlock()
+		return models.Room{}, ErrRoomNotFound
+	case e.State == models.RoomFinished:
+		r.mu.Unlock()
+		return models.Room{}, ErrRoomFinished
+	case slices.Contains(e.Players, playerID):
+		room := e.snapshot()
+		r.mu.Unlock()
+		return room, nil
+	case len(e.Players) >= e.Capacity:
+		r.mu.Unlock()
+		return models.Room{}, ErrRoomFull
+	}
+	e.Players = append(e.Players, playerID)
+	e.updatedAt = now
+	events = append(events, models.RoomEvent{Type: "joined", PlayerId: playerID, Room: e.snapshot(), At: now})
+	if e.State == models.RoomLobby && len(e.Players) == e.Capacity {
+		e.startAt = now.Add(r.startDelay)
+		events = append(events, e.setState(models.RoomStarting, now))
+	}
+	room := e.snapshot()
+	r.mu.Unlock()
+
+	r.publish(events...)
+	return room, nil
+}
+
+// Leave takes the player out of the room. A starting room goes back to
+// the lobby, and a running room nobody is left in is finished.
+func (r *RoomRegistry) Leave(id, playerID string) (models.Room, error) {
+	now := r.clock.Now()
+	var events []models.RoomEvent
+
+	r.mu.Lock()
+	e, ok := r.rooms[id]
+	if !ok {
+		r.mu.Unlock()
+		return models.Room{}, ErrRoomNotFound
+	}
+	i := slices.Index(e.Players, playerID)
+	if i < 0 {
+		room := e.snapshot()
+		r.mu.Unlock()
+		return room, nil
+	}
+	e.Players = slices.Delete(e.Players, i, i+1)
+	e.updatedAt = now
+	events = append(events, models.RoomEvent{Type: "left", PlayerId: playerID, Room: e.snapshot(), At: now})
+	switch {
+	case e.State == models.RoomStarting:
+		events = append(events, e.setState(models.RoomLobby, now))
+	case e.State == models.RoomRunning && len(e.Players) == 0:
+		events = append(events, e.setState(models.RoomFinished, now))
+	}
+	room := e.snapshot()
+	r.mu.Unlock()
+
+	r.publish(events...)
+	return room, nil
+}
+
+// End finishes the room whatever its state.
+func (r *RoomRegistry) End(id string) error {
+	now := r.clock.Now()
+
+	r.mu.Lock()
+	e, ok := r.rooms[id]
+	if !ok {
+		r.mu.Unlock()
+		return ErrRoomNotFound
+	}
+	if e.State == models.RoomFinished {
+		r.mu.Unlock()
+		return nil
+	}
+	event := e.setState(models.RoomFinished, now)
+	r.mu.Unlock()
+
+	r.publish(event)
+	return nil
+}
+
+// Sweep runs the time based transitions: starting rooms whose countdown is
+// over become running, and idle rooms are removed. It returns the rooms
+// that started.
+func (r *RoomRegistry) Sweep() []models.Room {
+	now := r.clock.Now()
+	var started []models.Room
+	var events []models.RoomEvent
+
+	r.mu.Lock()
+	ids := make([]string, 0, len(r.rooms))
+	for id := range r.rooms {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		e := r.rooms[id]
+		switch {
+		case e.State == models.RoomStarting && !now.Before(e.startAt):
+			events = append(events, e.setState(models.RoomRunning, now))
+			started = append(started, e.snapshot())
+		case (e.State == models.RoomFinished || len(e.Players) == 0) && now.Sub(e.updatedAt) >= r.idleTimeout:
+			if e.State != models.RoomFinished {
+				events = append(events, e.setState(models.RoomFinished, now))
+			}
+			delete(r.rooms, id)
+			events = append(events, models.RoomEvent{Type: "removed", Room: e.snapshot(), At: now})
+		}
+	}
+	r.mu.Unlock()
+
+	r.publish(events...)
+	return started
+}
+
+// publish is called without r.mu held, as producing waits for the broker.
+func (r *RoomRegistry) publish(events ...models.RoomEvent) {
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			slog.Error("RoomRegistry publish json.Marshal", "error", err)
+			continue
+		}
+		if err := r.producer.Produce(r.topic, string(data)); err != nil {
+			slog.Error("RoomRegistry publish", "roomID", event.Room.Id, "type", event.Type, "error", err)
+		}
+	}
+}
+
+func (e *roomEntry) setState(state models.RoomState, now time.Time) models.RoomEvent {
+	e.State = state
+	e.updatedAt = now
+	return models.RoomEvent{Type: "state", Room: e.snapshot(), At: now}
+}
+
+// snapshot copies the room so it can be handed out after r.mu is released.
+func (e *roomEntry) snapshot() models.Room {
+	room := e.Room
+	room.Players = slices.Clone(e.Players)
+	return room
+}
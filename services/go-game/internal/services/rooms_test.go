@@ -0,0 +1,156 @@
+package services
+
+import (
+	"encoding/json"
+	"go-game/internal/config"
+	"go-game/internal/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventsRecorder is a producer that keeps the room events sent to it.
+type eventsRecorder struct {
+	mu     sync.Mutex
+	events []models.RoomEvent
+}
+
+func (p *eventsRecorder) Produce(topic string, value string) error {
+	var event models.RoomEvent
+	if err := json.Unmarshal([]byte(value), &event); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *eventsRecorder) Close() {}
+
+// take returns "type:state" of the events recorded since the last call.
+func (p *eventsRecorder) take() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	types := make([]string, 0, len(p.events))
+	for _, e := range p.events {
+		types = append(types, e.Type+":"+string(e.Room.State))
+	}
+	p.events = nil
+	return types
+}
+
+func newTestRegistry() (*RoomRegistry, *fakeClock, *eventsRecorder) {
+	clock, producer := newFakeClock(), &eventsRecorder{}
+	cfg := &config.Config{
+		GameRoomCapacity:    2,
+		GameRoomStartDelay:  3,
+		GameRoomIdleTimeout: 60,
+		GameRoomEventsTopic: "game_room_events",
+	}
+	return NewRoomRegistry(cfg, producer, clock), clock, producer
+}
+
+func TestRoomRegistry_Lifecycle(t *testing.T) {
+	r, clock, events := newTestRegistry()
+
+	room := r.Create(0)
+	assert.Equal(t, models.RoomLobby, room.State)
+	assert.Equal(t, 2, room.Capacity)
+	assert.Equal(t, []string{"created:lobby"}, events.take())
+
+	_, err := r.Join(room.Id, "alice")
+	require.NoError(t, err)
+	room, err = r.Join(room.Id, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoomStarting, room.State)
+	assert.Equal(t, []string{"joined:lobby", "joined:lobby", "state:starting"}, events.take())
+
+	clock.Advance(t, 2*time.Second)
+	assert.Empty(t, r.Sweep(), "countdown isn't over")
+	clock.Advance(t, time.Second)
+	started := r.Sweep()
+	require.Len(t, started, 1)
+	assert.Equal(t, models.RoomRunning, started[0].State)
+	assert.Equal(t, []string{"alice", "bob"}, started[0].Players)
+	assert.Equal(t, []string{"state:running"}, events.take())
+
+	_, err = r.Leave(room.Id, "alice")
+	require.NoError(t, err)
+	room, err = r.Leave(room.Id, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoomFinished, room.State)
+	assert.Equal(t, []string{"left:running", "left:running", "state:finished"}, events.take())
+
+	_, err = r.Join(room.Id, "alice")
+	assert.ErrorIs(t, err, ErrRoomFinished)
+
+	clock.Advance(t, time.Minute)
+	r.Sweep()
+	_, err = r.Get(room.Id)
+	assert.ErrorIs(t, err, ErrRoomNotFound)
+	assert.Equal(t, []string{"removed:finished"}, events.take())
+}
+
+func TestRoomRegistry_Join(t *testing.T) {
+	r, _, _ := newTestRegistry()
+
+	_, err := r.Join("nope", "alice")
+	assert.ErrorIs(t, err, ErrRoomNotFound)
+
+	room := r.Create(2)
+	_, err = r.Join(room.Id, "alice")
+	require.NoError(t, err)
+	again, err := r.Join(room.Id, "alice")
+	require.NoError(t, err, "rejoining keeps the seat")
+	assert.Equal(t, []string{"alice"}, again.Players)
+
+	_, err = r.Join(room.Id, "bob")
+	require.NoError(t, err)
+	_, err = r.Join(room.Id, "carol")
+	assert.ErrorIs(t, err, ErrRoomFull)
+}
+
+func TestRoomRegistry_LeavingStopsCountdown(t *testing.T) {
+	r, clock, events := newTestRegistry()
+	room := r.Create(2)
+	r.Join(room.Id, "alice")
+	r.Join(room.Id, "bob")
+	events.take()
+
+	room, err := r.Leave(room.Id, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoomLobby, room.State)
+	assert.Equal(t, []string{"left:starting", "state:lobby"}, events.take())
+
+	clock.Advance(t, 5*time.Second)
+	assert.Empty(t, r.Sweep())
+}
+
+func TestRoomRegistry_IdleRooms(t *testing.T) {
+	r, clock, events := newTestRegistry()
+	empty := r.Create(2)
+	waiting := r.Create(2)
+	r.Join(waiting.Id, "alice")
+	ended := r.Create(2)
+	r.Join(ended.Id, "bob")
+	require.NoError(t, r.End(ended.Id))
+	events.take()
+
+	clock.Advance(t, 59*time.Second)
+	r.Sweep()
+	assert.Empty(t, events.take(), "nothing is idle yet")
+
+	clock.Advance(t, time.Second)
+	r.Sweep()
+	_, err := r.Get(empty.Id)
+	assert.ErrorIs(t, err, ErrRoomNotFound)
+	_, err = r.Get(ended.Id)
+	assert.ErrorIs(t, err, ErrRoomNotFound)
+	_, err = r.Get(waiting.Id)
+	assert.NoError(t, err, "a room with players in the lobby is kept")
+	assert.ElementsMatch(t, []string{"state:finished", "removed:finished", "removed:finished"}, events.take())
+}
@@ -1 +1,282 @@
 package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-game/internal/app"
+	"go-game/internal/models"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrUnknownMode = errors.New("unknown game mode")
+
+// matchInterval is how often the queue is matched.
+const matchInterval = time.Second
+
+// SearchGameService is matchmaking: players queue with a character and a
+// mode, and every matchInterval the players of a mode are grouped by the
+// level of their characters. The level difference a player accepts starts
+// at tolerance and widens by toleranceStep every widenInterval they wait,
+// up to maxTolerance. A full group gets a room and every player in it a
+// "match_found" message; a player still waiting after timeout gets
+// "match_timeout". The queue is shared by every instance of the service;
+// a room is created on the instance that matched it, which is recorded
+// in directory as its owner.
+type SearchGameService struct {
+	queue         app.MatchQueue
+	directory     app.RoomDirectory
+	characters    app.CharacterStore
+	registry      *RoomRegistry
+	producer      app.KProducer
+	clock         app.Clock
+	instanceID    string
+	responseTopic string
+	modes         map[string]int
+	tolerance     int
+	toleranceStep int
+	maxTolerance  int
+	widenInterval time.Duration
+	timeout       time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewSearchGameService starts matching the queue.
+func NewSearchGameService(queue app.MatchQueue, directory app.RoomDirectory, characters app.CharacterStore, registry *RoomRegistry, producer app.KProducer, cfg app.AppConfig, clock app.Clock) *SearchGameService {
+	c := cfg.GetConfig()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &SearchGameService{
+		queue:         queue,
+		directory:     directory,
+		characters:    characters,
+		registry:      registry,
+		producer:      producer,
+		clock:         clock,
+		instanceID:    c.InstanceID,
+		responseTopic: c.RTCResponseTopic,
+		modes:         c.MatchModes,
+		tolerance:     c.MatchTolerance,
+		toleranceStep: c.MatchToleranceStep,
+		maxTolerance:  max(c.MatchMaxTolerance, c.MatchTolerance),
+		widenInterval: time.Duration(c.MatchWidenInterval) * time.Second,
+		timeout:       time.Duration(c.MatchTimeout) * time.Second,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+// Search queues the player, replacing the search they may already have.
+func (s *SearchGameService) Search(ctx context.Context, playerID string, req models.SearchRequest) error {
+	if _, ok := s.modes[req.Mode]; !ok {
+		return fmt.Errorf("SearchGameService Search %q: %w", req.Mode, ErrUnknownMode)
+	}
+	level, err := s.characters.CharacterLevel(ctx, playerID, req.CharacterID)
+	if err != nil {
+		return fmt.Errorf("SearchGameService Search CharacterLevel: %w", err)
+	}
+	return s.queue.Enqueue(ctx, models.MatchTicket{
+		PlayerID:    playerID,
+		CharacterID: req.CharacterID,
+		Mode:        req.Mode,
+		Level:       level,
+		EnqueuedAt:  s.clock.Now(),
+	})
+}
+
+func (s *SearchGameService) CancelSearch(ctx context.Context, playerID string) error {
+	return s.queue.Remove(ctx, playerID)
+}
+
+// Close stops matching and waits for the round in progress.
+func (s *SearchGameService) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *SearchGameService) run() {
+	defer s.wg.Done()
+	ticker := s.clock.NewTicker(matchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C():
+			if err := s.Match(s.ctx); err != nil {
+				slog.Error("SearchGameService Match", "error", err)
+			}
+		}
+	}
+}
+
+// Match runs one round of matchmaking over the whole queue.
+func (s *SearchGameService) Match(ctx context.Context) error {
+	tickets, err := s.queue.Tickets(ctx)
+	if err != nil {
+		return err
+	}
+	now := s.clock.Now()
+	byMode := map[string][]models.MatchTicket{}
+	for _, t := range tickets {
+		_, known := s.modes[t.Mode]
+		if !known || now.Sub(t.EnqueuedAt) >= s.timeout {
+			if err := s.expire(ctx, t); err != nil {
+				return err
+			}
+			continue
+		}
+		byMode[t.Mode] = append(byMode[t.Mode], t)
+	}
+
+	modes := make([]string, 0, len(byMode))
+	for mode := range byMode {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	for _, mode := range modes {
+		if err := s.matchMode(ctx, mode, byMode[mode], now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchMode builds groups around the players that have waited longest,
+// adding the nearest levels first, as long as the spread of the group is
+// within what every player in it accepts.
+func (s *SearchGameService) matchMode(ctx context.Context, mode string, tickets []models.MatchTicket, now time.Time) error {
+	size := s.modes[mode]
+	sort.Slice(tickets, func(i, j int) bool {
+		if !tickets[i].EnqueuedAt.Equal(tickets[j].EnqueuedAt) {
+			return tickets[i].EnqueuedAt.Before(tickets[j].EnqueuedAt)
+		}
+		return tickets[i].PlayerID < tickets[j].PlayerID
+	})
+	used := make([]bool, len(tickets))
+
+	for i := range tickets {
+		if used[i] {
+			continue
+		}
+		anchor := tickets[i]
+		candidates := make([]int, 0, len(tickets))
+		for j := range tickets {
+			if j != i && !used[j] {
+				candidates = append(candidates, j)
+			}
+		}
+		sort.SliceStable(candidates, func(a, b int) bool {
+			return levelDistance(anchor, tickets[candidates[a]]) < levelDistance(anchor, tickets[candidates[b]])
+		})
+
+		group := []int{i}
+		lo, hi, tolerance := anchor.Level, anchor.Level, s.toleranceOf(anchor, now)
+		for _, j := range candidates {
+			if len(group) == size {
+				break
+			}
+			t := tickets[j]
+			l, h, tol := min(lo, t.Level), max(hi, t.Level), min(tolerance, s.toleranceOf(t, now))
+			if int(h-l) > tol {
+				continue
+			}
+			group = append(group, j)
+			lo, hi, tolerance = l, h, tol
+		}
+		if len(group) < size {
+			continue
+		}
+
+		players := make([]string, len(group))
+		for k, j := range group {
+			players[k] = tickets[j].PlayerID
+		}
+		// Someone may have cancelled, or been matched by another instance,
+		// since the queue was read; they are all still queued otherwise.
+		taken, err := s.queue.Take(ctx, players)
+		if err != nil {
+			return err
+		}
+		if !taken {
+			continue
+		}
+		for _, j := range group {
+			used[j] = true
+		}
+
+		room := s.registry.Create(size)
+		if err := s.directory.SetRoomOwner(ctx, room.Id, s.instanceID); err != nil {
+			// Nobody could reach the room; the players go back to the queue.
+			if err := s.registry.End(room.Id); err != nil {
+				slog.Error("SearchGameService registry.End", "gameID", room.Id, "error", err)
+			}
+			for _, j := range group {
+				if err := s.queue.Enqueue(ctx, tickets[j]); err != nil {
+					slog.Error("SearchGameService requeue", "playerID", tickets[j].PlayerID, "error", err)
+				}
+			}
+			return fmt.Errorf("SearchGameService SetRoomOwner: %w", err)
+		}
+		slog.Info("SearchGameService match found", "gameID", room.Id, "mode", mode, "players", players)
+		found := models.MatchFound{GameID: room.Id, Mode: mode, Players: players}
+		for _, p := range players {
+			s.notify(p, "match_found", found)
+		}
+	}
+	return nil
+}
+
+func (s *SearchGameService) expire(ctx context.Context, t models.MatchTicket) error {
+	taken, err := s.queue.Take(ctx, []string{t.PlayerID})
+	if err != nil || !taken {
+		return err
+	}
+	s.notify(t.PlayerID, "match_timeout", t)
+	return nil
+}
+
+func (s *SearchGameService) toleranceOf(t models.MatchTicket, now time.Time) int {
+	if s.widenInterval <= 0 {
+		return s.tolerance
+	}
+	steps := int(now.Sub(t.EnqueuedAt) / s.widenInterval)
+	return min(s.tolerance+steps*s.toleranceStep, s.maxTolerance)
+}
+
+func levelDistance(a, b models.MatchTicket) int32 {
+	if a.Level > b.Level {
+		return a.Level - b.Level
+	}
+	return b.Level - a.Level
+}
+
+// notify sends a message to the player's websocket through go-websocket.
+func (s *SearchGameService) notify(playerID, action string, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("SearchGameService notify json.Marshal", "error", err)
+		return
+	}
+	msg, err := json.Marshal(models.MessageDTO{
+		Action:   action,
+		Payload:  string(data),
+		Producer: playerID,
+	})
+	if err != nil {
+		slog.Error("SearchGameService notify json.Marshal", "error", err)
+		return
+	}
+	if err := s.producer.Produce(s.responseTopic, string(msg)); err != nil {
+		slog.Error("SearchGameService notify", "playerID", playerID, "action", action, "error", err)
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-game/internal/config"
+	"go-game/internal/models"
+	"go-game/pkg/matchqueue"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCharacter struct {
+	account string
+	level   int32
+}
+
+// fakeCharacters are characters by ID.
+type fakeCharacters map[string]fakeCharacter
+
+func (c fakeCharacters) CharacterLevel(ctx context.Context, accountID, characterID string) (int32, error) {
+	character, ok := c[characterID]
+	if !ok || character.account != accountID {
+		return 0, errors.New("character not found")
+	}
+	return character.level, nil
+}
+
+// messagesRecorder is a producer that keeps the messages sent to players
+// and the topics they were sent to.
+type messagesRecorder struct {
+	mu       sync.Mutex
+	messages []models.MessageDTO
+	topics   []string
+}
+
+func (p *messagesRecorder) Produce(topic string, value string) error {
+	var msg models.MessageDTO
+	if err := json.Unmarshal([]byte(value), &msg); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, msg)
+	p.topics = append(p.topics, topic)
+	return nil
+}
+
+func (p *messagesRecorder) Close() {}
+
+// take returns the messages sent since the last call by player.
+func (p *messagesRecorder) take() map[string]models.MessageDTO {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	byPlayer := map[string]models.MessageDTO{}
+	for _, m := range p.messages {
+		byPlayer[m.Producer] = m
+	}
+	p.messages, p.topics = nil, nil
+	return byPlayer
+}
+
+type searchTest struct {
+	*SearchGameService
+	clock    *fakeClock
+	cfg      *config.Config
+	queue    *matchqueue.MemoryQueue
+	registry *RoomRegistry
+	messages *messagesRecorder
+}
+
+func newSearchTest(t *testing.T, levels fakeCharacters) *searchTest {
+	return newInstanceSearchTest(t, levels, matchqueue.NewMemoryQueue(), "game-1")
+}
+
+// newInstanceSearchTest is the service of one instance; instances share
+// queue as they would share Redis.
+func newInstanceSearchTest(t *testing.T, levels fakeCharacters, queue *matchqueue.MemoryQueue, instanceID string) *searchTest {
+	registry, clock, _ := newTestRegistry()
+	messages := &messagesRecorder{}
+	cfg := &config.Config{
+		InstanceID:         instanceID,
+		RTCSignalTopic:     "rtc_signal",
+		RTCResponseTopic:   "rtc_response",
+		MatchModes:         map[string]int{"duel": 2, "ffa": 4},
+		MatchTolerance:     2,
+		MatchToleranceStep: 2,
+		MatchWidenInterval: 10,
+		MatchMaxTolerance:  20,
+		MatchTimeout:       300,
+	}
+	s := NewSearchGameService(queue, queue, levels, registry, messages, cfg, clock)
+	// Rounds are run by hand below.
+	s.Close()
+	return &searchTest{SearchGameService: s, clock: clock, cfg: cfg, queue: queue, registry: registry, messages: messages}
+}
+
+func (st *searchTest) search(t *testing.T, player, character, mode string) {
+	t.Helper()
+	require.NoError(t, st.Search(context.Background(), player, models.SearchRequest{CharacterID: character, Mode: mode}))
+}
+
+func (st *searchTest) match(t *testing.T) map[string]models.MatchFound {
+	t.Helper()
+	require.NoError(t, st.Match(context.Background()))
+	found := map[string]models.MatchFound{}
+	for player, msg := range st.messages.take() {
+		require.Equal(t, "match_found", msg.Action)
+		var m models.MatchFound
+		require.NoError(t, json.Unmarshal([]byte(msg.Payload), &m))
+		found[player] = m
+	}
+	return found
+}
+
+func TestSearchGameService_Search(t *testing.T) {
+	st := newSearchTest(t, fakeCharacters{"c1": {"alice", 10}, "c2": {"bob", 12}})
+	ctx := context.Background()
+
+	assert.ErrorIs(t, st.Search(ctx, "alice", models.SearchRequest{CharacterID: "c1", Mode: "chess"}), ErrUnknownMode)
+	assert.Error(t, st.Search(ctx, "alice", models.SearchRequest{CharacterID: "nope", Mode: "duel"}))
+	assert.Error(t, st.Search(ctx, "alice", models.SearchRequest{CharacterID: "c2", Mode: "duel"}), "c2 is bob's")
+	tickets, err := st.queue.Tickets(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tickets)
+
+	st.search(t, "alice", "c1", "duel")
+	st.search(t, "alice", "c1", "ffa")
+	tickets, err = st.queue.Tickets(ctx)
+	require.NoError(t, err)
+	require.Len(t, tickets, 1, "searching again replaces the ticket")
+	assert.Equal(t, models.MatchTicket{
+		PlayerID:    "alice",
+		CharacterID: "c1",
+		Mode:        "ffa",
+		Level:       10,
+		EnqueuedAt:  st.clock.Now(),
+	}, tickets[0])
+
+	require.NoError(t, st.CancelSearch(ctx, "alice"))
+	tickets, err = st.queue.Tickets(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tickets)
+}
+
+func TestSearchGameService_MatchesNearestLevels(t *testing.T) {
+	st := newSearchTest(t, fakeCharacters{"c10": {"alice", 10}, "c11": {"carol", 11}, "c12": {"dave", 12}, "c30": {"bob", 30}})
+	st.search(t, "alice", "c10", "duel")
+	st.search(t, "bob", "c30", "duel")
+	st.search(t, "carol", "c11", "duel")
+	st.search(t, "dave", "c12", "ffa")
+
+	found := st.match(t)
+	require.Len(t, found, 2)
+	assert.Equal(t, []string{"alice", "carol"}, found["alice"].Players)
+	assert.Equal(t, found["alice"], found["carol"])
+	assert.Equal(t, "duel", found["alice"].Mode)
+
+	room, err := st.registry.Get(found["alice"].GameID)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoomLobby, room.State)
+	assert.Equal(t, 2, room.Capacity)
+
+	tickets, err := st.queue.Tickets(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, tickets, 2, "bob and dave keep waiting")
+}
+
+func TestSearchGameService_ToleranceWidens(t *testing.T) {
+	st := newSearchTest(t, fakeCharacters{"c10": {"alice", 10}, "c16": {"bob", 16}})
+	st.search(t, "alice", "c10", "duel")
+
+	st.clock.Advance(t, 30*time.Second)
+	st.search(t, "bob", "c16", "duel")
+	assert.Empty(t, st.match(t), "bob only accepts 2 levels yet")
+
+	st.clock.Advance(t, 10*time.Second)
+	assert.Empty(t, st.match(t), "bob accepts 4")
+
+	st.clock.Advance(t, 10*time.Second)
+	assert.Len(t, st.match(t), 2)
+}
+
+func TestSearchGameService_Groups(t *testing.T) {
+	st := newSearchTest(t, fakeCharacters{"c1": {"alice", 1}, "c2": {"bob", 2}, "c3": {"carol", 3}, "c9": {"dave", 9}})
+	st.search(t, "alice", "c1", "ffa")
+	st.search(t, "bob", "c2", "ffa")
+	st.search(t, "carol", "c3", "ffa")
+	st.search(t, "dave", "c9", "ffa")
+	assert.Empty(t, st.match(t))
+
+	// After 40s everyone accepts 10 levels.
+	st.clock.Advance(t, 40*time.Second)
+	found := st.match(t)
+	require.Len(t, found, 4)
+	assert.ElementsMatch(t, []string{"alice", "bob", "carol", "dave"}, found["dave"].Players)
+}
+
+func TestSearchGameService_Timeout(t *testing.T) {
+	st := newSearchTest(t, fakeCharacters{"c1": {"alice", 1}})
+	st.search(t, "alice", "c1", "duel")
+
+	st.clock.Advance(t, 5*time.Minute)
+	require.NoError(t, st.Match(context.Background()))
+	msgs := st.messages.take()
+	assert.Equal(t, "match_timeout", msgs["alice"].Action)
+	tickets, err := st.queue.Tickets(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, tickets)
+}
+
+func TestSearchGameService_RoomOwnedAcrossInstances(t *testing.T) {
+	levels := fakeCharacters{"c1": {"alice", 1}, "c2": {"bob", 2}}
+	queue := matchqueue.NewMemoryQueue()
+	a := newInstanceSearchTest(t, levels, queue, "game-a")
+	b := newInstanceSearchTest(t, levels, queue, "game-b")
+	ctx := context.Background()
+
+	a.search(t, "alice", "c1", "duel")
+	b.search(t, "bob", "c2", "duel")
+	found := b.match(t)
+	require.Len(t, found, 2)
+	assert.Empty(t, a.match(t), "the players were taken by b")
+
+	gameID := found["alice"].GameID
+	_, err := b.registry.Get(gameID)
+	assert.NoError(t, err)
+	_, err = a.registry.Get(gameID)
+	assert.ErrorIs(t, err, ErrRoomNotFound, "the room is only in the registry of b")
+	owner, err := queue.RoomOwner(ctx, gameID)
+	require.NoError(t, err)
+	assert.Equal(t, "game-b", owner)
+
+	// The offer reaches a, which passes it on to b.
+	offer, err := json.Marshal(models.WebRTCOffer{SDP: "sdp", PlayerID: "alice", GameID: gameID, SessionID: "s1"})
+	require.NoError(t, err)
+	signal, err := json.Marshal(models.WebRTCSignal{Type: "offer", Payload: offer})
+	require.NoError(t, err)
+	msg := models.MessageDTO{Action: "webrtc", Payload: string(signal), Producer: "alice"}
+	aMessages := NewMessageService(nil, a.SearchGameService, queue, a.messages, a.cfg)
+	require.NoError(t, aMessages.HandleMessage(ctx, msg))
+	assert.Equal(t, []string{"rtc_signal.game-b"}, a.messages.topics)
+	assert.Equal(t, map[string]models.MessageDTO{"alice": msg}, a.messages.take())
+
+	// b owns the room and handles its signals itself.
+	bMessages := NewMessageService(nil, b.SearchGameService, queue, b.messages, b.cfg)
+	forwarded, err := bMessages.forward(ctx, gameID, msg)
+	require.NoError(t, err)
+	assert.False(t, forwarded)
+	forwarded, err = aMessages.forward(ctx, "unknown", msg)
+	require.NoError(t, err)
+	assert.False(t, forwarded, "signals for rooms nobody owns stay where they are")
+}
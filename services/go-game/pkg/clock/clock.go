@@ -0,0 +1,29 @@
+package clock
+
+import (
+	"go-game/internal/app"
+	"time"
+)
+
+// Real is the wall clock.
+type Real struct{}
+
+func New() Real {
+	return Real{}
+}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) NewTicker(d time.Duration) app.Ticker {
+	return ticker{time.NewTicker(d)}
+}
+
+type ticker struct {
+	*time.Ticker
+}
+
+func (t ticker) C() <-chan time.Time {
+	return t.Ticker.C
+}
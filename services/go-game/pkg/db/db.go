@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-game/internal/app"
+	models "go-game/internal/models/gen"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrCharacterNotFound = errors.New("character not found")
+
+type DB struct {
+	pool    *pgxpool.Pool
+	queries *models.Queries
+}
+
+func New(cfg app.AppConfig) *DB {
+	var c = cfg.GetConfig()
+
+	connString := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		c.PostgresHost, c.PostgresPort, c.PostgresUser, c.PostgresPassword, c.PostgresDB,
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		panic(err.Error())
+	}
+	if err := pool.Ping(ctx); err != nil {
+		panic(err.Error())
+	}
+
+	slog.Info("Postgres connection created", "host", c.PostgresHost, "port", c.PostgresPort)
+	return &DB{pool: pool, queries: models.New(pool)}
+}
+
+func (db *DB) Close() {
+	db.pool.Close()
+}
+
+// CharacterLevel returns the level of the character of accountID. Another
+// account's character is not found either.
+func (db *DB) CharacterLevel(ctx context.Context, accountID, characterID string) (int32, error) {
+	id, err := uuid.Parse(characterID)
+	if err != nil {
+		return 0, ErrCharacterNotFound
+	}
+	account, err := uuid.Parse(accountID)
+	if err != nil {
+		return 0, ErrCharacterNotFound
+	}
+	character, err := db.queries.GetAccountCharacter(ctx, models.GetAccountCharacterParams{ID: id, AccountID: &account})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrCharacterNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("DB CharacterLevel GetAccountCharacter: %w", err)
+	}
+	return character.Level, nil
+}
@@ -41,7 +41,9 @@ func NewConsumer(cfg app.AppConfig, handler app.MessageService) (*Consumer, erro
 		consumerGroup: consumerGroup,
 		handler:       handler,
 		ready:         make(chan bool),
-		topics:  []string{ c.GetConfig().RTCSignalTopic},
+		// Signals for the rooms this instance owns are forwarded to its own
+		// topic, which no other member of the group subscribes to.
+		topics:  []string{ c.GetConfig().RTCSignalTopic, c.InstanceSignalTopic(c.InstanceID)},
 	}, nil
 }
 
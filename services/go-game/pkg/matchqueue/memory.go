@@ -0,0 +1,76 @@
+package matchqueue
+
+import (
+	"context"
+	"go-game/internal/app"
+	"go-game/internal/models"
+	"sync"
+)
+
+var (
+	_ app.MatchQueue    = (*MemoryQueue)(nil)
+	_ app.RoomDirectory = (*MemoryQueue)(nil)
+)
+
+// MemoryQueue keeps the queue and the room owners in the process. It is
+// meant for tests and for running a single instance without Redis.
+type MemoryQueue struct {
+	mu      sync.Mutex
+	tickets map[string]models.MatchTicket
+	owners  map[string]string
+}
+
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{tickets: map[string]models.MatchTicket{}, owners: map[string]string{}}
+}
+
+func (q *MemoryQueue) Enqueue(ctx context.Context, ticket models.MatchTicket) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.tickets[ticket.PlayerID] = ticket
+	return nil
+}
+
+func (q *MemoryQueue) Remove(ctx context.Context, playerID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.tickets, playerID)
+	return nil
+}
+
+func (q *MemoryQueue) Tickets(ctx context.Context) ([]models.MatchTicket, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tickets := make([]models.MatchTicket, 0, len(q.tickets))
+	for _, t := range q.tickets {
+		tickets = append(tickets, t)
+	}
+	return tickets, nil
+}
+
+func (q *MemoryQueue) Take(ctx context.Context, playerIDs []string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, id := range playerIDs {
+		if _, ok := q.tickets[id]; !ok {
+			return false, nil
+		}
+	}
+	for _, id := range playerIDs {
+		delete(q.tickets, id)
+	}
+	return true, nil
+}
+
+func (q *MemoryQueue) SetRoomOwner(ctx context.Context, gameID, instanceID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.owners[gameID] = instanceID
+	return nil
+}
+
+func (q *MemoryQueue) RoomOwner(ctx context.Context, gameID string) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.owners[gameID], nil
+}
@@ -0,0 +1,29 @@
+package matchqueue
+
+import (
+	"context"
+	"go-game/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryQueue_Take(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue()
+	require.NoError(t, q.Enqueue(ctx, models.MatchTicket{PlayerID: "alice"}))
+	require.NoError(t, q.Enqueue(ctx, models.MatchTicket{PlayerID: "bob"}))
+
+	taken, err := q.Take(ctx, []string{"alice", "carol"})
+	require.NoError(t, err)
+	assert.False(t, taken)
+	tickets, _ := q.Tickets(ctx)
+	assert.Len(t, tickets, 2, "nothing is taken unless everyone is")
+
+	taken, err = q.Take(ctx, []string{"alice", "bob"})
+	require.NoError(t, err)
+	assert.True(t, taken)
+	tickets, _ = q.Tickets(ctx)
+	assert.Empty(t, tickets)
+}
@@ -0,0 +1,136 @@
+package matchqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-game/internal/app"
+	"go-game/internal/models"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	_ app.MatchQueue    = (*RedisQueue)(nil)
+	_ app.RoomDirectory = (*RedisQueue)(nil)
+)
+
+const (
+	// ticketsKey is a hash of player ID to ticket JSON, shared by every
+	// instance of the service.
+	ticketsKey = "matchmaking:tickets"
+	// roomOwnerPrefix keys the ID of the instance owning a room.
+	roomOwnerPrefix = "matchmaking:room:"
+	// roomOwnerTTL outlives any game; rooms aren't removed from Redis
+	// when they end.
+	roomOwnerTTL = 24 * time.Hour
+)
+
+// takeScript deletes the given fields only if all of them exist, so two
+// instances matching at once can't both take the same player.
+var takeScript = redis.NewScript(`
+for i, field in ipairs(ARGV) do
+	if redis.call("HEXISTS", KEYS[1], field) == 0 then
+		return 0
+	end
+end
+redis.call("HDEL", KEYS[1], unpack(ARGV))
+return 1
+`)
+
+type RedisQueue struct {
+	client *redis.Client
+}
+
+func NewRedisQueue(cfg app.AppConfig) *RedisQueue {
+	var redisAddr = cfg.GetConfig().RedisAddr
+
+	slog.Info("Connecting to Redis", "address", redisAddr)
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     redisAddr,
+		Password: "",
+		DB:       0,
+		PoolSize: 10,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := rdb.Ping(ctx).Result(); err != nil {
+		panic(err)
+	}
+	slog.Info("Successfully connected to Redis")
+
+	return &RedisQueue{client: rdb}
+}
+
+func (q *RedisQueue) Close() {
+	if err := q.client.Close(); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+func (q *RedisQueue) Enqueue(ctx context.Context, ticket models.MatchTicket) error {
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		return fmt.Errorf("RedisQueue Enqueue json.Marshal: %w", err)
+	}
+	return q.client.HSet(ctx, ticketsKey, ticket.PlayerID, data).Err()
+}
+
+func (q *RedisQueue) Remove(ctx context.Context, playerID string) error {
+	return q.client.HDel(ctx, ticketsKey, playerID).Err()
+}
+
+func (q *RedisQueue) Tickets(ctx context.Context) ([]models.MatchTicket, error) {
+	values, err := q.client.HVals(ctx, ticketsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("RedisQueue Tickets HVals: %w", err)
+	}
+	tickets := make([]models.MatchTicket, 0, len(values))
+	for _, v := range values {
+		var t models.MatchTicket
+		if err := json.Unmarshal([]byte(v), &t); err != nil {
+			slog.Error("RedisQueue Tickets bad ticket", "error", err)
+			continue
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, nil
+}
+
+func (q *RedisQueue) Take(ctx context.Context, playerIDs []string) (bool, error) {
+	if len(playerIDs) == 0 {
+		return true, nil
+	}
+	args := make([]interface{}, len(playerIDs))
+	for i, id := range playerIDs {
+		args[i] = id
+	}
+	taken, err := takeScript.Run(ctx, q.client, []string{ticketsKey}, args...).Int()
+	if err != nil {
+		return false, fmt.Errorf("RedisQueue Take: %w", err)
+	}
+	return taken == 1, nil
+}
+
+func (q *RedisQueue) SetRoomOwner(ctx context.Context, gameID, instanceID string) error {
+	if err := q.client.Set(ctx, roomOwnerPrefix+gameID, instanceID, roomOwnerTTL).Err(); err != nil {
+		return fmt.Errorf("RedisQueue SetRoomOwner: %w", err)
+	}
+	return nil
+}
+
+func (q *RedisQueue) RoomOwner(ctx context.Context, gameID string) (string, error) {
+	owner, err := q.client.Get(ctx, roomOwnerPrefix+gameID).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("RedisQueue RoomOwner: %w", err)
+	}
+	return owner, nil
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Метрики каналов данных, channel - "reliable" или "unreliable"
+var (
+	channelMessages = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "game_datachannel_messages_total",
+			Help: "Data channel messages, by channel and direction",
+		},
+		[]string{"channel", "direction"},
+	)
+	channelBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "game_datachannel_bytes_total",
+			Help: "Data channel payload bytes, by channel and direction",
+		},
+		[]string{"channel", "direction"},
+	)
+	channelDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "game_datachannel_dropped_total",
+			Help: "Messages that were not sent, by channel and reason",
+		},
+		[]string{"channel", "reason"},
+	)
+	channelsOpen = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "game_datachannels_open",
+			Help: "Open data channels",
+		},
+		[]string{"channel"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(channelMessages)
+	prometheus.MustRegister(channelBytes)
+	prometheus.MustRegister(channelDropped)
+	prometheus.MustRegister(channelsOpen)
+}
+
+func RecordSent(channel string, bytes int) {
+	channelMessages.WithLabelValues(channel, "out").Inc()
+	channelBytes.WithLabelValues(channel, "out").Add(float64(bytes))
+}
+
+func RecordReceived(channel string, bytes int) {
+	channelMessages.WithLabelValues(channel, "in").Inc()
+	channelBytes.WithLabelValues(channel, "in").Add(float64(bytes))
+}
+
+// RecordDropped counts a message that wasn't sent; reason is "not_open" or
+// "error".
+func RecordDropped(channel, reason string) {
+	channelDropped.WithLabelValues(channel, reason).Inc()
+}
+
+func ChannelOpened(channel string) {
+	channelsOpen.WithLabelValues(channel).Inc()
+}
+
+func ChannelClosed(channel string) {
+	channelsOpen.WithLabelValues(channel).Dec()
+}
+
+func Start(addr string) {
+	http.Handle("/metrics", promhttp.Handler())
+	go func() {
+		// Метрики на отдельном порту
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			slog.Error("metrics ListenAndServe", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// The metrics are global, so the tests compare values before and after.
+
+func TestRecordSentReceived(t *testing.T) {
+	outMessages := channelMessages.WithLabelValues("unreliable", "out")
+	outBytes := channelBytes.WithLabelValues("unreliable", "out")
+	inMessages := channelMessages.WithLabelValues("unreliable", "in")
+	inBytes := channelBytes.WithLabelValues("unreliable", "in")
+	reliableOut := channelMessages.WithLabelValues("reliable", "out")
+	before := []float64{testutil.ToFloat64(outMessages), testutil.ToFloat64(outBytes), testutil.ToFloat64(inMessages), testutil.ToFloat64(inBytes), testutil.ToFloat64(reliableOut)}
+
+	RecordSent("unreliable", 100)
+	RecordSent("unreliable", 20)
+	RecordReceived("unreliable", 7)
+
+	assert.Equal(t, before[0]+2, testutil.ToFloat64(outMessages))
+	assert.Equal(t, before[1]+120, testutil.ToFloat64(outBytes))
+	assert.Equal(t, before[2]+1, testutil.ToFloat64(inMessages))
+	assert.Equal(t, before[3]+7, testutil.ToFloat64(inBytes))
+	assert.Equal(t, before[4], testutil.ToFloat64(reliableOut), "other channels are counted apart")
+}
+
+func TestRecordDropped(t *testing.T) {
+	notOpen := channelDropped.WithLabelValues("reliable", "not_open")
+	failed := channelDropped.WithLabelValues("reliable", "error")
+	beforeNotOpen, beforeFailed := testutil.ToFloat64(notOpen), testutil.ToFloat64(failed)
+
+	RecordDropped("reliable", "not_open")
+	RecordDropped("reliable", "not_open")
+	RecordDropped("reliable", "error")
+
+	assert.Equal(t, beforeNotOpen+2, testutil.ToFloat64(notOpen))
+	assert.Equal(t, beforeFailed+1, testutil.ToFloat64(failed))
+}
+
+func TestChannelsOpen(t *testing.T) {
+	open := channelsOpen.WithLabelValues("reliable")
+	before := testutil.ToFloat64(open)
+
+	ChannelOpened("reliable")
+	ChannelOpened("reliable")
+	ChannelClosed("reliable")
+
+	assert.Equal(t, before+1, testutil.ToFloat64(open))
+}
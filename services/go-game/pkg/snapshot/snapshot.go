@@ -0,0 +1,426 @@
+// Package snapshot is the binary encoding of game states sent over data
+// channels.
+//
+// A snapshot starts with the schema version and its kind, followed by the
+// tick it describes. A full snapshot then lists every player and object. A
+// delta snapshot names the tick of its base, a snapshot the client has
+// acknowledged, and lists only what was removed or changed since then.
+// Integers are varints except positions and velocities, which are fixed
+// point numbers in 1/Scale units stored as little endian uint16 and int16.
+package snapshot
+
+import (
+	"encoding/binary"
+	"errors"
+	"go-game/internal/models"
+	"math"
+	"sort"
+)
+
+const Version byte = 1
+
+const (
+	KindFull  byte = 1
+	KindDelta byte = 2
+)
+
+// Scale is the number of steps per arena unit positions and velocities are
+// rounded to.
+const Scale = 32
+
+const maxIDLen = 255
+
+// Bits of the field mask of a changed entry in a delta. An entry that
+// isn't in the base has all of them set.
+const (
+	fieldPosition byte = 1 << iota
+	fieldVelocity
+	fieldLastInput
+	fieldKind
+)
+
+const (
+	playerFields = fieldPosition | fieldVelocity | fieldLastInput
+	objectFields = fieldPosition | fieldKind
+)
+
+var (
+	ErrVersion      = errors.New("snapshot: unsupported version")
+	ErrMalformed    = errors.New("snapshot: malformed")
+	ErrBaseMismatch = errors.New("snapshot: delta base not available")
+)
+
+// Quantize rounds positions and velocities to what the encoding keeps, so
+// that the server compares states the way the client will see them.
+func Quantize(state models.GameState) models.GameState {
+	q := models.GameState{
+		Tick:    state.Tick,
+		Players: make([]models.Player, len(state.Players)),
+		Objects: make([]models.Object, len(state.Objects)),
+	}
+	for i, p := range state.Players {
+		p.Position = models.Vec2{X: fromUnsigned(toUnsigned(p.Position.X)), Y: fromUnsigned(toUnsigned(p.Position.Y))}
+		p.Velocity = models.Vec2{X: fromSigned(toSigned(p.Velocity.X)), Y: fromSigned(toSigned(p.Velocity.Y))}
+		q.Players[i] = p
+	}
+	for i, o := range state.Objects {
+		o.Position = models.Vec2{X: fromUnsigned(toUnsigned(o.Position.X)), Y: fromUnsigned(toUnsigned(o.Position.Y))}
+		q.Objects[i] = o
+	}
+	return q
+}
+
+// EncodeFull encodes the whole state.
+func EncodeFull(state models.GameState) []byte {
+	b := make([]byte, 0, 16+len(state.Players)*24+len(state.Objects)*24)
+	b = append(b, Version, KindFull)
+	b = binary.AppendUvarint(b, state.Tick)
+
+	b = binary.AppendUvarint(b, uint64(len(state.Players)))
+	for _, p := range state.Players {
+		b = appendPlayer(b, p, playerFields)
+	}
+	b = binary.AppendUvarint(b, uint64(len(state.Objects)))
+	for _, o := range state.Objects {
+		b = appendObject(b, o, objectFields)
+	}
+	return b
+}
+
+// EncodeDelta encodes what changed between base and state. Both are
+// expected to be quantized.
+func EncodeDelta(base, state models.GameState) []byte {
+	b := make([]byte, 0, 32)
+	b = append(b, Version, KindDelta)
+	b = binary.AppendUvarint(b, state.Tick)
+	b = binary.AppendUvarint(b, base.Tick)
+
+	basePlayers := make(map[string]models.Player, len(base.Players))
+	for _, p := range base.Players {
+		basePlayers[p.Id] = p
+	}
+	var changedPlayers []models.Player
+	var playerMasks []byte
+	for _, p := range state.Players {
+		mask := playerFields
+		if old, ok := basePlayers[p.Id]; ok {
+			delete(basePlayers, p.Id)
+			mask = 0
+			if old.Position != p.Position {
+				mask |= fieldPosition
+			}
+			if old.Velocity != p.Velocity {
+				mask |= fieldVelocity
+			}
+			if old.LastInput != p.LastInput {
+				mask |= fieldLastInput
+			}
+		}
+		if mask != 0 {
+			changedPlayers = append(changedPlayers, p)
+			playerMasks = append(playerMasks, mask)
+		}
+	}
+	b = appendRemoved(b, basePlayers)
+	b = binary.AppendUvarint(b, uint64(len(changedPlayers)))
+	for i, p := range changedPlayers {
+		b = append(b, playerMasks[i])
+		b = appendPlayer(b, p, playerMasks[i])
+	}
+
+	baseObjects := make(map[string]models.Object, len(base.Objects))
+	for _, o := range base.Objects {
+		baseObjects[o.Id] = o
+	}
+	var changedObjects []models.Object
+	var objectMasks []byte
+	for _, o := range state.Objects {
+		mask := objectFields
+		if old, ok := baseObjects[o.Id]; ok {
+			delete(baseObjects, o.Id)
+			mask = 0
+			if old.Position != o.Position {
+				mask |= fieldPosition
+			}
+			if old.Kind != o.Kind {
+				mask |= fieldKind
+			}
+		}
+		if mask != 0 {
+			changedObjects = append(changedObjects, o)
+			objectMasks = append(objectMasks, mask)
+		}
+	}
+	b = appendRemoved(b, baseObjects)
+	b = binary.AppendUvarint(b, uint64(len(changedObjects)))
+	for i, o := range changedObjects {
+		b = append(b, objectMasks[i])
+		b = appendObject(b, o, objectMasks[i])
+	}
+	return b
+}
+
+// Decode reads a snapshot. A delta needs base to be the snapshot it was
+// encoded against. Players and objects come out sorted by id.
+func Decode(data []byte, base *models.GameState) (models.GameState, error) {
+	r := reader{b: data}
+	if r.byte() != Version {
+		if r.err != nil {
+			return models.GameState{}, r.err
+		}
+		return models.GameState{}, ErrVersion
+	}
+	kind := r.byte()
+	state := models.GameState{Tick: r.uvarint()}
+	if r.err != nil {
+		return models.GameState{}, r.err
+	}
+
+	switch kind {
+	case KindFull:
+		n := r.count()
+		state.Players = make([]models.Player, 0, n)
+		for i := 0; i < n && r.err == nil; i++ {
+			p := models.Player{Id: r.id()}
+			r.player(&p, playerFields)
+			state.Players = append(state.Players, p)
+		}
+		n = r.count()
+		state.Objects = make([]models.Object, 0, n)
+		for i := 0; i < n && r.err == nil; i++ {
+			o := models.Object{Id: r.id()}
+			r.object(&o, objectFields)
+			state.Objects = append(state.Objects, o)
+		}
+	case KindDelta:
+		baseTick := r.uvarint()
+		if r.err != nil {
+			return models.GameState{}, r.err
+		}
+		if base == nil || base.Tick != baseTick {
+			return models.GameState{}, ErrBaseMismatch
+		}
+		players := make(map[string]models.Player, len(base.Players))
+		for _, p := range base.Players {
+			players[p.Id] = p
+		}
+		for n := r.count(); n > 0 && r.err == nil; n-- {
+			delete(players, r.id())
+		}
+		for n := r.count(); n > 0 && r.err == nil; n-- {
+			mask := r.byte()
+			id := r.id()
+			p, ok := players[id]
+			if !ok && mask != playerFields {
+				r.fail()
+			}
+			p.Id = id
+			r.player(&p, mask)
+			players[id] = p
+		}
+
+		objects := make(map[string]models.Object, len(base.Objects))
+		for _, o := range base.Objects {
+			objects[o.Id] = o
+		}
+		for n := r.count(); n > 0 && r.err == nil; n-- {
+			delete(objects, r.id())
+		}
+		for n := r.count(); n > 0 && r.err == nil; n-- {
+			mask := r.byte()
+			id := r.id()
+			o, ok := objects[id]
+			if !ok && mask != objectFields {
+				r.fail()
+			}
+			o.Id = id
+			r.object(&o, mask)
+			objects[id] = o
+		}
+
+		state.Players = make([]models.Player, 0, len(players))
+		for _, p := range players {
+			state.Players = append(state.Players, p)
+		}
+		state.Objects = make([]models.Object, 0, len(objects))
+		for _, o := range objects {
+			state.Objects = append(state.Objects, o)
+		}
+	default:
+		return models.GameState{}, ErrMalformed
+	}
+	if r.err != nil {
+		return models.GameState{}, r.err
+	}
+	if len(r.b) != 0 {
+		return models.GameState{}, ErrMalformed
+	}
+	sort.Slice(state.Players, func(i, j int) bool { return state.Players[i].Id < state.Players[j].Id })
+	sort.Slice(state.Objects, func(i, j int) bool { return state.Objects[i].Id < state.Objects[j].Id })
+	return state, nil
+}
+
+// appendPlayer writes the id and the fields in mask. The mask itself is
+// written by the caller, as full snapshots don't have one.
+func appendPlayer(b []byte, p models.Player, mask byte) []byte {
+	b = appendID(b, p.Id)
+	if mask&fieldPosition != 0 {
+		b = binary.LittleEndian.AppendUint16(b, toUnsigned(p.Position.X))
+		b = binary.LittleEndian.AppendUint16(b, toUnsigned(p.Position.Y))
+	}
+	if mask&fieldVelocity != 0 {
+		b = binary.LittleEndian.AppendUint16(b, uint16(toSigned(p.Velocity.X)))
+		b = binary.LittleEndian.AppendUint16(b, uint16(toSigned(p.Velocity.Y)))
+	}
+	if mask&fieldLastInput != 0 {
+		b = binary.AppendUvarint(b, uint64(p.LastInput))
+	}
+	return b
+}
+
+func appendObject(b []byte, o models.Object, mask byte) []byte {
+	b = appendID(b, o.Id)
+	if mask&fieldPosition != 0 {
+		b = binary.LittleEndian.AppendUint16(b, toUnsigned(o.Position.X))
+		b = binary.LittleEndian.AppendUint16(b, toUnsigned(o.Position.Y))
+	}
+	if mask&fieldKind != 0 {
+		b = appendID(b, o.Kind)
+	}
+	return b
+}
+
+func appendRemoved[T any](b []byte, removed map[string]T) []byte {
+	ids := make([]string, 0, len(removed))
+	for id := range removed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	b = binary.AppendUvarint(b, uint64(len(ids)))
+	for _, id := range ids {
+		b = appendID(b, id)
+	}
+	return b
+}
+
+// appendID writes a length prefixed string, cut to maxIDLen bytes.
+func appendID(b []byte, id string) []byte {
+	if len(id) > maxIDLen {
+		id = id[:maxIDLen]
+	}
+	b = binary.AppendUvarint(b, uint64(len(id)))
+	return append(b, id...)
+}
+
+func toUnsigned(v float64) uint16 {
+	return uint16(math.Max(0, math.Min(math.MaxUint16, math.Round(v*Scale))))
+}
+
+func fromUnsigned(v uint16) float64 {
+	return float64(v) / Scale
+}
+
+func toSigned(v float64) int16 {
+	return int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(v*Scale))))
+}
+
+func fromSigned(v int16) float64 {
+	return float64(v) / Scale
+}
+
+// reader consumes a snapshot, remembering the first error so that callers
+// can check once after a run of reads.
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) fail() {
+	if r.err == nil {
+		r.err = ErrMalformed
+	}
+	r.b = nil
+}
+
+func (r *reader) byte() byte {
+	if len(r.b) < 1 {
+		r.fail()
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *reader) uint16() uint16 {
+	if len(r.b) < 2 {
+		r.fail()
+		return 0
+	}
+	v := binary.LittleEndian.Uint16(r.b)
+	r.b = r.b[2:]
+	return v
+}
+
+func (r *reader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+// count reads a number of entries, each at least one byte long, so a
+// corrupt count can't make the decoder allocate more than data holds.
+func (r *reader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.b)) {
+		r.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (r *reader) id() string {
+	n := r.uvarint()
+	if n > maxIDLen || n > uint64(len(r.b)) {
+		r.fail()
+		return ""
+	}
+	id := string(r.b[:n])
+	r.b = r.b[n:]
+	return id
+}
+
+func (r *reader) player(p *models.Player, mask byte) {
+	if mask&^playerFields != 0 {
+		r.fail()
+	}
+	if mask&fieldPosition != 0 {
+		p.Position = models.Vec2{X: fromUnsigned(r.uint16()), Y: fromUnsigned(r.uint16())}
+	}
+	if mask&fieldVelocity != 0 {
+		p.Velocity = models.Vec2{X: fromSigned(int16(r.uint16())), Y: fromSigned(int16(r.uint16()))}
+	}
+	if mask&fieldLastInput != 0 {
+		v := r.uvarint()
+		if v > math.MaxUint32 {
+			r.fail()
+		}
+		p.LastInput = uint32(v)
+	}
+}
+
+func (r *reader) object(o *models.Object, mask byte) {
+	if mask&^objectFields != 0 {
+		r.fail()
+	}
+	if mask&fieldPosition != 0 {
+		o.Position = models.Vec2{X: fromUnsigned(r.uint16()), Y: fromUnsigned(r.uint16())}
+	}
+	if mask&fieldKind != 0 {
+		o.Kind = r.id()
+	}
+}
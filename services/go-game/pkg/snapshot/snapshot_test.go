@@ -0,0 +1,252 @@
+package snapshot
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"go-game/internal/models"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testState(tick uint64, players int) models.GameState {
+	state := models.GameState{Tick: tick, Players: []models.Player{}, Objects: []models.Object{}}
+	for i := 0; i < players; i++ {
+		state.Players = append(state.Players, models.Player{
+			Id:        fmt.Sprintf("player-%02d", i),
+			Position:  models.Vec2{X: 100 + float64(i)*37.3 + float64(tick)*20.01, Y: 900 - float64(i)*11.7},
+			Velocity:  models.Vec2{X: 200, Y: -141.42},
+			LastInput: uint32(tick),
+		})
+	}
+	return state
+}
+
+func TestQuantize(t *testing.T) {
+	q := Quantize(models.GameState{Players: []models.Player{{
+		Position: models.Vec2{X: 100.01, Y: -5},
+		Velocity: models.Vec2{X: -141.4213, Y: 5000},
+	}}})
+	p := q.Players[0]
+	assert.Equal(t, models.Vec2{X: 100, Y: 0}, p.Position, "rounded to 1/32 and clamped at 0")
+	assert.Equal(t, -141.40625, p.Velocity.X)
+	assert.Equal(t, float64(32767)/Scale, p.Velocity.Y, "clamped to int16")
+	assert.Equal(t, q, Quantize(q), "quantizing is idempotent")
+}
+
+func TestFullRoundTrip(t *testing.T) {
+	state := Quantize(testState(7, 3))
+	state.Objects = []models.Object{{Id: "chest", Kind: "loot", Position: models.Vec2{X: 500, Y: 500}}}
+
+	decoded, err := Decode(EncodeFull(state), nil)
+	require.NoError(t, err)
+	assert.Equal(t, state, decoded)
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	base := Quantize(testState(10, 4))
+	base.Objects = []models.Object{{Id: "a", Kind: "rock"}, {Id: "b", Kind: "tree"}}
+
+	next := Quantize(testState(12, 4))
+	next.Players = append(next.Players[:1], next.Players[2:]...) // player-01 left
+	next.Players[0].Velocity = base.Players[0].Velocity
+	next.Players = append(next.Players, models.Player{Id: "zoe", Position: models.Vec2{X: 1, Y: 2}})
+	next.Objects = []models.Object{{Id: "a", Kind: "rock"}, {Id: "c", Kind: "gem", Position: models.Vec2{X: 3}}}
+
+	data := EncodeDelta(base, next)
+	decoded, err := Decode(data, &base)
+	require.NoError(t, err)
+	assert.Equal(t, next, decoded)
+	assert.Less(t, len(data), len(EncodeFull(next)))
+
+	same := EncodeDelta(next, Quantize(models.GameState{Tick: 13, Players: next.Players, Objects: next.Objects}))
+	assert.Len(t, same, 1+1+1+1+4, "no change is just the header and four empty lists")
+}
+
+func TestDecodeErrors(t *testing.T) {
+	base := Quantize(testState(10, 2))
+	delta := EncodeDelta(base, Quantize(testState(11, 2)))
+
+	_, err := Decode(delta, nil)
+	assert.ErrorIs(t, err, ErrBaseMismatch)
+	other := Quantize(testState(9, 2))
+	_, err = Decode(delta, &other)
+	assert.ErrorIs(t, err, ErrBaseMismatch)
+
+	_, err = Decode(append([]byte{Version + 1}, delta[1:]...), &base)
+	assert.ErrorIs(t, err, ErrVersion)
+
+	full := EncodeFull(base)
+	for i := 0; i < len(full); i++ {
+		_, err = Decode(full[:i], nil)
+		assert.Error(t, err, "truncated at %d", i)
+	}
+	_, err = Decode(append(full, 0), nil)
+	assert.ErrorIs(t, err, ErrMalformed, "trailing bytes")
+
+	_, err = Decode([]byte{Version, KindFull, 1, 0xff, 0xff, 0xff, 0x0f}, nil)
+	assert.ErrorIs(t, err, ErrMalformed, "count larger than the data")
+}
+
+func TestTracker(t *testing.T) {
+	var tr Tracker
+	decode := func(data []byte, base *models.GameState) models.GameState {
+		t.Helper()
+		state, err := Decode(data, base)
+		require.NoError(t, err)
+		return state
+	}
+
+	s1 := Quantize(testState(1, 2))
+	data := tr.Encode(s1)
+	assert.Equal(t, KindFull, data[1], "nothing acknowledged yet")
+	got1 := decode(data, nil)
+
+	tr.Ack(1)
+	s2 := Quantize(testState(2, 2))
+	data = tr.Encode(s2)
+	assert.Equal(t, KindDelta, data[1])
+	assert.Equal(t, s2, decode(data, &got1))
+
+	// Acks for snapshots that were never sent, or older than the base,
+	// change nothing.
+	tr.Ack(99)
+	tr.Ack(0)
+	data = tr.Encode(Quantize(testState(3, 2)))
+	assert.Equal(t, KindDelta, data[1])
+	assert.Equal(t, Quantize(testState(3, 2)), decode(data, &got1), "still against tick 1")
+
+	// Without acks the base ages out and full snapshots are sent again.
+	var last []byte
+	for tick := uint64(4); tick <= 1+MaxDeltaAge+1; tick++ {
+		last = tr.Encode(Quantize(testState(tick, 2)))
+	}
+	assert.Equal(t, KindFull, last[1])
+
+	tr.Ack(1 + MaxDeltaAge + 1)
+	data = tr.Encode(Quantize(testState(MaxDeltaAge+3, 2)))
+	assert.Equal(t, KindDelta, data[1], "deltas resume after an ack")
+}
+
+// The benchmarks report the size of a snapshot of eight moving players
+// next to the encoding time, for the JSON this replaces, full snapshots,
+// and deltas one tick apart.
+
+func BenchmarkJSON(b *testing.B) {
+	state := testState(100, 8)
+	var data []byte
+	for i := 0; i < b.N; i++ {
+		data, _ = json.Marshal(state)
+	}
+	b.ReportMetric(float64(len(data)), "bytes/snapshot")
+}
+
+func BenchmarkFull(b *testing.B) {
+	state := Quantize(testState(100, 8))
+	var data []byte
+	for i := 0; i < b.N; i++ {
+		data = EncodeFull(state)
+	}
+	b.ReportMetric(float64(len(data)), "bytes/snapshot")
+}
+
+func BenchmarkDelta(b *testing.B) {
+	base, state := Quantize(testState(99, 8)), Quantize(testState(100, 8))
+	var data []byte
+	for i := 0; i < b.N; i++ {
+		data = EncodeDelta(base, state)
+	}
+	b.ReportMetric(float64(len(data)), "bytes/snapshot")
+}
+
+func BenchmarkDeltaIdle(b *testing.B) {
+	base := Quantize(testState(100, 8))
+	state := base
+	state.Tick++
+	var data []byte
+	for i := 0; i < b.N; i++ {
+		data = EncodeDelta(base, state)
+	}
+	b.ReportMetric(float64(len(data)), "bytes/snapshot")
+}
+
+// BenchmarkAckFlow runs a client against a Tracker the way a connection
+// does, 10 minutes of eight moving players at 10 ticks a second. Snapshots
+// are lost at the given rate; the client decodes the rest against the
+// states it keeps and acks each one with {"type":"ack","tick":N}, which
+// the server gets ackDelay ticks later. It reports the bytes sent per tick
+// next to what JSON would have sent for the same states, and the bytes of
+// the acks.
+func BenchmarkAckFlow(b *testing.B) {
+	const ticks, ackDelay = 6000, 3
+	for _, loss := range []int{0, 5, 20} {
+		b.Run(fmt.Sprintf("loss=%d%%", loss), func(b *testing.B) {
+			var flow ackFlow
+			for i := 0; i < b.N; i++ {
+				flow = runAckFlow(b, ticks, ackDelay, loss)
+			}
+			b.ReportMetric(float64(flow.sent)/ticks, "bytes/snapshot")
+			b.ReportMetric(float64(flow.json)/ticks, "json-bytes/snapshot")
+			b.ReportMetric(float64(flow.acks)/ticks, "ack-bytes/snapshot")
+			b.ReportMetric(float64(flow.full)/ticks*100, "%full")
+		})
+	}
+}
+
+type ackFlow struct {
+	sent, json, acks, full int
+}
+
+func runAckFlow(b *testing.B, ticks, ackDelay, loss int) ackFlow {
+	type pendingAck struct {
+		at   int
+		tick uint64
+	}
+	var (
+		flow     ackFlow
+		tracker  Tracker
+		pending  []pendingAck
+		received = map[uint64]models.GameState{}
+		rng      = rand.New(rand.NewPCG(1, uint64(loss)))
+	)
+	for tick := 1; tick <= ticks; tick++ {
+		for len(pending) > 0 && pending[0].at <= tick {
+			tracker.Ack(pending[0].tick)
+			pending = pending[1:]
+		}
+
+		state := testState(uint64(tick), 8)
+		js, err := json.Marshal(state)
+		require.NoError(b, err)
+		flow.json += len(js)
+		data := tracker.Encode(Quantize(state))
+		flow.sent += len(data)
+		if data[1] == KindFull {
+			flow.full++
+		}
+		if rng.IntN(100) < loss {
+			continue
+		}
+
+		var base *models.GameState
+		if data[1] == KindDelta {
+			_, n := binary.Uvarint(data[2:])
+			baseTick, _ := binary.Uvarint(data[2+n:])
+			if s, ok := received[baseTick]; ok {
+				base = &s
+			}
+		}
+		decoded, err := Decode(data, base)
+		require.NoError(b, err, "the server only bases deltas on acked snapshots")
+		received[decoded.Tick] = decoded
+		delete(received, decoded.Tick-2*MaxDeltaAge)
+
+		// What web-rtc.service.ts sends.
+		flow.acks += len(fmt.Sprintf(`{"type":"ack","tick":%d}`, decoded.Tick))
+		pending = append(pending, pendingAck{at: tick + ackDelay, tick: decoded.Tick})
+	}
+	return flow
+}
@@ -0,0 +1,58 @@
+package snapshot
+
+import (
+	"go-game/internal/models"
+	"sync"
+)
+
+// MaxDeltaAge is how many ticks back a delta base may be. Without a newer
+// ack the client is assumed to be losing snapshots, and gets full ones
+// until it acknowledges one of them.
+const MaxDeltaAge = 32
+
+// Tracker remembers, for one client, the snapshots sent to it and the
+// latest one it acknowledged. The zero value is ready to use.
+type Tracker struct {
+	mu   sync.Mutex
+	sent []models.GameState
+	base *models.GameState
+}
+
+// Encode encodes a quantized state for the client, as a delta against its
+// acknowledged snapshot when that is recent enough.
+func (t *Tracker) Encode(state models.GameState) []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var data []byte
+	if t.base != nil && state.Tick > t.base.Tick && state.Tick-t.base.Tick <= MaxDeltaAge {
+		data = EncodeDelta(*t.base, state)
+	} else {
+		data = EncodeFull(state)
+	}
+
+	t.sent = append(t.sent, state)
+	if len(t.sent) > MaxDeltaAge {
+		t.sent = t.sent[len(t.sent)-MaxDeltaAge:]
+	}
+	return data
+}
+
+// Ack records that the client has the snapshot of tick. Acks for ticks
+// that are older than the current base or no longer kept are ignored.
+func (t *Tracker) Ack(tick uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.base != nil && tick <= t.base.Tick {
+		return
+	}
+	for i := range t.sent {
+		if t.sent[i].Tick == tick {
+			base := t.sent[i]
+			t.base = &base
+			// Nothing older can become the base any more.
+			t.sent = t.sent[i+1:]
+			return
+		}
+	}
+}
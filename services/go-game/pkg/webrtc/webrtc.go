@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"go-game/internal/app"
 	"go-game/internal/models"
+	"go-game/pkg/metrics"
+	"go-game/pkg/snapshot"
 	"log/slog"
 	"net"
 	"strings"
@@ -23,15 +25,32 @@ type RTCManager struct {
 	config   webrtc.Configuration
 	api      *webrtc.API
 			turnAuth *TurnAuthenticator
+	input    app.GameInput
 
 }
 
+// Каналы данных, которые открывает клиент
+const (
+	// ReliableChannel is ordered and retransmitted: chat, inventory and
+	// game events, where every message matters.
+	ReliableChannel = "reliable"
+	// UnreliableChannel is unordered without retransmits: snapshots and
+	// inputs, where a late message is worth less than the next one.
+	UnreliableChannel = "unreliable"
+)
+
 type PeerConnection struct {
 	*webrtc.PeerConnection
 	PlayerID string
 	GameID   string
-	DataChan *webrtc.DataChannel // ссылка на канал
 
+	mu         sync.RWMutex
+	reliable   *webrtc.DataChannel
+	unreliable *webrtc.DataChannel
+
+	// Snapshots tracks what the player has acknowledged, so that states
+	// can be sent as deltas.
+	Snapshots snapshot.Tracker
 }
 type TurnAuthenticator struct {
 	username string
@@ -186,28 +205,61 @@ func (m *RTCManager) HandleOffer(ctx context.Context, offer models.WebRTCOffer)
 
 	// data handler
 	peerConnection.OnDataChannel(func(d *webrtc.DataChannel) {
-		peer.DataChan = d // Сохраняем ссылку на канал
-		
+		name := d.Label()
+		if err := checkChannel(d); err != nil {
+			slog.Warn("Data channel rejected",
+				"label", name,
+				"playerID", peer.PlayerID,
+				"error", err)
+			d.Close()
+			return
+		}
+
 		d.OnOpen(func() {
 			slog.Info("Data channel opened",
+				"channel", name,
 				"playerID", peer.PlayerID,
 				"gameID", peer.GameID)
+			peer.setChannel(name, d)
+			metrics.ChannelOpened(name)
+			// The player is in the game while the reliable channel is open.
+			if name != ReliableChannel || m.input == nil {
+				return
+			}
+			if err := m.input.PlayerConnected(peer.GameID, peer.PlayerID); err != nil {
+				slog.Warn("Player rejected",
+					"playerID", peer.PlayerID,
+					"gameID", peer.GameID,
+					"error", err)
+				// Close waits for the callbacks, this one included.
+				go peer.Close()
+			}
 		})
-		
+
 		d.OnMessage(func(msg webrtc.DataChannelMessage) {
+			metrics.RecordReceived(name, len(msg.Data))
 			// Обработка игровых сообщений
-			slog.Info("Received game message",
-				"playerID", peer.PlayerID,
-				"data", string(msg.Data))
+			if m.input != nil {
+				m.input.HandleInput(peer.GameID, peer.PlayerID, msg.Data)
+			}
 		})
 
 		d.OnClose(func() {
-			slog.Info("Data channel closed")
+			slog.Info("Data channel closed", "channel", name, "playerID", peer.PlayerID)
+			peer.setChannel(name, nil)
+			metrics.ChannelClosed(name)
+			if name != ReliableChannel {
+				return
+			}
 			m.peers.Delete(offer.SessionID)
+			if m.input != nil {
+				m.input.PlayerDisconnected(peer.GameID, peer.PlayerID)
+			}
 		})
-		
+
 		d.OnError(func(err error) {
-			slog.Error("Data channel error", 
+			slog.Error("Data channel error",
+				"channel", name,
 				"error", err,
 				"playerID", peer.PlayerID)
 		})
@@ -256,6 +308,12 @@ func (m *RTCManager) HandleOffer(ctx context.Context, offer models.WebRTCOffer)
 	return m.producer.Produce(responseTopic, string(data))
 }
 
+// SetGameInput routes data channel events to in. It is set once, before
+// the first offer is handled.
+func (m *RTCManager) SetGameInput(in app.GameInput) {
+	m.input = in
+}
+
 func (m *RTCManager) HandleAnswer(ctx context.Context, answer models.WebRTCAnswer) error {
 	value, ok := m.peers.Load(answer.SessionID)
 	if !ok {
@@ -281,11 +339,13 @@ func (m *RTCManager) HandleICECandidate(ctx context.Context, candidate models.IC
 	})
 }
 
+// BroadcastToGame sends data to the players of the game over their
+// reliable channels.
 func (m *RTCManager) BroadcastToGame(gameID string, data []byte) {
 	m.peers.Range(func(key, value interface{}) bool {
 		peer := value.(*PeerConnection)
-		if peer.GameID == gameID && peer.DataChan != nil {
-			if err := peer.DataChan.Send(data); err != nil {
+		if peer.GameID == gameID {
+			if err := peer.send(ReliableChannel, data); err != nil {
 				slog.Error("Failed to send game data",
 					"playerID", peer.PlayerID,
 					"error", err)
@@ -295,28 +355,115 @@ func (m *RTCManager) BroadcastToGame(gameID string, data []byte) {
 	})
 }
 
-func (m *RTCManager) SendToPlayer(playerID string, data []byte) error {
-	var found bool
+// BroadcastSnapshot sends state to the players of the game over their
+// unreliable channels, in the binary snapshot format, each as a delta
+// against what they acknowledged last.
+func (m *RTCManager) BroadcastSnapshot(gameID string, state models.GameState) {
+	state = snapshot.Quantize(state)
 	m.peers.Range(func(key, value interface{}) bool {
 		peer := value.(*PeerConnection)
-		if peer.PlayerID == playerID && peer.DataChan != nil {
-			if err := peer.DataChan.Send(data); err != nil {
-				slog.Error("Failed to send player data",
-					"playerID", playerID,
+		if peer.GameID == gameID {
+			if err := peer.send(UnreliableChannel, peer.Snapshots.Encode(state)); err != nil {
+				slog.Debug("Failed to send snapshot",
+					"playerID", peer.PlayerID,
 					"error", err)
-				return false
 			}
-			found = true
+		}
+		return true
+	})
+}
+
+// AckSnapshot records that the player has the snapshot of tick.
+func (m *RTCManager) AckSnapshot(gameID, playerID string, tick uint64) {
+	m.peers.Range(func(key, value interface{}) bool {
+		peer := value.(*PeerConnection)
+		if peer.GameID == gameID && peer.PlayerID == playerID {
+			peer.Snapshots.Ack(tick)
+		}
+		return true
+	})
+}
+
+// SendReliable sends data to the player in the game over the ordered,
+// retransmitted channel.
+func (m *RTCManager) SendReliable(gameID, playerID string, data []byte) error {
+	return m.sendToPlayer(gameID, playerID, ReliableChannel, data)
+}
+
+// SendUnreliable sends data to the player in the game over the channel
+// that may drop or reorder it.
+func (m *RTCManager) SendUnreliable(gameID, playerID string, data []byte) error {
+	return m.sendToPlayer(gameID, playerID, UnreliableChannel, data)
+}
+
+// sendToPlayer looks the peer up by game as well, since a player who
+// rejoins may still have the connection of their previous game.
+func (m *RTCManager) sendToPlayer(gameID, playerID, channel string, data []byte) error {
+	var peer *PeerConnection
+	m.peers.Range(func(key, value interface{}) bool {
+		if p := value.(*PeerConnection); p.GameID == gameID && p.PlayerID == playerID {
+			peer = p
 			return false
 		}
 		return true
 	})
+	if peer == nil {
+		return errors.New("player not found")
+	}
+	if err := peer.send(channel, data); err != nil {
+		return fmt.Errorf("RTCManager send to %s: %w", playerID, err)
+	}
+	return nil
+}
+
+// checkChannel accepts only the channels we know, negotiated the way their
+// names promise.
+func checkChannel(d *webrtc.DataChannel) error {
+	switch d.Label() {
+	case ReliableChannel:
+		if !d.Ordered() || d.MaxRetransmits() != nil || d.MaxPacketLifeTime() != nil {
+			return errors.New("reliable channel must be ordered without retransmit limits")
+		}
+	case UnreliableChannel:
+		if d.Ordered() || d.MaxRetransmits() == nil || *d.MaxRetransmits() != 0 {
+			return errors.New("unreliable channel must be unordered with maxRetransmits 0")
+		}
+	default:
+		return errors.New("unknown data channel")
+	}
+	return nil
+}
 
-	if !found {
-		return errors.New("player not found or data channel not ready")
+func (p *PeerConnection) setChannel(name string, d *webrtc.DataChannel) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if name == ReliableChannel {
+		p.reliable = d
+	} else {
+		p.unreliable = d
+	}
+}
+
+// send writes data to the named channel and counts it.
+func (p *PeerConnection) send(name string, data []byte) error {
+	p.mu.RLock()
+	d := p.unreliable
+	if name == ReliableChannel {
+		d = p.reliable
 	}
+	p.mu.RUnlock()
+	if d == nil {
+		metrics.RecordDropped(name, "not_open")
+		return errors.New(name + " data channel not ready")
+	}
+	if err := d.Send(data); err != nil {
+		metrics.RecordDropped(name, "error")
+		return err
+	}
+	metrics.RecordSent(name, len(data))
 	return nil
 }
+
 func (m *RTCManager) restartICE(sessionID string) {
 	value, ok := m.peers.Load(sessionID)
 	if !ok {
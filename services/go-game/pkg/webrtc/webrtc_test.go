@@ -0,0 +1,219 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPeer(t *testing.T) *webrtc.PeerConnection {
+	t.Helper()
+	settingEngine := webrtc.SettingEngine{}
+	settingEngine.SetIncludeLoopbackCandidate(true)
+	settingEngine.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	settingEngine.SetInterfaceFilter(func(iface string) bool { return iface == "lo" })
+	pc, err := webrtc.NewAPI(webrtc.WithSettingEngine(settingEngine)).NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	t.Cleanup(func() { pc.Close() })
+	return pc
+}
+
+func unreliableInit() *webrtc.DataChannelInit {
+	ordered, retransmits := false, uint16(0)
+	return &webrtc.DataChannelInit{Ordered: &ordered, MaxRetransmits: &retransmits}
+}
+
+func TestCheckChannel(t *testing.T) {
+	ordered, unordered := true, false
+	zero, three := uint16(0), uint16(3)
+	lifetime := uint16(100)
+
+	tests := []struct {
+		name    string
+		label   string
+		init    *webrtc.DataChannelInit
+		wantErr bool
+	}{
+		{name: "reliable", label: ReliableChannel},
+		{name: "unreliable", label: UnreliableChannel, init: unreliableInit()},
+		{name: "unknown label", label: "chat", wantErr: true},
+		{name: "reliable unordered", label: ReliableChannel, init: &webrtc.DataChannelInit{Ordered: &unordered}, wantErr: true},
+		{name: "reliable with retransmit limit", label: ReliableChannel, init: &webrtc.DataChannelInit{MaxRetransmits: &three}, wantErr: true},
+		{name: "reliable with packet lifetime", label: ReliableChannel, init: &webrtc.DataChannelInit{MaxPacketLifeTime: &lifetime}, wantErr: true},
+		{name: "unreliable ordered", label: UnreliableChannel, init: &webrtc.DataChannelInit{Ordered: &ordered, MaxRetransmits: &zero}, wantErr: true},
+		{name: "unreliable retransmitted", label: UnreliableChannel, init: &webrtc.DataChannelInit{Ordered: &unordered, MaxRetransmits: &three}, wantErr: true},
+		{name: "unreliable without limit", label: UnreliableChannel, init: &webrtc.DataChannelInit{Ordered: &unordered}, wantErr: true},
+	}
+
+	pc := newTestPeer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := pc.CreateDataChannel(tt.label, tt.init)
+			require.NoError(t, err)
+			if tt.wantErr {
+				assert.Error(t, checkChannel(d))
+			} else {
+				assert.NoError(t, checkChannel(d))
+			}
+		})
+	}
+}
+
+// received is a message that arrived at the client, by channel label.
+type received struct {
+	channel string
+	data    string
+}
+
+// connectTestPeer connects a server side peer of playerID in gameID to a
+// client over loopback, with both channels open. What the client receives
+// is sent to the returned channel.
+func connectTestPeer(t *testing.T, gameID, playerID string) (*PeerConnection, <-chan received) {
+	t.Helper()
+	server, client := newTestPeer(t), newTestPeer(t)
+	messages := make(chan received, 16)
+	client.OnDataChannel(func(d *webrtc.DataChannel) {
+		d.OnMessage(func(msg webrtc.DataChannelMessage) {
+			messages <- received{channel: d.Label(), data: string(msg.Data)}
+		})
+	})
+
+	peer := &PeerConnection{PeerConnection: server, PlayerID: playerID, GameID: gameID}
+	opened := make(chan struct{}, 2)
+	for _, d := range []struct {
+		label string
+		init  *webrtc.DataChannelInit
+	}{{ReliableChannel, nil}, {UnreliableChannel, unreliableInit()}} {
+		channel, err := server.CreateDataChannel(d.label, d.init)
+		require.NoError(t, err)
+		channel.OnOpen(func() {
+			peer.setChannel(d.label, channel)
+			opened <- struct{}{}
+		})
+	}
+
+	offer, err := server.CreateOffer(nil)
+	require.NoError(t, err)
+	gathered := webrtc.GatheringCompletePromise(server)
+	require.NoError(t, server.SetLocalDescription(offer))
+	<-gathered
+	require.NoError(t, client.SetRemoteDescription(*server.LocalDescription()))
+	answer, err := client.CreateAnswer(nil)
+	require.NoError(t, err)
+	gathered = webrtc.GatheringCompletePromise(client)
+	require.NoError(t, client.SetLocalDescription(answer))
+	<-gathered
+	require.NoError(t, server.SetRemoteDescription(*client.LocalDescription()))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-opened:
+		case <-time.After(10 * time.Second):
+			t.Fatal("data channels did not open")
+		}
+	}
+	return peer, messages
+}
+
+func receive(t *testing.T, messages <-chan received) received {
+	t.Helper()
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing received")
+		return received{}
+	}
+}
+
+// counter reads a data channel counter from the default registry.
+func counter(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			if hasLabels(m, labels) {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func hasLabels(m *dto.Metric, labels map[string]string) bool {
+	matched := 0
+	for _, l := range m.GetLabel() {
+		if v, ok := labels[l.GetName()]; ok {
+			if v != l.GetValue() {
+				return false
+			}
+			matched++
+		}
+	}
+	return matched == len(labels)
+}
+
+func TestRTCManager_SendRouting(t *testing.T) {
+	m := &RTCManager{}
+	peer, messages := connectTestPeer(t, "game-1", "alice")
+	m.peers.Store("session-1", peer)
+	// alice's connection to a game she has left is still around.
+	stale := &PeerConnection{PlayerID: "alice", GameID: "game-0"}
+	m.peers.Store("session-0", stale)
+
+	sent := func(channel string) float64 {
+		return counter(t, "game_datachannel_messages_total", map[string]string{"channel": channel, "direction": "out"})
+	}
+	sentBytes := func(channel string) float64 {
+		return counter(t, "game_datachannel_bytes_total", map[string]string{"channel": channel, "direction": "out"})
+	}
+	reliable, unreliable := sent(ReliableChannel), sent(UnreliableChannel)
+	reliableBytes := sentBytes(ReliableChannel)
+
+	require.NoError(t, m.SendReliable("game-1", "alice", []byte("loot")))
+	assert.Equal(t, received{channel: ReliableChannel, data: "loot"}, receive(t, messages))
+	require.NoError(t, m.SendUnreliable("game-1", "alice", []byte("state")))
+	assert.Equal(t, received{channel: UnreliableChannel, data: "state"}, receive(t, messages))
+
+	assert.Equal(t, reliable+1, sent(ReliableChannel))
+	assert.Equal(t, unreliable+1, sent(UnreliableChannel))
+	assert.Equal(t, reliableBytes+4, sentBytes(ReliableChannel))
+
+	assert.Error(t, m.SendReliable("game-2", "alice", []byte("x")), "alice isn't in game-2")
+	assert.Error(t, m.SendReliable("game-1", "bob", []byte("x")))
+
+	// The stale connection has no open channels; sending to it is a drop.
+	dropped := func(channel string) float64 {
+		return counter(t, "game_datachannel_dropped_total", map[string]string{"channel": channel, "reason": "not_open"})
+	}
+	before := dropped(UnreliableChannel)
+	assert.Error(t, m.SendUnreliable("game-0", "alice", []byte("x")))
+	assert.Equal(t, before+1, dropped(UnreliableChannel))
+	assert.Equal(t, unreliable+1, sent(UnreliableChannel), "dropped messages aren't counted as sent")
+}
+
+func TestRTCManager_BroadcastToGame(t *testing.T) {
+	m := &RTCManager{}
+	alice, aliceMessages := connectTestPeer(t, "game-1", "alice")
+	bob, bobMessages := connectTestPeer(t, "game-2", "bob")
+	m.peers.Store("session-1", alice)
+	m.peers.Store("session-2", bob)
+
+	m.BroadcastToGame("game-1", []byte("event"))
+
+	assert.Equal(t, received{channel: ReliableChannel, data: "event"}, receive(t, aliceMessages))
+	select {
+	case msg := <-bobMessages:
+		t.Fatalf("bob got %v from another game", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
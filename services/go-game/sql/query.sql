@@ -12,6 +12,9 @@ RETURNING *;
 -- name: GetCharacterByID :one
 SELECT * FROM character WHERE id = $1;
 
+-- name: GetAccountCharacter :one
+SELECT * FROM character WHERE id = $1 AND account_id = $2;
+
 -- name: GetCharacterWithDetails :one
 SELECT 
   c.*,